@@ -0,0 +1,88 @@
+package integration
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	rdsdataapi "github.com/aws/aws-sdk-go-v2/service/rdsdata"
+	"github.com/shogo82148/go-rdsdata"
+	"github.com/shogo82148/go-rdsdata/rdsdatatest"
+)
+
+// runCommenterTest records the statements with sqlcommenter comments via RDS Data API,
+// and replays them with another trace context.
+func runCommenterTest(t *testing.T, resourceArn, secretArn string) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	golden := filepath.Join(t.TempDir(), "golden.json")
+
+	run := func(client rdsdata.Client, traceparent string) {
+		t.Helper()
+		connector := rdsdata.NewConnector(&rdsdata.Config{
+			ResourceArn: resourceArn,
+			SecretArn:   secretArn,
+			Client:      client,
+			Commenter:   &rdsdata.Commenter{Application: "go-rdsdata-integration"},
+		})
+		db := sql.OpenDB(connector)
+		defer func() {
+			if err := db.Close(); err != nil {
+				t.Fatal(err)
+			}
+		}()
+
+		ctx := rdsdata.WithCommentTags(ctx, map[string]string{"traceparent": traceparent})
+		var one int64
+		if err := db.QueryRowContext(ctx, "SELECT 1 AS one").Scan(&one); err != nil {
+			t.Fatal(err)
+		}
+		if one != 1 {
+			t.Errorf("unexpected result: %d", one)
+		}
+
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := tx.QueryRowContext(ctx, "SELECT 1 AS one;").Scan(&one); err != nil {
+			t.Fatal(err)
+		}
+		if err := tx.Commit(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// record the statements sent to RDS Data API.
+	awsConfig, err := config.LoadDefaultConfig(ctx, config.WithRegion(os.Getenv("AWS_REGION")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder := rdsdatatest.NewRecorder(rdsdataapi.NewFromConfig(awsConfig))
+	run(recorder, "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	if err := recorder.Save(golden); err != nil {
+		t.Fatal(err)
+	}
+
+	// the comments differ from the recorded ones, but the statements still match.
+	replayer, err := rdsdatatest.NewReplayer(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	run(replayer, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if err := replayer.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMySQL_Commenter(t *testing.T) {
+	runCommenterTest(t, os.Getenv("RDSDATA_MYSQL_RESOURCE_ARN"), os.Getenv("RDSDATA_MYSQL_SECRET_ARN"))
+}
+
+func TestPostgres_Commenter(t *testing.T) {
+	runCommenterTest(t, os.Getenv("RDSDATA_POSTGRES_RESOURCE_ARN"), os.Getenv("RDSDATA_POSTGRES_SECRET_ARN"))
+}
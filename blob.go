@@ -0,0 +1,260 @@
+package rdsdata
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"unicode/utf8"
+)
+
+// DefaultChunkSize is the default size of the chunks of LargeObject.
+// The Data API limits the sizes of the requests and the responses,
+// so large values are written and read in chunks.
+const DefaultChunkSize = 256 * 1024
+
+// RowQueryer is the interface to query a row, implemented by *sql.DB, *sql.Conn and *sql.Tx.
+type RowQueryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// LargeObject is a BLOB or TEXT column of a row, which is too large to send in a Data API call.
+//
+//	obj := &rdsdata.LargeObject{
+//		Engine: rdsdata.EngineMySQL,
+//		Table:  "files",
+//		Column: "body",
+//		Where:  "id = ?",
+//		Args:   []any{id},
+//	}
+//	n, err := obj.Write(ctx, tx, file)
+//
+// Table, Column and Where are embedded in the statements as is, so they must not come from untrusted input.
+type LargeObject struct {
+	// Engine is the database engine: EngineMySQL or EnginePostgres.
+	Engine Engine
+
+	// Table is the name of the table.
+	Table string
+
+	// Column is the name of the column.
+	Column string
+
+	// Where is the condition that selects the row, e.g. "id = ?" for MySQL and "id = $1" for PostgreSQL.
+	Where string
+
+	// Args are the arguments of Where.
+	Args []any
+
+	// Text reports whether the column is a text type, such as TEXT.
+	// The chunks of text columns are split on UTF-8 boundaries and sent as strings,
+	// and the windows of reading are counted in characters.
+	Text bool
+
+	// ChunkSize is the size of the chunks in bytes for writing,
+	// and in bytes (or characters for text columns) for reading.
+	// The default is DefaultChunkSize.
+	ChunkSize int
+}
+
+func (o *LargeObject) chunkSize() int {
+	if o.ChunkSize > 0 {
+		return o.ChunkSize
+	}
+	return DefaultChunkSize
+}
+
+// placeholder returns the placeholder of the n-th argument, counted from 1.
+func (o *LargeObject) placeholder(n int) string {
+	if o.Engine == EnginePostgres {
+		return "$" + strconv.Itoa(n)
+	}
+	return "?"
+}
+
+func (o *LargeObject) validate() error {
+	if o.Engine != EngineMySQL && o.Engine != EnginePostgres {
+		return fmt.Errorf("rdsdata: unsupported engine for large objects: %q", o.Engine)
+	}
+	if o.Table == "" || o.Column == "" || o.Where == "" {
+		return errors.New("rdsdata: Table, Column and Where of the large object are required")
+	}
+	return nil
+}
+
+// Write replaces the value of the column with the content of r.
+// The first chunk replaces the value, and the following chunks are appended by UPDATE statements,
+// so Write should be called in a transaction to avoid exposing the partial value.
+// It returns the number of bytes written, and sql.ErrNoRows if no row matches Where.
+func (o *LargeObject) Write(ctx context.Context, tx *sql.Tx, r io.Reader) (int64, error) {
+	if err := o.validate(); err != nil {
+		return 0, err
+	}
+
+	// the arguments of Where come first, because PostgreSQL numbers them in Where.
+	// MySQL binds the placeholders in the order, so the chunk comes first for it.
+	var set, appendChunk string
+	if o.Engine == EnginePostgres {
+		p := o.placeholder(len(o.Args) + 1)
+		set = fmt.Sprintf("UPDATE %s SET %s = %s WHERE %s", o.Table, o.Column, p, o.Where)
+		appendChunk = fmt.Sprintf("UPDATE %s SET %s = %s || %s WHERE %s", o.Table, o.Column, o.Column, p, o.Where)
+	} else {
+		set = fmt.Sprintf("UPDATE %s SET %s = ? WHERE %s", o.Table, o.Column, o.Where)
+		appendChunk = fmt.Sprintf("UPDATE %s SET %s = CONCAT(%s, ?) WHERE %s", o.Table, o.Column, o.Column, o.Where)
+	}
+	args := func(chunk []byte) []any {
+		var v any = chunk
+		if o.Text {
+			v = string(chunk)
+		}
+		if o.Engine == EnginePostgres {
+			return append(append([]any{}, o.Args...), v)
+		}
+		return append([]any{v}, o.Args...)
+	}
+
+	buf := make([]byte, o.chunkSize())
+	var carry int // the bytes of an incomplete UTF-8 sequence carried to the next chunk
+	var written int64
+	first := true
+	for {
+		n, readErr := io.ReadFull(r, buf[carry:])
+		n += carry
+		if readErr != nil && readErr != io.EOF && readErr != io.ErrUnexpectedEOF {
+			return written, readErr
+		}
+		eof := readErr != nil
+
+		chunk := buf[:n]
+		carry = 0
+		if o.Text && !eof {
+			chunk, carry = splitUTF8(chunk)
+		}
+		if len(chunk) > 0 || first {
+			query := appendChunk
+			if first {
+				query = set
+			}
+			result, err := tx.ExecContext(ctx, query, args(chunk)...)
+			if err != nil {
+				return written, err
+			}
+			if first {
+				if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+					if err := o.checkExists(ctx, tx); err != nil {
+						return written, err
+					}
+				}
+			}
+			written += int64(len(chunk))
+			first = false
+		}
+		if eof {
+			return written, nil
+		}
+		copy(buf, buf[len(chunk):n])
+	}
+}
+
+// checkExists returns sql.ErrNoRows if no row matches Where.
+// MySQL counts only the changed rows as affected, so rewriting the same value affects no rows,
+// and the existence of the row is checked by a query.
+func (o *LargeObject) checkExists(ctx context.Context, tx *sql.Tx) error {
+	if o.Engine == EnginePostgres {
+		return sql.ErrNoRows
+	}
+	var one int
+	query := fmt.Sprintf("SELECT 1 FROM %s WHERE %s LIMIT 1", o.Table, o.Where)
+	return tx.QueryRowContext(ctx, query, o.Args...).Scan(&one)
+}
+
+// splitUTF8 splits the incomplete UTF-8 sequence at the end of b.
+func splitUTF8(b []byte) ([]byte, int) {
+	// a UTF-8 sequence is at most 4 bytes, so check the last 3 bytes.
+	for i := 1; i < utf8.UTFMax && i <= len(b); i++ {
+		c := b[len(b)-i]
+		if c < utf8.RuneSelf {
+			// ASCII
+			return b, 0
+		}
+		if utf8.RuneStart(c) {
+			if utf8.FullRune(b[len(b)-i:]) {
+				return b, 0
+			}
+			return b[:len(b)-i], i
+		}
+	}
+	return b, 0
+}
+
+// NewReader returns a reader that reads the value of the column in windows of SUBSTRING.
+// The value is read by multiple queries, so q should be a transaction to read a consistent value.
+// A NULL value is read as empty.
+func (o *LargeObject) NewReader(ctx context.Context, q RowQueryer) io.Reader {
+	return &largeObjectReader{ctx: ctx, obj: o, q: q, pos: 1}
+}
+
+type largeObjectReader struct {
+	ctx context.Context
+	obj *LargeObject
+	q   RowQueryer
+	pos int // the position of the next window, counted from 1.
+	buf []byte
+	eof bool
+	err error
+}
+
+func (r *largeObjectReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		if r.eof {
+			return 0, io.EOF
+		}
+		r.err = r.fetch()
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// fetch reads the next window.
+func (r *largeObjectReader) fetch() error {
+	o := r.obj
+	if err := o.validate(); err != nil {
+		return err
+	}
+	size := o.chunkSize()
+
+	var query string
+	var args []any
+	if o.Engine == EnginePostgres {
+		n := len(o.Args)
+		query = fmt.Sprintf("SELECT SUBSTRING(%s FROM %s FOR %s) FROM %s WHERE %s",
+			o.Column, o.placeholder(n+1), o.placeholder(n+2), o.Table, o.Where)
+		args = append(append([]any{}, o.Args...), int64(r.pos), int64(size))
+	} else {
+		query = fmt.Sprintf("SELECT SUBSTRING(%s, ?, ?) FROM %s WHERE %s", o.Column, o.Table, o.Where)
+		args = append([]any{int64(r.pos), int64(size)}, o.Args...)
+	}
+
+	var window []byte
+	if err := r.q.QueryRowContext(r.ctx, query, args...).Scan(&window); err != nil {
+		return err
+	}
+
+	// the window is counted in characters for text columns.
+	length := len(window)
+	if o.Text {
+		length = utf8.RuneCount(window)
+	}
+	if length < size {
+		r.eof = true
+	}
+	r.pos += length
+	r.buf = window
+	return nil
+}
@@ -0,0 +1,272 @@
+package rdsdata
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata"
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata/types"
+)
+
+// blobStore is a client that stores the value of a column,
+// and executes the statements of LargeObject on it.
+type blobStore struct {
+	t       *testing.T
+	value   []byte
+	sqls    []string
+	missing bool // the row doesn't exist
+}
+
+func (s *blobStore) client() Client {
+	return &awsClientMock{
+		ExecuteStatementFunc: func(ctx context.Context, input *rdsdata.ExecuteStatementInput, optFns ...func(*rdsdata.Options)) (*rdsdata.ExecuteStatementOutput, error) {
+			query := aws.ToString(input.Sql)
+			s.sqls = append(s.sqls, query)
+			params := map[string]types.Field{}
+			for _, p := range input.Parameters {
+				params[aws.ToString(p.Name)] = p.Value
+			}
+			bytesOf := func(f types.Field) []byte {
+				switch v := f.(type) {
+				case *types.FieldMemberBlobValue:
+					return bytes.Clone(v.Value)
+				case *types.FieldMemberStringValue:
+					return []byte(v.Value)
+				}
+				s.t.Fatalf("unexpected field: %T", f)
+				return nil
+			}
+
+			if s.missing {
+				if query == "SELECT 1 FROM files WHERE id = :1 LIMIT 1" {
+					return &rdsdata.ExecuteStatementOutput{
+						ColumnMetadata: []types.ColumnMetadata{{Name: aws.String("1"), TypeName: aws.String("BIGINT")}},
+					}, nil
+				}
+				return &rdsdata.ExecuteStatementOutput{}, nil
+			}
+
+			switch query {
+			case "UPDATE files SET body = :1 WHERE id = :2":
+				// MySQL counts only the changed rows.
+				value := bytesOf(params["1"])
+				if bytes.Equal(s.value, value) {
+					return &rdsdata.ExecuteStatementOutput{}, nil
+				}
+				s.value = value
+				return &rdsdata.ExecuteStatementOutput{NumberOfRecordsUpdated: 1}, nil
+			case "SELECT 1 FROM files WHERE id = :1 LIMIT 1":
+				return &rdsdata.ExecuteStatementOutput{
+					ColumnMetadata: []types.ColumnMetadata{{Name: aws.String("1"), TypeName: aws.String("BIGINT")}},
+					Records:        [][]types.Field{{&types.FieldMemberLongValue{Value: 1}}},
+				}, nil
+			case "UPDATE files SET body = CONCAT(body, :1) WHERE id = :2":
+				s.value = append(s.value, bytesOf(params["1"])...)
+				return &rdsdata.ExecuteStatementOutput{NumberOfRecordsUpdated: 1}, nil
+			case "UPDATE files SET body = :2 WHERE id = :1":
+				s.value = bytesOf(params["2"])
+				return &rdsdata.ExecuteStatementOutput{NumberOfRecordsUpdated: 1}, nil
+			case "UPDATE files SET body = body || :2 WHERE id = :1":
+				s.value = append(s.value, bytesOf(params["2"])...)
+				return &rdsdata.ExecuteStatementOutput{NumberOfRecordsUpdated: 1}, nil
+			case "SELECT SUBSTRING(body, :1, :2) FROM files WHERE id = :3":
+				return s.substring(params["1"], params["2"]), nil
+			case "SELECT SUBSTRING(body FROM :2 FOR :3) FROM files WHERE id = :1":
+				return s.substring(params["2"], params["3"]), nil
+			}
+			s.t.Fatalf("unexpected SQL: %s", query)
+			return nil, nil
+		},
+		BeginTransactionFunc: func(ctx context.Context, input *rdsdata.BeginTransactionInput, optFns ...func(*rdsdata.Options)) (*rdsdata.BeginTransactionOutput, error) {
+			return &rdsdata.BeginTransactionOutput{TransactionId: aws.String("tx")}, nil
+		},
+		CommitTransactionFunc: func(ctx context.Context, input *rdsdata.CommitTransactionInput, optFns ...func(*rdsdata.Options)) (*rdsdata.CommitTransactionOutput, error) {
+			return &rdsdata.CommitTransactionOutput{}, nil
+		},
+	}
+}
+
+func (s *blobStore) substring(pos, length types.Field) *rdsdata.ExecuteStatementOutput {
+	start := int(pos.(*types.FieldMemberLongValue).Value) - 1
+	end := min(start+int(length.(*types.FieldMemberLongValue).Value), len(s.value))
+	start = min(start, len(s.value))
+	return &rdsdata.ExecuteStatementOutput{
+		ColumnMetadata: []types.ColumnMetadata{{Name: aws.String("SUBSTRING"), TypeName: aws.String("BLOB")}},
+		Records: [][]types.Field{
+			{&types.FieldMemberBlobValue{Value: bytes.Clone(s.value[start:end])}},
+		},
+	}
+}
+
+func TestLargeObject(t *testing.T) {
+	tests := []struct {
+		name   string
+		engine Engine
+		where  string
+		writes []string
+	}{
+		{
+			name:   "mysql",
+			engine: EngineMySQL,
+			where:  "id = ?",
+			writes: []string{
+				"UPDATE files SET body = :1 WHERE id = :2",
+				"UPDATE files SET body = CONCAT(body, :1) WHERE id = :2",
+				"UPDATE files SET body = CONCAT(body, :1) WHERE id = :2",
+			},
+		},
+		{
+			name:   "postgres",
+			engine: EnginePostgres,
+			where:  "id = $1",
+			writes: []string{
+				"UPDATE files SET body = :2 WHERE id = :1",
+				"UPDATE files SET body = body || :2 WHERE id = :1",
+				"UPDATE files SET body = body || :2 WHERE id = :1",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &blobStore{t: t}
+			db := sql.OpenDB(NewConnector(&Config{
+				ResourceArn: "arn:aws:rds:us-east-1:123456789012:cluster:cluster",
+				SecretArn:   "arn:aws:secretsmanager:us-east-1:123456789012:secret:secretArn",
+				Engine:      tt.engine,
+				Client:      store.client(),
+			}))
+			defer db.Close()
+			ctx := context.Background()
+
+			obj := &LargeObject{
+				Engine:    tt.engine,
+				Table:     "files",
+				Column:    "body",
+				Where:     tt.where,
+				Args:      []any{int64(1)},
+				ChunkSize: 4,
+			}
+			tx, err := db.BeginTx(ctx, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			n, err := obj.Write(ctx, tx, strings.NewReader("0123456789"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := tx.Commit(); err != nil {
+				t.Fatal(err)
+			}
+			if n != 10 {
+				t.Errorf("unexpected written bytes: %d", n)
+			}
+			if string(store.value) != "0123456789" {
+				t.Errorf("unexpected value: %q", store.value)
+			}
+			if strings.Join(store.sqls, "\n") != strings.Join(tt.writes, "\n") {
+				t.Errorf("unexpected SQL:\n got: %q\nwant: %q", store.sqls, tt.writes)
+			}
+
+			store.sqls = nil
+			got, err := io.ReadAll(obj.NewReader(ctx, db))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != "0123456789" {
+				t.Errorf("unexpected read value: %q", got)
+			}
+			if len(store.sqls) != 3 {
+				t.Errorf("unexpected number of windows: %d", len(store.sqls))
+			}
+		})
+	}
+}
+
+func TestLargeObject_Empty(t *testing.T) {
+	store := &blobStore{t: t, value: []byte("old")}
+	db := sql.OpenDB(NewConnector(&Config{
+		ResourceArn: "arn:aws:rds:us-east-1:123456789012:cluster:cluster",
+		SecretArn:   "arn:aws:secretsmanager:us-east-1:123456789012:secret:secretArn",
+		Engine:      EngineMySQL,
+		Client:      store.client(),
+	}))
+	defer db.Close()
+	ctx := context.Background()
+
+	obj := &LargeObject{Engine: EngineMySQL, Table: "files", Column: "body", Where: "id = ?", Args: []any{int64(1)}}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := obj.Write(ctx, tx, strings.NewReader("")); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.value) != 0 {
+		t.Errorf("the value is not cleared: %q", store.value)
+	}
+}
+
+func TestSplitUTF8(t *testing.T) {
+	tests := []struct {
+		in    string
+		want  string
+		carry int
+	}{
+		{"abc", "abc", 0},
+		{"", "", 0},
+		{"aあ", "aあ", 0},
+		{"a" + "あ"[:1], "a", 1},
+		{"a" + "あ"[:2], "a", 2},
+		{"a" + "😀"[:3], "a", 3},
+	}
+	for _, tt := range tests {
+		got, carry := splitUTF8([]byte(tt.in))
+		if string(got) != tt.want || carry != tt.carry {
+			t.Errorf("splitUTF8(%q) = %q, %d, want %q, %d", tt.in, got, carry, tt.want, tt.carry)
+		}
+	}
+}
+
+func TestLargeObject_SameValue(t *testing.T) {
+	tests := []struct {
+		name    string
+		missing bool
+		err     error
+	}{
+		{name: "same value", missing: false, err: nil},
+		{name: "missing row", missing: true, err: sql.ErrNoRows},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &blobStore{t: t, value: []byte("same"), missing: tt.missing}
+			db := sql.OpenDB(NewConnector(&Config{
+				ResourceArn: "arn:aws:rds:us-east-1:123456789012:cluster:cluster",
+				SecretArn:   "arn:aws:secretsmanager:us-east-1:123456789012:secret:secretArn",
+				Engine:      EngineMySQL,
+				Client:      store.client(),
+			}))
+			defer db.Close()
+			ctx := context.Background()
+
+			obj := &LargeObject{Engine: EngineMySQL, Table: "files", Column: "body", Where: "id = ?", Args: []any{int64(1)}}
+			tx, err := db.BeginTx(ctx, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := obj.Write(ctx, tx, strings.NewReader("same")); err != tt.err {
+				t.Errorf("unexpected error: %v, want %v", err, tt.err)
+			}
+			if err := tx.Commit(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
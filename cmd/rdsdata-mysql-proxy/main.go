@@ -0,0 +1,131 @@
+// The rdsdata-mysql-proxy command is a proxy that speaks the MySQL protocol
+// and executes the statements through the RDS Data API.
+// It allows the MySQL clients and tools to connect to Aurora clusters
+// that are only reachable by the Data API.
+//
+// Usage:
+//
+//	rdsdata-mysql-proxy [flags] [DSN]
+//
+// The DSN is in the format that rdsdata.ParseDSN understands, e.g.
+//
+//	rdsdata-mysql-proxy 'rdsdata://?resource_arn=arn:aws:rds:...&secret_arn=arn:aws:secretsmanager:...'
+//
+// The connection can also be configured by the flags:
+//
+//	rdsdata-mysql-proxy -resource-arn arn:aws:rds:... -secret-arn arn:aws:secretsmanager:... -region us-east-1
+//
+// Then connect to the proxy with any MySQL client:
+//
+//	mysql -h 127.0.0.1 -P 3306 -u root
+//
+// COM_QUERY, COM_STMT_PREPARE and COM_STMT_EXECUTE are executed on a connection of the driver.
+// BEGIN, COMMIT and ROLLBACK are mapped to the Data API transactions,
+// and USE switches the database of the connection.
+// The other statements that change the session state (e.g. SET) have no effect,
+// because the Data API does not keep sessions between the calls.
+//
+// The proxy has no TLS support. It accepts any client unless -user or -password is set,
+// so do not listen on the public addresses.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/shogo82148/go-rdsdata"
+)
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "rdsdata-mysql-proxy:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	flags := flag.NewFlagSet("rdsdata-mysql-proxy", flag.ContinueOnError)
+	var (
+		listen      = flags.String("listen", "127.0.0.1:3306", "the address to listen on")
+		resourceArn = flags.String("resource-arn", "", "the ARN of the Aurora cluster")
+		secretArn   = flags.String("secret-arn", "", "the ARN of the secret")
+		database    = flags.String("database", "", "the default database")
+		region      = flags.String("region", "", "the AWS region (default: the region of the resource ARN)")
+		user        = flags.String("user", "", "the user name that the clients must use")
+		password    = flags.String("password", "", "the password that the clients must use")
+		verbose     = flags.Bool("v", false, "log the connections")
+	)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: rdsdata-mysql-proxy [flags] [DSN]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := newConfig(flags.Arg(0), *resourceArn, *secretArn, *database, *region)
+	if err != nil {
+		return err
+	}
+
+	level := slog.LevelInfo
+	if *verbose {
+		level = slog.LevelDebug
+	}
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	l, err := net.Listen("tcp", *listen)
+	if err != nil {
+		return err
+	}
+	logger.Info("listening", slog.String("addr", l.Addr().String()))
+
+	srv := &server{
+		cfg:      cfg,
+		user:     *user,
+		password: *password,
+		logger:   logger,
+	}
+	return srv.serve(ctx, l)
+}
+
+// newConfig builds the config from the DSN or the flags.
+// The flags override the values in the DSN.
+func newConfig(dsn, resourceArn, secretArn, database, region string) (*rdsdata.Config, error) {
+	cfg := &rdsdata.Config{}
+	if dsn != "" {
+		var err error
+		cfg, err = rdsdata.ParseDSN(dsn)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if resourceArn != "" {
+		cfg.ResourceArn = resourceArn
+	}
+	if secretArn != "" {
+		cfg.SecretArn = secretArn
+	}
+	if database != "" {
+		cfg.Database = database
+	}
+	if region != "" {
+		cfg.AWSRegion = region
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	// The proxy encodes the values of DATE, DATETIME and TIMESTAMP by itself.
+	cfg.ParseTime = false
+	return cfg, nil
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+)
+
+// maxPacketSize is the maximum payload size of a single packet.
+// The larger payloads are split into multiple packets.
+const maxPacketSize = 1<<24 - 1
+
+// packetConn reads and writes the MySQL packets.
+type packetConn struct {
+	r   *bufio.Reader
+	w   *bufio.Writer
+	seq byte
+}
+
+func newPacketConn(rw io.ReadWriter) *packetConn {
+	return &packetConn{
+		r: bufio.NewReader(rw),
+		w: bufio.NewWriter(rw),
+	}
+}
+
+// readPacket reads a payload.
+func (c *packetConn) readPacket() ([]byte, error) {
+	var payload []byte
+	for {
+		var header [4]byte
+		if _, err := io.ReadFull(c.r, header[:]); err != nil {
+			return nil, err
+		}
+		length := int(uint32(header[0]) | uint32(header[1])<<8 | uint32(header[2])<<16)
+		if header[3] != c.seq {
+			return nil, errors.New("packets out of order")
+		}
+		c.seq++
+
+		buf := make([]byte, length)
+		if _, err := io.ReadFull(c.r, buf); err != nil {
+			return nil, err
+		}
+		payload = append(payload, buf...)
+		if length < maxPacketSize {
+			return payload, nil
+		}
+	}
+}
+
+// writePacket writes a payload into the buffer.
+// Call flush to send it.
+func (c *packetConn) writePacket(payload []byte) error {
+	for {
+		length := min(len(payload), maxPacketSize)
+		header := [4]byte{byte(length), byte(length >> 8), byte(length >> 16), c.seq}
+		c.seq++
+		if _, err := c.w.Write(header[:]); err != nil {
+			return err
+		}
+		if _, err := c.w.Write(payload[:length]); err != nil {
+			return err
+		}
+		payload = payload[length:]
+		if length < maxPacketSize {
+			return nil
+		}
+	}
+}
+
+func (c *packetConn) flush() error {
+	return c.w.Flush()
+}
+
+// resetSequence resets the sequence ID at the beginning of a command.
+func (c *packetConn) resetSequence() {
+	c.seq = 0
+}
+
+// appendLengthEncodedInt appends a length-encoded integer.
+func appendLengthEncodedInt(b []byte, n uint64) []byte {
+	switch {
+	case n < 251:
+		return append(b, byte(n))
+	case n < 1<<16:
+		return append(b, 0xfc, byte(n), byte(n>>8))
+	case n < 1<<24:
+		return append(b, 0xfd, byte(n), byte(n>>8), byte(n>>16))
+	default:
+		b = append(b, 0xfe)
+		return binary.LittleEndian.AppendUint64(b, n)
+	}
+}
+
+// appendLengthEncodedString appends a length-encoded string.
+func appendLengthEncodedString(b []byte, s []byte) []byte {
+	b = appendLengthEncodedInt(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+var errMalformedPacket = errors.New("malformed packet")
+
+// reader decodes the fields of a payload.
+type reader struct {
+	buf []byte
+	err error
+}
+
+func (r *reader) bytes(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || n > len(r.buf) {
+		r.err = errMalformedPacket
+		return nil
+	}
+	b := r.buf[:n:n]
+	r.buf = r.buf[n:]
+	return b
+}
+
+func (r *reader) uint8() uint8 {
+	b := r.bytes(1)
+	if b == nil {
+		return 0
+	}
+	return b[0]
+}
+
+func (r *reader) uint16() uint16 {
+	b := r.bytes(2)
+	if b == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint16(b)
+}
+
+func (r *reader) uint32() uint32 {
+	b := r.bytes(4)
+	if b == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint32(b)
+}
+
+func (r *reader) uint64() uint64 {
+	b := r.bytes(8)
+	if b == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint64(b)
+}
+
+func (r *reader) lengthEncodedInt() uint64 {
+	switch first := r.uint8(); first {
+	case 0xfc:
+		return uint64(r.uint16())
+	case 0xfd:
+		b := r.bytes(3)
+		if b == nil {
+			return 0
+		}
+		return uint64(b[0]) | uint64(b[1])<<8 | uint64(b[2])<<16
+	case 0xfe:
+		return r.uint64()
+	default:
+		return uint64(first)
+	}
+}
+
+func (r *reader) lengthEncodedString() []byte {
+	n := r.lengthEncodedInt()
+	if n > math.MaxInt32 {
+		r.err = errMalformedPacket
+		return nil
+	}
+	return r.bytes(int(n))
+}
+
+// nulString reads a NUL-terminated string.
+func (r *reader) nulString() []byte {
+	if r.err != nil {
+		return nil
+	}
+	for i, c := range r.buf {
+		if c == 0 {
+			s := r.buf[:i:i]
+			r.buf = r.buf[i+1:]
+			return s
+		}
+	}
+	r.err = errMalformedPacket
+	return nil
+}
+
+// rest returns the remaining bytes.
+func (r *reader) rest() []byte {
+	return r.bytes(len(r.buf))
+}
@@ -0,0 +1,439 @@
+package main
+
+import (
+	"database/sql/driver"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata/types"
+)
+
+// column types of the MySQL protocol.
+const (
+	typeDecimal    = 0x00
+	typeTiny       = 0x01
+	typeShort      = 0x02
+	typeLong       = 0x03
+	typeFloat      = 0x04
+	typeDouble     = 0x05
+	typeNull       = 0x06
+	typeTimestamp  = 0x07
+	typeLongLong   = 0x08
+	typeInt24      = 0x09
+	typeDate       = 0x0a
+	typeTime       = 0x0b
+	typeDateTime   = 0x0c
+	typeYear       = 0x0d
+	typeVarChar    = 0x0f
+	typeBit        = 0x10
+	typeJSON       = 0xf5
+	typeNewDecimal = 0xf6
+	typeEnum       = 0xf7
+	typeSet        = 0xf8
+	typeTinyBlob   = 0xf9
+	typeMediumBlob = 0xfa
+	typeLongBlob   = 0xfb
+	typeBlob       = 0xfc
+	typeVarString  = 0xfd
+	typeString     = 0xfe
+	typeGeometry   = 0xff
+)
+
+// column flags of the MySQL protocol.
+const (
+	flagNotNull       = 0x0001
+	flagBlob          = 0x0010
+	flagUnsigned      = 0x0020
+	flagBinary        = 0x0080
+	flagAutoIncrement = 0x0200
+)
+
+// character sets of the MySQL protocol.
+const (
+	charsetUTF8MB4 = 255 // utf8mb4_0900_ai_ci
+	charsetBinary  = 63
+)
+
+// column is the definition of a column in a result set.
+type column struct {
+	schema   string
+	table    string
+	orgTable string
+	name     string
+	orgName  string
+	charset  uint16
+	length   uint32
+	typ      byte
+	flags    uint16
+	decimals byte
+}
+
+// newColumn converts the metadata of the Data API into the column definition.
+func newColumn(meta types.ColumnMetadata) *column {
+	col := &column{
+		schema:   aws.ToString(meta.SchemaName),
+		table:    aws.ToString(meta.TableName),
+		orgTable: aws.ToString(meta.TableName),
+		name:     aws.ToString(meta.Label),
+		orgName:  aws.ToString(meta.Name),
+		charset:  charsetBinary,
+		length:   uint32(max(meta.Precision, 0)),
+	}
+	if col.name == "" {
+		col.name = col.orgName
+	}
+
+	typeName := strings.ToUpper(aws.ToString(meta.TypeName))
+	if name, ok := strings.CutSuffix(typeName, " UNSIGNED"); ok {
+		typeName = name
+		col.flags |= flagUnsigned
+	}
+	if meta.Nullable == 0 {
+		col.flags |= flagNotNull
+	}
+	if meta.IsAutoIncrement {
+		col.flags |= flagAutoIncrement
+	}
+
+	binary := false
+	switch typeName {
+	case "TINYINT", "BOOL", "BOOLEAN":
+		col.typ = typeTiny
+	case "SMALLINT":
+		col.typ = typeShort
+	case "MEDIUMINT":
+		col.typ = typeInt24
+	case "INT", "INTEGER":
+		col.typ = typeLong
+	case "BIGINT":
+		col.typ = typeLongLong
+	case "FLOAT":
+		col.typ = typeFloat
+		col.decimals = 0x1f
+	case "DOUBLE", "REAL":
+		col.typ = typeDouble
+		col.decimals = 0x1f
+	case "DECIMAL", "NUMERIC":
+		col.typ = typeNewDecimal
+		col.decimals = byte(meta.Scale)
+	case "DATE":
+		col.typ = typeDate
+	case "TIME":
+		col.typ = typeTime
+		col.decimals = byte(meta.Scale)
+	case "DATETIME":
+		col.typ = typeDateTime
+		col.decimals = byte(meta.Scale)
+	case "TIMESTAMP":
+		col.typ = typeTimestamp
+		col.decimals = byte(meta.Scale)
+	case "YEAR":
+		col.typ = typeYear
+	case "BIT":
+		col.typ = typeBit
+		binary = true
+	case "JSON":
+		col.typ = typeJSON
+	case "CHAR", "ENUM", "SET":
+		col.typ = typeString
+		col.charset = charsetUTF8MB4
+	case "BINARY":
+		col.typ = typeString
+		binary = true
+	case "VARBINARY":
+		col.typ = typeVarString
+		binary = true
+	case "TINYTEXT", "TEXT", "MEDIUMTEXT", "LONGTEXT":
+		col.typ = typeBlob
+		col.flags |= flagBlob
+		col.charset = charsetUTF8MB4
+	case "TINYBLOB", "BLOB", "MEDIUMBLOB", "LONGBLOB":
+		col.typ = typeBlob
+		col.flags |= flagBlob
+		binary = true
+	case "GEOMETRY", "POINT", "LINESTRING", "POLYGON":
+		col.typ = typeGeometry
+		binary = true
+	default:
+		// VARCHAR and the types of other engines are sent as strings.
+		col.typ = typeVarString
+		col.charset = charsetUTF8MB4
+	}
+	if binary {
+		col.flags |= flagBinary
+	}
+	return col
+}
+
+// appendColumnDefinition appends the Protocol::ColumnDefinition41 packet.
+func appendColumnDefinition(b []byte, col *column) []byte {
+	b = appendLengthEncodedString(b, []byte("def"))
+	b = appendLengthEncodedString(b, []byte(col.schema))
+	b = appendLengthEncodedString(b, []byte(col.table))
+	b = appendLengthEncodedString(b, []byte(col.orgTable))
+	b = appendLengthEncodedString(b, []byte(col.name))
+	b = appendLengthEncodedString(b, []byte(col.orgName))
+	b = append(b, 0x0c) // the length of the fixed length fields
+	b = binary.LittleEndian.AppendUint16(b, col.charset)
+	b = binary.LittleEndian.AppendUint32(b, col.length)
+	b = append(b, col.typ)
+	b = binary.LittleEndian.AppendUint16(b, col.flags)
+	b = append(b, col.decimals)
+	b = append(b, 0, 0) // filler
+	return b
+}
+
+// formatText formats a value for the text protocol.
+func formatText(v driver.Value) []byte {
+	switch v := v.(type) {
+	case []byte:
+		return v
+	case string:
+		return []byte(v)
+	case int64:
+		return strconv.AppendInt(nil, v, 10)
+	case uint64:
+		return strconv.AppendUint(nil, v, 10)
+	case float32:
+		return strconv.AppendFloat(nil, float64(v), 'g', -1, 32)
+	case float64:
+		return strconv.AppendFloat(nil, v, 'g', -1, 64)
+	case bool:
+		if v {
+			return []byte("1")
+		}
+		return []byte("0")
+	case time.Time:
+		return []byte(v.Format("2006-01-02 15:04:05.999999"))
+	default:
+		return []byte(fmt.Sprint(v))
+	}
+}
+
+// appendTextRow appends a row of the text protocol.
+func appendTextRow(b []byte, values []driver.Value) []byte {
+	for _, v := range values {
+		if v == nil {
+			b = append(b, 0xfb)
+			continue
+		}
+		b = appendLengthEncodedString(b, formatText(v))
+	}
+	return b
+}
+
+// appendBinaryRow appends a row of the binary protocol.
+func appendBinaryRow(b []byte, columns []*column, values []driver.Value) ([]byte, error) {
+	b = append(b, 0x00)
+
+	// the NULL bitmap has the offset of 2 bits.
+	bitmap := len(b)
+	b = append(b, make([]byte, (len(values)+7+2)/8)...)
+	for i, v := range values {
+		if v == nil {
+			pos := i + 2
+			b[bitmap+pos/8] |= 1 << (pos % 8)
+		}
+	}
+
+	var err error
+	for i, v := range values {
+		if v == nil {
+			continue
+		}
+		b, err = appendBinaryValue(b, columns[i], v)
+		if err != nil {
+			return nil, fmt.Errorf("column %q: %w", columns[i].name, err)
+		}
+	}
+	return b, nil
+}
+
+// appendBinaryValue appends a value of the binary protocol.
+func appendBinaryValue(b []byte, col *column, v driver.Value) ([]byte, error) {
+	switch col.typ {
+	case typeTiny, typeShort, typeYear, typeInt24, typeLong, typeLongLong:
+		n, err := toUint64(v)
+		if err != nil {
+			return nil, err
+		}
+		switch col.typ {
+		case typeTiny:
+			return append(b, byte(n)), nil
+		case typeShort, typeYear:
+			return binary.LittleEndian.AppendUint16(b, uint16(n)), nil
+		case typeInt24, typeLong:
+			return binary.LittleEndian.AppendUint32(b, uint32(n)), nil
+		default:
+			return binary.LittleEndian.AppendUint64(b, n), nil
+		}
+
+	case typeFloat:
+		f, err := toFloat64(v)
+		if err != nil {
+			return nil, err
+		}
+		return binary.LittleEndian.AppendUint32(b, math.Float32bits(float32(f))), nil
+
+	case typeDouble:
+		f, err := toFloat64(v)
+		if err != nil {
+			return nil, err
+		}
+		return binary.LittleEndian.AppendUint64(b, math.Float64bits(f)), nil
+
+	case typeDate, typeDateTime, typeTimestamp:
+		t, err := toTime(v)
+		if err != nil {
+			return nil, err
+		}
+		return appendBinaryDateTime(b, t), nil
+
+	case typeTime:
+		return appendBinaryTime(b, string(formatText(v)))
+	}
+	return appendLengthEncodedString(b, formatText(v)), nil
+}
+
+func toUint64(v driver.Value) (uint64, error) {
+	switch v := v.(type) {
+	case int64:
+		return uint64(v), nil
+	case uint64:
+		return v, nil
+	case bool:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	case []byte:
+		return parseUint64(string(v))
+	case string:
+		return parseUint64(v)
+	}
+	return 0, fmt.Errorf("unexpected integer value: %T", v)
+}
+
+func parseUint64(s string) (uint64, error) {
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return uint64(n), nil
+	}
+	return strconv.ParseUint(s, 10, 64)
+}
+
+func toFloat64(v driver.Value) (float64, error) {
+	switch v := v.(type) {
+	case float32:
+		return float64(v), nil
+	case float64:
+		return v, nil
+	case int64:
+		return float64(v), nil
+	case []byte:
+		return strconv.ParseFloat(string(v), 64)
+	case string:
+		return strconv.ParseFloat(v, 64)
+	}
+	return 0, fmt.Errorf("unexpected float value: %T", v)
+}
+
+func toTime(v driver.Value) (time.Time, error) {
+	var s string
+	switch v := v.(type) {
+	case time.Time:
+		return v, nil
+	case []byte:
+		s = string(v)
+	case string:
+		s = v
+	default:
+		return time.Time{}, fmt.Errorf("unexpected time value: %T", v)
+	}
+	if len(s) == len("2006-01-02") {
+		return time.Parse("2006-01-02", s)
+	}
+	return time.Parse("2006-01-02 15:04:05.999999999", s)
+}
+
+// appendBinaryDateTime appends DATE, DATETIME or TIMESTAMP of the binary protocol.
+func appendBinaryDateTime(b []byte, t time.Time) []byte {
+	micro := uint32(t.Nanosecond() / 1000)
+	switch {
+	case micro != 0:
+		b = append(b, 11)
+	case t.Hour() != 0 || t.Minute() != 0 || t.Second() != 0:
+		b = append(b, 7)
+	case t.Year() != 0 || t.Month() != 0 || t.Day() != 0:
+		b = append(b, 4)
+	default:
+		return append(b, 0)
+	}
+	length := b[len(b)-1]
+	b = binary.LittleEndian.AppendUint16(b, uint16(t.Year()))
+	b = append(b, byte(t.Month()), byte(t.Day()))
+	if length == 4 {
+		return b
+	}
+	b = append(b, byte(t.Hour()), byte(t.Minute()), byte(t.Second()))
+	if length == 7 {
+		return b
+	}
+	return binary.LittleEndian.AppendUint32(b, micro)
+}
+
+// appendBinaryTime appends TIME of the binary protocol.
+// s is in the format of "[-]hhh:mm:ss[.ffffff]".
+func appendBinaryTime(b []byte, s string) ([]byte, error) {
+	negative := false
+	if rest, ok := strings.CutPrefix(s, "-"); ok {
+		negative = true
+		s = rest
+	}
+	s, frac, _ := strings.Cut(s, ".")
+	parts := strings.Split(s, ":")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("unexpected time value: %q", s)
+	}
+	var hms [3]uint64
+	for i, p := range parts {
+		n, err := strconv.ParseUint(p, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected time value: %q", s)
+		}
+		hms[i] = n
+	}
+	var micro uint64
+	if frac != "" {
+		frac = (frac + "000000")[:6]
+		n, err := strconv.ParseUint(frac, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected time value: %q", s)
+		}
+		micro = n
+	}
+
+	if hms == [3]uint64{} && micro == 0 {
+		return append(b, 0), nil
+	}
+	if micro != 0 {
+		b = append(b, 12)
+	} else {
+		b = append(b, 8)
+	}
+	if negative {
+		b = append(b, 1)
+	} else {
+		b = append(b, 0)
+	}
+	b = binary.LittleEndian.AppendUint32(b, uint32(hms[0]/24))
+	b = append(b, byte(hms[0]%24), byte(hms[1]), byte(hms[2]))
+	if micro != 0 {
+		b = binary.LittleEndian.AppendUint32(b, uint32(micro))
+	}
+	return b, nil
+}
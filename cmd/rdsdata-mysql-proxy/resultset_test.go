@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata/types"
+	"github.com/shogo82148/go-rdsdata/rdsdatatest"
+)
+
+func TestNewColumn(t *testing.T) {
+	testCases := []struct {
+		typeName string
+		typ      byte
+		flags    uint16
+		charset  uint16
+	}{
+		{"INT", typeLong, flagNotNull, charsetBinary},
+		{"BIGINT UNSIGNED", typeLongLong, flagNotNull | flagUnsigned, charsetBinary},
+		{"DOUBLE", typeDouble, flagNotNull, charsetBinary},
+		{"DECIMAL", typeNewDecimal, flagNotNull, charsetBinary},
+		{"DATETIME", typeDateTime, flagNotNull, charsetBinary},
+		{"VARCHAR", typeVarString, flagNotNull, charsetUTF8MB4},
+		{"TEXT", typeBlob, flagNotNull | flagBlob, charsetUTF8MB4},
+		{"VARBINARY", typeVarString, flagNotNull | flagBinary, charsetBinary},
+		{"int8", typeVarString, flagNotNull, charsetUTF8MB4},
+	}
+
+	for _, tc := range testCases {
+		col := newColumn(rdsdatatest.Column("c", tc.typeName))
+		if col.typ != tc.typ || col.flags != tc.flags || col.charset != tc.charset {
+			t.Errorf("newColumn(%q) = {typ: %#x, flags: %#x, charset: %d}, want {typ: %#x, flags: %#x, charset: %d}",
+				tc.typeName, col.typ, col.flags, col.charset, tc.typ, tc.flags, tc.charset)
+		}
+	}
+
+	nullable := rdsdatatest.Column("c", "INT")
+	nullable.Nullable = 1
+	if col := newColumn(nullable); col.flags&flagNotNull != 0 {
+		t.Errorf("nullable column has NOT_NULL flag: %#x", col.flags)
+	}
+}
+
+func TestAppendBinaryValue(t *testing.T) {
+	testCases := []struct {
+		typeName string
+		value    driver.Value
+		want     []byte
+	}{
+		{"TINYINT", int64(-1), []byte{0xff}},
+		{"SMALLINT", int64(2), []byte{0x02, 0x00}},
+		{"INT", int64(3), []byte{0x03, 0x00, 0x00, 0x00}},
+		{"BIGINT UNSIGNED", uint64(1 << 63), []byte{0, 0, 0, 0, 0, 0, 0, 0x80}},
+		{"FLOAT", float32(1), []byte{0x00, 0x00, 0x80, 0x3f}},
+		{"DATE", []byte("2024-01-02"), []byte{4, 0xe8, 0x07, 1, 2}},
+		{"DATETIME", []byte("2024-01-02 03:04:05"), []byte{7, 0xe8, 0x07, 1, 2, 3, 4, 5}},
+		{"DATETIME", []byte("2024-01-02 03:04:05.5"), []byte{11, 0xe8, 0x07, 1, 2, 3, 4, 5, 0x20, 0xa1, 0x07, 0x00}},
+		{"TIMESTAMP", time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), []byte{4, 0xe8, 0x07, 1, 2}},
+		{"TIME", []byte("-25:00:01"), []byte{8, 1, 1, 0, 0, 0, 1, 0, 1}},
+		{"TIME", []byte("00:00:00"), []byte{0}},
+		{"DECIMAL", []byte("1.50"), []byte("\x041.50")},
+		{"VARCHAR", []byte("alice"), []byte("\x05alice")},
+	}
+
+	for _, tc := range testCases {
+		col := newColumn(types.ColumnMetadata{TypeName: &tc.typeName})
+		got, err := appendBinaryValue(nil, col, tc.value)
+		if err != nil {
+			t.Errorf("%s %v: %v", tc.typeName, tc.value, err)
+			continue
+		}
+		if !bytes.Equal(got, tc.want) {
+			t.Errorf("%s %v: got %x, want %x", tc.typeName, tc.value, got, tc.want)
+		}
+	}
+}
+
+func TestReadBinaryValue(t *testing.T) {
+	testCases := []struct {
+		paramType uint16
+		data      []byte
+		want      driver.Value
+	}{
+		{typeTiny, []byte{0xff}, int64(-1)},
+		{typeTiny | paramUnsigned<<8, []byte{0xff}, int64(255)},
+		{typeLongLong | paramUnsigned<<8, []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, "18446744073709551615"},
+		{typeDouble, []byte{0, 0, 0, 0, 0, 0, 0xf8, 0x3f}, float64(1.5)},
+		{typeDateTime, []byte{7, 0xe8, 0x07, 1, 2, 3, 4, 5}, "2024-01-02 03:04:05"},
+		{typeDate, []byte{4, 0xe8, 0x07, 1, 2}, "2024-01-02"},
+		{typeTime, []byte{8, 1, 1, 0, 0, 0, 1, 0, 1}, "-25:00:01"},
+		{typeString, []byte("\x05alice"), "alice"},
+		{typeBlob, []byte("\x02\xff\xfe"), []byte{0xff, 0xfe}},
+	}
+
+	for _, tc := range testCases {
+		got, err := readBinaryValue(&reader{buf: tc.data}, tc.paramType)
+		if err != nil {
+			t.Errorf("%#x %x: %v", tc.paramType, tc.data, err)
+			continue
+		}
+		if b, ok := tc.want.([]byte); ok {
+			if !bytes.Equal(got.([]byte), b) {
+				t.Errorf("%#x %x: got %v, want %v", tc.paramType, tc.data, got, tc.want)
+			}
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("%#x %x: got %v, want %v", tc.paramType, tc.data, got, tc.want)
+		}
+	}
+}
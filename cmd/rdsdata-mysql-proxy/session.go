@@ -0,0 +1,517 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"database/sql/driver"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata/types"
+	"github.com/shogo82148/go-rdsdata"
+	"github.com/shogo82148/go-rdsdata/internal/sqllex"
+)
+
+// capability flags of the MySQL protocol.
+const (
+	clientLongPassword               = 0x00000001
+	clientFoundRows                  = 0x00000002
+	clientLongFlag                   = 0x00000004
+	clientConnectWithDB              = 0x00000008
+	clientProtocol41                 = 0x00000200
+	clientTransactions               = 0x00002000
+	clientSecureConnection           = 0x00008000
+	clientMultiResults               = 0x00020000
+	clientPluginAuth                 = 0x00080000
+	clientConnectAttrs               = 0x00100000
+	clientPluginAuthLenencClientData = 0x00200000
+)
+
+const serverCapabilities uint32 = clientLongPassword | clientFoundRows | clientLongFlag | clientConnectWithDB |
+	clientProtocol41 | clientTransactions | clientSecureConnection | clientMultiResults |
+	clientPluginAuth | clientConnectAttrs | clientPluginAuthLenencClientData
+
+// status flags of the MySQL protocol.
+const (
+	statusInTrans    = 0x0001
+	statusAutocommit = 0x0002
+)
+
+// commands of the MySQL protocol.
+const (
+	comQuit             = 0x01
+	comInitDB           = 0x02
+	comQuery            = 0x03
+	comPing             = 0x0e
+	comStmtPrepare      = 0x16
+	comStmtExecute      = 0x17
+	comStmtSendLongData = 0x18
+	comStmtClose        = 0x19
+	comStmtReset        = 0x1a
+	comSetOption        = 0x1b
+	comResetConnection  = 0x1f
+)
+
+// error codes of the MySQL protocol.
+const (
+	erAccessDenied       = 1045
+	erUnknownCommand     = 1047
+	erUnknownStmtHandler = 1243
+	erUnknownError       = 1105
+	erEmptyQuery         = 1065
+	erWrongArguments     = 1210
+)
+
+const (
+	serverVersion  = "8.0.0-rdsdata-proxy"
+	nativePassword = "mysql_native_password"
+)
+
+// sqlError is an error sent to the client in the ERR packet.
+type sqlError struct {
+	code     uint16
+	sqlState string
+	message  string
+}
+
+func (e *sqlError) Error() string {
+	return fmt.Sprintf("Error %d (%s): %s", e.code, e.sqlState, e.message)
+}
+
+// session is a client connection of the proxy.
+// The statements are executed on a connection of the driver.
+type session struct {
+	server *server
+	pc     *packetConn
+	id     uint32
+	logger *slog.Logger
+
+	capabilities uint32
+	database     string
+	conn         *rdsdata.Conn
+	tx           driver.Tx
+	stmts        map[uint32]*preparedStmt
+	nextStmtID   uint32
+}
+
+// serve handles the connection until the client quits.
+func (s *session) serve(ctx context.Context) error {
+	defer s.close()
+	if err := s.handshake(ctx); err != nil {
+		return err
+	}
+	for {
+		s.pc.resetSequence()
+		payload, err := s.pc.readPacket()
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if len(payload) == 0 {
+			return errMalformedPacket
+		}
+		if payload[0] == comQuit {
+			return nil
+		}
+		if err := s.dispatch(ctx, payload[0], payload[1:]); err != nil {
+			return err
+		}
+		if err := s.pc.flush(); err != nil {
+			return err
+		}
+	}
+}
+
+// handshake runs the connection phase.
+func (s *session) handshake(ctx context.Context) error {
+	salt := make([]byte, 20)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	for i := range salt {
+		// the salt must not contain NUL.
+		salt[i] = salt[i]&0x7f | 1
+	}
+
+	// Protocol::HandshakeV10
+	b := []byte{10}
+	b = append(b, serverVersion...)
+	b = append(b, 0)
+	b = binary.LittleEndian.AppendUint32(b, s.id)
+	b = append(b, salt[:8]...)
+	b = append(b, 0)
+	b = binary.LittleEndian.AppendUint16(b, uint16(serverCapabilities&0xffff))
+	b = append(b, charsetUTF8MB4)
+	b = binary.LittleEndian.AppendUint16(b, statusAutocommit)
+	b = binary.LittleEndian.AppendUint16(b, uint16(serverCapabilities>>16))
+	b = append(b, byte(len(salt)+1))
+	b = append(b, make([]byte, 10)...)
+	b = append(b, salt[8:]...)
+	b = append(b, 0)
+	b = append(b, nativePassword...)
+	b = append(b, 0)
+	if err := s.writePacket(b); err != nil {
+		return err
+	}
+	if err := s.pc.flush(); err != nil {
+		return err
+	}
+
+	// Protocol::HandshakeResponse41
+	payload, err := s.pc.readPacket()
+	if err != nil {
+		return err
+	}
+	r := &reader{buf: payload}
+	s.capabilities = r.uint32() & serverCapabilities
+	r.uint32() // max packet size
+	r.uint8()  // character set
+	r.bytes(23)
+	user := string(r.nulString())
+	var authResponse []byte
+	switch {
+	case s.capabilities&clientPluginAuthLenencClientData != 0:
+		authResponse = r.lengthEncodedString()
+	case s.capabilities&clientSecureConnection != 0:
+		authResponse = r.bytes(int(r.uint8()))
+	default:
+		authResponse = r.nulString()
+	}
+	if s.capabilities&clientConnectWithDB != 0 && len(r.buf) > 0 {
+		s.database = string(r.nulString())
+	}
+	plugin := nativePassword
+	if s.capabilities&clientPluginAuth != 0 && len(r.buf) > 0 {
+		plugin = string(r.nulString())
+	}
+	if r.err != nil {
+		return r.err
+	}
+	if s.capabilities&clientProtocol41 == 0 {
+		return s.abort(&sqlError{code: erUnknownError, sqlState: "08004", message: "the client does not support the protocol 4.1"})
+	}
+
+	if plugin != nativePassword {
+		// ask the client to switch to mysql_native_password.
+		b := []byte{0xfe}
+		b = append(b, nativePassword...)
+		b = append(b, 0)
+		b = append(b, salt...)
+		b = append(b, 0)
+		if err := s.writePacket(b); err != nil {
+			return err
+		}
+		if err := s.pc.flush(); err != nil {
+			return err
+		}
+		authResponse, err = s.pc.readPacket()
+		if err != nil {
+			return err
+		}
+	}
+
+	if !s.server.authenticate(user, salt, authResponse) {
+		return s.abort(&sqlError{
+			code:     erAccessDenied,
+			sqlState: "28000",
+			message:  fmt.Sprintf("Access denied for user '%s'", user),
+		})
+	}
+
+	if err := s.connect(ctx, s.database); err != nil {
+		return s.abort(err)
+	}
+	if err := s.writeOK(0, 0); err != nil {
+		return err
+	}
+	return s.pc.flush()
+}
+
+// abort sends the error to the client and returns it to close the connection.
+func (s *session) abort(err error) error {
+	if werr := s.writeError(err); werr != nil {
+		return werr
+	}
+	return err
+}
+
+// scramblePassword computes SHA1(password) XOR SHA1(salt + SHA1(SHA1(password))).
+func scramblePassword(salt []byte, password string) []byte {
+	stage1 := sha1.Sum([]byte(password))
+	stage2 := sha1.Sum(stage1[:])
+	h := sha1.New()
+	h.Write(salt)
+	h.Write(stage2[:])
+	scramble := h.Sum(nil)
+	for i := range scramble {
+		scramble[i] ^= stage1[i]
+	}
+	return scramble
+}
+
+// connect switches the connection of the driver to the database.
+func (s *session) connect(ctx context.Context, database string) error {
+	c, err := s.server.connect(ctx, database)
+	if err != nil {
+		return err
+	}
+	if s.conn != nil {
+		s.conn.Close()
+	}
+	s.conn = c
+	s.database = database
+	return nil
+}
+
+func (s *session) close() {
+	if s.tx != nil {
+		if err := s.tx.Rollback(); err != nil {
+			s.logger.Warn("failed to roll back the transaction", slog.Any("error", err))
+		}
+		s.tx = nil
+	}
+	if s.conn != nil {
+		s.conn.Close()
+	}
+}
+
+// dispatch handles a command.
+// The errors of the statements are sent to the client,
+// and the returned error means the connection is broken.
+func (s *session) dispatch(ctx context.Context, cmd byte, data []byte) error {
+	var err error
+	switch cmd {
+	case comPing, comResetConnection:
+		err = s.writeOK(0, 0)
+	case comInitDB:
+		err = s.initDB(ctx, string(data))
+	case comQuery:
+		err = s.query(ctx, string(data))
+	case comStmtPrepare:
+		err = s.prepare(string(data))
+	case comStmtExecute:
+		err = s.execute(ctx, data)
+	case comStmtSendLongData:
+		// COM_STMT_SEND_LONG_DATA has no response.
+		s.sendLongData(data)
+		return nil
+	case comStmtClose:
+		// COM_STMT_CLOSE has no response.
+		if len(data) >= 4 {
+			delete(s.stmts, binary.LittleEndian.Uint32(data))
+		}
+		return nil
+	case comStmtReset:
+		err = s.resetStmt(data)
+	case comSetOption:
+		err = s.writeEOF()
+	default:
+		err = &sqlError{code: erUnknownCommand, sqlState: "08S01", message: fmt.Sprintf("unknown command: 0x%02x", cmd)}
+	}
+	if err != nil {
+		return s.writeError(err)
+	}
+	return nil
+}
+
+func (s *session) initDB(ctx context.Context, database string) error {
+	if s.tx != nil {
+		return &sqlError{code: erUnknownError, sqlState: "25000", message: "cannot change the database in a transaction"}
+	}
+	if err := s.connect(ctx, database); err != nil {
+		return err
+	}
+	return s.writeOK(0, 0)
+}
+
+// query handles COM_QUERY.
+func (s *session) query(ctx context.Context, query string) error {
+	stmt := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(sqllex.TrimLeadingComments(sqllex.MySQL, query)), ";"))
+	words := strings.Fields(strings.ToUpper(stmt))
+	if len(words) == 0 {
+		return &sqlError{code: erEmptyQuery, sqlState: "42000", message: "Query was empty"}
+	}
+
+	// The Data API is stateless, so the statements that change the session
+	// are mapped to the transactions or the connections of the driver.
+	switch words[0] {
+	case "BEGIN":
+		return s.begin(ctx)
+	case "START":
+		if len(words) > 1 && words[1] == "TRANSACTION" {
+			return s.begin(ctx)
+		}
+	case "COMMIT":
+		return s.endTransaction(true)
+	case "ROLLBACK":
+		// ROLLBACK TO SAVEPOINT is executed as a normal statement.
+		if len(words) == 1 || words[1] == "WORK" {
+			return s.endTransaction(false)
+		}
+	case "USE":
+		if len(words) == 2 {
+			return s.initDB(ctx, strings.Trim(strings.TrimSpace(stmt[len("USE"):]), "`"))
+		}
+	}
+
+	rows, err := s.conn.QueryContext(ctx, query, nil)
+	if err != nil {
+		return err
+	}
+	return s.writeResult(rows, false)
+}
+
+func (s *session) begin(ctx context.Context) error {
+	if s.tx != nil {
+		// MySQL commits the current transaction implicitly.
+		tx := s.tx
+		s.tx = nil
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	tx, err := s.conn.BeginTx(ctx, driver.TxOptions{})
+	if err != nil {
+		return err
+	}
+	s.tx = tx
+	return s.writeOK(0, 0)
+}
+
+// endTransaction commits or rolls back the transaction.
+// It is not an error that no transaction is in progress, as in MySQL.
+func (s *session) endTransaction(commit bool) error {
+	if tx := s.tx; tx != nil {
+		s.tx = nil
+		var err error
+		if commit {
+			err = tx.Commit()
+		} else {
+			err = tx.Rollback()
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return s.writeOK(0, 0)
+}
+
+// writeResult writes the result set or the OK packet.
+func (s *session) writeResult(r driver.Rows, binaryProtocol bool) error {
+	rows, ok := r.(*rdsdata.Rows)
+	if !ok {
+		r.Close()
+		return fmt.Errorf("unexpected rows of the driver: %T", r)
+	}
+	metadata := rows.ColumnMetadata()
+	if len(metadata) == 0 {
+		result := rows.Result()
+		affected, _ := result.RowsAffected()
+		lastInsertID, _ := result.LastInsertId()
+		return s.writeOK(uint64(affected), uint64(lastInsertID))
+	}
+
+	// encode all the rows before sending,
+	// so that the encoding errors can be sent in the ERR packet.
+	columns := make([]*column, len(metadata))
+	for i, meta := range metadata {
+		columns[i] = newColumn(meta)
+	}
+	var packets [][]byte
+	values := make([]driver.Value, len(columns))
+	for {
+		err := rows.Next(values)
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return err
+		}
+		var row []byte
+		if binaryProtocol {
+			row, err = appendBinaryRow(nil, columns, values)
+			if err != nil {
+				return err
+			}
+		} else {
+			row = appendTextRow(nil, values)
+		}
+		packets = append(packets, row)
+	}
+
+	if err := s.writePacket(appendLengthEncodedInt(nil, uint64(len(columns)))); err != nil {
+		return err
+	}
+	for _, col := range columns {
+		if err := s.writePacket(appendColumnDefinition(nil, col)); err != nil {
+			return err
+		}
+	}
+	if err := s.writeEOF(); err != nil {
+		return err
+	}
+	for _, row := range packets {
+		if err := s.writePacket(row); err != nil {
+			return err
+		}
+	}
+	return s.writeEOF()
+}
+
+func (s *session) status() uint16 {
+	if s.tx != nil {
+		return statusInTrans
+	}
+	return statusAutocommit
+}
+
+func (s *session) writePacket(payload []byte) error {
+	return s.pc.writePacket(payload)
+}
+
+func (s *session) writeOK(affectedRows, lastInsertID uint64) error {
+	b := []byte{0x00}
+	b = appendLengthEncodedInt(b, affectedRows)
+	b = appendLengthEncodedInt(b, lastInsertID)
+	b = binary.LittleEndian.AppendUint16(b, s.status())
+	b = binary.LittleEndian.AppendUint16(b, 0) // warnings
+	return s.writePacket(b)
+}
+
+func (s *session) writeEOF() error {
+	b := []byte{0xfe}
+	b = binary.LittleEndian.AppendUint16(b, 0) // warnings
+	b = binary.LittleEndian.AppendUint16(b, s.status())
+	return s.writePacket(b)
+}
+
+// writeError writes the ERR packet.
+func (s *session) writeError(err error) error {
+	var sqlErr *sqlError
+	if !errors.As(err, &sqlErr) {
+		sqlErr = &sqlError{code: erUnknownError, sqlState: "HY000", message: errorMessage(err)}
+	}
+	b := []byte{0xff}
+	b = binary.LittleEndian.AppendUint16(b, sqlErr.code)
+	b = append(b, '#')
+	b = append(b, sqlErr.sqlState...)
+	b = append(b, sqlErr.message...)
+	if err := s.writePacket(b); err != nil {
+		return err
+	}
+	return s.pc.flush()
+}
+
+// errorMessage returns the message of the database error if possible.
+func errorMessage(err error) string {
+	var dbErr *types.DatabaseErrorException
+	if errors.As(err, &dbErr) {
+		return dbErr.ErrorMessage()
+	}
+	return err.Error()
+}
@@ -0,0 +1,331 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"log/slog"
+	"net"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata/types"
+	"github.com/shogo82148/go-rdsdata"
+	"github.com/shogo82148/go-rdsdata/rdsdatatest"
+)
+
+// testClient is a minimal MySQL client for the tests.
+type testClient struct {
+	t  *testing.T
+	pc *packetConn
+}
+
+// startSession starts a session and returns the client connected to it.
+func startSession(t *testing.T, fake *rdsdatatest.Fake, password, clientPassword string) (*testClient, []byte) {
+	t.Helper()
+	srv := &server{
+		cfg: &rdsdata.Config{
+			Client:      fake,
+			ResourceArn: rdsdatatest.DefaultResourceArn,
+			SecretArn:   rdsdatatest.DefaultSecretArn,
+		},
+		password: password,
+		logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+	serverConn, clientConn := net.Pipe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer serverConn.Close()
+		s := &session{
+			server: srv,
+			pc:     newPacketConn(serverConn),
+			id:     1,
+			logger: srv.logger,
+		}
+		s.serve(context.Background())
+	}()
+	t.Cleanup(func() {
+		clientConn.Close()
+		<-done
+	})
+
+	c := &testClient{t: t, pc: newPacketConn(clientConn)}
+
+	// read the initial handshake.
+	handshake := c.read()
+	r := &reader{buf: handshake}
+	if v := r.uint8(); v != 10 {
+		t.Fatalf("unexpected protocol version: %d", v)
+	}
+	r.nulString() // server version
+	r.uint32()    // connection id
+	salt := append([]byte{}, r.bytes(8)...)
+	r.bytes(1 + 2 + 1 + 2 + 2 + 1 + 10)
+	salt = append(salt, r.bytes(12)...)
+	if r.err != nil {
+		t.Fatal(r.err)
+	}
+
+	// send the handshake response.
+	var auth []byte
+	if clientPassword != "" {
+		auth = scramblePassword(salt, clientPassword)
+	}
+	b := binary.LittleEndian.AppendUint32(nil, clientProtocol41|clientSecureConnection|clientPluginAuth)
+	b = binary.LittleEndian.AppendUint32(b, maxPacketSize)
+	b = append(b, charsetUTF8MB4)
+	b = append(b, make([]byte, 23)...)
+	b = append(b, "root\x00"...)
+	b = append(b, byte(len(auth)))
+	b = append(b, auth...)
+	b = append(b, nativePassword+"\x00"...)
+	c.write(b)
+	return c, c.read()
+}
+
+func (c *testClient) read() []byte {
+	c.t.Helper()
+	payload, err := c.pc.readPacket()
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	return payload
+}
+
+func (c *testClient) write(payload []byte) {
+	c.t.Helper()
+	if err := c.pc.writePacket(payload); err != nil {
+		c.t.Fatal(err)
+	}
+	if err := c.pc.flush(); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+func (c *testClient) command(cmd byte, data []byte) []byte {
+	c.t.Helper()
+	c.pc.resetSequence()
+	c.write(append([]byte{cmd}, data...))
+	return c.read()
+}
+
+// readResultSet reads the column definitions and the rows after the column count.
+func (c *testClient) readResultSet(first []byte) ([]*column, [][]byte) {
+	c.t.Helper()
+	r := &reader{buf: first}
+	n := int(r.lengthEncodedInt())
+	columns := make([]*column, n)
+	for i := range columns {
+		r := &reader{buf: c.read()}
+		for range 4 {
+			r.lengthEncodedString() // catalog, schema, table, org_table
+		}
+		col := &column{name: string(r.lengthEncodedString())}
+		r.lengthEncodedString() // org_name
+		r.lengthEncodedInt()
+		col.charset = r.uint16()
+		col.length = r.uint32()
+		col.typ = r.uint8()
+		col.flags = r.uint16()
+		if r.err != nil {
+			c.t.Fatal(r.err)
+		}
+		columns[i] = col
+	}
+	if eof := c.read(); eof[0] != 0xfe {
+		c.t.Fatalf("want EOF, got %x", eof)
+	}
+	var rows [][]byte
+	for {
+		row := c.read()
+		if row[0] == 0xfe && len(row) < 9 {
+			return columns, rows
+		}
+		rows = append(rows, row)
+	}
+}
+
+// readOK reads the affected rows and the status flags of the OK packet.
+func readOK(t *testing.T, payload []byte) (affectedRows uint64, status uint16) {
+	t.Helper()
+	if payload[0] != 0x00 {
+		t.Fatalf("want OK, got %q", payload)
+	}
+	r := &reader{buf: payload[1:]}
+	affectedRows = r.lengthEncodedInt()
+	r.lengthEncodedInt() // last insert id
+	status = r.uint16()
+	if r.err != nil {
+		t.Fatal(r.err)
+	}
+	return
+}
+
+func TestHandshake(t *testing.T) {
+	fake := rdsdatatest.New()
+	_, resp := startSession(t, fake, "secret", "secret")
+	readOK(t, resp)
+}
+
+func TestHandshake_AccessDenied(t *testing.T) {
+	fake := rdsdatatest.New()
+	_, resp := startSession(t, fake, "secret", "wrong")
+	if resp[0] != 0xff {
+		t.Fatalf("want ERR, got %q", resp)
+	}
+	if code := binary.LittleEndian.Uint16(resp[1:]); code != erAccessDenied {
+		t.Errorf("unexpected error code: got %d, want %d", code, erAccessDenied)
+	}
+}
+
+func TestQuery(t *testing.T) {
+	fake := rdsdatatest.New()
+	fake.ExpectStatement("SELECT id, name FROM users").WillReturnRecords(
+		[]types.ColumnMetadata{
+			rdsdatatest.Column("id", "BIGINT"),
+			rdsdatatest.Column("name", "VARCHAR"),
+		},
+		[]types.Field{rdsdatatest.Long(1), rdsdatatest.String("alice")},
+		[]types.Field{rdsdatatest.Long(2), rdsdatatest.Null()},
+	)
+	c, resp := startSession(t, fake, "", "")
+	readOK(t, resp)
+
+	columns, rows := c.readResultSet(c.command(comQuery, []byte("SELECT id, name FROM users")))
+	if len(columns) != 2 {
+		t.Fatalf("unexpected columns: %d", len(columns))
+	}
+	if columns[0].name != "id" || columns[0].typ != typeLongLong {
+		t.Errorf("unexpected column: %+v", columns[0])
+	}
+	if columns[1].name != "name" || columns[1].typ != typeVarString {
+		t.Errorf("unexpected column: %+v", columns[1])
+	}
+	want := [][]byte{
+		[]byte("\x011\x05alice"),
+		[]byte("\x012\xfb"),
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("unexpected rows: %q", rows)
+	}
+	for i := range want {
+		if !bytes.Equal(rows[i], want[i]) {
+			t.Errorf("row %d: got %q, want %q", i, rows[i], want[i])
+		}
+	}
+
+	if err := fake.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestQuery_Error(t *testing.T) {
+	fake := rdsdatatest.New()
+	fake.ExpectStatement("SELECT * FROM missing").WillReturnError(&types.DatabaseErrorException{
+		Message: aws.String("Table 'test.missing' doesn't exist"),
+	})
+	c, resp := startSession(t, fake, "", "")
+	readOK(t, resp)
+
+	got := c.command(comQuery, []byte("SELECT * FROM missing"))
+	if got[0] != 0xff {
+		t.Fatalf("want ERR, got %q", got)
+	}
+	if msg := string(got[9:]); msg != "Table 'test.missing' doesn't exist" {
+		t.Errorf("unexpected message: %q", msg)
+	}
+
+	// the connection is still usable.
+	if _, status := readOK(t, c.command(comPing, nil)); status != statusAutocommit {
+		t.Errorf("unexpected status: %x", status)
+	}
+}
+
+func TestTransaction(t *testing.T) {
+	fake := rdsdatatest.New()
+	fake.ExpectBegin().WillReturnTransactionID("tx-1")
+	fake.ExpectStatement("UPDATE users SET name = 'bob'").InTransaction().WillReturnResult(3)
+	fake.ExpectCommit()
+	c, resp := startSession(t, fake, "", "")
+	readOK(t, resp)
+
+	if _, status := readOK(t, c.command(comQuery, []byte("# comment\nBEGIN"))); status != statusInTrans {
+		t.Errorf("unexpected status: %x", status)
+	}
+	affected, status := readOK(t, c.command(comQuery, []byte("UPDATE users SET name = 'bob'")))
+	if affected != 3 {
+		t.Errorf("unexpected affected rows: %d", affected)
+	}
+	if status != statusInTrans {
+		t.Errorf("unexpected status: %x", status)
+	}
+	if _, status := readOK(t, c.command(comQuery, []byte("COMMIT;"))); status != statusAutocommit {
+		t.Errorf("unexpected status: %x", status)
+	}
+
+	if err := fake.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPreparedStatement(t *testing.T) {
+	fake := rdsdatatest.New()
+	fake.ExpectStatement("SELECT id, name FROM users WHERE id = :1").
+		WithParameters(rdsdatatest.Param("1", rdsdatatest.Long(42))).
+		WillReturnRecords(
+			[]types.ColumnMetadata{
+				rdsdatatest.Column("id", "BIGINT"),
+				rdsdatatest.Column("name", "VARCHAR"),
+			},
+			[]types.Field{rdsdatatest.Long(42), rdsdatatest.String("alice")},
+		)
+	c, resp := startSession(t, fake, "", "")
+	readOK(t, resp)
+
+	// prepare
+	prepareOK := c.command(comStmtPrepare, []byte("SELECT id, name FROM users WHERE id = ?"))
+	r := &reader{buf: prepareOK}
+	if status := r.uint8(); status != 0x00 {
+		t.Fatalf("want COM_STMT_PREPARE_OK, got %q", prepareOK)
+	}
+	id := r.uint32()
+	r.uint16() // columns
+	if params := r.uint16(); params != 1 {
+		t.Fatalf("unexpected number of parameters: %d", params)
+	}
+	c.read() // the definition of the parameter
+	if eof := c.read(); eof[0] != 0xfe {
+		t.Fatalf("want EOF, got %x", eof)
+	}
+
+	// execute
+	b := binary.LittleEndian.AppendUint32(nil, id)
+	b = append(b, 0x00)                         // flags
+	b = binary.LittleEndian.AppendUint32(b, 1)  // iteration count
+	b = append(b, 0x00)                         // NULL bitmap
+	b = append(b, 0x01)                         // new params bound
+	b = append(b, typeLongLong, 0x00)           // type
+	b = binary.LittleEndian.AppendUint64(b, 42) // value
+	columns, rows := c.readResultSet(c.command(comStmtExecute, b))
+	if len(columns) != 2 {
+		t.Fatalf("unexpected columns: %d", len(columns))
+	}
+	want := []byte("\x00\x00\x2a\x00\x00\x00\x00\x00\x00\x00\x05alice")
+	if len(rows) != 1 || !bytes.Equal(rows[0], want) {
+		t.Errorf("unexpected rows: got %q, want %q", rows, want)
+	}
+
+	// close
+	c.pc.resetSequence()
+	c.write(append([]byte{comStmtClose}, binary.LittleEndian.AppendUint32(nil, id)...))
+	got := c.command(comStmtExecute, b)
+	if got[0] != 0xff || binary.LittleEndian.Uint16(got[1:]) != erUnknownStmtHandler {
+		t.Errorf("want ERR of unknown statement, got %q", got)
+	}
+
+	if err := fake.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// paramUnsigned is the flag of the parameter types in COM_STMT_EXECUTE.
+const paramUnsigned = 0x80
+
+// preparedStmt is a statement prepared by COM_STMT_PREPARE.
+// The Data API has no prepared statements,
+// so the statement is sent with the parameters on every execution.
+type preparedStmt struct {
+	id        uint32
+	query     string
+	numParams int
+
+	// paramTypes are the types bound by the last execution.
+	paramTypes []uint16
+
+	// longData is the data sent by COM_STMT_SEND_LONG_DATA.
+	longData map[int][]byte
+}
+
+// prepare handles COM_STMT_PREPARE.
+func (s *session) prepare(query string) error {
+	s.nextStmtID++
+	stmt := &preparedStmt{
+		id:    s.nextStmtID,
+		query: query,
+		// the driver replaces every "?" with a named parameter,
+		// so count them in the same way.
+		numParams: strings.Count(query, "?"),
+		longData:  map[int][]byte{},
+	}
+	if s.stmts == nil {
+		s.stmts = map[uint32]*preparedStmt{}
+	}
+	s.stmts[stmt.id] = stmt
+
+	// COM_STMT_PREPARE_OK
+	b := []byte{0x00}
+	b = binary.LittleEndian.AppendUint32(b, stmt.id)
+	// The columns are unknown until the statement is executed.
+	// The clients read them from the result set of COM_STMT_EXECUTE.
+	b = binary.LittleEndian.AppendUint16(b, 0)
+	b = binary.LittleEndian.AppendUint16(b, uint16(stmt.numParams))
+	b = append(b, 0x00)
+	b = binary.LittleEndian.AppendUint16(b, 0) // warnings
+	if err := s.writePacket(b); err != nil {
+		return err
+	}
+	if stmt.numParams == 0 {
+		return nil
+	}
+	param := &column{name: "?", charset: charsetBinary, typ: typeVarString}
+	for range stmt.numParams {
+		if err := s.writePacket(appendColumnDefinition(nil, param)); err != nil {
+			return err
+		}
+	}
+	return s.writeEOF()
+}
+
+// sendLongData handles COM_STMT_SEND_LONG_DATA.
+func (s *session) sendLongData(data []byte) {
+	r := &reader{buf: data}
+	id := r.uint32()
+	param := int(r.uint16())
+	chunk := r.rest()
+	if r.err != nil {
+		return
+	}
+	stmt, ok := s.stmts[id]
+	if !ok || param >= stmt.numParams {
+		return
+	}
+	stmt.longData[param] = append(stmt.longData[param], chunk...)
+}
+
+// resetStmt handles COM_STMT_RESET.
+func (s *session) resetStmt(data []byte) error {
+	r := &reader{buf: data}
+	id := r.uint32()
+	if r.err != nil {
+		return r.err
+	}
+	stmt, ok := s.stmts[id]
+	if !ok {
+		return errUnknownStmt(id)
+	}
+	clear(stmt.longData)
+	return s.writeOK(0, 0)
+}
+
+func errUnknownStmt(id uint32) error {
+	return &sqlError{
+		code:     erUnknownStmtHandler,
+		sqlState: "HY000",
+		message:  fmt.Sprintf("Unknown prepared statement handler (%d) given to mysqld_stmt_execute", id),
+	}
+}
+
+// execute handles COM_STMT_EXECUTE.
+func (s *session) execute(ctx context.Context, data []byte) error {
+	r := &reader{buf: data}
+	id := r.uint32()
+	r.uint8()  // flags
+	r.uint32() // iteration count
+	if r.err != nil {
+		return r.err
+	}
+	stmt, ok := s.stmts[id]
+	if !ok {
+		return errUnknownStmt(id)
+	}
+	defer clear(stmt.longData)
+
+	args, err := stmt.readParams(r)
+	if err != nil {
+		return &sqlError{code: erWrongArguments, sqlState: "HY000", message: fmt.Sprintf("Incorrect arguments to mysqld_stmt_execute: %v", err)}
+	}
+
+	rows, err := s.conn.QueryContext(ctx, stmt.query, args)
+	if err != nil {
+		return err
+	}
+	return s.writeResult(rows, true)
+}
+
+// readParams decodes the parameters of COM_STMT_EXECUTE.
+func (stmt *preparedStmt) readParams(r *reader) ([]driver.NamedValue, error) {
+	if stmt.numParams == 0 {
+		return nil, nil
+	}
+
+	nullBitmap := r.bytes((stmt.numParams + 7) / 8)
+	if r.uint8() == 1 {
+		// new parameters are bound.
+		stmt.paramTypes = make([]uint16, stmt.numParams)
+		for i := range stmt.paramTypes {
+			stmt.paramTypes[i] = r.uint16()
+		}
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	if stmt.paramTypes == nil {
+		return nil, fmt.Errorf("the types of the parameters are not bound")
+	}
+
+	args := make([]driver.NamedValue, stmt.numParams)
+	for i := range args {
+		args[i].Ordinal = i + 1
+		if nullBitmap[i/8]&(1<<(i%8)) != 0 {
+			continue
+		}
+		if data, ok := stmt.longData[i]; ok {
+			args[i].Value = bytesValue(data)
+			continue
+		}
+		v, err := readBinaryValue(r, stmt.paramTypes[i])
+		if err != nil {
+			return nil, fmt.Errorf("parameter %d: %w", i+1, err)
+		}
+		args[i].Value = v
+	}
+	return args, nil
+}
+
+// readBinaryValue decodes a value of the binary protocol
+// into a value that the driver accepts.
+func readBinaryValue(r *reader, paramType uint16) (driver.Value, error) {
+	unsigned := paramType>>8&paramUnsigned != 0
+	var v driver.Value
+	switch byte(paramType) {
+	case typeNull:
+		return nil, nil
+	case typeTiny:
+		n := r.uint8()
+		if unsigned {
+			v = int64(n)
+		} else {
+			v = int64(int8(n))
+		}
+	case typeShort, typeYear:
+		n := r.uint16()
+		if unsigned {
+			v = int64(n)
+		} else {
+			v = int64(int16(n))
+		}
+	case typeInt24, typeLong:
+		n := r.uint32()
+		if unsigned {
+			v = int64(n)
+		} else {
+			v = int64(int32(n))
+		}
+	case typeLongLong:
+		n := r.uint64()
+		if unsigned && n > math.MaxInt64 {
+			// the Data API has no unsigned integers.
+			v = strconv.FormatUint(n, 10)
+		} else {
+			v = int64(n)
+		}
+	case typeFloat:
+		v = float64(math.Float32frombits(r.uint32()))
+	case typeDouble:
+		v = math.Float64frombits(r.uint64())
+	case typeDate, typeDateTime, typeTimestamp:
+		v = readBinaryDateTime(r)
+	case typeTime:
+		v = readBinaryTime(r)
+	default:
+		v = bytesValue(r.lengthEncodedString())
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return v, nil
+}
+
+// bytesValue passes the text as a string and the binary data as a blob.
+func bytesValue(data []byte) driver.Value {
+	if utf8.Valid(data) {
+		return string(data)
+	}
+	return data
+}
+
+// readBinaryDateTime decodes DATE, DATETIME or TIMESTAMP
+// into the format of MySQL.
+func readBinaryDateTime(r *reader) string {
+	length := r.uint8()
+	var year uint16
+	var month, day, hour, minute, second uint8
+	var micro uint32
+	if length >= 4 {
+		year = r.uint16()
+		month = r.uint8()
+		day = r.uint8()
+	}
+	if length >= 7 {
+		hour = r.uint8()
+		minute = r.uint8()
+		second = r.uint8()
+	}
+	if length >= 11 {
+		micro = r.uint32()
+	}
+
+	s := fmt.Sprintf("%04d-%02d-%02d", year, month, day)
+	if length <= 4 {
+		return s
+	}
+	s += fmt.Sprintf(" %02d:%02d:%02d", hour, minute, second)
+	if micro != 0 {
+		s += fmt.Sprintf(".%06d", micro)
+	}
+	return s
+}
+
+// readBinaryTime decodes TIME into the format of MySQL.
+func readBinaryTime(r *reader) string {
+	length := r.uint8()
+	if length == 0 {
+		return "00:00:00"
+	}
+	negative := r.uint8() == 1
+	days := r.uint32()
+	hour := r.uint8()
+	minute := r.uint8()
+	second := r.uint8()
+	var micro uint32
+	if length >= 12 {
+		micro = r.uint32()
+	}
+
+	s := fmt.Sprintf("%02d:%02d:%02d", uint64(days)*24+uint64(hour), minute, second)
+	if micro != 0 {
+		s += fmt.Sprintf(".%06d", micro)
+	}
+	if negative {
+		s = "-" + s
+	}
+	return s
+}
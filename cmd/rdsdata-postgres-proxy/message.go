@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// maxMessageSize limits the size of the messages from the clients.
+const maxMessageSize = 64 << 20
+
+// the codes of the startup packets.
+const (
+	protocolVersion3  = 196608
+	sslRequestCode    = 80877103
+	gssEncRequestCode = 80877104
+	cancelRequestCode = 80877102
+)
+
+// messageConn reads and writes the messages of the frontend/backend protocol.
+type messageConn struct {
+	r *bufio.Reader
+	w *bufio.Writer
+}
+
+func newMessageConn(rw io.ReadWriter) *messageConn {
+	return &messageConn{
+		r: bufio.NewReader(rw),
+		w: bufio.NewWriter(rw),
+	}
+}
+
+// readStartup reads a packet of the startup phase, which has no type.
+func (c *messageConn) readStartup() ([]byte, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(c.r, header[:]); err != nil {
+		return nil, err
+	}
+	return c.readBody(header[:])
+}
+
+// readMessage reads a message and returns its type and body.
+func (c *messageConn) readMessage() (byte, []byte, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(c.r, header[:]); err != nil {
+		return 0, nil, err
+	}
+	body, err := c.readBody(header[1:])
+	if err != nil {
+		return 0, nil, err
+	}
+	return header[0], body, nil
+}
+
+func (c *messageConn) readBody(length []byte) ([]byte, error) {
+	n := int(int32(binary.BigEndian.Uint32(length))) - 4
+	if n < 0 || n > maxMessageSize {
+		return nil, fmt.Errorf("invalid message length: %d", n+4)
+	}
+	body := make([]byte, n)
+	if _, err := io.ReadFull(c.r, body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// writeMessage writes a message into the buffer.
+// Call flush to send it.
+func (c *messageConn) writeMessage(typ byte, body []byte) error {
+	var header [5]byte
+	header[0] = typ
+	binary.BigEndian.PutUint32(header[1:], uint32(len(body)+4))
+	if _, err := c.w.Write(header[:]); err != nil {
+		return err
+	}
+	_, err := c.w.Write(body)
+	return err
+}
+
+// writeByte writes a single byte without the framing,
+// which is used for the responses to SSLRequest and GSSENCRequest.
+func (c *messageConn) writeByte(b byte) error {
+	if err := c.w.WriteByte(b); err != nil {
+		return err
+	}
+	return c.w.Flush()
+}
+
+func (c *messageConn) flush() error {
+	return c.w.Flush()
+}
+
+func appendInt16(b []byte, n int16) []byte {
+	return binary.BigEndian.AppendUint16(b, uint16(n))
+}
+
+func appendInt32(b []byte, n int32) []byte {
+	return binary.BigEndian.AppendUint32(b, uint32(n))
+}
+
+func appendString(b []byte, s string) []byte {
+	b = append(b, s...)
+	return append(b, 0)
+}
+
+var errMalformedMessage = errors.New("malformed message")
+
+// reader decodes the fields of a message.
+type reader struct {
+	buf []byte
+	err error
+}
+
+func (r *reader) bytes(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || n > len(r.buf) {
+		r.err = errMalformedMessage
+		return nil
+	}
+	b := r.buf[:n:n]
+	r.buf = r.buf[n:]
+	return b
+}
+
+func (r *reader) byte() byte {
+	b := r.bytes(1)
+	if b == nil {
+		return 0
+	}
+	return b[0]
+}
+
+func (r *reader) int16() int16 {
+	b := r.bytes(2)
+	if b == nil {
+		return 0
+	}
+	return int16(binary.BigEndian.Uint16(b))
+}
+
+func (r *reader) int32() int32 {
+	b := r.bytes(4)
+	if b == nil {
+		return 0
+	}
+	return int32(binary.BigEndian.Uint32(b))
+}
+
+// string reads a NUL-terminated string.
+func (r *reader) string() string {
+	if r.err != nil {
+		return ""
+	}
+	for i, c := range r.buf {
+		if c == 0 {
+			s := string(r.buf[:i])
+			r.buf = r.buf[i+1:]
+			return s
+		}
+	}
+	r.err = errMalformedMessage
+	return ""
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"sync"
+	"sync/atomic"
+
+	"github.com/shogo82148/go-rdsdata"
+)
+
+// server accepts the PostgreSQL clients.
+type server struct {
+	cfg      *rdsdata.Config
+	user     string
+	password string
+	logger   *slog.Logger
+
+	lastPID atomic.Int32
+}
+
+// serve accepts the connections until ctx is canceled.
+func (srv *server) serve(ctx context.Context, l net.Listener) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		<-ctx.Done()
+		l.Close()
+	}()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		nc, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			srv.handle(ctx, nc)
+		}()
+	}
+}
+
+// handle serves a client connection.
+func (srv *server) handle(ctx context.Context, nc net.Conn) {
+	defer nc.Close()
+
+	// close the connection on shutdown to unblock the reads.
+	stop := context.AfterFunc(ctx, func() { nc.Close() })
+	defer stop()
+
+	var secret [4]byte
+	rand.Read(secret[:])
+	pid := srv.lastPID.Add(1)
+	logger := srv.logger.With(slog.Int("pid", int(pid)), slog.String("remote_addr", nc.RemoteAddr().String()))
+	s := &session{
+		server: srv,
+		mc:     newMessageConn(nc),
+		pid:    pid,
+		secret: int32(binary.BigEndian.Uint32(secret[:])),
+		logger: logger,
+	}
+	logger.DebugContext(ctx, "connected")
+	if err := s.serve(ctx); err != nil && !errors.Is(err, net.ErrClosed) {
+		logger.WarnContext(ctx, "connection closed", slog.Any("error", err))
+		return
+	}
+	logger.DebugContext(ctx, "disconnected")
+}
+
+// authenticate verifies the user name and the password.
+// Any client is accepted if they are not configured.
+func (srv *server) authenticate(user, password string) bool {
+	if srv.user != "" && user != srv.user {
+		return false
+	}
+	if srv.password == "" {
+		return true
+	}
+	return subtle.ConstantTimeCompare([]byte(password), []byte(srv.password)) == 1
+}
+
+// connect opens a new connection of the driver.
+// The database of the config is used if database is empty.
+func (srv *server) connect(ctx context.Context, database string) (*rdsdata.Conn, error) {
+	cfg := srv.cfg.Clone()
+	if database != "" {
+		cfg.Database = database
+	}
+	conn, err := rdsdata.NewConnector(cfg).Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c, ok := conn.(*rdsdata.Conn)
+	if !ok {
+		conn.Close()
+		return nil, fmt.Errorf("unexpected connection of the driver: %T", conn)
+	}
+	return c, nil
+}
@@ -0,0 +1,833 @@
+package main
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata/types"
+	"github.com/shogo82148/go-rdsdata"
+	"github.com/shogo82148/go-rdsdata/internal/sqllex"
+)
+
+// the status indicators of ReadyForQuery.
+const (
+	txIdle   = 'I'
+	txActive = 'T'
+	txFailed = 'E'
+)
+
+const serverVersion = "16.0 (rdsdata-proxy)"
+
+// pgError is an error sent to the client in ErrorResponse.
+type pgError struct {
+	severity string
+	code     string
+	message  string
+}
+
+func (e *pgError) Error() string {
+	return fmt.Sprintf("%s: %s (SQLSTATE %s)", e.severity, e.message, e.code)
+}
+
+func newError(code, format string, args ...any) *pgError {
+	return &pgError{
+		severity: "ERROR",
+		code:     code,
+		message:  fmt.Sprintf(format, args...),
+	}
+}
+
+// preparedStmt is a statement created by Parse.
+// The Data API has no prepared statements,
+// so the statement is sent with the parameters on every execution.
+type preparedStmt struct {
+	query     string
+	paramOIDs []int32
+}
+
+// portal is a statement bound to the parameters by Bind.
+type portal struct {
+	stmt    *preparedStmt
+	args    []driver.NamedValue
+	formats []int16
+
+	// result is the result of the execution.
+	// It is nil until the portal is executed.
+	result *result
+	pos    int
+}
+
+// result is the result of a statement.
+type result struct {
+	columns []types.ColumnMetadata
+	rows    [][]driver.Value
+	tag     string
+}
+
+// session is a client connection of the proxy.
+// The statements are executed on a connection of the driver.
+type session struct {
+	server *server
+	mc     *messageConn
+	pid    int32
+	secret int32
+	logger *slog.Logger
+
+	conn     *rdsdata.Conn
+	tx       driver.Tx
+	txFailed bool
+	stmts    map[string]*preparedStmt
+	portals  map[string]*portal
+
+	// ignoreTillSync is set by an error in the extended query protocol.
+	// The messages are discarded until Sync.
+	ignoreTillSync bool
+}
+
+// serve handles the connection until the client terminates.
+func (s *session) serve(ctx context.Context) error {
+	defer s.close()
+	if err := s.startup(ctx); err != nil {
+		return err
+	}
+	for {
+		typ, body, err := s.mc.readMessage()
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if typ == 'X' {
+			// Terminate
+			return nil
+		}
+		if s.ignoreTillSync && typ != 'S' {
+			continue
+		}
+		if err := s.dispatch(ctx, typ, body); err != nil {
+			return err
+		}
+	}
+}
+
+// startup runs the startup phase.
+func (s *session) startup(ctx context.Context) error {
+	var params map[string]string
+	for params == nil {
+		body, err := s.mc.readStartup()
+		if err != nil {
+			return err
+		}
+		r := &reader{buf: body}
+		switch code := r.int32(); code {
+		case sslRequestCode, gssEncRequestCode:
+			// TLS and GSSAPI encryption are not supported.
+			if err := s.mc.writeByte('N'); err != nil {
+				return err
+			}
+		case cancelRequestCode:
+			// The Data API cannot cancel the running statements.
+			return nil
+		case protocolVersion3:
+			params = map[string]string{}
+			for {
+				key := r.string()
+				if key == "" || r.err != nil {
+					break
+				}
+				params[key] = r.string()
+			}
+			if r.err != nil {
+				return r.err
+			}
+		default:
+			return s.abort(&pgError{severity: "FATAL", code: "0A000", message: fmt.Sprintf("unsupported frontend protocol %d.%d", code>>16, code&0xffff)})
+		}
+	}
+
+	user := params["user"]
+	if s.server.password != "" {
+		// AuthenticationCleartextPassword
+		if err := s.mc.writeMessage('R', appendInt32(nil, 3)); err != nil {
+			return err
+		}
+		if err := s.mc.flush(); err != nil {
+			return err
+		}
+		typ, body, err := s.mc.readMessage()
+		if err != nil {
+			return err
+		}
+		r := &reader{buf: body}
+		password := r.string()
+		if typ != 'p' || r.err != nil || !s.server.authenticate(user, password) {
+			return s.abort(&pgError{severity: "FATAL", code: "28P01", message: fmt.Sprintf("password authentication failed for user %q", user)})
+		}
+	} else if !s.server.authenticate(user, "") {
+		return s.abort(&pgError{severity: "FATAL", code: "28000", message: fmt.Sprintf("role %q is not permitted to log in", user)})
+	}
+
+	conn, err := s.server.connect(ctx, params["database"])
+	if err != nil {
+		return s.abort(&pgError{severity: "FATAL", code: "08006", message: errorMessage(err)})
+	}
+	s.conn = conn
+
+	// AuthenticationOk
+	if err := s.mc.writeMessage('R', appendInt32(nil, 0)); err != nil {
+		return err
+	}
+	status := [][2]string{
+		{"server_version", serverVersion},
+		{"server_encoding", "UTF8"},
+		{"client_encoding", "UTF8"},
+		{"DateStyle", "ISO, MDY"},
+		{"TimeZone", "UTC"},
+		{"integer_datetimes", "on"},
+		{"standard_conforming_strings", "on"},
+		{"application_name", params["application_name"]},
+		{"session_authorization", user},
+		{"is_superuser", "off"},
+	}
+	for _, kv := range status {
+		b := appendString(nil, kv[0])
+		b = appendString(b, kv[1])
+		if err := s.mc.writeMessage('S', b); err != nil {
+			return err
+		}
+	}
+	// BackendKeyData
+	if err := s.mc.writeMessage('K', appendInt32(appendInt32(nil, s.pid), s.secret)); err != nil {
+		return err
+	}
+	return s.readyForQuery()
+}
+
+// abort sends the error to the client and returns it to close the connection.
+func (s *session) abort(err *pgError) error {
+	if werr := s.writeError(err); werr != nil {
+		return werr
+	}
+	if werr := s.mc.flush(); werr != nil {
+		return werr
+	}
+	return err
+}
+
+func (s *session) close() {
+	if s.tx != nil {
+		if err := s.tx.Rollback(); err != nil {
+			s.logger.Warn("failed to roll back the transaction", slog.Any("error", err))
+		}
+		s.tx = nil
+	}
+	if s.conn != nil {
+		s.conn.Close()
+	}
+}
+
+// dispatch handles a message.
+// The errors of the statements are sent to the client,
+// and the returned error means the connection is broken.
+func (s *session) dispatch(ctx context.Context, typ byte, body []byte) error {
+	var err error
+	switch typ {
+	case 'Q':
+		return s.simpleQuery(ctx, body)
+	case 'S':
+		// Sync
+		s.ignoreTillSync = false
+		return s.readyForQuery()
+	case 'H':
+		// Flush
+		return s.mc.flush()
+	case 'P':
+		err = s.parse(body)
+	case 'B':
+		err = s.bind(body)
+	case 'D':
+		err = s.describe(ctx, body)
+	case 'E':
+		err = s.execute(ctx, body)
+	case 'C':
+		err = s.closeStmt(body)
+	default:
+		return s.abort(&pgError{severity: "FATAL", code: "08P01", message: fmt.Sprintf("unsupported message type %q", typ)})
+	}
+	if err != nil {
+		s.ignoreTillSync = true
+		return s.writeError(err)
+	}
+	return nil
+}
+
+// simpleQuery handles Query of the simple query protocol.
+func (s *session) simpleQuery(ctx context.Context, body []byte) error {
+	r := &reader{buf: body}
+	query := r.string()
+	if r.err != nil {
+		return r.err
+	}
+
+	if strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(sqllex.TrimLeadingComments(sqllex.PostgreSQL, query)), ";")) == "" {
+		if err := s.mc.writeMessage('I', nil); err != nil {
+			return err
+		}
+		return s.readyForQuery()
+	}
+
+	res, err := s.run(ctx, query, nil)
+	if err != nil {
+		if err := s.writeError(err); err != nil {
+			return err
+		}
+		return s.readyForQuery()
+	}
+	if len(res.columns) > 0 {
+		if err := s.mc.writeMessage('T', rowDescription(res.columns, nil)); err != nil {
+			return err
+		}
+		for _, row := range res.rows {
+			b, err := dataRow(res.columns, nil, row)
+			if err != nil {
+				if err := s.writeError(err); err != nil {
+					return err
+				}
+				return s.readyForQuery()
+			}
+			if err := s.mc.writeMessage('D', b); err != nil {
+				return err
+			}
+		}
+	}
+	if err := s.mc.writeMessage('C', appendString(nil, res.tag)); err != nil {
+		return err
+	}
+	return s.readyForQuery()
+}
+
+// parse handles Parse.
+func (s *session) parse(body []byte) error {
+	r := &reader{buf: body}
+	name := r.string()
+	query := r.string()
+	oids := make([]int32, max(r.int16(), 0))
+	for i := range oids {
+		oids[i] = r.int32()
+	}
+	if r.err != nil {
+		return r.err
+	}
+	if name != "" {
+		if _, ok := s.stmts[name]; ok {
+			return newError("42P05", "prepared statement %q already exists", name)
+		}
+	}
+	if s.stmts == nil {
+		s.stmts = map[string]*preparedStmt{}
+	}
+	s.stmts[name] = &preparedStmt{
+		query:     query,
+		paramOIDs: oids,
+	}
+	// ParseComplete
+	return s.mc.writeMessage('1', nil)
+}
+
+// bind handles Bind.
+func (s *session) bind(body []byte) error {
+	r := &reader{buf: body}
+	portalName := r.string()
+	stmtName := r.string()
+	paramFormats := make([]int16, max(r.int16(), 0))
+	for i := range paramFormats {
+		paramFormats[i] = r.int16()
+	}
+	values := make([][]byte, max(r.int16(), 0))
+	for i := range values {
+		n := r.int32()
+		if n < 0 {
+			continue // NULL
+		}
+		values[i] = r.bytes(int(n))
+		if values[i] == nil {
+			values[i] = []byte{}
+		}
+	}
+	resultFormats := make([]int16, max(r.int16(), 0))
+	for i := range resultFormats {
+		resultFormats[i] = r.int16()
+	}
+	if r.err != nil {
+		return r.err
+	}
+
+	stmt, ok := s.stmts[stmtName]
+	if !ok {
+		return newError("26000", "prepared statement %q does not exist", stmtName)
+	}
+	args := make([]driver.NamedValue, len(values))
+	for i, data := range values {
+		args[i].Ordinal = i + 1
+		if data == nil {
+			continue
+		}
+		var oid int32 = oidUnspecified
+		if i < len(stmt.paramOIDs) {
+			oid = stmt.paramOIDs[i]
+		}
+		v, err := decodeParam(data, oid, formatOf(paramFormats, i))
+		if err != nil {
+			return newError("22P02", "invalid value for parameter $%d: %v", i+1, err)
+		}
+		args[i].Value = v
+	}
+
+	if s.portals == nil {
+		s.portals = map[string]*portal{}
+	}
+	s.portals[portalName] = &portal{
+		stmt:    stmt,
+		args:    args,
+		formats: resultFormats,
+	}
+	// BindComplete
+	return s.mc.writeMessage('2', nil)
+}
+
+// describe handles Describe.
+func (s *session) describe(ctx context.Context, body []byte) error {
+	r := &reader{buf: body}
+	kind := r.byte()
+	name := r.string()
+	if r.err != nil {
+		return r.err
+	}
+
+	switch kind {
+	case 'S':
+		stmt, ok := s.stmts[name]
+		if !ok {
+			return newError("26000", "prepared statement %q does not exist", name)
+		}
+		columns, err := s.describeStmt(ctx, stmt)
+		if err != nil {
+			return err
+		}
+
+		// ParameterDescription
+		n := max(len(stmt.paramOIDs), numParams(stmt.query))
+		b := appendInt16(nil, int16(n))
+		for i := range n {
+			var oid int32 = oidUnspecified
+			if i < len(stmt.paramOIDs) {
+				oid = stmt.paramOIDs[i]
+			}
+			b = appendInt32(b, oid)
+		}
+		if err := s.mc.writeMessage('t', b); err != nil {
+			return err
+		}
+		if len(columns) == 0 {
+			// NoData
+			return s.mc.writeMessage('n', nil)
+		}
+		return s.mc.writeMessage('T', rowDescription(columns, nil))
+
+	case 'P':
+		p, ok := s.portals[name]
+		if !ok {
+			return newError("34000", "portal %q does not exist", name)
+		}
+		// The columns are known after the execution,
+		// so execute the portal here and keep the result for Execute.
+		if err := s.executePortal(ctx, p); err != nil {
+			return err
+		}
+		if len(p.result.columns) == 0 {
+			return s.mc.writeMessage('n', nil)
+		}
+		return s.mc.writeMessage('T', rowDescription(p.result.columns, p.formats))
+	}
+	return newError("08P01", "invalid DESCRIBE message subtype %d", kind)
+}
+
+// describeStmt returns the columns of the statement without the parameters.
+// The Data API cannot describe statements, so the queries are executed
+// in a subquery that returns no rows.
+// The other statements, including the ones with RETURNING, are reported as returning no rows.
+func (s *session) describeStmt(ctx context.Context, stmt *preparedStmt) ([]types.ColumnMetadata, error) {
+	words := strings.Fields(strings.ToUpper(sqllex.TrimLeadingComments(sqllex.PostgreSQL, stmt.query)))
+	if len(words) == 0 {
+		return nil, nil
+	}
+	switch strings.TrimLeft(words[0], "(") {
+	case "SELECT", "WITH", "VALUES", "TABLE":
+	default:
+		return nil, nil
+	}
+	if s.txFailed {
+		return nil, errTxAborted
+	}
+
+	query := strings.TrimSuffix(strings.TrimSpace(stmt.query), ";")
+	query = "SELECT * FROM (" + query + "\n) AS rdsdata_describe LIMIT 0"
+	args := make([]driver.NamedValue, numParams(stmt.query))
+	for i := range args {
+		args[i].Ordinal = i + 1
+	}
+	res, err := s.query(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+	return res.columns, nil
+}
+
+// execute handles Execute.
+func (s *session) execute(ctx context.Context, body []byte) error {
+	r := &reader{buf: body}
+	name := r.string()
+	maxRows := int(r.int32())
+	if r.err != nil {
+		return r.err
+	}
+	p, ok := s.portals[name]
+	if !ok {
+		return newError("34000", "portal %q does not exist", name)
+	}
+	if err := s.executePortal(ctx, p); err != nil {
+		return err
+	}
+
+	res := p.result
+	if len(res.columns) > 0 {
+		end := len(res.rows)
+		if maxRows > 0 {
+			end = min(end, p.pos+maxRows)
+		}
+		for ; p.pos < end; p.pos++ {
+			b, err := dataRow(res.columns, p.formats, res.rows[p.pos])
+			if err != nil {
+				return err
+			}
+			if err := s.mc.writeMessage('D', b); err != nil {
+				return err
+			}
+		}
+		if p.pos < len(res.rows) {
+			// PortalSuspended
+			return s.mc.writeMessage('s', nil)
+		}
+	}
+	return s.mc.writeMessage('C', appendString(nil, res.tag))
+}
+
+// executePortal executes the portal if it is not executed yet.
+func (s *session) executePortal(ctx context.Context, p *portal) error {
+	if p.result != nil {
+		return nil
+	}
+	res, err := s.run(ctx, p.stmt.query, p.args)
+	if err != nil {
+		return err
+	}
+	p.result = res
+	return nil
+}
+
+// closeStmt handles Close.
+func (s *session) closeStmt(body []byte) error {
+	r := &reader{buf: body}
+	kind := r.byte()
+	name := r.string()
+	if r.err != nil {
+		return r.err
+	}
+	switch kind {
+	case 'S':
+		delete(s.stmts, name)
+	case 'P':
+		delete(s.portals, name)
+	default:
+		return newError("08P01", "invalid CLOSE message subtype %d", kind)
+	}
+	// CloseComplete
+	return s.mc.writeMessage('3', nil)
+}
+
+var errTxAborted = newError("25P02", "current transaction is aborted, commands ignored until end of transaction block")
+
+// run executes a statement.
+// The transaction control statements are mapped to the Data API transactions.
+func (s *session) run(ctx context.Context, query string, args []driver.NamedValue) (*result, error) {
+	stmt := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(sqllex.TrimLeadingComments(sqllex.PostgreSQL, query)), ";"))
+	words := strings.Fields(strings.ToUpper(stmt))
+	if len(words) == 0 {
+		return &result{}, nil
+	}
+
+	switch words[0] {
+	case "BEGIN":
+		return s.begin(ctx)
+	case "START":
+		if len(words) > 1 && words[1] == "TRANSACTION" {
+			return s.begin(ctx)
+		}
+	case "COMMIT", "END":
+		if len(words) == 1 || words[1] == "WORK" || words[1] == "TRANSACTION" {
+			return s.endTransaction(true)
+		}
+	case "ROLLBACK", "ABORT":
+		// ROLLBACK TO SAVEPOINT is executed as a normal statement.
+		if len(words) == 1 || words[1] == "WORK" || words[1] == "TRANSACTION" {
+			return s.endTransaction(false)
+		}
+	}
+	if s.txFailed {
+		return nil, errTxAborted
+	}
+
+	res, err := s.query(ctx, query, args)
+	if err != nil {
+		if s.tx != nil {
+			s.txFailed = true
+		}
+		return nil, err
+	}
+	res.tag = commandTag(words, res)
+	return res, nil
+}
+
+// query executes a statement on the connection of the driver.
+func (s *session) query(ctx context.Context, query string, args []driver.NamedValue) (*result, error) {
+	r, err := s.conn.QueryContext(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	rows, ok := r.(*rdsdata.Rows)
+	if !ok {
+		return nil, fmt.Errorf("unexpected rows of the driver: %T", r)
+	}
+
+	res := &result{
+		columns: rows.ColumnMetadata(),
+	}
+	if len(res.columns) == 0 {
+		affected, _ := rows.Result().RowsAffected()
+		res.tag = strconv.FormatInt(affected, 10)
+		return res, nil
+	}
+	for {
+		row := make([]driver.Value, len(res.columns))
+		err := rows.Next(row)
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		res.rows = append(res.rows, row)
+	}
+	res.tag = strconv.Itoa(len(res.rows))
+	return res, nil
+}
+
+func (s *session) begin(ctx context.Context) (*result, error) {
+	if s.tx == nil {
+		tx, err := s.conn.BeginTx(ctx, driver.TxOptions{})
+		if err != nil {
+			return nil, err
+		}
+		s.tx = tx
+	}
+	// PostgreSQL warns and ignores the nested BEGIN.
+	return &result{tag: "BEGIN"}, nil
+}
+
+// endTransaction commits or rolls back the transaction.
+// COMMIT of a failed transaction rolls it back as PostgreSQL does.
+func (s *session) endTransaction(commit bool) (*result, error) {
+	tag := "ROLLBACK"
+	if commit && !s.txFailed {
+		tag = "COMMIT"
+	}
+	tx := s.tx
+	if tx == nil {
+		return &result{tag: tag}, nil
+	}
+	s.tx = nil
+	s.txFailed = false
+	var err error
+	if tag == "COMMIT" {
+		err = tx.Commit()
+	} else {
+		err = tx.Rollback()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &result{tag: tag}, nil
+}
+
+// commandTag returns the tag of CommandComplete.
+// res.tag has the number of the rows.
+func commandTag(words []string, res *result) string {
+	switch words[0] {
+	case "INSERT":
+		return "INSERT 0 " + res.tag
+	case "UPDATE", "DELETE", "MERGE", "FETCH", "MOVE", "COPY":
+		return words[0] + " " + res.tag
+	case "CREATE", "DROP", "ALTER":
+		if len(words) > 1 {
+			return words[0] + " " + words[1]
+		}
+	}
+	if len(res.columns) > 0 {
+		return "SELECT " + res.tag
+	}
+	return words[0]
+}
+
+// rowDescription returns the body of RowDescription.
+func rowDescription(columns []types.ColumnMetadata, formats []int16) []byte {
+	b := appendInt16(nil, int16(len(columns)))
+	for i, col := range columns {
+		name := col.Label
+		if name == nil {
+			name = col.Name
+		}
+		typ := typeOf(col)
+		if name != nil {
+			b = appendString(b, *name)
+		} else {
+			b = appendString(b, "?column?")
+		}
+		b = appendInt32(b, 0) // table OID
+		b = appendInt16(b, 0) // column attribute number
+		b = appendInt32(b, typ.oid)
+		b = appendInt16(b, typ.size)
+		b = appendInt32(b, -1) // type modifier
+		b = appendInt16(b, formatOf(formats, i))
+	}
+	return b
+}
+
+// dataRow returns the body of DataRow.
+func dataRow(columns []types.ColumnMetadata, formats []int16, values []driver.Value) ([]byte, error) {
+	b := appendInt16(nil, int16(len(values)))
+	for i, v := range values {
+		if v == nil {
+			b = appendInt32(b, -1)
+			continue
+		}
+		oid := typeOf(columns[i]).oid
+		var data []byte
+		if formatOf(formats, i) == formatBinary {
+			var err error
+			data, err = encodeBinary(v, oid)
+			if err != nil {
+				return nil, newError("22P03", "failed to encode column %d: %v", i+1, err)
+			}
+		} else {
+			data = encodeText(v, oid)
+		}
+		b = appendInt32(b, int32(len(data)))
+		b = append(b, data...)
+	}
+	return b, nil
+}
+
+// formatOf returns the format code of the i-th value.
+// No codes mean text, and a single code applies to all values.
+func formatOf(formats []int16, i int) int16 {
+	switch len(formats) {
+	case 0:
+		return formatText
+	case 1:
+		return formats[0]
+	}
+	if i < len(formats) {
+		return formats[i]
+	}
+	return formatText
+}
+
+var placeholderRegex = regexp.MustCompile(`\$([0-9]+)`)
+
+// numParams returns the number of the parameters in the query.
+// It finds the placeholders in the same way as the driver.
+func numParams(query string) int {
+	n := 0
+	for _, m := range placeholderRegex.FindAllStringSubmatch(query, -1) {
+		if i, err := strconv.Atoi(m[1]); err == nil {
+			n = max(n, i)
+		}
+	}
+	return n
+}
+
+func (s *session) readyForQuery() error {
+	status := byte(txIdle)
+	switch {
+	case s.txFailed:
+		status = txFailed
+	case s.tx != nil:
+		status = txActive
+	}
+	if err := s.mc.writeMessage('Z', []byte{status}); err != nil {
+		return err
+	}
+	return s.mc.flush()
+}
+
+// writeError writes ErrorResponse.
+func (s *session) writeError(err error) error {
+	var pgErr *pgError
+	if !errors.As(err, &pgErr) {
+		pgErr = databaseError(err)
+	}
+	b := append([]byte{'S'}, pgErr.severity...)
+	b = append(b, 0, 'V')
+	b = append(b, pgErr.severity...)
+	b = append(b, 0, 'C')
+	b = append(b, pgErr.code...)
+	b = append(b, 0, 'M')
+	b = append(b, pgErr.message...)
+	b = append(b, 0, 0)
+	return s.mc.writeMessage('E', b)
+}
+
+// sqlStateRegex matches the SQLSTATE in the messages of the Data API,
+// e.g. `ERROR: relation "users" does not exist\n  Position: 15; SQLState: 42P01`.
+var sqlStateRegex = regexp.MustCompile(`;\s*SQLState:\s*([0-9A-Z]{5})\s*$`)
+
+// databaseError converts an error of the Data API into the error of PostgreSQL.
+func databaseError(err error) *pgError {
+	var dbErr *types.DatabaseErrorException
+	if !errors.As(err, &dbErr) {
+		return newError("XX000", "%s", err.Error())
+	}
+	msg := dbErr.ErrorMessage()
+	code := "XX000"
+	if m := sqlStateRegex.FindStringSubmatchIndex(msg); m != nil {
+		code = msg[m[2]:m[3]]
+		msg = msg[:m[0]]
+	}
+	msg = strings.TrimPrefix(msg, "ERROR: ")
+	if i := strings.IndexByte(msg, '\n'); i >= 0 {
+		msg = msg[:i]
+	}
+	return newError(code, "%s", msg)
+}
+
+// errorMessage returns the message of the database error if possible.
+func errorMessage(err error) string {
+	return databaseError(err).message
+}
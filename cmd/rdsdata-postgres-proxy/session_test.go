@@ -0,0 +1,366 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"log/slog"
+	"net"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata/types"
+	"github.com/shogo82148/go-rdsdata"
+	"github.com/shogo82148/go-rdsdata/rdsdatatest"
+)
+
+// testClient is a minimal PostgreSQL client for the tests.
+type testClient struct {
+	t  *testing.T
+	mc *messageConn
+}
+
+// message is a message from the server.
+type message struct {
+	typ  byte
+	body []byte
+}
+
+// startSession starts a session and returns the client connected to it.
+// It returns the messages until the first ReadyForQuery or ErrorResponse.
+func startSession(t *testing.T, fake *rdsdatatest.Fake, password, clientPassword string) (*testClient, []message) {
+	t.Helper()
+	fake.Version = rdsdatatest.VersionPostgres
+	srv := &server{
+		cfg: &rdsdata.Config{
+			Client:      fake,
+			ResourceArn: rdsdatatest.DefaultResourceArn,
+			SecretArn:   rdsdatatest.DefaultSecretArn,
+		},
+		password: password,
+		logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+	serverConn, clientConn := net.Pipe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer serverConn.Close()
+		s := &session{
+			server: srv,
+			mc:     newMessageConn(serverConn),
+			pid:    1,
+			logger: srv.logger,
+		}
+		s.serve(context.Background())
+	}()
+	t.Cleanup(func() {
+		clientConn.Close()
+		<-done
+	})
+
+	c := &testClient{t: t, mc: newMessageConn(clientConn)}
+
+	// SSLRequest is refused.
+	c.writeRaw(binary.BigEndian.AppendUint32(binary.BigEndian.AppendUint32(nil, 8), sslRequestCode))
+	if b, err := c.mc.r.ReadByte(); err != nil || b != 'N' {
+		t.Fatalf("unexpected response to SSLRequest: %q, %v", b, err)
+	}
+
+	// StartupMessage
+	body := appendInt32(nil, protocolVersion3)
+	body = appendString(body, "user")
+	body = appendString(body, "postgres")
+	body = append(body, 0)
+	c.writeRaw(append(binary.BigEndian.AppendUint32(nil, uint32(len(body)+4)), body...))
+
+	if password != "" {
+		if msg := c.read(); msg.typ != 'R' || !bytes.Equal(msg.body, []byte{0, 0, 0, 3}) {
+			t.Fatalf("want AuthenticationCleartextPassword, got %q", msg.typ)
+		}
+		c.write('p', appendString(nil, clientPassword))
+	}
+	return c, c.readUntilReady()
+}
+
+func (c *testClient) writeRaw(b []byte) {
+	c.t.Helper()
+	if _, err := c.mc.w.Write(b); err != nil {
+		c.t.Fatal(err)
+	}
+	if err := c.mc.flush(); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+func (c *testClient) write(typ byte, body []byte) {
+	c.t.Helper()
+	if err := c.mc.writeMessage(typ, body); err != nil {
+		c.t.Fatal(err)
+	}
+	if err := c.mc.flush(); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+func (c *testClient) read() message {
+	c.t.Helper()
+	typ, body, err := c.mc.readMessage()
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	return message{typ: typ, body: body}
+}
+
+// readUntilReady reads the messages until ReadyForQuery.
+// ErrorResponse of FATAL also ends the messages.
+func (c *testClient) readUntilReady() []message {
+	c.t.Helper()
+	var msgs []message
+	for {
+		msg := c.read()
+		msgs = append(msgs, msg)
+		if msg.typ == 'Z' || (msg.typ == 'E' && bytes.HasPrefix(msg.body, []byte("SFATAL"))) {
+			return msgs
+		}
+	}
+}
+
+// messageTypes returns the types of the messages.
+func messageTypes(msgs []message) string {
+	var b []byte
+	for _, msg := range msgs {
+		b = append(b, msg.typ)
+	}
+	return string(b)
+}
+
+// dataRowValues decodes the body of DataRow.
+func dataRowValues(t *testing.T, body []byte) [][]byte {
+	t.Helper()
+	r := &reader{buf: body}
+	values := make([][]byte, r.int16())
+	for i := range values {
+		if n := r.int32(); n >= 0 {
+			values[i] = r.bytes(int(n))
+		}
+	}
+	if r.err != nil {
+		t.Fatal(r.err)
+	}
+	return values
+}
+
+func TestStartup(t *testing.T) {
+	fake := rdsdatatest.New()
+	_, msgs := startSession(t, fake, "secret", "secret")
+	if got := msgs[0]; got.typ != 'R' || !bytes.Equal(got.body, []byte{0, 0, 0, 0}) {
+		t.Errorf("want AuthenticationOk, got %q %x", got.typ, got.body)
+	}
+	if last := msgs[len(msgs)-1]; last.typ != 'Z' || last.body[0] != txIdle {
+		t.Errorf("want ReadyForQuery, got %q %q", last.typ, last.body)
+	}
+}
+
+func TestStartup_AuthenticationFailed(t *testing.T) {
+	fake := rdsdatatest.New()
+	_, msgs := startSession(t, fake, "secret", "wrong")
+	last := msgs[len(msgs)-1]
+	if last.typ != 'E' || !bytes.Contains(last.body, []byte("C28P01\x00")) {
+		t.Errorf("want ErrorResponse, got %q %q", last.typ, last.body)
+	}
+}
+
+func TestSimpleQuery(t *testing.T) {
+	fake := rdsdatatest.New()
+	fake.ExpectStatement("SELECT id, name, active FROM users").WillReturnRecords(
+		[]types.ColumnMetadata{
+			rdsdatatest.Column("id", "int8"),
+			rdsdatatest.Column("name", "varchar"),
+			rdsdatatest.Column("active", "bool"),
+		},
+		[]types.Field{rdsdatatest.Long(1), rdsdatatest.String("alice"), rdsdatatest.Bool(true)},
+		[]types.Field{rdsdatatest.Long(2), rdsdatatest.Null(), rdsdatatest.Bool(false)},
+	)
+	fake.ExpectStatement("UPDATE users SET active = false").WillReturnResult(2)
+	c, _ := startSession(t, fake, "", "")
+
+	c.write('Q', appendString(nil, "SELECT id, name, active FROM users"))
+	msgs := c.readUntilReady()
+	if got := messageTypes(msgs); got != "TDDCZ" {
+		t.Fatalf("unexpected messages: %q", got)
+	}
+	r := &reader{buf: msgs[0].body}
+	if n := r.int16(); n != 3 {
+		t.Fatalf("unexpected number of columns: %d", n)
+	}
+	if name := r.string(); name != "id" {
+		t.Errorf("unexpected column name: %q", name)
+	}
+	r.int32()
+	r.int16()
+	if oid := r.int32(); oid != oidInt8 {
+		t.Errorf("unexpected type OID: %d", oid)
+	}
+	want := [][][]byte{
+		{[]byte("1"), []byte("alice"), []byte("t")},
+		{[]byte("2"), nil, []byte("f")},
+	}
+	for i, w := range want {
+		got := dataRowValues(t, msgs[i+1].body)
+		for j := range w {
+			if !bytes.Equal(got[j], w[j]) || (got[j] == nil) != (w[j] == nil) {
+				t.Errorf("row %d column %d: got %q, want %q", i, j, got[j], w[j])
+			}
+		}
+	}
+	if tag := string(msgs[3].body); tag != "SELECT 2\x00" {
+		t.Errorf("unexpected tag: %q", tag)
+	}
+
+	c.write('Q', appendString(nil, "UPDATE users SET active = false"))
+	msgs = c.readUntilReady()
+	if got := messageTypes(msgs); got != "CZ" {
+		t.Fatalf("unexpected messages: %q", got)
+	}
+	if tag := string(msgs[0].body); tag != "UPDATE 2\x00" {
+		t.Errorf("unexpected tag: %q", tag)
+	}
+
+	if err := fake.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestExtendedQuery(t *testing.T) {
+	fake := rdsdatatest.New()
+	columns := []types.ColumnMetadata{
+		rdsdatatest.Column("id", "int8"),
+		rdsdatatest.Column("price", "numeric"),
+	}
+	// Describe of the statement
+	fake.ExpectStatement("SELECT * FROM (SELECT id, price FROM items WHERE id = :1\n) AS rdsdata_describe LIMIT 0").
+		WithParameters(rdsdatatest.Param("1", rdsdatatest.Null())).
+		WillReturnRecords(columns)
+	// Execute
+	fake.ExpectStatement("SELECT id, price FROM items WHERE id = :1").
+		WithParameters(rdsdatatest.Param("1", rdsdatatest.Long(42))).
+		WillReturnRecords(columns, []types.Field{rdsdatatest.Long(42), rdsdatatest.String("12.50")})
+	c, _ := startSession(t, fake, "", "")
+
+	// Parse, Describe and Sync
+	body := appendString(nil, "s1")
+	body = appendString(body, "SELECT id, price FROM items WHERE id = $1")
+	body = appendInt16(body, 1)
+	body = appendInt32(body, oidInt8)
+	c.write('P', body)
+	c.write('D', appendString([]byte{'S'}, "s1"))
+	c.write('S', nil)
+	msgs := c.readUntilReady()
+	if got := messageTypes(msgs); got != "1tTZ" {
+		t.Fatalf("unexpected messages: %q", got)
+	}
+	if !bytes.Equal(msgs[1].body, []byte{0, 1, 0, 0, 0, oidInt8}) {
+		t.Errorf("unexpected ParameterDescription: %x", msgs[1].body)
+	}
+
+	// Bind with a binary parameter and binary results, Execute and Sync
+	body = appendString(nil, "")
+	body = appendString(body, "s1")
+	body = appendInt16(body, 1)
+	body = appendInt16(body, formatBinary)
+	body = appendInt16(body, 1)
+	body = appendInt32(body, 8)
+	body = binary.BigEndian.AppendUint64(body, 42)
+	body = appendInt16(body, 1)
+	body = appendInt16(body, formatBinary)
+	c.write('B', body)
+	c.write('E', appendInt32(appendString(nil, ""), 0))
+	c.write('S', nil)
+	msgs = c.readUntilReady()
+	if got := messageTypes(msgs); got != "2DCZ" {
+		t.Fatalf("unexpected messages: %q", got)
+	}
+	values := dataRowValues(t, msgs[1].body)
+	if want := []byte{0, 0, 0, 0, 0, 0, 0, 42}; !bytes.Equal(values[0], want) {
+		t.Errorf("unexpected id: got %x, want %x", values[0], want)
+	}
+	// 12.50 = 12 * 10000^0 + 5000 * 10000^-1 with the scale 2
+	if want := []byte{0, 2, 0, 0, 0, 0, 0, 2, 0, 12, 0x13, 0x88}; !bytes.Equal(values[1], want) {
+		t.Errorf("unexpected price: got %x, want %x", values[1], want)
+	}
+	if tag := string(msgs[2].body); tag != "SELECT 1\x00" {
+		t.Errorf("unexpected tag: %q", tag)
+	}
+
+	if err := fake.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestExtendedQuery_Error(t *testing.T) {
+	fake := rdsdatatest.New()
+	c, _ := startSession(t, fake, "", "")
+
+	// Bind to an unknown statement; the messages until Sync are ignored.
+	body := appendString(nil, "")
+	body = appendString(body, "missing")
+	body = appendInt16(body, 0)
+	body = appendInt16(body, 0)
+	body = appendInt16(body, 0)
+	c.write('B', body)
+	c.write('E', appendInt32(appendString(nil, ""), 0))
+	c.write('S', nil)
+	msgs := c.readUntilReady()
+	if got := messageTypes(msgs); got != "EZ" {
+		t.Fatalf("unexpected messages: %q", got)
+	}
+	if !bytes.Contains(msgs[0].body, []byte("C26000\x00")) {
+		t.Errorf("unexpected error: %q", msgs[0].body)
+	}
+}
+
+func TestTransaction(t *testing.T) {
+	fake := rdsdatatest.New()
+	fake.ExpectBegin().WillReturnTransactionID("tx-1")
+	fake.ExpectStatement("INSERT INTO users (name) VALUES ('bob')").InTransaction().WillReturnError(&types.DatabaseErrorException{
+		Message: aws.String("ERROR: duplicate key value violates unique constraint \"users_name_key\"\n  Detail: Key (name)=(bob) already exists.; SQLState: 23505"),
+	})
+	fake.ExpectRollback()
+	c, _ := startSession(t, fake, "", "")
+
+	// the block comments of PostgreSQL are nested.
+	c.write('Q', appendString(nil, "/* a /* b */ c */ BEGIN"))
+	msgs := c.readUntilReady()
+	if got := messageTypes(msgs); got != "CZ" || msgs[1].body[0] != txActive {
+		t.Fatalf("unexpected messages: %q %q", got, msgs[1].body)
+	}
+
+	c.write('Q', appendString(nil, "INSERT INTO users (name) VALUES ('bob')"))
+	msgs = c.readUntilReady()
+	if got := messageTypes(msgs); got != "EZ" || msgs[1].body[0] != txFailed {
+		t.Fatalf("unexpected messages: %q %q", got, msgs[1].body)
+	}
+	want := "SERROR\x00VERROR\x00C23505\x00Mduplicate key value violates unique constraint \"users_name_key\"\x00\x00"
+	if got := string(msgs[0].body); got != want {
+		t.Errorf("unexpected error: got %q, want %q", got, want)
+	}
+
+	// the statements are rejected until the end of the transaction.
+	c.write('Q', appendString(nil, "SELECT 1"))
+	msgs = c.readUntilReady()
+	if got := messageTypes(msgs); got != "EZ" || !bytes.Contains(msgs[0].body, []byte("C25P02\x00")) {
+		t.Fatalf("unexpected messages: %q %q", got, msgs[0].body)
+	}
+
+	// COMMIT of the failed transaction rolls it back.
+	c.write('Q', appendString(nil, "COMMIT"))
+	msgs = c.readUntilReady()
+	if got := messageTypes(msgs); got != "CZ" || string(msgs[0].body) != "ROLLBACK\x00" || msgs[1].body[0] != txIdle {
+		t.Fatalf("unexpected messages: %q %q", got, msgs)
+	}
+
+	if err := fake.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
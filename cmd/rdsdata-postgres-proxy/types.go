@@ -0,0 +1,541 @@
+package main
+
+import (
+	"database/sql/driver"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata/types"
+)
+
+// the OIDs of the types.
+const (
+	oidUnspecified = 0
+	oidBool        = 16
+	oidBytea       = 17
+	oidName        = 19
+	oidInt8        = 20
+	oidInt2        = 21
+	oidInt4        = 23
+	oidText        = 25
+	oidOID         = 26
+	oidJSON        = 114
+	oidFloat4      = 700
+	oidFloat8      = 701
+	oidBPChar      = 1042
+	oidVarChar     = 1043
+	oidDate        = 1082
+	oidTimestamp   = 1114
+	oidTimestampTZ = 1184
+	oidNumeric     = 1700
+	oidUUID        = 2950
+	oidJSONB       = 3802
+)
+
+// the format codes.
+const (
+	formatText   = 0
+	formatBinary = 1
+)
+
+// pgType is a type that the proxy can encode in both formats.
+type pgType struct {
+	oid  int32
+	size int16
+}
+
+// pgTypes maps the type names in ColumnMetadata to the types.
+// The other types are described as text,
+// so that the clients do not request the binary format that the proxy cannot encode.
+var pgTypes = map[string]pgType{
+	"bool":        {oidBool, 1},
+	"boolean":     {oidBool, 1},
+	"bytea":       {oidBytea, -1},
+	"name":        {oidName, 64},
+	"int8":        {oidInt8, 8},
+	"bigint":      {oidInt8, 8},
+	"bigserial":   {oidInt8, 8},
+	"serial8":     {oidInt8, 8},
+	"int2":        {oidInt2, 2},
+	"smallint":    {oidInt2, 2},
+	"smallserial": {oidInt2, 2},
+	"serial2":     {oidInt2, 2},
+	"int4":        {oidInt4, 4},
+	"int":         {oidInt4, 4},
+	"integer":     {oidInt4, 4},
+	"serial":      {oidInt4, 4},
+	"serial4":     {oidInt4, 4},
+	"text":        {oidText, -1},
+	"oid":         {oidOID, 4},
+	"json":        {oidJSON, -1},
+	"float4":      {oidFloat4, 4},
+	"real":        {oidFloat4, 4},
+	"float8":      {oidFloat8, 8},
+	"bpchar":      {oidBPChar, -1},
+	"char":        {oidBPChar, -1},
+	"varchar":     {oidVarChar, -1},
+	"date":        {oidDate, 4},
+	"timestamp":   {oidTimestamp, 8},
+	"timestamptz": {oidTimestampTZ, 8},
+	"numeric":     {oidNumeric, -1},
+	"decimal":     {oidNumeric, -1},
+	"uuid":        {oidUUID, 16},
+	"jsonb":       {oidJSONB, -1},
+}
+
+// typeOf returns the type of the column.
+func typeOf(col types.ColumnMetadata) pgType {
+	if t, ok := pgTypes[strings.ToLower(aws.ToString(col.TypeName))]; ok {
+		return t
+	}
+	return pgType{oidText, -1}
+}
+
+// the epoch of the binary format of date and time.
+var postgresEpoch = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// encodeText encodes a value from the driver in the text format.
+func encodeText(v driver.Value, oid int32) []byte {
+	switch v := v.(type) {
+	case bool:
+		if v {
+			return []byte("t")
+		}
+		return []byte("f")
+	case int64:
+		return strconv.AppendInt(nil, v, 10)
+	case float64:
+		bitSize := 64
+		if oid == oidFloat4 {
+			bitSize = 32
+		}
+		switch {
+		case math.IsInf(v, 1):
+			return []byte("Infinity")
+		case math.IsInf(v, -1):
+			return []byte("-Infinity")
+		case math.IsNaN(v):
+			return []byte("NaN")
+		}
+		return strconv.AppendFloat(nil, v, 'g', -1, bitSize)
+	case []byte:
+		if oid == oidBytea {
+			return append([]byte(`\x`), hex.EncodeToString(v)...)
+		}
+		return v
+	case string:
+		if oid == oidTimestampTZ && !hasZone(v) {
+			// the Data API returns timestamptz in UTC without the offset.
+			return []byte(v + "+00")
+		}
+		return []byte(v)
+	case types.ArrayValue:
+		return appendArray(nil, v)
+	default:
+		return []byte(fmt.Sprint(v))
+	}
+}
+
+// hasZone reports whether the timestamp has the time zone offset.
+func hasZone(s string) bool {
+	if strings.HasSuffix(s, "Z") {
+		return true
+	}
+	// the date part also contains "-".
+	if i := strings.LastIndexAny(s, "+-"); i > len("2006-01-02") {
+		return true
+	}
+	return false
+}
+
+// appendArray appends an array in the text format, e.g. {1,2,3}.
+func appendArray(b []byte, array types.ArrayValue) []byte {
+	b = append(b, '{')
+	switch v := array.(type) {
+	case *types.ArrayValueMemberLongValues:
+		for i, n := range v.Value {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			b = strconv.AppendInt(b, n, 10)
+		}
+	case *types.ArrayValueMemberDoubleValues:
+		for i, f := range v.Value {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			b = strconv.AppendFloat(b, f, 'g', -1, 64)
+		}
+	case *types.ArrayValueMemberBooleanValues:
+		for i, t := range v.Value {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			if t {
+				b = append(b, 't')
+			} else {
+				b = append(b, 'f')
+			}
+		}
+	case *types.ArrayValueMemberStringValues:
+		for i, s := range v.Value {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			b = append(b, '"')
+			for _, c := range []byte(s) {
+				if c == '"' || c == '\\' {
+					b = append(b, '\\')
+				}
+				b = append(b, c)
+			}
+			b = append(b, '"')
+		}
+	case *types.ArrayValueMemberArrayValues:
+		for i, a := range v.Value {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			b = appendArray(b, a)
+		}
+	}
+	return append(b, '}')
+}
+
+// encodeBinary encodes a value from the driver in the binary format.
+func encodeBinary(v driver.Value, oid int32) ([]byte, error) {
+	switch oid {
+	case oidBool:
+		b, ok := v.(bool)
+		if !ok {
+			return nil, fmt.Errorf("unexpected bool value: %T", v)
+		}
+		if b {
+			return []byte{1}, nil
+		}
+		return []byte{0}, nil
+
+	case oidInt2, oidInt4, oidInt8, oidOID:
+		n, err := toInt64(v)
+		if err != nil {
+			return nil, err
+		}
+		switch oid {
+		case oidInt2:
+			return binary.BigEndian.AppendUint16(nil, uint16(n)), nil
+		case oidInt8:
+			return binary.BigEndian.AppendUint64(nil, uint64(n)), nil
+		default:
+			return binary.BigEndian.AppendUint32(nil, uint32(n)), nil
+		}
+
+	case oidFloat4, oidFloat8:
+		f, err := toFloat64(v)
+		if err != nil {
+			return nil, err
+		}
+		if oid == oidFloat4 {
+			return binary.BigEndian.AppendUint32(nil, math.Float32bits(float32(f))), nil
+		}
+		return binary.BigEndian.AppendUint64(nil, math.Float64bits(f)), nil
+
+	case oidBytea:
+		switch v := v.(type) {
+		case []byte:
+			return v, nil
+		case string:
+			return []byte(v), nil
+		}
+		return nil, fmt.Errorf("unexpected bytea value: %T", v)
+
+	case oidJSONB:
+		// the version of the binary format.
+		return append([]byte{1}, encodeText(v, oid)...), nil
+
+	case oidUUID:
+		s := strings.ReplaceAll(string(encodeText(v, oid)), "-", "")
+		b, err := hex.DecodeString(s)
+		if err != nil || len(b) != 16 {
+			return nil, fmt.Errorf("unexpected uuid value: %q", s)
+		}
+		return b, nil
+
+	case oidDate:
+		t, err := time.Parse("2006-01-02", string(encodeText(v, oid)))
+		if err != nil {
+			return nil, err
+		}
+		days := t.Sub(postgresEpoch) / (24 * time.Hour)
+		return binary.BigEndian.AppendUint32(nil, uint32(int32(days))), nil
+
+	case oidTimestamp, oidTimestampTZ:
+		t, err := parseTimestamp(string(encodeText(v, oidTimestamp)))
+		if err != nil {
+			return nil, err
+		}
+		micro := t.Sub(postgresEpoch).Microseconds()
+		return binary.BigEndian.AppendUint64(nil, uint64(micro)), nil
+
+	case oidNumeric:
+		return encodeNumeric(string(encodeText(v, oid)))
+	}
+
+	// the other types are described as text.
+	return encodeText(v, oid), nil
+}
+
+func toInt64(v driver.Value) (int64, error) {
+	switch v := v.(type) {
+	case int64:
+		return v, nil
+	case string:
+		return strconv.ParseInt(v, 10, 64)
+	}
+	return 0, fmt.Errorf("unexpected integer value: %T", v)
+}
+
+func toFloat64(v driver.Value) (float64, error) {
+	switch v := v.(type) {
+	case float64:
+		return v, nil
+	case int64:
+		return float64(v), nil
+	case string:
+		return strconv.ParseFloat(v, 64)
+	}
+	return 0, fmt.Errorf("unexpected float value: %T", v)
+}
+
+// parseTimestamp parses a timestamp in the format of PostgreSQL.
+func parseTimestamp(s string) (time.Time, error) {
+	for _, layout := range []string{
+		"2006-01-02 15:04:05.999999999Z07:00",
+		"2006-01-02 15:04:05.999999999Z07",
+		"2006-01-02 15:04:05.999999999",
+		time.RFC3339Nano,
+	} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unexpected timestamp value: %q", s)
+}
+
+// encodeNumeric encodes a decimal string in the binary format of numeric.
+func encodeNumeric(s string) ([]byte, error) {
+	const (
+		signPositive = 0x0000
+		signNegative = 0x4000
+		signNaN      = 0xc000
+	)
+	if s == "NaN" {
+		b := binary.BigEndian.AppendUint16(nil, 0)
+		b = binary.BigEndian.AppendUint16(b, 0)
+		b = binary.BigEndian.AppendUint16(b, signNaN)
+		return binary.BigEndian.AppendUint16(b, 0), nil
+	}
+
+	sign := uint16(signPositive)
+	if rest, ok := strings.CutPrefix(s, "-"); ok {
+		sign = signNegative
+		s = rest
+	}
+	intPart, fracPart, _ := strings.Cut(s, ".")
+	for _, c := range intPart + fracPart {
+		if c < '0' || c > '9' {
+			return nil, fmt.Errorf("unexpected numeric value: %q", s)
+		}
+	}
+	dscale := len(fracPart)
+
+	// split the digits into the groups of 4 digits aligned at the decimal point.
+	intPart = strings.Repeat("0", (4-len(intPart)%4)%4) + intPart
+	fracPart += strings.Repeat("0", (4-len(fracPart)%4)%4)
+	all := intPart + fracPart
+	digits := make([]uint16, 0, len(all)/4)
+	for i := 0; i < len(all); i += 4 {
+		n, _ := strconv.ParseUint(all[i:i+4], 10, 16)
+		digits = append(digits, uint16(n))
+	}
+	weight := len(intPart)/4 - 1
+
+	// trim the zeros.
+	for len(digits) > 0 && digits[0] == 0 {
+		digits = digits[1:]
+		weight--
+	}
+	for len(digits) > 0 && digits[len(digits)-1] == 0 {
+		digits = digits[:len(digits)-1]
+	}
+	if len(digits) == 0 {
+		weight = 0
+		sign = signPositive
+	}
+
+	b := binary.BigEndian.AppendUint16(nil, uint16(len(digits)))
+	b = binary.BigEndian.AppendUint16(b, uint16(int16(weight)))
+	b = binary.BigEndian.AppendUint16(b, sign)
+	b = binary.BigEndian.AppendUint16(b, uint16(dscale))
+	for _, d := range digits {
+		b = binary.BigEndian.AppendUint16(b, d)
+	}
+	return b, nil
+}
+
+// decodeNumeric decodes the binary format of numeric into a decimal string.
+func decodeNumeric(data []byte) (string, error) {
+	r := &reader{buf: data}
+	ndigits := int(r.int16())
+	weight := int(r.int16())
+	sign := uint16(r.int16())
+	dscale := int(r.int16())
+	digits := make([]int16, ndigits)
+	for i := range digits {
+		digits[i] = r.int16()
+	}
+	if r.err != nil {
+		return "", r.err
+	}
+	if sign == 0xc000 {
+		return "NaN", nil
+	}
+
+	var sb strings.Builder
+	if sign == 0x4000 {
+		sb.WriteByte('-')
+	}
+	digit := func(i int) int16 {
+		if i >= 0 && i < len(digits) {
+			return digits[i]
+		}
+		return 0
+	}
+	if weight < 0 {
+		sb.WriteByte('0')
+	}
+	for i := 0; i <= weight; i++ {
+		if i == 0 {
+			sb.WriteString(strconv.Itoa(int(digit(i))))
+		} else {
+			fmt.Fprintf(&sb, "%04d", digit(i))
+		}
+	}
+	if dscale > 0 {
+		var frac strings.Builder
+		for i := weight + 1; frac.Len() < dscale; i++ {
+			fmt.Fprintf(&frac, "%04d", digit(i))
+		}
+		sb.WriteByte('.')
+		sb.WriteString(frac.String()[:dscale])
+	}
+	return sb.String(), nil
+}
+
+// decodeParam decodes a parameter of Bind into a value that the driver accepts.
+// The types that the driver has no counterparts are passed as strings.
+func decodeParam(data []byte, oid int32, format int16) (driver.Value, error) {
+	if format == formatText {
+		return decodeTextParam(string(data), oid)
+	}
+	return decodeBinaryParam(data, oid)
+}
+
+func decodeTextParam(s string, oid int32) (driver.Value, error) {
+	switch oid {
+	case oidInt2, oidInt4, oidInt8, oidOID:
+		return strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	case oidFloat4, oidFloat8:
+		return strconv.ParseFloat(strings.TrimSpace(s), 64)
+	case oidBool:
+		switch strings.ToLower(strings.TrimSpace(s)) {
+		case "t", "true", "y", "yes", "on", "1":
+			return true, nil
+		case "f", "false", "n", "no", "off", "0":
+			return false, nil
+		}
+		return nil, fmt.Errorf("invalid input syntax for type boolean: %q", s)
+	case oidBytea:
+		if rest, ok := strings.CutPrefix(s, `\x`); ok {
+			return hex.DecodeString(rest)
+		}
+		return []byte(s), nil
+	case oidTimestamp, oidTimestampTZ:
+		// time.Time is sent with the TIMESTAMP type hint.
+		return parseTimestamp(s)
+	}
+	return s, nil
+}
+
+func decodeBinaryParam(data []byte, oid int32) (driver.Value, error) {
+	switch oid {
+	case oidBool:
+		if len(data) != 1 {
+			return nil, errMalformedMessage
+		}
+		return data[0] != 0, nil
+	case oidInt2, oidInt4, oidInt8, oidOID:
+		switch len(data) {
+		case 2:
+			return int64(int16(binary.BigEndian.Uint16(data))), nil
+		case 4:
+			if oid == oidOID {
+				return int64(binary.BigEndian.Uint32(data)), nil
+			}
+			return int64(int32(binary.BigEndian.Uint32(data))), nil
+		case 8:
+			return int64(binary.BigEndian.Uint64(data)), nil
+		}
+		return nil, errMalformedMessage
+	case oidFloat4:
+		if len(data) != 4 {
+			return nil, errMalformedMessage
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(data))), nil
+	case oidFloat8:
+		if len(data) != 8 {
+			return nil, errMalformedMessage
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(data)), nil
+	case oidBytea:
+		return data, nil
+	case oidUUID:
+		if len(data) != 16 {
+			return nil, errMalformedMessage
+		}
+		h := hex.EncodeToString(data)
+		return h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:], nil
+	case oidDate:
+		if len(data) != 4 {
+			return nil, errMalformedMessage
+		}
+		days := int32(binary.BigEndian.Uint32(data))
+		return postgresEpoch.AddDate(0, 0, int(days)).Format("2006-01-02"), nil
+	case oidTimestamp, oidTimestampTZ:
+		if len(data) != 8 {
+			return nil, errMalformedMessage
+		}
+		micro := int64(binary.BigEndian.Uint64(data))
+		return postgresEpoch.Add(time.Duration(micro) * time.Microsecond), nil
+	case oidNumeric:
+		return decodeNumeric(data)
+	case oidJSONB:
+		if len(data) == 0 || data[0] != 1 {
+			return nil, errors.New("unsupported jsonb version")
+		}
+		return string(data[1:]), nil
+	}
+
+	// text, varchar, json and the unspecified types.
+	if utf8.Valid(data) {
+		return string(data), nil
+	}
+	return data, nil
+}
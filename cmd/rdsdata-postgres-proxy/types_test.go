@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata/types"
+)
+
+func TestEncodeText(t *testing.T) {
+	testCases := []struct {
+		value driver.Value
+		oid   int32
+		want  string
+	}{
+		{true, oidBool, "t"},
+		{int64(-1), oidInt8, "-1"},
+		{float64(1.5), oidFloat8, "1.5"},
+		{[]byte{0xde, 0xad}, oidBytea, `\xdead`},
+		{"2024-01-02 03:04:05", oidTimestampTZ, "2024-01-02 03:04:05+00"},
+		{"2024-01-02 03:04:05+09", oidTimestampTZ, "2024-01-02 03:04:05+09"},
+		{&types.ArrayValueMemberStringValues{Value: []string{"a", `b"c`}}, oidText, `{"a","b\"c"}`},
+		{&types.ArrayValueMemberArrayValues{Value: []types.ArrayValue{
+			&types.ArrayValueMemberLongValues{Value: []int64{1, 2}},
+			&types.ArrayValueMemberLongValues{Value: []int64{3, 4}},
+		}}, oidText, "{{1,2},{3,4}}"},
+	}
+
+	for _, tc := range testCases {
+		if got := string(encodeText(tc.value, tc.oid)); got != tc.want {
+			t.Errorf("encodeText(%v, %d) = %q, want %q", tc.value, tc.oid, got, tc.want)
+		}
+	}
+}
+
+func TestEncodeBinary(t *testing.T) {
+	testCases := []struct {
+		value driver.Value
+		oid   int32
+		want  []byte
+	}{
+		{true, oidBool, []byte{1}},
+		{int64(-2), oidInt2, []byte{0xff, 0xfe}},
+		{int64(1), oidInt4, []byte{0, 0, 0, 1}},
+		{float64(1), oidFloat4, []byte{0x3f, 0x80, 0, 0}},
+		{"a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11", oidUUID, []byte{0xa0, 0xee, 0xbc, 0x99, 0x9c, 0x0b, 0x4e, 0xf8, 0xbb, 0x6d, 0x6b, 0xb9, 0xbd, 0x38, 0x0a, 0x11}},
+		{"2000-01-02", oidDate, []byte{0, 0, 0, 1}},
+		{"2000-01-01 00:00:01", oidTimestamp, []byte{0, 0, 0, 0, 0, 0x0f, 0x42, 0x40}},
+		{`{"a":1}`, oidJSONB, []byte("\x01{\"a\":1}")},
+		{"0", oidNumeric, []byte{0, 0, 0, 0, 0, 0, 0, 0}},
+		{"-0.0012", oidNumeric, []byte{0, 1, 0xff, 0xff, 0x40, 0, 0, 4, 0, 12}},
+		{"10000", oidNumeric, []byte{0, 1, 0, 1, 0, 0, 0, 0, 0, 1}},
+		{"interval", oidText, []byte("interval")},
+	}
+
+	for _, tc := range testCases {
+		got, err := encodeBinary(tc.value, tc.oid)
+		if err != nil {
+			t.Errorf("encodeBinary(%v, %d): %v", tc.value, tc.oid, err)
+			continue
+		}
+		if !bytes.Equal(got, tc.want) {
+			t.Errorf("encodeBinary(%v, %d) = %x, want %x", tc.value, tc.oid, got, tc.want)
+		}
+	}
+}
+
+func TestNumeric(t *testing.T) {
+	for _, s := range []string{"0", "1", "-1", "12.50", "10000", "123456789.000123", "-0.0012", "0.00", "NaN"} {
+		b, err := encodeNumeric(s)
+		if err != nil {
+			t.Errorf("encodeNumeric(%q): %v", s, err)
+			continue
+		}
+		got, err := decodeNumeric(b)
+		if err != nil {
+			t.Errorf("decodeNumeric(%x): %v", b, err)
+			continue
+		}
+		if got != s {
+			t.Errorf("round trip of %q: got %q", s, got)
+		}
+	}
+}
+
+func TestDecodeParam(t *testing.T) {
+	testCases := []struct {
+		data   []byte
+		oid    int32
+		format int16
+		want   driver.Value
+	}{
+		{[]byte("42"), oidInt4, formatText, int64(42)},
+		{[]byte("on"), oidBool, formatText, true},
+		{[]byte("42"), oidUnspecified, formatText, "42"},
+		{[]byte{0xff, 0xff, 0xff, 0xfe}, oidInt4, formatBinary, int64(-2)},
+		{[]byte{0x3f, 0xf8, 0, 0, 0, 0, 0, 0}, oidFloat8, formatBinary, float64(1.5)},
+		{[]byte{0, 0, 0, 1}, oidDate, formatBinary, "2000-01-02"},
+		{[]byte{0, 0, 0, 0, 0, 0x0f, 0x42, 0x40}, oidTimestamp, formatBinary, time.Date(2000, 1, 1, 0, 0, 1, 0, time.UTC)},
+		{[]byte("2000-01-01 00:00:01"), oidTimestamp, formatText, time.Date(2000, 1, 1, 0, 0, 1, 0, time.UTC)},
+		{[]byte("hello"), oidText, formatBinary, "hello"},
+	}
+
+	for _, tc := range testCases {
+		got, err := decodeParam(tc.data, tc.oid, tc.format)
+		if err != nil {
+			t.Errorf("decodeParam(%x, %d, %d): %v", tc.data, tc.oid, tc.format, err)
+			continue
+		}
+		if tm, ok := tc.want.(time.Time); ok {
+			if !tm.Equal(got.(time.Time)) {
+				t.Errorf("decodeParam(%x, %d, %d) = %v, want %v", tc.data, tc.oid, tc.format, got, tc.want)
+			}
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("decodeParam(%x, %d, %d) = %v, want %v", tc.data, tc.oid, tc.format, got, tc.want)
+		}
+	}
+}
+
+func TestDatabaseError(t *testing.T) {
+	testCases := []struct {
+		err     error
+		code    string
+		message string
+	}{
+		{
+			err: &types.DatabaseErrorException{
+				Message: aws.String("ERROR: relation \"users\" does not exist\n  Position: 15; SQLState: 42P01"),
+			},
+			code:    "42P01",
+			message: `relation "users" does not exist`,
+		},
+		{
+			err:     &types.DatabaseErrorException{Message: aws.String("something wrong")},
+			code:    "XX000",
+			message: "something wrong",
+		},
+		{
+			err:     errors.New("network error"),
+			code:    "XX000",
+			message: "network error",
+		},
+	}
+
+	for _, tc := range testCases {
+		got := databaseError(tc.err)
+		if got.code != tc.code || got.message != tc.message {
+			t.Errorf("databaseError(%v) = {%s, %q}, want {%s, %q}", tc.err, got.code, got.message, tc.code, tc.message)
+		}
+	}
+}
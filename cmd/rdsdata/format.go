@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// resultSet is a result set of a query.
+type resultSet struct {
+	columns []string
+	rows    [][]any
+}
+
+// formatValue formats a value for the table, CSV and TSV output.
+func formatValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "NULL"
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.Format("2006-01-02 15:04:05.999999999")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// writeTable writes the result set as a table like the mysql command.
+func writeTable(w io.Writer, rs *resultSet) error {
+	widths := make([]int, len(rs.columns))
+	for i, col := range rs.columns {
+		widths[i] = utf8.RuneCountInString(col)
+	}
+	cells := make([][]string, len(rs.rows))
+	for i, row := range rs.rows {
+		cells[i] = make([]string, len(row))
+		for j, v := range row {
+			s := formatValue(v)
+			cells[i][j] = s
+			widths[j] = max(widths[j], utf8.RuneCountInString(s))
+		}
+	}
+
+	var buf strings.Builder
+	border := func() {
+		buf.WriteByte('+')
+		for _, w := range widths {
+			buf.WriteString(strings.Repeat("-", w+2))
+			buf.WriteByte('+')
+		}
+		buf.WriteByte('\n')
+	}
+	line := func(values []string) {
+		buf.WriteByte('|')
+		for i, v := range values {
+			buf.WriteByte(' ')
+			buf.WriteString(v)
+			buf.WriteString(strings.Repeat(" ", widths[i]-utf8.RuneCountInString(v)+1))
+			buf.WriteByte('|')
+		}
+		buf.WriteByte('\n')
+	}
+
+	border()
+	line(rs.columns)
+	border()
+	for _, row := range cells {
+		line(row)
+	}
+	border()
+	_, err := io.WriteString(w, buf.String())
+	return err
+}
+
+// writeDelimited writes the result set as CSV or TSV.
+func writeDelimited(w io.Writer, rs *resultSet, comma rune) error {
+	cw := csv.NewWriter(w)
+	cw.Comma = comma
+	if err := cw.Write(rs.columns); err != nil {
+		return err
+	}
+	record := make([]string, len(rs.columns))
+	for _, row := range rs.rows {
+		for i, v := range row {
+			record[i] = formatValue(v)
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeJSON writes the result set as a JSON array of objects.
+func writeJSON(w io.Writer, rs *resultSet) error {
+	rows := make([]json.RawMessage, 0, len(rs.rows))
+	for _, row := range rs.rows {
+		// build the object manually to keep the order of the columns.
+		var buf strings.Builder
+		buf.WriteByte('{')
+		for i, v := range row {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			key, err := json.Marshal(rs.columns[i])
+			if err != nil {
+				return err
+			}
+			value, err := json.Marshal(jsonValue(v))
+			if err != nil {
+				return err
+			}
+			buf.Write(key)
+			buf.WriteByte(':')
+			buf.Write(value)
+		}
+		buf.WriteByte('}')
+		rows = append(rows, json.RawMessage(buf.String()))
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(rows)
+}
+
+func jsonValue(v any) any {
+	switch v := v.(type) {
+	case []byte:
+		if utf8.Valid(v) {
+			return string(v)
+		}
+		return v
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	default:
+		return v
+	}
+}
+
+// writeResult writes the result set in the format.
+func writeResult(w io.Writer, format string, rs *resultSet) error {
+	switch format {
+	case "table":
+		return writeTable(w, rs)
+	case "csv":
+		return writeDelimited(w, rs, ',')
+	case "tsv":
+		return writeDelimited(w, rs, '\t')
+	case "json":
+		return writeJSON(w, rs)
+	}
+	return fmt.Errorf("unknown format: %q", format)
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestWriteResult(t *testing.T) {
+	rs := &resultSet{
+		columns: []string{"id", "name", "created_at"},
+		rows: [][]any{
+			{int64(1), []byte("alice"), time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+			{int64(2), nil, nil},
+		},
+	}
+
+	testCases := []struct {
+		format string
+		want   string
+	}{
+		{
+			format: "table",
+			want: "+----+-------+---------------------+\n" +
+				"| id | name  | created_at          |\n" +
+				"+----+-------+---------------------+\n" +
+				"| 1  | alice | 2024-01-02 03:04:05 |\n" +
+				"| 2  | NULL  | NULL                |\n" +
+				"+----+-------+---------------------+\n",
+		},
+		{
+			format: "csv",
+			want: "id,name,created_at\n" +
+				"1,alice,2024-01-02 03:04:05\n" +
+				"2,NULL,NULL\n",
+		},
+		{
+			format: "tsv",
+			want: "id\tname\tcreated_at\n" +
+				"1\talice\t2024-01-02 03:04:05\n" +
+				"2\tNULL\tNULL\n",
+		},
+		{
+			format: "json",
+			want: "[\n" +
+				"  {\n" +
+				"    \"id\": 1,\n" +
+				"    \"name\": \"alice\",\n" +
+				"    \"created_at\": \"2024-01-02T03:04:05Z\"\n" +
+				"  },\n" +
+				"  {\n" +
+				"    \"id\": 2,\n" +
+				"    \"name\": null,\n" +
+				"    \"created_at\": null\n" +
+				"  }\n" +
+				"]\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := writeResult(&buf, tc.format, rs); err != nil {
+				t.Fatal(err)
+			}
+			if got := buf.String(); got != tc.want {
+				t.Errorf("unexpected output:\n%s\nwant:\n%s", got, tc.want)
+			}
+		})
+	}
+}
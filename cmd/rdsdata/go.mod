@@ -0,0 +1,30 @@
+module github.com/shogo82148/go-rdsdata/cmd/rdsdata
+
+go 1.23.0
+
+require (
+	github.com/aws/aws-sdk-go-v2/service/rdsdata v1.32.7
+	github.com/chzyer/readline v1.5.1
+	github.com/shogo82148/go-rdsdata v0.0.0-20241126165402-f706116fb8b6
+)
+
+require (
+	github.com/aws/aws-sdk-go-v2 v1.39.3 // indirect
+	github.com/aws/aws-sdk-go-v2/config v1.31.13 // indirect
+	github.com/aws/aws-sdk-go-v2/credentials v1.18.17 // indirect
+	github.com/aws/aws-sdk-go-v2/feature/ec2/imds v1.18.10 // indirect
+	github.com/aws/aws-sdk-go-v2/internal/configsources v1.4.10 // indirect
+	github.com/aws/aws-sdk-go-v2/internal/endpoints/v2 v2.7.10 // indirect
+	github.com/aws/aws-sdk-go-v2/internal/ini v1.8.4 // indirect
+	github.com/aws/aws-sdk-go-v2/service/internal/accept-encoding v1.13.2 // indirect
+	github.com/aws/aws-sdk-go-v2/service/internal/presigned-url v1.13.10 // indirect
+	github.com/aws/aws-sdk-go-v2/service/secretsmanager v1.35.4 // indirect
+	github.com/aws/aws-sdk-go-v2/service/sso v1.29.7 // indirect
+	github.com/aws/aws-sdk-go-v2/service/ssooidc v1.35.2 // indirect
+	github.com/aws/aws-sdk-go-v2/service/sts v1.38.7 // indirect
+	github.com/aws/smithy-go v1.23.1 // indirect
+	github.com/shogo82148/go-retry/v2 v2.0.1 // indirect
+	golang.org/x/sys v0.35.0 // indirect
+)
+
+replace github.com/shogo82148/go-rdsdata => ../../
@@ -0,0 +1,295 @@
+// The rdsdata command is an interactive SQL shell for the RDS Data API.
+//
+// Usage:
+//
+//	rdsdata [flags] [DSN]
+//
+// The DSN is in the format that rdsdata.ParseDSN understands, e.g.
+//
+//	rdsdata 'rdsdata://?resource_arn=arn:aws:rds:...&secret_arn=arn:aws:secretsmanager:...'
+//
+// The connection can also be configured by the flags:
+//
+//	rdsdata -resource-arn arn:aws:rds:... -secret-arn arn:aws:secretsmanager:... -region us-east-1
+//
+// In the interactive mode, the statements are terminated by ";".
+// BEGIN, COMMIT and ROLLBACK are mapped to the Data API transactions.
+// The transaction modes ISOLATION LEVEL, READ ONLY and READ WRITE of BEGIN and
+// START TRANSACTION are supported, and the other modes are rejected.
+// The meta commands are:
+//
+//	\timing  toggle the timing of the statements
+//	\q       quit
+//	\?       show the help
+//
+// With the -e flag, the statements are executed non-interactively,
+// and the results are printed in the format specified by -format (csv, tsv or json).
+//
+// The migrate subcommand applies the schema migrations in the directory specified by -dir
+// (see the package github.com/shogo82148/go-rdsdata/migrate):
+//
+//	rdsdata migrate [flags] up
+//	rdsdata migrate [flags] down [N]
+//	rdsdata migrate [flags] status
+//	rdsdata migrate [flags] force VERSION
+//
+// The DSN of the migrate subcommand is given by the -dsn flag.
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	"github.com/chzyer/readline"
+	"github.com/shogo82148/go-rdsdata"
+	"github.com/shogo82148/go-rdsdata/internal/sqllex"
+	"github.com/shogo82148/go-rdsdata/migrate"
+)
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "rdsdata:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	if len(args) > 0 && args[0] == "migrate" {
+		return runMigrate(args[1:])
+	}
+
+	flags := flag.NewFlagSet("rdsdata", flag.ContinueOnError)
+	var (
+		conn    = addConnectionFlags(flags)
+		execute = flags.String("e", "", "execute the statements and quit")
+		format  = flags.String("format", "tsv", "the output format of -e: csv, tsv or json")
+	)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: rdsdata [flags] [DSN]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := conn.config(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	db := sql.OpenDB(rdsdata.NewConnector(cfg))
+	defer db.Close()
+	c, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	engine, err := migrate.DetectConnEngine(c)
+	if err != nil {
+		return err
+	}
+
+	s := &session{
+		ctx:    ctx,
+		conn:   c,
+		syntax: sqllex.ForEngine(string(engine)),
+		out:    os.Stdout,
+		format: "table",
+	}
+	defer s.close()
+
+	if *execute != "" {
+		switch *format {
+		case "csv", "tsv", "json":
+		default:
+			return fmt.Errorf("unknown format: %q", *format)
+		}
+		s.format = *format
+		return runScript(ctx, s, *execute)
+	}
+
+	if !readline.DefaultIsTerminal() {
+		// read the statements from the pipe.
+		data, err := io.ReadAll(bufio.NewReader(os.Stdin))
+		if err != nil {
+			return err
+		}
+		s.format = *format
+		return runScript(ctx, s, string(data))
+	}
+
+	// The interactive mode handles the interrupts by itself.
+	stop()
+	s.ctx = context.Background()
+	return repl(s)
+}
+
+// connectionFlags are the flags that configure the connection.
+type connectionFlags struct {
+	resourceArn *string
+	secretArn   *string
+	database    *string
+	region      *string
+}
+
+func addConnectionFlags(flags *flag.FlagSet) *connectionFlags {
+	return &connectionFlags{
+		resourceArn: flags.String("resource-arn", "", "the ARN of the Aurora cluster"),
+		secretArn:   flags.String("secret-arn", "", "the ARN of the secret"),
+		database:    flags.String("database", "", "the name of the database"),
+		region:      flags.String("region", "", "the AWS region (default: the region of the resource ARN)"),
+	}
+}
+
+// config builds the config from the DSN or the flags.
+// The flags override the values in the DSN.
+func (f *connectionFlags) config(dsn string) (*rdsdata.Config, error) {
+	cfg := &rdsdata.Config{}
+	if dsn != "" {
+		var err error
+		cfg, err = rdsdata.ParseDSN(dsn)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if *f.resourceArn != "" {
+		cfg.ResourceArn = *f.resourceArn
+	}
+	if *f.secretArn != "" {
+		cfg.SecretArn = *f.secretArn
+	}
+	if *f.database != "" {
+		cfg.Database = *f.database
+	}
+	if *f.region != "" {
+		cfg.AWSRegion = *f.region
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// runScript executes the statements in the script.
+func runScript(ctx context.Context, s *session, script string) error {
+	stmts, rest := splitStatements(s.syntax, script)
+	if rest := strings.TrimSpace(rest); rest != "" {
+		stmts = append(stmts, rest)
+	}
+	for _, stmt := range stmts {
+		if err := s.run(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const help = `Statements are terminated by ";".
+BEGIN, COMMIT and ROLLBACK are mapped to the Data API transactions.
+BEGIN and START TRANSACTION accept ISOLATION LEVEL, READ ONLY and READ WRITE.
+
+  \timing  toggle the timing of the statements
+  \q       quit
+  \?       show this help
+`
+
+// repl runs the read-eval-print loop.
+func repl(s *session) error {
+	historyFile := ""
+	if home, err := os.UserHomeDir(); err == nil {
+		historyFile = filepath.Join(home, ".rdsdata_history")
+	}
+	rl, err := readline.NewEx(&readline.Config{
+		Prompt:          "rdsdata> ",
+		HistoryFile:     historyFile,
+		InterruptPrompt: "^C",
+		EOFPrompt:       "exit",
+	})
+	if err != nil {
+		return err
+	}
+	defer rl.Close()
+	return interact(s, rl)
+}
+
+// lineReader reads the input of the interactive mode.
+// It is implemented by *readline.Instance.
+type lineReader interface {
+	Readline() (string, error)
+	SetPrompt(prompt string)
+	Stderr() io.Writer
+}
+
+// interact reads the statements from rl, and executes them until the end of the input.
+func interact(s *session, rl lineReader) error {
+	var buf strings.Builder
+	for {
+		switch {
+		case buf.Len() > 0:
+			rl.SetPrompt("      -> ")
+		case s.tx != nil:
+			rl.SetPrompt("rdsdata*> ")
+		default:
+			rl.SetPrompt("rdsdata> ")
+		}
+
+		line, err := rl.Readline()
+		if errors.Is(err, readline.ErrInterrupt) {
+			buf.Reset()
+			continue
+		} else if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if buf.Len() == 0 {
+			switch strings.TrimSpace(line) {
+			case "":
+				continue
+			case `\q`, "exit", "quit":
+				return nil
+			case `\?`, `\h`, "help":
+				fmt.Fprint(s.out, help)
+				continue
+			case `\timing`:
+				s.timing = !s.timing
+				if s.timing {
+					fmt.Fprintln(s.out, "Timing is on.")
+				} else {
+					fmt.Fprintln(s.out, "Timing is off.")
+				}
+				continue
+			}
+		}
+
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+		stmts, rest := splitStatements(s.syntax, buf.String())
+		buf.Reset()
+		if strings.TrimSpace(rest) != "" {
+			buf.WriteString(rest)
+		}
+
+		for _, stmt := range stmts {
+			// cancel the running statement by Ctrl+C.
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+			err := s.run(ctx, stmt)
+			stop()
+			if err != nil {
+				fmt.Fprintln(rl.Stderr(), "ERROR:", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata/types"
+	"github.com/shogo82148/go-rdsdata/internal/sqllex"
+	"github.com/shogo82148/go-rdsdata/rdsdatatest"
+)
+
+// lines is a lineReader that returns the lines in order.
+type lines struct {
+	lines  []string
+	stderr bytes.Buffer
+}
+
+func (l *lines) Readline() (string, error) {
+	if len(l.lines) == 0 {
+		return "", io.EOF
+	}
+	line := l.lines[0]
+	l.lines = l.lines[1:]
+	return line, nil
+}
+
+func (l *lines) SetPrompt(prompt string) {}
+
+func (l *lines) Stderr() io.Writer {
+	return &l.stderr
+}
+
+func TestInteract_Transaction(t *testing.T) {
+	fake := rdsdatatest.New()
+	fake.ExpectBegin().WillReturnTransactionID("tx")
+	fake.ExpectStatement("INSERT INTO users (id) VALUES (1)").InTransaction().WillReturnResult(1)
+	fake.ExpectCommit()
+
+	ctx := context.Background()
+	db := fake.OpenDB(nil)
+	defer db.Close()
+	c, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	s := &session{
+		ctx:    ctx,
+		conn:   c,
+		syntax: sqllex.MySQL,
+		out:    &out,
+		format: "table",
+	}
+	defer s.close()
+
+	// the statements run after BEGIN must be in the transaction,
+	// even though each statement has its own context.
+	rl := &lines{lines: []string{"BEGIN;", "INSERT INTO users (id)", "VALUES (1);", "COMMIT;"}}
+	if err := interact(s, rl); err != nil {
+		t.Fatal(err)
+	}
+	if rl.stderr.Len() > 0 {
+		t.Errorf("unexpected error: %s", rl.stderr.String())
+	}
+	want := "Transaction started\nQuery OK, 1 row affected\nTransaction committed\n"
+	if got := out.String(); got != want {
+		t.Errorf("unexpected output: %q, want %q", got, want)
+	}
+	if err := fake.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestInteract_ReadOnlyTransaction(t *testing.T) {
+	fake := rdsdatatest.New()
+	fake.ExpectBegin().WillReturnTransactionID("tx")
+	fake.ExpectStatement("START TRANSACTION READ ONLY").InTransaction().WillReturnResult(0)
+	fake.ExpectStatement("SELECT 1").InTransaction().
+		WillReturnRecords(
+			[]types.ColumnMetadata{rdsdatatest.Column("1", "BIGINT")},
+			[]types.Field{rdsdatatest.Long(1)},
+		)
+	fake.ExpectCommit()
+
+	ctx := context.Background()
+	db := fake.OpenDB(nil)
+	defer db.Close()
+	c, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	s := &session{
+		ctx:    ctx,
+		conn:   c,
+		syntax: sqllex.MySQL,
+		out:    &out,
+		format: "csv",
+	}
+	defer s.close()
+
+	// the unsupported modes are rejected without starting the transaction.
+	rl := &lines{lines: []string{"START TRANSACTION WITH CONSISTENT SNAPSHOT;", "START TRANSACTION READ ONLY;", "SELECT 1;", "COMMIT;"}}
+	if err := interact(s, rl); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := rl.stderr.String(), "ERROR: unsupported transaction mode: WITH CONSISTENT SNAPSHOT\n"; got != want {
+		t.Errorf("unexpected error: %q, want %q", got, want)
+	}
+	if err := fake.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/shogo82148/go-rdsdata"
+	"github.com/shogo82148/go-rdsdata/migrate"
+)
+
+const migrateUsage = `Usage: rdsdata migrate [flags] COMMAND
+
+Commands:
+  up             apply all the pending migrations
+  down [N]       revert the latest N migrations (default 1)
+  status         show the status of the migrations
+  force VERSION  record VERSION as the current version without running migrations
+
+Flags:
+`
+
+// runMigrate runs the migrate subcommand.
+func runMigrate(args []string) error {
+	flags := flag.NewFlagSet("rdsdata migrate", flag.ContinueOnError)
+	var (
+		conn        = addConnectionFlags(flags)
+		dsn         = flags.String("dsn", "", "the DSN of the database")
+		dir         = flags.String("dir", "migrations", "the directory of the migration files")
+		table       = flags.String("table", "schema_migrations", "the name of the version table")
+		lockTimeout = flags.Duration("lock-timeout", time.Minute, "how long to wait for the lock held by another process")
+		quiet       = flags.Bool("q", false, "don't report the progress")
+	)
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), migrateUsage)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return errors.New("migrate: the command is required")
+	}
+
+	cfg, err := conn.config(*dsn)
+	if err != nil {
+		return err
+	}
+	migrations, err := migrate.Load(os.DirFS(*dir))
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	db := sql.OpenDB(rdsdata.NewConnector(cfg))
+	defer db.Close()
+
+	opts := []migrate.Option{
+		migrate.WithTable(*table),
+		migrate.WithLockTimeout(*lockTimeout),
+	}
+	if !*quiet {
+		opts = append(opts, migrate.WithLogger(slog.New(slog.NewTextHandler(os.Stderr, nil))))
+	}
+	m, err := migrate.New(db, migrations, opts...)
+	if err != nil {
+		return err
+	}
+
+	cmd, cmdArgs := flags.Arg(0), flags.Args()[1:]
+	switch cmd {
+	case "up":
+		return m.Up(ctx)
+	case "down":
+		steps := 1
+		if len(cmdArgs) > 0 {
+			steps, err = strconv.Atoi(cmdArgs[0])
+			if err != nil || steps <= 0 {
+				return fmt.Errorf("migrate: invalid number of steps: %q", cmdArgs[0])
+			}
+		}
+		err := m.Down(ctx, steps)
+		if errors.Is(err, migrate.ErrNoChange) {
+			fmt.Fprintln(os.Stderr, "no migration is applied")
+			return nil
+		}
+		return err
+	case "status":
+		status, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		return printStatus(os.Stdout, status)
+	case "force":
+		if len(cmdArgs) == 0 {
+			return errors.New("migrate: the version is required")
+		}
+		version, err := strconv.ParseInt(cmdArgs[0], 10, 64)
+		if err != nil || version < 0 {
+			return fmt.Errorf("migrate: invalid version: %q", cmdArgs[0])
+		}
+		return m.Force(ctx, version)
+	default:
+		return fmt.Errorf("migrate: unknown command: %q", cmd)
+	}
+}
+
+// printStatus prints the status of the migrations as a table.
+func printStatus(w io.Writer, status []migrate.Status) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tNAME\tSTATUS")
+	for _, s := range status {
+		state := "pending"
+		switch {
+		case s.Dirty:
+			state = "dirty"
+		case s.Applied:
+			state = "applied"
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\n", s.Version, s.Name, state)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/shogo82148/go-rdsdata/migrate"
+)
+
+func TestPrintStatus(t *testing.T) {
+	var buf strings.Builder
+	err := printStatus(&buf, []migrate.Status{
+		{Version: 1, Name: "create_users", Applied: true},
+		{Version: 2, Name: "add_name", Applied: true, Dirty: true},
+		{Version: 3, Name: "add_email"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "VERSION  NAME          STATUS\n" +
+		"1        create_users  applied\n" +
+		"2        add_name      dirty\n" +
+		"3        add_email     pending\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/shogo82148/go-rdsdata/internal/sqllex"
+)
+
+// queryer is the common interface of *sql.Conn and *sql.Tx.
+type queryer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// session is a session of the shell.
+// All the statements are executed on the same connection.
+type session struct {
+	// ctx is the lifetime of the session.
+	// The transactions are bound to it, because database/sql rolls back
+	// the transaction when the context passed to BeginTx is canceled.
+	ctx context.Context
+
+	conn   *sql.Conn
+	syntax sqllex.Syntax
+	tx     *sql.Tx
+	out    io.Writer
+	format string
+	timing bool
+}
+
+func (s *session) queryer() queryer {
+	if s.tx != nil {
+		return s.tx
+	}
+	return s.conn
+}
+
+// run executes a statement and writes the result.
+func (s *session) run(ctx context.Context, stmt string) error {
+	start := time.Now()
+	msg, err := s.execute(ctx, stmt)
+	if err != nil {
+		return err
+	}
+	if s.format != "table" {
+		return nil
+	}
+	if s.timing {
+		msg += fmt.Sprintf(" (%.3f sec)", time.Since(start).Seconds())
+	}
+	_, err = fmt.Fprintln(s.out, msg)
+	return err
+}
+
+func (s *session) execute(ctx context.Context, stmt string) (string, error) {
+	switch statementKind(s.syntax, stmt) {
+	case kindBegin:
+		if s.tx != nil {
+			return "", errors.New("already in a transaction")
+		}
+		opts, err := txOptions(s.syntax, stmt)
+		if err != nil {
+			return "", err
+		}
+		tx, err := s.conn.BeginTx(s.ctx, opts)
+		if err != nil {
+			return "", err
+		}
+		s.tx = tx
+		return "Transaction started", nil
+
+	case kindCommit:
+		if s.tx == nil {
+			return "", errors.New("not in a transaction")
+		}
+		tx := s.tx
+		s.tx = nil
+		if err := tx.Commit(); err != nil {
+			return "", err
+		}
+		return "Transaction committed", nil
+
+	case kindRollback:
+		if s.tx == nil {
+			return "", errors.New("not in a transaction")
+		}
+		tx := s.tx
+		s.tx = nil
+		if err := tx.Rollback(); err != nil {
+			return "", err
+		}
+		return "Transaction rolled back", nil
+
+	case kindQuery:
+		rs, err := s.query(ctx, stmt)
+		if err != nil {
+			return "", err
+		}
+		if s.format == "table" && len(rs.columns) == 0 {
+			return "Query OK", nil
+		}
+		if err := writeResult(s.out, s.format, rs); err != nil {
+			return "", err
+		}
+		switch len(rs.rows) {
+		case 0:
+			return "Empty set", nil
+		case 1:
+			return "1 row in set", nil
+		default:
+			return fmt.Sprintf("%d rows in set", len(rs.rows)), nil
+		}
+
+	default:
+		result, err := s.queryer().ExecContext(ctx, stmt)
+		if err != nil {
+			return "", err
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return "", err
+		}
+		if n == 1 {
+			return "Query OK, 1 row affected", nil
+		}
+		return fmt.Sprintf("Query OK, %d rows affected", n), nil
+	}
+}
+
+func (s *session) query(ctx context.Context, stmt string) (*resultSet, error) {
+	rows, err := s.queryer().QueryContext(ctx, stmt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	rs := &resultSet{columns: columns}
+	for rows.Next() {
+		values := make([]any, len(columns))
+		ptrs := make([]any, len(columns))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+		rs.rows = append(rs.rows, values)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return rs, nil
+}
+
+// close rolls back the open transaction and closes the connection.
+func (s *session) close() error {
+	var errs []error
+	if s.tx != nil {
+		errs = append(errs, s.tx.Rollback())
+		s.tx = nil
+	}
+	errs = append(errs, s.conn.Close())
+	return errors.Join(errs...)
+}
+
+type kind int
+
+const (
+	kindExec kind = iota
+	kindQuery
+	kindBegin
+	kindCommit
+	kindRollback
+)
+
+// statementKind classifies the statement by its leading keywords.
+func statementKind(syntax sqllex.Syntax, stmt string) kind {
+	var words []string
+	for _, token := range sqllex.Code(syntax, stmt) {
+		if token.Kind == sqllex.Word {
+			words = append(words, strings.ToUpper(token.Text))
+		}
+	}
+	if len(words) == 0 {
+		return kindExec
+	}
+	switch words[0] {
+	case "BEGIN":
+		return kindBegin
+	case "START":
+		if len(words) > 1 && words[1] == "TRANSACTION" {
+			return kindBegin
+		}
+	case "COMMIT", "END":
+		return kindCommit
+	case "ROLLBACK", "ABORT":
+		// ROLLBACK TO SAVEPOINT is executed as a normal statement.
+		if len(words) > 1 && words[1] == "TO" {
+			return kindExec
+		}
+		return kindRollback
+	case "SELECT", "SHOW", "WITH", "DESCRIBE", "DESC", "EXPLAIN", "VALUES", "TABLE":
+		return kindQuery
+	}
+	for _, w := range words {
+		if w == "RETURNING" {
+			return kindQuery
+		}
+	}
+	return kindExec
+}
+
+// txOptions parses the transaction modes of BEGIN and START TRANSACTION,
+// such as "START TRANSACTION READ ONLY" and "BEGIN ISOLATION LEVEL SERIALIZABLE".
+// The modes are passed to BeginTx, because the Data API starts the transactions by itself
+// and the statements are never sent to the database.
+// It returns an error for the modes that can't be passed, such as WITH CONSISTENT SNAPSHOT.
+func txOptions(syntax sqllex.Syntax, stmt string) (*sql.TxOptions, error) {
+	var words []string
+	for _, token := range sqllex.Code(syntax, stmt) {
+		if token.Kind == sqllex.Operator && token.Text == ";" {
+			continue
+		}
+		words = append(words, strings.ToUpper(token.Text))
+	}
+
+	// skip BEGIN [WORK | TRANSACTION] and START TRANSACTION.
+	if hasWords(words, "START", "TRANSACTION") {
+		words = words[2:]
+	} else if len(words) > 0 {
+		words = words[1:]
+		if hasWords(words, "WORK") || hasWords(words, "TRANSACTION") {
+			words = words[1:]
+		}
+	}
+
+	opts := &sql.TxOptions{}
+	for len(words) > 0 {
+		switch {
+		case hasWords(words, "READ", "ONLY"):
+			opts.ReadOnly = true
+			words = words[2:]
+		case hasWords(words, "READ", "WRITE"):
+			opts.ReadOnly = false
+			words = words[2:]
+		case hasWords(words, "ISOLATION", "LEVEL", "READ", "UNCOMMITTED"):
+			opts.Isolation = sql.LevelReadUncommitted
+			words = words[4:]
+		case hasWords(words, "ISOLATION", "LEVEL", "READ", "COMMITTED"):
+			opts.Isolation = sql.LevelReadCommitted
+			words = words[4:]
+		case hasWords(words, "ISOLATION", "LEVEL", "REPEATABLE", "READ"):
+			opts.Isolation = sql.LevelRepeatableRead
+			words = words[4:]
+		case hasWords(words, "ISOLATION", "LEVEL", "SERIALIZABLE"):
+			opts.Isolation = sql.LevelSerializable
+			words = words[3:]
+		default:
+			return nil, fmt.Errorf("unsupported transaction mode: %s", strings.Join(words, " "))
+		}
+
+		// the modes are separated by commas, which PostgreSQL allows to omit.
+		if hasWords(words, ",") {
+			words = words[1:]
+		}
+	}
+	return opts, nil
+}
+
+// hasWords reports whether words starts with the keywords.
+func hasWords(words []string, keywords ...string) bool {
+	return len(words) >= len(keywords) && slices.Equal(words[:len(keywords)], keywords)
+}
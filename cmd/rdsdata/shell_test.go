@@ -0,0 +1,83 @@
+package main
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/shogo82148/go-rdsdata/internal/sqllex"
+)
+
+func TestStatementKind(t *testing.T) {
+	testCases := []struct {
+		stmt string
+		want kind
+	}{
+		{"SELECT 1", kindQuery},
+		{"  select * from t", kindQuery},
+		{"/* comment */ SHOW TABLES", kindQuery},
+		{"-- comment\nWITH t AS (SELECT 1) SELECT * FROM t", kindQuery},
+		{"(SELECT 1) UNION (SELECT 2)", kindQuery},
+		{"INSERT INTO t VALUES (1) RETURNING id", kindQuery},
+		{"INSERT INTO t VALUES (1)", kindExec},
+		{"INSERT INTO t VALUES ('returning') -- RETURNING", kindExec},
+		{"# comment\nSELECT 1", kindQuery},
+		{"CREATE TABLE t (id INT)", kindExec},
+		{"BEGIN", kindBegin},
+		{"start transaction", kindBegin},
+		{"COMMIT", kindCommit},
+		{"ROLLBACK", kindRollback},
+		{"ROLLBACK TO SAVEPOINT sp", kindExec},
+	}
+
+	for _, tc := range testCases {
+		if got := statementKind(sqllex.MySQL, tc.stmt); got != tc.want {
+			t.Errorf("statementKind(%q) = %d, want %d", tc.stmt, got, tc.want)
+		}
+	}
+}
+
+func TestTxOptions(t *testing.T) {
+	testCases := []struct {
+		syntax sqllex.Syntax
+		stmt   string
+		want   sql.TxOptions
+	}{
+		{sqllex.MySQL, "BEGIN", sql.TxOptions{}},
+		{sqllex.MySQL, "BEGIN WORK;", sql.TxOptions{}},
+		{sqllex.MySQL, "START TRANSACTION", sql.TxOptions{}},
+		{sqllex.MySQL, "start transaction read only", sql.TxOptions{ReadOnly: true}},
+		{sqllex.MySQL, "START TRANSACTION READ WRITE", sql.TxOptions{}},
+		{sqllex.PostgreSQL, "BEGIN ISOLATION LEVEL SERIALIZABLE", sql.TxOptions{Isolation: sql.LevelSerializable}},
+		{sqllex.PostgreSQL, "BEGIN TRANSACTION ISOLATION LEVEL REPEATABLE READ, READ ONLY", sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true}},
+		{sqllex.PostgreSQL, "START TRANSACTION READ ONLY ISOLATION LEVEL READ COMMITTED", sql.TxOptions{Isolation: sql.LevelReadCommitted, ReadOnly: true}},
+		{sqllex.PostgreSQL, "BEGIN /* comment */ ISOLATION LEVEL READ UNCOMMITTED;", sql.TxOptions{Isolation: sql.LevelReadUncommitted}},
+	}
+
+	for _, tc := range testCases {
+		got, err := txOptions(tc.syntax, tc.stmt)
+		if err != nil {
+			t.Errorf("txOptions(%q) returns an error: %v", tc.stmt, err)
+			continue
+		}
+		if *got != tc.want {
+			t.Errorf("txOptions(%q) = %+v, want %+v", tc.stmt, *got, tc.want)
+		}
+	}
+}
+
+func TestTxOptions_Unsupported(t *testing.T) {
+	testCases := []struct {
+		syntax sqllex.Syntax
+		stmt   string
+	}{
+		{sqllex.MySQL, "START TRANSACTION WITH CONSISTENT SNAPSHOT"},
+		{sqllex.PostgreSQL, "BEGIN ISOLATION LEVEL SNAPSHOT"},
+		{sqllex.PostgreSQL, "BEGIN READ ONLY, DEFERRABLE"},
+	}
+
+	for _, tc := range testCases {
+		if _, err := txOptions(tc.syntax, tc.stmt); err == nil {
+			t.Errorf("txOptions(%q) must return an error", tc.stmt)
+		}
+	}
+}
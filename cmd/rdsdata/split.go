@@ -0,0 +1,33 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/shogo82148/go-rdsdata/internal/sqllex"
+)
+
+// splitStatements splits the input into the statements terminated by ";".
+// It returns the complete statements and the rest of the input that is not terminated yet.
+// Semicolons in quoted strings, quoted identifiers, comments and
+// dollar-quoted strings of PostgreSQL are ignored, following the syntax of the engine.
+func splitStatements(syntax sqllex.Syntax, input string) (stmts []string, rest string) {
+	start := 0
+	s := sqllex.NewScanner(syntax, input)
+	for {
+		token, ok := s.Next()
+		if !ok {
+			break
+		}
+		if token.Unterminated {
+			// wait for the rest of the string or the comment.
+			return stmts, input[start:]
+		}
+		if token.Kind == sqllex.Operator && token.Text == ";" {
+			if stmt := strings.TrimSpace(input[start:token.Pos]); stmt != "" {
+				stmts = append(stmts, stmt)
+			}
+			start = token.End()
+		}
+	}
+	return stmts, input[start:]
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/shogo82148/go-rdsdata/internal/sqllex"
+)
+
+func TestSplitStatements(t *testing.T) {
+	testCases := []struct {
+		syntax sqllex.Syntax
+		input  string
+		stmts  []string
+		rest   string
+	}{
+		{
+			syntax: sqllex.MySQL,
+			input:  "SELECT 1; SELECT 2;",
+			stmts:  []string{"SELECT 1", "SELECT 2"},
+			rest:   "",
+		},
+		{
+			syntax: sqllex.MySQL,
+			input:  "SELECT 1; SELECT",
+			stmts:  []string{"SELECT 1"},
+			rest:   " SELECT",
+		},
+		{
+			syntax: sqllex.MySQL,
+			input:  "SELECT ';', \";\", `;`; -- comment;\nSELECT /* ; */ 2;",
+			stmts:  []string{"SELECT ';', \";\", `;`", "-- comment;\nSELECT /* ; */ 2"},
+			rest:   "",
+		},
+		{
+			syntax: sqllex.MySQL,
+			input:  "SELECT 'it\\'s;';",
+			stmts:  []string{"SELECT 'it\\'s;'"},
+			rest:   "",
+		},
+		{
+			syntax: sqllex.PostgreSQL,
+			input:  "CREATE FUNCTION f() RETURNS int AS $body$ SELECT 1; $body$ LANGUAGE sql; SELECT $1;",
+			stmts:  []string{"CREATE FUNCTION f() RETURNS int AS $body$ SELECT 1; $body$ LANGUAGE sql", "SELECT $1"},
+			rest:   "",
+		},
+		{
+			// backslashes don't escape the quotes in PostgreSQL.
+			syntax: sqllex.PostgreSQL,
+			input:  "SELECT 'a\\'; /* a /* ; */ b */ SELECT \"c;\";",
+			stmts:  []string{"SELECT 'a\\'", "/* a /* ; */ b */ SELECT \"c;\""},
+			rest:   "",
+		},
+		{
+			syntax: sqllex.MySQL,
+			input:  "SELECT 'unterminated;",
+			stmts:  nil,
+			rest:   "SELECT 'unterminated;",
+		},
+		{
+			syntax: sqllex.MySQL,
+			input:  ";;",
+			stmts:  nil,
+			rest:   "",
+		},
+	}
+
+	for _, tc := range testCases {
+		stmts, rest := splitStatements(tc.syntax, tc.input)
+		if !slices.Equal(stmts, tc.stmts) {
+			t.Errorf("splitStatements(%q): unexpected statements: %q, want %q", tc.input, stmts, tc.stmts)
+		}
+		if rest != tc.rest {
+			t.Errorf("splitStatements(%q): unexpected rest: %q, want %q", tc.input, rest, tc.rest)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package rdsdata
+
+import (
+	"context"
+	"maps"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// Commenter appends sqlcommenter comments to the statements, such as
+//
+//	SELECT * FROM users /*application='app',route='%2Fusers',traceparent='00-...'*/
+//
+// so that Performance Insights and the database logs can attribute the statements.
+// See https://google.github.io/sqlcommenter/spec/ for the format.
+//
+// The keys and the values are URL-encoded, so the comment never contains quotes, comment delimiters or colons,
+// which would break the placeholders of the named parameters.
+// The statements that already have comments are not modified, as the specification requires.
+type Commenter struct {
+	// Application is the value of the "application" tag.
+	Application string
+
+	// Tags returns the tags of the statements executed with the context, e.g. the traceparent tag.
+	// It can be nil.
+	Tags func(ctx context.Context) map[string]string
+}
+
+type commentTagsKey struct{}
+
+// WithCommentTags returns a new context that adds the tags to the comments of the statements executed with it,
+// e.g. the route or the controller of the request.
+// The tags override the tags of Commenter.
+//
+//	ctx = rdsdata.WithCommentTags(ctx, map[string]string{"route": "/users/{id}", "controller": "users"})
+func WithCommentTags(ctx context.Context, tags map[string]string) context.Context {
+	merged := maps.Clone(commentTagsFromContext(ctx))
+	if merged == nil {
+		merged = make(map[string]string, len(tags))
+	}
+	maps.Copy(merged, tags)
+	return context.WithValue(ctx, commentTagsKey{}, merged)
+}
+
+func commentTagsFromContext(ctx context.Context) map[string]string {
+	tags, _ := ctx.Value(commentTagsKey{}).(map[string]string)
+	return tags
+}
+
+// tags returns the tags of the statements executed with ctx.
+func (c *Commenter) tags(ctx context.Context) map[string]string {
+	tags := map[string]string{}
+	if c.Application != "" {
+		tags["application"] = c.Application
+	}
+	if c.Tags != nil {
+		maps.Copy(tags, c.Tags(ctx))
+	}
+	maps.Copy(tags, commentTagsFromContext(ctx))
+	return tags
+}
+
+// comment appends the comment of the tags to the query.
+func (c *Commenter) comment(ctx context.Context, query string) string {
+	if strings.Contains(query, "/*") || strings.Contains(query, "--") {
+		return query
+	}
+	tags := c.tags(ctx)
+	pairs := make([]string, 0, len(tags))
+	for _, key := range slices.Sorted(maps.Keys(tags)) {
+		value := tags[key]
+		if value == "" {
+			continue
+		}
+		pairs = append(pairs, commentEscape(key)+"='"+commentEscape(value)+"'")
+	}
+	if len(pairs) == 0 {
+		return query
+	}
+
+	trimmed := strings.TrimRight(query, "; \t\r\n")
+	return trimmed + " /*" + strings.Join(pairs, ",") + "*/" + query[len(trimmed):]
+}
+
+// commentEscape URL-encodes s.
+// The spaces are encoded as %20 instead of +, following the examples of the specification.
+func commentEscape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
@@ -0,0 +1,75 @@
+package rdsdata
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata"
+)
+
+func TestCommenter_comment(t *testing.T) {
+	c := &Commenter{
+		Application: "app",
+		Tags: func(ctx context.Context) map[string]string {
+			return map[string]string{"traceparent": "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"}
+		},
+	}
+	ctx := WithCommentTags(context.Background(), map[string]string{"route": "/users/{id}"})
+	ctx = WithCommentTags(ctx, map[string]string{"controller": "it's users", "application": ""})
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{
+			query: "SELECT * FROM users WHERE id = :1",
+			want:  "SELECT * FROM users WHERE id = :1 /*controller='it%27s%20users',route='%2Fusers%2F%7Bid%7D',traceparent='00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01'*/",
+		},
+		{
+			query: "DELETE FROM users;\n",
+			want:  "DELETE FROM users /*controller='it%27s%20users',route='%2Fusers%2F%7Bid%7D',traceparent='00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01'*/;\n",
+		},
+		{
+			// the statements that already have comments are not modified.
+			query: "/* ping */ SELECT 1",
+			want:  "/* ping */ SELECT 1",
+		},
+	}
+	for _, tt := range tests {
+		if got := c.comment(ctx, tt.query); got != tt.want {
+			t.Errorf("comment(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+
+	if got := (&Commenter{}).comment(context.Background(), "SELECT 1"); got != "SELECT 1" {
+		t.Errorf("unexpected comment without tags: %q", got)
+	}
+}
+
+func TestCommenter(t *testing.T) {
+	var got string
+	db := sql.OpenDB(NewConnector(&Config{
+		ResourceArn: "arn:aws:rds:us-east-1:123456789012:cluster:cluster",
+		SecretArn:   "arn:aws:secretsmanager:us-east-1:123456789012:secret:secretArn",
+		Engine:      EngineMySQL,
+		Commenter:   &Commenter{Application: "app:1"},
+		Client: &awsClientMock{
+			ExecuteStatementFunc: func(ctx context.Context, input *rdsdata.ExecuteStatementInput, optFns ...func(*rdsdata.Options)) (*rdsdata.ExecuteStatementOutput, error) {
+				got = aws.ToString(input.Sql)
+				return &rdsdata.ExecuteStatementOutput{}, nil
+			},
+		},
+	}))
+	defer db.Close()
+
+	if _, err := db.ExecContext(context.Background(), "DELETE FROM users WHERE id = ?", 1); err != nil {
+		t.Fatal(err)
+	}
+	// the colon in the tag is encoded, so it can't be taken as a placeholder.
+	want := "DELETE FROM users WHERE id = :1 /*application='app%3A1'*/"
+	if got != want {
+		t.Errorf("unexpected SQL: %q, want %q", got, want)
+	}
+}
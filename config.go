@@ -3,9 +3,14 @@ package rdsdata
 import (
 	"errors"
 	"fmt"
+	"log/slog"
+	"maps"
 	"net/url"
+	"slices"
 	"strconv"
 	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
 )
 
 const (
@@ -16,8 +21,39 @@ const (
 	keyLocation     = "location"
 	keyParseTime    = "parse_time"
 	keyTimeTruncate = "time_truncate"
+	keySlowQuery    = "slow_query_threshold"
+	keyEngine       = "engine"
+	keySMEndpoint   = "secretsmanager_endpoint"
+	keyReturning    = "returning"
+	keyTimeout      = "timeout"
+	keyContinue     = "continue_after_timeout"
+	keySchema       = "schema"
+	keyReadOnly     = "read_only"
 )
 
+// Engine is the database engine of the cluster.
+type Engine string
+
+const (
+	// EngineAuto detects the database engine by "SELECT VERSION()" on connect.
+	EngineAuto Engine = "auto"
+
+	// EngineMySQL is Aurora MySQL.
+	EngineMySQL Engine = "mysql"
+
+	// EnginePostgres is Aurora PostgreSQL.
+	EnginePostgres Engine = "postgres"
+)
+
+// parseEngine parses the value of the engine parameter.
+func parseEngine(v string) (Engine, error) {
+	switch e := Engine(v); e {
+	case EngineAuto, EngineMySQL, EnginePostgres:
+		return e, nil
+	}
+	return "", fmt.Errorf("rdsdata: unknown engine %q", v)
+}
+
 // ErrInvalidDSNScheme is returned when the DSN scheme is not valid.
 var ErrInvalidDSNScheme = errors.New("rdsdata: invalid DSN scheme")
 
@@ -29,12 +65,39 @@ type Config struct {
 
 	// SecretArn is the Amazon Resource Name (ARN) of the secret
 	// managed by Secrets Manager.
+	// It can also be the name of the secret, which is resolved to the ARN through Secrets Manager once.
 	SecretArn string
 
+	// SecretProvider provides the secret for each call instead of SecretArn.
+	// It can't be set by the DSN.
+	SecretProvider SecretProvider
+
+	// SecretsManagerClient is the Secrets Manager client to resolve the name of the secret,
+	// and to check the rotation of the secret when the authentication fails.
+	// If it is nil, a client is created from the default AWS config.
+	// It can't be set by the DSN.
+	SecretsManagerClient SecretsManagerClient
+
+	// SecretsManagerEndpoint overrides the endpoint of Secrets Manager
+	// to resolve the name of the secret, e.g. a local stand-in of Secrets Manager.
+	SecretsManagerEndpoint string
+
 	// Database is the name of the database.
 	Database string
 
+	// Schema is the schema of the statements on PostgreSQL.
+	// The Data API ignores its Schema parameter, so the driver sets search_path to the schema
+	// in the transaction of each statement, wrapping the statements outside of transactions in new transactions.
+	// Nothing is done for the default schema of the database.
+	// The Schema option of WithOptions overrides it for a call,
+	// so that a connection pool can serve the tenants in different schemas.
+	// The statements that can't run in a transaction, e.g. CREATE INDEX CONCURRENTLY and VACUUM,
+	// need WithOptions(ctx, Schema("")) and the names qualified by the schema.
+	// It is not supported on MySQL.
+	Schema string
+
 	// AWSRegion is the AWS region.
+	// If it is empty, the region of ResourceArn is used.
 	AWSRegion string
 
 	// Location specifies the location for time.Time values.
@@ -46,6 +109,115 @@ type Config struct {
 
 	// TimeTruncate truncates time.Time values to the nearest.
 	TimeTruncate time.Duration
+
+	// Failover is the ordered list of the endpoints to fail over to,
+	// e.g. the secondary clusters of Aurora Global Database.
+	// When the primary endpoint, which is ResourceArn in AWSRegion, fails with endpoint-level errors,
+	// the calls are sent to the next available endpoint.
+	// A statement fails over only if the error guarantees that it has not been executed,
+	// or it is marked by the Idempotent option.
+	// A throttled call may be sent to the next endpoint too, but throttling doesn't mark the endpoint as failed.
+	// The failed endpoints are probed with pings every FailoverProbeInterval until they recover.
+	// Transactions never move between endpoints: they fail with ErrTransactionEndpointFailed.
+	// It can't be set by the DSN.
+	Failover []Endpoint
+
+	// FailoverProbeInterval is the interval to probe the failed endpoints.
+	// The default is 30 seconds.
+	// It can't be set by the DSN.
+	FailoverProbeInterval time.Duration
+
+	// Engine is the database engine of the cluster.
+	// The empty value is the same as EngineAuto.
+	// Setting EngineMySQL or EnginePostgres skips the detection on connect,
+	// which saves a round-trip and works while the cluster is resuming,
+	// but the server version is unknown then.
+	Engine Engine
+
+	// Timeout is the timeout of each statement, including the retries on authentication failures.
+	// When it expires, the statement fails with TimeoutError.
+	// WithStatementTimeout overrides it for a call.
+	// Zero disables it.
+	Timeout time.Duration
+
+	// ContinueAfterTimeout keeps the statements running on the server
+	// after the synchronous timeout of the Data API, which is 45 seconds.
+	// It is recommended for long DDL statements.
+	// The statements that time out fail with TimeoutError, whose MayBeRunning is true.
+	ContinueAfterTimeout bool
+
+	// Returning is the column that is appended as a RETURNING clause to the INSERT statements on PostgreSQL,
+	// e.g. "id", so that LastInsertId returns the generated value of the column.
+	// The Data API returns the generated fields only for MySQL.
+	// It applies to all the INSERT statements of the connector, except the ones that already have a RETURNING clause,
+	// so the INSERT statements into the tables without the column fail.
+	// A statement can override it with a sql.Named("returning", column) argument,
+	// or disable it with NoReturning.
+	Returning string
+
+	// ReadOnly rejects the statements that may write, such as DML and DDL, with StatementNotAllowedError
+	// before calling the Data API, and begins the transactions as READ ONLY.
+	// The classifier is conservative: it allows only SELECT, SHOW, DESCRIBE, EXPLAIN, WITH, VALUES and TABLE statements
+	// without data-modifying CTEs, SELECT INTO and locking reads.
+	// It can't detect the functions that write, so the database user should still have the least privileges.
+	ReadOnly bool
+
+	// StatementAllowlist is the list of the fingerprints of the allowed statements.
+	// See Fingerprint for the format.
+	// The statements whose fingerprints are not in the list are rejected with StatementNotAllowedError
+	// before calling the Data API.
+	// If it is nil, all the statements are allowed.
+	// It can't be set by the DSN.
+	StatementAllowlist []string
+
+	// Dialect is the dialect used by every connection.
+	// If it is set, Engine and DialectFactory are ignored, and the engine is not detected.
+	// It can't be set by the DSN.
+	Dialect Dialect
+
+	// DialectFactory returns the dialect of a new connection.
+	// base is the dialect that the driver builds for the engine,
+	// so the factory can return it as is, wrap it to override a part of it, or replace it.
+	// serverVersion is empty if the engine is not detected.
+	// It can't be set by the DSN.
+	DialectFactory DialectFactory
+
+	// FieldConverters are the field converters of the connections, keyed by ColumnMetadata.TypeName,
+	// e.g. "BIGINT UNSIGNED" for MySQL and "int8" for PostgreSQL.
+	// They take precedence over the defaults registered by RegisterFieldConverter and the built-in ones,
+	// so that the connectors in a process can convert the same type differently.
+	// They are ignored if Dialect is set, or the dialect returned by DialectFactory doesn't use them.
+	// It can't be set by the DSN.
+	FieldConverters map[string]FieldConverter
+
+	// Client is the RDS Data API client.
+	// If it is nil, a client is created from the default AWS config and AWSRegion.
+	// It can't be set by the DSN.
+	Client Client
+
+	// Hooks is called around every Data API call.
+	// It can't be set by the DSN. Use Connector.WithHooks to add hooks to the connector opened from a DSN.
+	Hooks Hooks
+
+	// Commenter appends sqlcommenter comments to the statements.
+	// If it is nil, the statements are sent as is.
+	// It can't be set by the DSN.
+	Commenter *Commenter
+
+	// Logger writes structured logs of every statement.
+	// If it is nil, no logs are written.
+	// It can't be set by the DSN.
+	Logger *slog.Logger
+
+	// SlowQueryThreshold is the threshold for logging statements at WARN level.
+	// Other successful statements are logged at DEBUG level.
+	// Zero disables it.
+	SlowQueryThreshold time.Duration
+
+	// RedactParameter reports whether the value of the named parameter should be redacted in the logs.
+	// If it is nil, all the values are redacted.
+	// It can't be set by the DSN.
+	RedactParameter func(name string) bool
 }
 
 // ParseDSN parses the DSN string to a Config.
@@ -69,6 +241,14 @@ func ParseDSN(dsn string) (*Config, error) {
 			cfg.SecretArn = v
 		case keyDatabase:
 			cfg.Database = v
+		case keySchema:
+			cfg.Schema = v
+		case keyReadOnly:
+			readOnly, err := strconv.ParseBool(v)
+			if err != nil {
+				return nil, err
+			}
+			cfg.ReadOnly = readOnly
 		case keyAWSRegion:
 			cfg.AWSRegion = v
 		case keyLocation:
@@ -89,6 +269,34 @@ func ParseDSN(dsn string) (*Config, error) {
 				return nil, err
 			}
 			cfg.TimeTruncate = timeTruncate
+		case keySlowQuery:
+			threshold, err := time.ParseDuration(v)
+			if err != nil {
+				return nil, err
+			}
+			cfg.SlowQueryThreshold = threshold
+		case keyEngine:
+			engine, err := parseEngine(v)
+			if err != nil {
+				return nil, err
+			}
+			cfg.Engine = engine
+		case keySMEndpoint:
+			cfg.SecretsManagerEndpoint = v
+		case keyReturning:
+			cfg.Returning = v
+		case keyTimeout:
+			timeout, err := time.ParseDuration(v)
+			if err != nil {
+				return nil, err
+			}
+			cfg.Timeout = timeout
+		case keyContinue:
+			continueAfterTimeout, err := strconv.ParseBool(v)
+			if err != nil {
+				return nil, err
+			}
+			cfg.ContinueAfterTimeout = continueAfterTimeout
 		default:
 			return nil, fmt.Errorf("rdsdata: unknown parameter %q", k)
 		}
@@ -104,6 +312,9 @@ func (cfg *Config) FormatDSN() string {
 	if cfg.Database != "" {
 		v.Add(keyDatabase, cfg.Database)
 	}
+	if cfg.Schema != "" {
+		v.Add(keySchema, cfg.Schema)
+	}
 	v.Add(keyAWSRegion, cfg.AWSRegion)
 	if cfg.Location != nil {
 		v.Add(keyLocation, cfg.Location.String())
@@ -114,17 +325,143 @@ func (cfg *Config) FormatDSN() string {
 	if cfg.TimeTruncate != 0 {
 		v.Add(keyTimeTruncate, cfg.TimeTruncate.String())
 	}
+	if cfg.SlowQueryThreshold != 0 {
+		v.Add(keySlowQuery, cfg.SlowQueryThreshold.String())
+	}
+	if cfg.Engine != "" && cfg.Engine != EngineAuto {
+		v.Add(keyEngine, string(cfg.Engine))
+	}
+	if cfg.SecretsManagerEndpoint != "" {
+		v.Add(keySMEndpoint, cfg.SecretsManagerEndpoint)
+	}
+	if cfg.Returning != "" {
+		v.Add(keyReturning, cfg.Returning)
+	}
+	if cfg.Timeout != 0 {
+		v.Add(keyTimeout, cfg.Timeout.String())
+	}
+	if cfg.ContinueAfterTimeout {
+		v.Add(keyContinue, strconv.FormatBool(cfg.ContinueAfterTimeout))
+	}
+	if cfg.ReadOnly {
+		v.Add(keyReadOnly, strconv.FormatBool(cfg.ReadOnly))
+	}
 	return "rdsdata://?" + v.Encode()
 }
 
+// Validate reports whether the config is valid.
+// Both ARNs are required and must be in the same region.
+// The secret can be a name instead of the ARN, or can be omitted if SecretProvider is set.
+// The DSN is not validated by ParseDSN, but by Driver.OpenConnector.
+func (cfg *Config) Validate() error {
+	resource, err := parseARN(keyResourceARN, cfg.ResourceArn, "rds")
+	if err != nil {
+		return err
+	}
+	switch {
+	case cfg.SecretArn == "" && cfg.SecretProvider != nil:
+	case cfg.SecretArn != "" && !isSecretArn(cfg.SecretArn):
+		// the name of the secret is resolved later.
+	default:
+		secret, err := parseARN(keySecretARN, cfg.SecretArn, "secretsmanager")
+		if err != nil {
+			return err
+		}
+		if resource.Region != secret.Region {
+			return fmt.Errorf("rdsdata: region mismatch: the cluster is in %q, but the secret is in %q", resource.Region, secret.Region)
+		}
+	}
+	if cfg.Engine != "" {
+		if _, err := parseEngine(string(cfg.Engine)); err != nil {
+			return err
+		}
+	}
+	for i, e := range cfg.Failover {
+		key := fmt.Sprintf("failover[%d].resource_arn", i)
+		resource, err := parseARN(key, e.ResourceArn, "rds")
+		if err != nil {
+			return err
+		}
+		if e.SecretArn == "" {
+			continue
+		}
+		key = fmt.Sprintf("failover[%d].secret_arn", i)
+		secret, err := parseARN(key, e.SecretArn, "secretsmanager")
+		if err != nil {
+			return err
+		}
+		if resource.Region != secret.Region {
+			return fmt.Errorf("rdsdata: region mismatch: the failover cluster %d is in %q, but the secret is in %q", i, resource.Region, secret.Region)
+		}
+	}
+	return nil
+}
+
+// parseARN parses the ARN of the parameter key, and checks its service.
+func parseARN(key, value, service string) (arn.ARN, error) {
+	if value == "" {
+		return arn.ARN{}, fmt.Errorf("rdsdata: %s is required", key)
+	}
+	a, err := arn.Parse(value)
+	if err != nil {
+		return arn.ARN{}, fmt.Errorf("rdsdata: invalid %s %q: %w", key, value, err)
+	}
+	if a.Service != service {
+		return arn.ARN{}, fmt.Errorf("rdsdata: invalid %s %q: the service must be %s", key, value, service)
+	}
+	if a.Region == "" {
+		return arn.ARN{}, fmt.Errorf("rdsdata: invalid %s %q: the region is missing", key, value)
+	}
+	return a, nil
+}
+
+// region returns AWSRegion, or the region of ResourceArn if it is empty.
+func (cfg *Config) region() string {
+	if cfg.AWSRegion != "" {
+		return cfg.AWSRegion
+	}
+	if a, err := arn.Parse(cfg.ResourceArn); err == nil {
+		return a.Region
+	}
+	return ""
+}
+
 func (cfg *Config) Clone() *Config {
 	return &Config{
 		ResourceArn:  cfg.ResourceArn,
 		SecretArn:    cfg.SecretArn,
 		Database:     cfg.Database,
+		Schema:       cfg.Schema,
 		AWSRegion:    cfg.AWSRegion,
 		Location:     cfg.Location,
 		ParseTime:    cfg.ParseTime,
 		TimeTruncate: cfg.TimeTruncate,
+		Engine:       cfg.Engine,
+		Returning:    cfg.Returning,
+
+		Timeout:              cfg.Timeout,
+		ContinueAfterTimeout: cfg.ContinueAfterTimeout,
+
+		ReadOnly:           cfg.ReadOnly,
+		StatementAllowlist: slices.Clone(cfg.StatementAllowlist),
+
+		SecretProvider:         cfg.SecretProvider,
+		SecretsManagerClient:   cfg.SecretsManagerClient,
+		SecretsManagerEndpoint: cfg.SecretsManagerEndpoint,
+
+		Failover:              append([]Endpoint(nil), cfg.Failover...),
+		FailoverProbeInterval: cfg.FailoverProbeInterval,
+
+		Dialect:         cfg.Dialect,
+		DialectFactory:  cfg.DialectFactory,
+		FieldConverters: maps.Clone(cfg.FieldConverters),
+
+		Client:    cfg.Client,
+		Hooks:     cfg.Hooks,
+		Commenter: cfg.Commenter,
+
+		Logger:             cfg.Logger,
+		SlowQueryThreshold: cfg.SlowQueryThreshold,
+		RedactParameter:    cfg.RedactParameter,
 	}
 }
@@ -83,6 +83,91 @@ func TestParseDSN(t *testing.T) {
 		}
 	})
 
+	t.Run("slowQueryThreshold", func(t *testing.T) {
+		dns := "rdsdata://?slow_query_threshold=500ms"
+		cfg, err := ParseDSN(dns)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.SlowQueryThreshold != 500*time.Millisecond {
+			t.Errorf("unexpected SlowQueryThreshold: %v", cfg.SlowQueryThreshold)
+		}
+	})
+
+	t.Run("invalid slowQueryThreshold", func(t *testing.T) {
+		dns := "rdsdata://?slow_query_threshold=invalid"
+		_, err := ParseDSN(dns)
+		if err == nil {
+			t.Fatal("expected error, but got nil")
+		}
+	})
+
+	t.Run("engine", func(t *testing.T) {
+		dns := "rdsdata://?engine=postgres"
+		cfg, err := ParseDSN(dns)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.Engine != EnginePostgres {
+			t.Errorf("unexpected Engine: %v", cfg.Engine)
+		}
+	})
+
+	t.Run("schema", func(t *testing.T) {
+		dns := "rdsdata://?schema=tenant1"
+		cfg, err := ParseDSN(dns)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.Schema != "tenant1" {
+			t.Errorf("unexpected Schema: %v", cfg.Schema)
+		}
+	})
+
+	t.Run("returning", func(t *testing.T) {
+		dns := "rdsdata://?returning=id"
+		cfg, err := ParseDSN(dns)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.Returning != "id" {
+			t.Errorf("unexpected Returning: %v", cfg.Returning)
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		dns := "rdsdata://?timeout=5m&continue_after_timeout=true"
+		cfg, err := ParseDSN(dns)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.Timeout != 5*time.Minute {
+			t.Errorf("unexpected Timeout: %v", cfg.Timeout)
+		}
+		if !cfg.ContinueAfterTimeout {
+			t.Errorf("unexpected ContinueAfterTimeout: %v", cfg.ContinueAfterTimeout)
+		}
+	})
+
+	t.Run("read_only", func(t *testing.T) {
+		dns := "rdsdata://?read_only=true"
+		cfg, err := ParseDSN(dns)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !cfg.ReadOnly {
+			t.Errorf("unexpected ReadOnly: %v", cfg.ReadOnly)
+		}
+	})
+
+	t.Run("invalid engine", func(t *testing.T) {
+		dns := "rdsdata://?engine=oracle"
+		_, err := ParseDSN(dns)
+		if err == nil {
+			t.Fatal("expected error, but got nil")
+		}
+	})
+
 	t.Run("returns error when the DSN scheme is invalid", func(t *testing.T) {
 		dsn := "invalid://?resource_arn=resourceARN&secret_arn=secretARN&database=database&aws_region=region"
 		_, err := ParseDSN(dsn)
@@ -146,6 +231,67 @@ func TestConfig_FormatDSN(t *testing.T) {
 			},
 			want: "rdsdata://?aws_region=region&resource_arn=resourceARN&secret_arn=SecretARN&time_truncate=1s",
 		},
+		{
+			name: "slowQueryThreshold",
+			cfg: &Config{
+				ResourceArn:        "resourceARN",
+				SecretArn:          "SecretARN",
+				AWSRegion:          "region",
+				SlowQueryThreshold: 500 * time.Millisecond,
+			},
+			want: "rdsdata://?aws_region=region&resource_arn=resourceARN&secret_arn=SecretARN&slow_query_threshold=500ms",
+		},
+		{
+			name: "engine",
+			cfg: &Config{
+				ResourceArn: "resourceARN",
+				SecretArn:   "SecretARN",
+				AWSRegion:   "region",
+				Engine:      EngineMySQL,
+			},
+			want: "rdsdata://?aws_region=region&engine=mysql&resource_arn=resourceARN&secret_arn=SecretARN",
+		},
+		{
+			name: "schema",
+			cfg: &Config{
+				ResourceArn: "resourceARN",
+				SecretArn:   "SecretARN",
+				AWSRegion:   "region",
+				Schema:      "tenant1",
+			},
+			want: "rdsdata://?aws_region=region&resource_arn=resourceARN&schema=tenant1&secret_arn=SecretARN",
+		},
+		{
+			name: "returning",
+			cfg: &Config{
+				ResourceArn: "resourceARN",
+				SecretArn:   "SecretARN",
+				AWSRegion:   "region",
+				Returning:   "id",
+			},
+			want: "rdsdata://?aws_region=region&resource_arn=resourceARN&returning=id&secret_arn=SecretARN",
+		},
+		{
+			name: "timeout",
+			cfg: &Config{
+				ResourceArn:          "resourceARN",
+				SecretArn:            "SecretARN",
+				AWSRegion:            "region",
+				Timeout:              5 * time.Minute,
+				ContinueAfterTimeout: true,
+			},
+			want: "rdsdata://?aws_region=region&continue_after_timeout=true&resource_arn=resourceARN&secret_arn=SecretARN&timeout=5m0s",
+		},
+		{
+			name: "read_only",
+			cfg: &Config{
+				ResourceArn: "resourceARN",
+				SecretArn:   "SecretARN",
+				AWSRegion:   "region",
+				ReadOnly:    true,
+			},
+			want: "rdsdata://?aws_region=region&read_only=true&resource_arn=resourceARN&secret_arn=SecretARN",
+		},
 	}
 
 	for _, tc := range testCases {
@@ -157,3 +303,86 @@ func TestConfig_FormatDSN(t *testing.T) {
 		})
 	}
 }
+
+func TestConfig_Validate(t *testing.T) {
+	const (
+		resourceARN = "arn:aws:rds:us-east-1:123456789012:cluster:test"
+		secretARN   = "arn:aws:secretsmanager:us-east-1:123456789012:secret:test-AbCdEf"
+	)
+	testCases := []struct {
+		name    string
+		cfg     *Config
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			cfg:  &Config{ResourceArn: resourceARN, SecretArn: secretARN},
+		},
+		{
+			name:    "missing resource ARN",
+			cfg:     &Config{SecretArn: secretARN},
+			wantErr: true,
+		},
+		{
+			name:    "missing secret ARN",
+			cfg:     &Config{ResourceArn: resourceARN},
+			wantErr: true,
+		},
+		{
+			name:    "malformed resource ARN",
+			cfg:     &Config{ResourceArn: "resourceARN", SecretArn: secretARN},
+			wantErr: true,
+		},
+		{
+			name:    "swapped ARNs",
+			cfg:     &Config{ResourceArn: secretARN, SecretArn: resourceARN},
+			wantErr: true,
+		},
+		{
+			name: "region mismatch",
+			cfg: &Config{
+				ResourceArn: resourceARN,
+				SecretArn:   "arn:aws:secretsmanager:ap-northeast-1:123456789012:secret:test-AbCdEf",
+			},
+			wantErr: true,
+		},
+		{
+			name: "failover region mismatch",
+			cfg: &Config{
+				ResourceArn: resourceARN,
+				SecretArn:   secretARN,
+				Failover: []Endpoint{{
+					ResourceArn: "arn:aws:rds:us-west-2:123456789012:cluster:test",
+					SecretArn:   secretARN,
+				}},
+			},
+			wantErr: true,
+		},
+		{
+			name:    "unknown engine",
+			cfg:     &Config{ResourceArn: resourceARN, SecretArn: secretARN, Engine: "oracle"},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.cfg.Validate()
+			if (err != nil) != tc.wantErr {
+				t.Errorf("unexpected error: %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestConfig_region(t *testing.T) {
+	cfg := &Config{ResourceArn: "arn:aws:rds:us-east-1:123456789012:cluster:test"}
+	if got := cfg.region(); got != "us-east-1" {
+		t.Errorf("unexpected region: %q", got)
+	}
+
+	cfg.AWSRegion = "ap-northeast-1"
+	if got := cfg.region(); got != "ap-northeast-1" {
+		t.Errorf("unexpected region: %q", got)
+	}
+}
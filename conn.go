@@ -19,8 +19,9 @@ var _ driver.Pinger = (*Conn)(nil)
 var _ driver.ExecerContext = (*Conn)(nil)
 var _ driver.QueryerContext = (*Conn)(nil)
 
-// awsClientInterface interface that captures methods required by the driver. In this case, replicating the RDS API
-type awsClientInterface interface {
+// Client is the interface that captures methods of the RDS Data API client required by the driver.
+// *rdsdata.Client satisfies it.
+type Client interface {
 	ExecuteStatement(ctx context.Context, e *rdsdata.ExecuteStatementInput, optFns ...func(*rdsdata.Options)) (*rdsdata.ExecuteStatementOutput, error)
 	BeginTransaction(ctx context.Context, b *rdsdata.BeginTransactionInput, optFns ...func(*rdsdata.Options)) (*rdsdata.BeginTransactionOutput, error)
 	CommitTransaction(ctx context.Context, c *rdsdata.CommitTransactionInput, optFns ...func(*rdsdata.Options)) (*rdsdata.CommitTransactionOutput, error)
@@ -28,14 +29,61 @@ type awsClientInterface interface {
 }
 
 type Conn struct {
-	client    awsClientInterface
+	client    Client
 	connector *Connector
 	dialect   Dialect
 
+	// engine is the database engine of the connection.
+	// It is empty if Config.Dialect is set without Config.Engine, and the dialect is not built in.
+	engine Engine
+
+	// serverVersion is the result of "SELECT VERSION()" on connect.
+	serverVersion string
+
+	// defaultSchemas caches the default schemas of the databases on PostgreSQL.
+	// See Stmt.defaultSchema.
+	defaultSchemas map[string]string
+
 	// Tx is the current transaction.
 	tx *Tx
 }
 
+// DialectConn is the interface implemented by the connections of the driver, *Conn and *RoutingConn.
+// It can be accessed via sql.Conn.Raw.
+type DialectConn interface {
+	// Dialect returns the dialect of the database engine.
+	Dialect() Dialect
+
+	// Engine returns the database engine.
+	// It is empty if the engine of a custom Dialect is unknown.
+	Engine() Engine
+}
+
+// compile time type check
+var _ DialectConn = (*Conn)(nil)
+var _ DialectConn = (*RoutingConn)(nil)
+
+// Dialect returns the dialect of the database engine detected on connect.
+// It can be accessed via sql.Conn.Raw.
+func (c *Conn) Dialect() Dialect {
+	return c.dialect
+}
+
+// Engine returns the database engine detected on connect or set by Config.Engine.
+// It is empty if the engine of a custom Dialect is unknown.
+// It can be accessed via sql.Conn.Raw.
+func (c *Conn) Engine() Engine {
+	return c.engine
+}
+
+// ServerVersion returns the version of the database server detected on connect,
+// e.g. "8.0.32" for MySQL and "PostgreSQL 16.1 on x86_64-pc-linux-gnu" for PostgreSQL.
+// It returns the empty string if the engine is set by Config.Engine, because the detection is skipped.
+// It can be accessed via sql.Conn.Raw.
+func (c *Conn) ServerVersion() string {
+	return c.serverVersion
+}
+
 // Prepare prepares a query.
 func (c *Conn) Prepare(query string) (driver.Stmt, error) {
 	return c.prepareContext(query)
@@ -47,6 +95,9 @@ func (c *Conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, e
 }
 
 func (c *Conn) prepareContext(query string) (*Stmt, error) {
+	if err := c.checkStatement(query); err != nil {
+		return nil, err
+	}
 	stmt := &Stmt{
 		conn:    c,
 		queries: []string{query},
@@ -68,39 +119,51 @@ func (c *Conn) Begin() (driver.Tx, error) {
 }
 
 func (c *Conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if c.connector.cfg.ReadOnly {
+		opts.ReadOnly = true
+	}
 	level := sql.IsolationLevel(opts.Isolation)
 	if !c.dialect.IsIsolationLevelSupported(level) {
 		return nil, fmt.Errorf("rdsdata: unsupported isolation level: %s", level.String())
 	}
 
-	out, err := c.client.BeginTransaction(ctx, &rdsdata.BeginTransactionInput{
-		ResourceArn: &c.connector.cfg.ResourceArn,
-		SecretArn:   &c.connector.cfg.SecretArn,
-		Database:    &c.connector.cfg.Database,
+	// the transaction is bound to the database, which the Database option can override.
+	database := c.connector.cfg.Database
+	if o := optionsFromContext(ctx); o.database != nil {
+		database = *o.database
+	}
+
+	var out *rdsdata.BeginTransactionOutput
+	var secretArn string
+	err := c.withSecret(ctx, func(ctx context.Context, secret string) error {
+		var err error
+		out, err = c.beginTransaction(ctx, &rdsdata.BeginTransactionInput{
+			ResourceArn: &c.connector.cfg.ResourceArn,
+			SecretArn:   aws.String(secret),
+			Database:    aws.String(database),
+		})
+		secretArn = secret
+		return err
 	})
 	if err != nil {
 		return nil, err
 	}
 
 	tx := &Tx{
-		ctx:  ctx,
-		id:   out.TransactionId,
-		conn: c,
+		ctx:       ctx,
+		id:        out.TransactionId,
+		secretArn: secretArn,
+		database:  database,
+		conn:      c,
 	}
 
-	var clause []string
-	if level != sql.LevelDefault {
-		clause = append(clause, "ISOLATION LEVEL "+level.String())
-	}
-	if opts.ReadOnly {
-		clause = append(clause, "READ ONLY")
-	}
-	if len(clause) > 0 {
-		if _, err := c.client.ExecuteStatement(ctx, &rdsdata.ExecuteStatementInput{
-			ResourceArn: &c.connector.cfg.ResourceArn,
-			SecretArn:   &c.connector.cfg.SecretArn,
-			Database:    &c.connector.cfg.Database,
-			Sql:         aws.String("SET TRANSACTION " + strings.Join(clause, ", ")),
+	for _, query := range transactionStatements(c.engine, level, opts.ReadOnly) {
+		if _, err := c.executeStatement(ctx, callExec, &rdsdata.ExecuteStatementInput{
+			ResourceArn:   &c.connector.cfg.ResourceArn,
+			SecretArn:     &tx.secretArn,
+			Database:      &tx.database,
+			Sql:           aws.String(query),
+			TransactionId: out.TransactionId,
 		}); err != nil {
 			_ = tx.Rollback()
 			return nil, err
@@ -111,6 +174,43 @@ func (c *Conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, e
 	return tx, nil
 }
 
+// transactionStatements returns the statements that set the characteristics of the transaction begun by the Data API.
+// They run in the transaction before any other statement.
+//
+// PostgreSQL accepts SET TRANSACTION until the first query of the transaction.
+// MySQL rejects SET TRANSACTION in a transaction in progress,
+// so the transaction is restarted by START TRANSACTION on the same connection.
+// START TRANSACTION implicitly commits the transaction begun by the Data API, which has done nothing yet.
+// The isolation level is set by SET TRANSACTION between COMMIT and START TRANSACTION,
+// because START TRANSACTION doesn't have it.
+func transactionStatements(engine Engine, level sql.IsolationLevel, readOnly bool) []string {
+	if engine == EngineMySQL {
+		if level == sql.LevelDefault && !readOnly {
+			return nil
+		}
+		start := "START TRANSACTION"
+		if readOnly {
+			start += " READ ONLY"
+		}
+		if level == sql.LevelDefault {
+			return []string{start}
+		}
+		return []string{"COMMIT", "SET TRANSACTION ISOLATION LEVEL " + level.String(), start}
+	}
+
+	var clause []string
+	if level != sql.LevelDefault {
+		clause = append(clause, "ISOLATION LEVEL "+level.String())
+	}
+	if readOnly {
+		clause = append(clause, "READ ONLY")
+	}
+	if len(clause) == 0 {
+		return nil
+	}
+	return []string{"SET TRANSACTION " + strings.Join(clause, ", ")}
+}
+
 // ExecContext executes a query.
 func (c *Conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
 	stmt, err := c.prepareContext(query)
@@ -131,11 +231,13 @@ func (c *Conn) QueryContext(ctx context.Context, query string, args []driver.Nam
 
 // Ping ping the database to check if the connection is still alive.
 func (c *Conn) Ping(ctx context.Context) error {
-	_, err := c.client.ExecuteStatement(ctx, &rdsdata.ExecuteStatementInput{
-		ResourceArn: &c.connector.cfg.ResourceArn,
-		SecretArn:   &c.connector.cfg.SecretArn,
-		Database:    &c.connector.cfg.Database,
-		Sql:         aws.String("/* ping */ SELECT 1"),
+	return c.withSecret(ctx, func(ctx context.Context, secretArn string) error {
+		_, err := c.executeStatement(ctx, callQuery, &rdsdata.ExecuteStatementInput{
+			ResourceArn: &c.connector.cfg.ResourceArn,
+			SecretArn:   aws.String(secretArn),
+			Database:    &c.connector.cfg.Database,
+			Sql:         aws.String("/* ping */ SELECT 1"),
+		})
+		return err
 	})
-	return err
 }
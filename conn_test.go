@@ -2,14 +2,18 @@ package rdsdata
 
 import (
 	"context"
+	"database/sql"
 	"database/sql/driver"
+	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/rdsdata"
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata/types"
 )
 
-var _ awsClientInterface = (*awsClientMock)(nil)
+var _ Client = (*awsClientMock)(nil)
 
 type awsClientMock struct {
 	ExecuteStatementFunc    func(ctx context.Context, e *rdsdata.ExecuteStatementInput, optFns ...func(*rdsdata.Options)) (*rdsdata.ExecuteStatementOutput, error)
@@ -40,7 +44,7 @@ func TestConn_Ping(t *testing.T) {
 			if aws.ToString(input.ResourceArn) != "resourceArn" {
 				t.Errorf("unexpected ResourceArn: %s", aws.ToString(input.ResourceArn))
 			}
-			if aws.ToString(input.SecretArn) != "secretArn" {
+			if aws.ToString(input.SecretArn) != "arn:aws:secretsmanager:us-east-1:123456789012:secret:secretArn" {
 				t.Errorf("unexpected SecretArn: %s", aws.ToString(input.SecretArn))
 			}
 			if aws.ToString(input.Database) != "database" {
@@ -57,7 +61,7 @@ func TestConn_Ping(t *testing.T) {
 		connector: &Connector{
 			cfg: &Config{
 				ResourceArn: "resourceArn",
-				SecretArn:   "secretArn",
+				SecretArn:   "arn:aws:secretsmanager:us-east-1:123456789012:secret:secretArn",
 				Database:    "database",
 			},
 		},
@@ -73,7 +77,7 @@ func TestConn_BeginTx(t *testing.T) {
 			if aws.ToString(input.ResourceArn) != "resourceArn" {
 				t.Errorf("unexpected ResourceArn: %s", aws.ToString(input.ResourceArn))
 			}
-			if aws.ToString(input.SecretArn) != "secretArn" {
+			if aws.ToString(input.SecretArn) != "arn:aws:secretsmanager:us-east-1:123456789012:secret:secretArn" {
 				t.Errorf("unexpected SecretArn: %s", aws.ToString(input.SecretArn))
 			}
 			if aws.ToString(input.Database) != "database" {
@@ -87,7 +91,7 @@ func TestConn_BeginTx(t *testing.T) {
 			if aws.ToString(input.ResourceArn) != "resourceArn" {
 				t.Errorf("unexpected ResourceArn: %s", aws.ToString(input.ResourceArn))
 			}
-			if aws.ToString(input.SecretArn) != "secretArn" {
+			if aws.ToString(input.SecretArn) != "arn:aws:secretsmanager:us-east-1:123456789012:secret:secretArn" {
 				t.Errorf("unexpected SecretArn: %s", aws.ToString(input.SecretArn))
 			}
 			if aws.ToString(input.Database) != "database" {
@@ -105,7 +109,7 @@ func TestConn_BeginTx(t *testing.T) {
 			if aws.ToString(input.ResourceArn) != "resourceArn" {
 				t.Errorf("unexpected ResourceArn: %s", aws.ToString(input.ResourceArn))
 			}
-			if aws.ToString(input.SecretArn) != "secretArn" {
+			if aws.ToString(input.SecretArn) != "arn:aws:secretsmanager:us-east-1:123456789012:secret:secretArn" {
 				t.Errorf("unexpected SecretArn: %s", aws.ToString(input.SecretArn))
 			}
 			if aws.ToString(input.TransactionId) != "transactionId" {
@@ -119,7 +123,7 @@ func TestConn_BeginTx(t *testing.T) {
 		connector: &Connector{
 			cfg: &Config{
 				ResourceArn: "resourceArn",
-				SecretArn:   "secretArn",
+				SecretArn:   "arn:aws:secretsmanager:us-east-1:123456789012:secret:secretArn",
 				Database:    "database",
 			},
 		},
@@ -138,3 +142,286 @@ func TestConn_BeginTx(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestConn_BeginTx_Options(t *testing.T) {
+	tests := []struct {
+		engine Engine
+		opts   driver.TxOptions
+		want   []string
+	}{
+		{
+			engine: EngineMySQL,
+			opts:   driver.TxOptions{},
+			want:   []string{"BEGIN", "COMMIT"},
+		},
+		{
+			engine: EngineMySQL,
+			opts:   driver.TxOptions{ReadOnly: true},
+			want:   []string{"BEGIN", "START TRANSACTION READ ONLY @tx", "COMMIT"},
+		},
+		{
+			engine: EngineMySQL,
+			opts:   driver.TxOptions{Isolation: driver.IsolationLevel(sql.LevelSerializable), ReadOnly: true},
+			want: []string{
+				"BEGIN",
+				"COMMIT @tx",
+				"SET TRANSACTION ISOLATION LEVEL Serializable @tx",
+				"START TRANSACTION READ ONLY @tx",
+				"COMMIT",
+			},
+		},
+		{
+			engine: EngineMySQL,
+			opts:   driver.TxOptions{Isolation: driver.IsolationLevel(sql.LevelReadCommitted)},
+			want: []string{
+				"BEGIN",
+				"COMMIT @tx",
+				"SET TRANSACTION ISOLATION LEVEL Read Committed @tx",
+				"START TRANSACTION @tx",
+				"COMMIT",
+			},
+		},
+		{
+			engine: EnginePostgres,
+			opts:   driver.TxOptions{ReadOnly: true},
+			want:   []string{"BEGIN", "SET TRANSACTION READ ONLY @tx", "COMMIT"},
+		},
+		{
+			engine: EnginePostgres,
+			opts:   driver.TxOptions{Isolation: driver.IsolationLevel(sql.LevelSerializable), ReadOnly: true},
+			want:   []string{"BEGIN", "SET TRANSACTION ISOLATION LEVEL Serializable, READ ONLY @tx", "COMMIT"},
+		},
+	}
+	for _, tt := range tests {
+		var calls []string
+		client := &awsClientMock{
+			BeginTransactionFunc: func(ctx context.Context, input *rdsdata.BeginTransactionInput, optFns ...func(*rdsdata.Options)) (*rdsdata.BeginTransactionOutput, error) {
+				calls = append(calls, "BEGIN")
+				return &rdsdata.BeginTransactionOutput{TransactionId: aws.String("tx")}, nil
+			},
+			ExecuteStatementFunc: func(ctx context.Context, input *rdsdata.ExecuteStatementInput, optFns ...func(*rdsdata.Options)) (*rdsdata.ExecuteStatementOutput, error) {
+				calls = append(calls, aws.ToString(input.Sql)+" @"+aws.ToString(input.TransactionId))
+				return &rdsdata.ExecuteStatementOutput{}, nil
+			},
+			CommitTransactionFunc: func(ctx context.Context, input *rdsdata.CommitTransactionInput, optFns ...func(*rdsdata.Options)) (*rdsdata.CommitTransactionOutput, error) {
+				calls = append(calls, "COMMIT")
+				return &rdsdata.CommitTransactionOutput{}, nil
+			},
+		}
+		conn := &Conn{
+			client: client,
+			connector: &Connector{
+				cfg: &Config{
+					ResourceArn: "resourceArn",
+					SecretArn:   "arn:aws:secretsmanager:us-east-1:123456789012:secret:secretArn",
+					Database:    "database",
+				},
+			},
+			engine:  tt.engine,
+			dialect: &DialectMySQL{},
+		}
+		tx, err := conn.BeginTx(context.Background(), tt.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := tx.Commit(); err != nil {
+			t.Fatal(err)
+		}
+		if fmt.Sprint(calls) != fmt.Sprint(tt.want) {
+			t.Errorf("%s %+v: unexpected calls:\n got: %q\nwant: %q", tt.engine, tt.opts, calls, tt.want)
+		}
+	}
+}
+
+func TestConn_ExecContext_Returning(t *testing.T) {
+	tests := []struct {
+		name    string
+		engine  Engine
+		query   string
+		args    []driver.NamedValue
+		wantSQL string
+		wantID  int64
+	}{
+		{
+			name:    "config",
+			engine:  EnginePostgres,
+			query:   "INSERT INTO users (name) VALUES ($1);",
+			args:    []driver.NamedValue{{Ordinal: 1, Value: "alice"}},
+			wantSQL: "INSERT INTO users (name) VALUES (:1) RETURNING id;",
+			wantID:  42,
+		},
+		{
+			name:    "returning in a string and a comment",
+			engine:  EnginePostgres,
+			query:   "INSERT INTO logs (msg) VALUES ('returning soon') -- returning\n",
+			wantSQL: "INSERT INTO logs (msg) VALUES ('returning soon') RETURNING id -- returning\n",
+			wantID:  42,
+		},
+		{
+			name:   "named argument",
+			engine: EnginePostgres,
+			query:  "INSERT INTO users (name) VALUES ($1)",
+			args: []driver.NamedValue{
+				{Ordinal: 1, Name: "returning", Value: "user_id"},
+				{Ordinal: 2, Value: "alice"},
+			},
+			wantSQL: "INSERT INTO users (name) VALUES (:1) RETURNING user_id",
+			wantID:  42,
+		},
+		{
+			name:    "already returning",
+			engine:  EnginePostgres,
+			query:   "INSERT INTO users (name) VALUES ('alice') RETURNING id",
+			wantSQL: "INSERT INTO users (name) VALUES ('alice') RETURNING id",
+			wantID:  42,
+		},
+		{
+			name:    "already returning after on conflict",
+			engine:  EnginePostgres,
+			query:   "INSERT INTO users (name) VALUES ('alice') ON CONFLICT (name) DO UPDATE SET name = excluded.name RETURNING user_id",
+			wantSQL: "INSERT INTO users (name) VALUES ('alice') ON CONFLICT (name) DO UPDATE SET name = excluded.name RETURNING user_id",
+			wantID:  42,
+		},
+		{
+			name:   "disabled",
+			engine: EnginePostgres,
+			query:  "INSERT INTO logs (msg) VALUES ($1)",
+			args: []driver.NamedValue{
+				{Ordinal: 1, Name: NoReturning.Name, Value: NoReturning.Value},
+				{Ordinal: 2, Value: "hello"},
+			},
+			wantSQL: "INSERT INTO logs (msg) VALUES (:1)",
+		},
+		{
+			name:    "not insert",
+			engine:  EnginePostgres,
+			query:   "UPDATE users SET name = 'alice'",
+			wantSQL: "UPDATE users SET name = 'alice'",
+		},
+		{
+			name:    "mysql",
+			engine:  EngineMySQL,
+			query:   "INSERT INTO users (name) VALUES ('alice')",
+			wantSQL: "INSERT INTO users (name) VALUES ('alice')",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &awsClientMock{
+				ExecuteStatementFunc: func(ctx context.Context, input *rdsdata.ExecuteStatementInput, optFns ...func(*rdsdata.Options)) (*rdsdata.ExecuteStatementOutput, error) {
+					if aws.ToString(input.Sql) != tt.wantSQL {
+						t.Errorf("unexpected SQL: %s", aws.ToString(input.Sql))
+					}
+					if !strings.Contains(aws.ToString(input.Sql), "RETURNING") {
+						return &rdsdata.ExecuteStatementOutput{NumberOfRecordsUpdated: 1}, nil
+					}
+					return &rdsdata.ExecuteStatementOutput{
+						NumberOfRecordsUpdated: 1,
+						Records:                [][]types.Field{{&types.FieldMemberLongValue{Value: 42}}},
+					}, nil
+				},
+			}
+			conn := &Conn{
+				client: client,
+				connector: &Connector{
+					cfg: &Config{
+						ResourceArn: "resourceArn",
+						SecretArn:   "arn:aws:secretsmanager:us-east-1:123456789012:secret:secretArn",
+						Returning:   "id",
+					},
+				},
+				dialect: &DialectPostgres{},
+				engine:  tt.engine,
+			}
+			if tt.engine == EngineMySQL {
+				conn.dialect = &DialectMySQL{}
+			}
+			result, err := conn.ExecContext(context.Background(), tt.query, tt.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			id, err := result.LastInsertId()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if id != tt.wantID {
+				t.Errorf("unexpected LastInsertId: %d, want %d", id, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestConn_ExecContext_ReturningMySQL(t *testing.T) {
+	conn := &Conn{
+		client: &awsClientMock{},
+		connector: &Connector{
+			cfg: &Config{
+				ResourceArn: "resourceArn",
+				SecretArn:   "arn:aws:secretsmanager:us-east-1:123456789012:secret:secretArn",
+			},
+		},
+		dialect: &DialectMySQL{},
+		engine:  EngineMySQL,
+	}
+	_, err := conn.ExecContext(context.Background(), "INSERT INTO users (name) VALUES (?)", []driver.NamedValue{
+		{Ordinal: 1, Value: "alice"},
+		{Ordinal: 2, Name: "returning", Value: "id"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "only on PostgreSQL") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestConn_QueryContext_Returning(t *testing.T) {
+	client := &awsClientMock{
+		ExecuteStatementFunc: func(ctx context.Context, input *rdsdata.ExecuteStatementInput, optFns ...func(*rdsdata.Options)) (*rdsdata.ExecuteStatementOutput, error) {
+			if aws.ToString(input.Sql) != "INSERT INTO users (name) VALUES (:1) RETURNING *" {
+				t.Errorf("unexpected SQL: %s", aws.ToString(input.Sql))
+			}
+			if len(input.Parameters) != 1 {
+				t.Errorf("unexpected parameters: %v", input.Parameters)
+			}
+			return &rdsdata.ExecuteStatementOutput{
+				NumberOfRecordsUpdated: 1,
+				ColumnMetadata: []types.ColumnMetadata{
+					{Label: aws.String("id"), TypeName: aws.String("serial")},
+					{Label: aws.String("name"), TypeName: aws.String("text")},
+				},
+				Records: [][]types.Field{
+					{&types.FieldMemberLongValue{Value: 42}, &types.FieldMemberStringValue{Value: "alice"}},
+				},
+			}, nil
+		},
+	}
+	conn := &Conn{
+		client: client,
+		connector: &Connector{
+			cfg: &Config{
+				ResourceArn: "resourceArn",
+				SecretArn:   "arn:aws:secretsmanager:us-east-1:123456789012:secret:secretArn",
+				Returning:   "id",
+			},
+		},
+		dialect: &DialectPostgres{},
+		engine:  EnginePostgres,
+	}
+	rows, err := conn.QueryContext(context.Background(), "INSERT INTO users (name) VALUES ($1)", []driver.NamedValue{
+		{Ordinal: 1, Value: "alice"},
+		{Ordinal: 2, Name: "returning", Value: "*"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	if cols := rows.Columns(); strings.Join(cols, ",") != "id,name" {
+		t.Errorf("unexpected columns: %v", cols)
+	}
+	dest := make([]driver.Value, 2)
+	if err := rows.Next(dest); err != nil {
+		t.Fatal(err)
+	}
+	if dest[0] != int64(42) || dest[1] != "alice" {
+		t.Errorf("unexpected row: %v", dest)
+	}
+}
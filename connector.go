@@ -4,7 +4,9 @@ import (
 	"context"
 	"database/sql/driver"
 	"errors"
+	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -19,7 +21,17 @@ var _ driver.Connector = (*Connector)(nil)
 type Connector struct {
 	driver *Driver
 	cfg    *Config
+	hooks  Hooks
 	policy *retry.Policy
+
+	// secrets caches the ARNs of the secrets resolved from the names.
+	secretsMu      sync.Mutex
+	secrets        map[string]string
+	secretsManager SecretsManagerClient
+
+	// failover is shared by the connections, so that they share the circuit breakers.
+	failoverMu sync.Mutex
+	failover   *failoverClient
 }
 
 func NewConnector(cfg *Config) *Connector {
@@ -27,9 +39,15 @@ func NewConnector(cfg *Config) *Connector {
 }
 
 func newConnector(driver *Driver, cfg *Config) *Connector {
+	cfg = cfg.Clone()
+	hooks := cfg.Hooks
+	if cfg.Logger != nil {
+		hooks = MultiHooks(hooks, newLogHooks(cfg))
+	}
 	return &Connector{
 		driver: driver,
-		cfg:    cfg.Clone(),
+		cfg:    cfg,
+		hooks:  hooks,
 		policy: &retry.Policy{
 			MinDelay: time.Second,
 			MaxDelay: 30 * time.Second,
@@ -39,75 +57,200 @@ func newConnector(driver *Driver, cfg *Config) *Connector {
 	}
 }
 
+// WithHooks returns a new Connector that calls hooks in addition to Config.Hooks of c.
+// Config.Hooks can't be set by the DSN, so use it to observe the connector
+// opened from a DSN by Driver.OpenConnector:
+//
+//	connector, err := rdsdata.NewDriver().OpenConnector(dsn)
+//	if err != nil {
+//		return err
+//	}
+//	db := sql.OpenDB(connector.(*rdsdata.Connector).WithHooks(hooks))
+//
+// The new Connector doesn't share the connections, the cached secrets and the circuit breakers with c.
+func (c *Connector) WithHooks(hooks ...Hooks) *Connector {
+	cfg := c.cfg.Clone()
+	cfg.Hooks = MultiHooks(append([]Hooks{cfg.Hooks}, hooks...)...)
+	return newConnector(c.driver, cfg)
+}
+
 func (c *Connector) Connect(ctx context.Context) (driver.Conn, error) {
-	awsConfig, err := config.LoadDefaultConfig(ctx, config.WithRegion(c.cfg.AWSRegion))
-	if err != nil {
-		return nil, err
-	}
-	client := rdsdata.NewFromConfig(awsConfig)
-	dialect, err := c.detectDatabaseEngine(ctx, client)
+	client, err := c.newClient(ctx)
 	if err != nil {
 		return nil, err
 	}
-	return &Conn{
+	conn := &Conn{
 		client:    client,
 		connector: c,
-		dialect:   dialect,
-	}, nil
+	}
+	if c.cfg.Dialect != nil {
+		conn.dialect = c.cfg.Dialect
+		conn.engine = dialectEngine(c.cfg.Engine, c.cfg.Dialect)
+		return conn, nil
+	}
+
+	engine := c.cfg.Engine
+	switch engine {
+	case "", EngineAuto:
+		version, err := c.queryServerVersion(ctx, conn)
+		if err != nil {
+			return nil, err
+		}
+		conn.serverVersion = version
+		engine = detectDatabaseEngine(version)
+	case EngineMySQL, EnginePostgres:
+	default:
+		return nil, fmt.Errorf("rdsdata: unknown engine %q", engine)
+	}
+
+	var dialect Dialect
+	if engine == EnginePostgres {
+		dialect = c.newDialectPostgres()
+	} else {
+		dialect = c.newDialectMySQL()
+	}
+	if c.cfg.DialectFactory != nil {
+		dialect, err = c.cfg.DialectFactory(engine, conn.serverVersion, dialect)
+		if err != nil {
+			return nil, err
+		}
+	}
+	conn.dialect = dialect
+	conn.engine = engine
+	return conn, nil
 }
 
-func (c *Connector) Driver() driver.Driver {
-	return c.driver
+// dialectEngine returns the engine of the fixed dialect.
+func dialectEngine(engine Engine, dialect Dialect) Engine {
+	if engine == EngineMySQL || engine == EnginePostgres {
+		return engine
+	}
+	switch dialect.(type) {
+	case *DialectMySQL:
+		return EngineMySQL
+	case *DialectPostgres:
+		return EnginePostgres
+	}
+	return ""
 }
 
-func (c *Connector) detectDatabaseEngine(ctx context.Context, client awsClientInterface) (Dialect, error) {
-	in := &rdsdata.ExecuteStatementInput{
-		ResourceArn: &c.cfg.ResourceArn,
-		SecretArn:   &c.cfg.SecretArn,
-		Database:    &c.cfg.Database,
-		Sql:         aws.String("SELECT VERSION()"),
+func (c *Connector) newClient(ctx context.Context) (Client, error) {
+	if len(c.cfg.Failover) > 0 {
+		return c.newFailoverClient(ctx)
+	}
+	if c.cfg.Client != nil {
+		return c.cfg.Client, nil
 	}
+	return newRegionalClient(ctx, c.cfg.region())
+}
 
-	return retry.DoValue(ctx, c.policy, func() (Dialect, error) {
-		out, err := client.ExecuteStatement(ctx, in)
+func newRegionalClient(ctx context.Context, region string) (Client, error) {
+	awsConfig, err := config.LoadDefaultConfig(ctx, config.WithRegion(region))
+	if err != nil {
+		return nil, err
+	}
+	return rdsdata.NewFromConfig(awsConfig), nil
+}
+
+// newFailoverClient returns the client that fails over the primary and the endpoints in Config.Failover.
+func (c *Connector) newFailoverClient(ctx context.Context) (Client, error) {
+	c.failoverMu.Lock()
+	defer c.failoverMu.Unlock()
+	if c.failover != nil {
+		return c.failover, nil
+	}
+
+	primary := Endpoint{
+		AWSRegion:   c.cfg.region(),
+		ResourceArn: c.cfg.ResourceArn,
+		Client:      c.cfg.Client,
+	}
+	endpoints := append([]Endpoint{primary}, c.cfg.Failover...)
+	for i := range endpoints {
+		if endpoints[i].Client != nil {
+			continue
+		}
+		client, err := newRegionalClient(ctx, endpoints[i].region())
 		if err != nil {
 			return nil, err
 		}
+		endpoints[i].Client = client
+	}
+
+	interval := c.cfg.FailoverProbeInterval
+	if interval == 0 {
+		interval = 30 * time.Second
+	}
+	c.failover = newFailoverClient(endpoints, interval)
+	return c.failover, nil
+}
+
+func (c *Connector) Driver() driver.Driver {
+	return c.driver
+}
+
+// queryServerVersion returns the result of "SELECT VERSION()".
+func (c *Connector) queryServerVersion(ctx context.Context, conn *Conn) (string, error) {
+	var attempt int
+	return retry.DoValue(ctx, c.policy, func() (string, error) {
+		attempt++
+		secretArn, err := c.secretArn(ctx)
+		if err != nil {
+			return "", retry.MarkPermanent(err)
+		}
+		in := &rdsdata.ExecuteStatementInput{
+			ResourceArn: &c.cfg.ResourceArn,
+			SecretArn:   aws.String(secretArn),
+			Database:    &c.cfg.Database,
+			Sql:         aws.String("SELECT VERSION()"),
+		}
+		out, err := conn.executeStatement(withAttempt(ctx, attempt), callQuery, in)
+		if err != nil {
+			return "", err
+		}
 		if len(out.Records) == 0 {
-			return nil, errors.New("rdsdata: invalid response to version request")
+			return "", errors.New("rdsdata: invalid response to version request")
 		}
 
 		row := out.Records[0]
 		if len(row) == 0 {
-			return nil, errors.New("rdsdata: invalid response to version request")
+			return "", errors.New("rdsdata: invalid response to version request")
 		}
 
 		field := row[0]
 		version, ok := field.(*types.FieldMemberStringValue)
 		if !ok {
-			return nil, errors.New("rdsdata: invalid response to version request")
+			return "", errors.New("rdsdata: invalid response to version request")
 		}
-
-		if strings.Contains(strings.ToLower(version.Value), "postgresql") {
-			return c.newDialectPostgres(), nil
-		}
-
-		return c.newDialectMySQL(), nil
+		return version.Value, nil
 	})
 }
 
+// detectDatabaseEngine returns the engine for the server version.
+// PostgreSQL reports its name in the version, e.g. "PostgreSQL 16.1 on x86_64-pc-linux-gnu",
+// and MySQL doesn't, e.g. "8.0.32".
+func detectDatabaseEngine(version string) Engine {
+	if strings.Contains(strings.ToLower(version), "postgresql") {
+		return EnginePostgres
+	}
+	return EngineMySQL
+}
+
 func (c *Connector) newDialectMySQL() *DialectMySQL {
 	loc := time.UTC
 	if c.cfg.Location != nil {
 		loc = c.cfg.Location
 	}
 	return &DialectMySQL{
-		location:     loc,
-		parseTime:    c.cfg.ParseTime,
-		timeTruncate: c.cfg.TimeTruncate,
+		location:        loc,
+		parseTime:       c.cfg.ParseTime,
+		timeTruncate:    c.cfg.TimeTruncate,
+		fieldConverters: c.cfg.FieldConverters,
 	}
 }
 
 func (c *Connector) newDialectPostgres() *DialectPostgres {
-	return &DialectPostgres{}
+	return &DialectPostgres{
+		fieldConverters: c.cfg.FieldConverters,
+	}
 }
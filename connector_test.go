@@ -0,0 +1,192 @@
+package rdsdata
+
+import (
+	"context"
+	"database/sql/driver"
+	"slices"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata"
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata/types"
+)
+
+func TestConnector_Connect(t *testing.T) {
+	t.Run("detects the engine", func(t *testing.T) {
+		client := &awsClientMock{
+			ExecuteStatementFunc: func(ctx context.Context, input *rdsdata.ExecuteStatementInput, optFns ...func(*rdsdata.Options)) (*rdsdata.ExecuteStatementOutput, error) {
+				if aws.ToString(input.Sql) != "SELECT VERSION()" {
+					t.Errorf("unexpected SQL: %s", aws.ToString(input.Sql))
+				}
+				return &rdsdata.ExecuteStatementOutput{
+					Records: [][]types.Field{
+						{&types.FieldMemberStringValue{Value: "PostgreSQL 16.1 on x86_64-pc-linux-gnu"}},
+					},
+				}, nil
+			},
+		}
+		c := NewConnector(&Config{Client: client})
+		conn, err := c.Connect(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		rc := conn.(*Conn)
+		if _, ok := rc.Dialect().(*DialectPostgres); !ok {
+			t.Errorf("unexpected dialect: %T", rc.Dialect())
+		}
+		if rc.ServerVersion() != "PostgreSQL 16.1 on x86_64-pc-linux-gnu" {
+			t.Errorf("unexpected server version: %q", rc.ServerVersion())
+		}
+	})
+
+	t.Run("skips the detection", func(t *testing.T) {
+		client := &awsClientMock{
+			ExecuteStatementFunc: func(ctx context.Context, input *rdsdata.ExecuteStatementInput, optFns ...func(*rdsdata.Options)) (*rdsdata.ExecuteStatementOutput, error) {
+				t.Errorf("unexpected SQL: %s", aws.ToString(input.Sql))
+				return nil, nil
+			},
+		}
+		c := NewConnector(&Config{Client: client, Engine: EnginePostgres})
+		conn, err := c.Connect(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		rc := conn.(*Conn)
+		if _, ok := rc.Dialect().(*DialectPostgres); !ok {
+			t.Errorf("unexpected dialect: %T", rc.Dialect())
+		}
+		if rc.ServerVersion() != "" {
+			t.Errorf("unexpected server version: %q", rc.ServerVersion())
+		}
+	})
+
+	t.Run("dialect factory", func(t *testing.T) {
+		custom := &DialectPostgres{}
+		c := NewConnector(&Config{
+			Client: &awsClientMock{},
+			Engine: EngineMySQL,
+			DialectFactory: func(engine Engine, serverVersion string, base Dialect) (Dialect, error) {
+				if engine != EngineMySQL {
+					t.Errorf("unexpected engine: %q", engine)
+				}
+				if _, ok := base.(*DialectMySQL); !ok {
+					t.Errorf("unexpected base dialect: %T", base)
+				}
+				return custom, nil
+			},
+		})
+		conn, err := c.Connect(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if conn.(*Conn).Dialect() != custom {
+			t.Errorf("unexpected dialect: %T", conn.(*Conn).Dialect())
+		}
+	})
+
+	t.Run("dialect", func(t *testing.T) {
+		custom := &DialectPostgres{}
+		c := NewConnector(&Config{Client: &awsClientMock{}, Dialect: custom})
+		conn, err := c.Connect(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if conn.(*Conn).Dialect() != custom {
+			t.Errorf("unexpected dialect: %T", conn.(*Conn).Dialect())
+		}
+	})
+
+	t.Run("field converters", func(t *testing.T) {
+		RegisterFieldConverter(EngineMySQL, "FLOAT", func(field types.Field) (driver.Value, error) {
+			return "default", nil
+		})
+		t.Cleanup(func() {
+			RegisterFieldConverter(EngineMySQL, "FLOAT", nil)
+		})
+
+		convert := func(cfg *Config) driver.Value {
+			t.Helper()
+			conn, err := NewConnector(cfg).Connect(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			v, err := conn.(*Conn).Dialect().GetFieldConverter("FLOAT")(&types.FieldMemberDoubleValue{Value: 42.0})
+			if err != nil {
+				t.Fatal(err)
+			}
+			return v
+		}
+
+		// the converters of a config don't leak into the other connectors.
+		v := convert(&Config{
+			Client: &awsClientMock{},
+			Engine: EngineMySQL,
+			FieldConverters: map[string]FieldConverter{
+				"FLOAT": func(field types.Field) (driver.Value, error) {
+					return "overridden", nil
+				},
+			},
+		})
+		if v != "overridden" {
+			t.Errorf("unexpected value: %v, want overridden", v)
+		}
+		v = convert(&Config{Client: &awsClientMock{}, Engine: EngineMySQL})
+		if v != "default" {
+			t.Errorf("unexpected value: %v, want default", v)
+		}
+	})
+
+	t.Run("unknown engine", func(t *testing.T) {
+		c := NewConnector(&Config{Client: &awsClientMock{}, Engine: "oracle"})
+		if _, err := c.Connect(context.Background()); err == nil {
+			t.Fatal("expected error, but got nil")
+		}
+	})
+}
+
+func TestConnector_WithHooks(t *testing.T) {
+	client := &awsClientMock{
+		ExecuteStatementFunc: func(ctx context.Context, input *rdsdata.ExecuteStatementInput, optFns ...func(*rdsdata.Options)) (*rdsdata.ExecuteStatementOutput, error) {
+			return &rdsdata.ExecuteStatementOutput{
+				Records: [][]types.Field{
+					{&types.FieldMemberLongValue{Value: 1}},
+				},
+			}, nil
+		},
+	}
+	base := &recordHooks{}
+	added := &recordHooks{}
+	c := NewConnector(&Config{Client: client, Engine: EngineMySQL, Hooks: base})
+	c2 := c.WithHooks(added)
+
+	query := func(c *Connector) {
+		t.Helper()
+		conn, err := c.Connect(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		if _, err := conn.(*Conn).QueryContext(context.Background(), "SELECT 1", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// both the hooks of the config and the added hooks are called.
+	query(c2)
+	want := []string{"BeforeQuery: SELECT 1", "AfterQuery: SELECT 1"}
+	if !slices.Equal(base.calls, want) {
+		t.Errorf("unexpected calls of the base hooks: %v, want %v", base.calls, want)
+	}
+	if !slices.Equal(added.calls, want) {
+		t.Errorf("unexpected calls of the added hooks: %v, want %v", added.calls, want)
+	}
+
+	// the original connector is not modified.
+	query(c)
+	if len(added.calls) != 2 {
+		t.Errorf("unexpected calls of the added hooks: %v", added.calls)
+	}
+	if c.cfg.Hooks != base {
+		t.Errorf("unexpected hooks of the original config: %v", c.cfg.Hooks)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/service/rdsdata"
@@ -26,6 +27,50 @@ type Dialect interface {
 	GetFieldConverter(columnType string) FieldConverter
 }
 
+// DialectFactory returns the dialect for the engine.
+// See Config.DialectFactory.
+type DialectFactory func(engine Engine, serverVersion string, base Dialect) (Dialect, error)
+
+var (
+	fieldConvertersMu sync.RWMutex
+	fieldConverters   = map[Engine]map[string]FieldConverter{}
+)
+
+// RegisterFieldConverter registers the default field converter for the columns of the type in the engine.
+// typeName is compared with ColumnMetadata.TypeName as is,
+// e.g. "BIGINT UNSIGNED" for MySQL and "int8" for PostgreSQL.
+// The registered converters take precedence over the built-in ones of DialectMySQL and DialectPostgres,
+// and are shared by all the connectors in the process.
+// Prefer Config.FieldConverters, which overrides them for a connector.
+// If conv is nil, the converter is unregistered.
+func RegisterFieldConverter(engine Engine, typeName string, conv FieldConverter) {
+	fieldConvertersMu.Lock()
+	defer fieldConvertersMu.Unlock()
+
+	if conv == nil {
+		delete(fieldConverters[engine], typeName)
+		return
+	}
+	if fieldConverters[engine] == nil {
+		fieldConverters[engine] = map[string]FieldConverter{}
+	}
+	fieldConverters[engine][typeName] = conv
+}
+
+// lookupFieldConverter returns the field converter in converters, which are Config.FieldConverters,
+// or the default one registered by RegisterFieldConverter.
+func lookupFieldConverter(converters map[string]FieldConverter, engine Engine, typeName string) (FieldConverter, bool) {
+	if conv, ok := converters[typeName]; ok {
+		return conv, true
+	}
+
+	fieldConvertersMu.RLock()
+	defer fieldConvertersMu.RUnlock()
+
+	conv, ok := fieldConverters[engine][typeName]
+	return conv, ok
+}
+
 // isOrdinal returns true if the arguments are ordinal.
 func isOrdinal(args []driver.NamedValue) (bool, error) {
 	// Make sure we're not mixing and matching.
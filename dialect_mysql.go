@@ -20,9 +20,10 @@ var _ Dialect = (*DialectMySQL)(nil)
 
 // DialectMySQL is the MySQL dialect.
 type DialectMySQL struct {
-	location     *time.Location
-	parseTime    bool
-	timeTruncate time.Duration
+	location        *time.Location
+	parseTime       bool
+	timeTruncate    time.Duration
+	fieldConverters map[string]FieldConverter
 }
 
 // MigrateQuery converts a MySQL query into an RDS statement.
@@ -150,6 +151,10 @@ func (d *DialectMySQL) getLocation() *time.Location {
 }
 
 func (d *DialectMySQL) GetFieldConverter(columnType string) FieldConverter {
+	if conv, ok := lookupFieldConverter(d.fieldConverters, EngineMySQL, columnType); ok {
+		return conv
+	}
+
 	// log.Printf("columnType: %s\n", columnType)
 	switch columnType {
 	case "BIGINT UNSIGNED":
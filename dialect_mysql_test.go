@@ -212,6 +212,33 @@ func TestDialectMySQL_MigrateQuery(t *testing.T) {
 }
 
 func TestDialectMySQL_GetFieldConverter(t *testing.T) {
+	t.Run("registered converter", func(t *testing.T) {
+		RegisterFieldConverter(EngineMySQL, "FLOAT", func(field types.Field) (driver.Value, error) {
+			return "overridden", nil
+		})
+		t.Cleanup(func() {
+			RegisterFieldConverter(EngineMySQL, "FLOAT", nil)
+		})
+
+		d := &DialectMySQL{}
+		v, err := d.GetFieldConverter("FLOAT")(&types.FieldMemberDoubleValue{Value: 42.0})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v != "overridden" {
+			t.Errorf("unexpected value: %v, want overridden", v)
+		}
+
+		// the converters of the other engines are not affected.
+		v, err = (&DialectPostgres{}).GetFieldConverter("FLOAT")(&types.FieldMemberDoubleValue{Value: 42.0})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v != 42.0 {
+			t.Errorf("unexpected value: %v, want 42.0", v)
+		}
+	})
+
 	t.Run("BIGINT UNSIGNED", func(t *testing.T) {
 		d := &DialectMySQL{}
 		conv := d.GetFieldConverter("BIGINT UNSIGNED")
@@ -15,7 +15,9 @@ var postgresRegex = regexp.MustCompile(`\$([0-9]+)`)
 var _ Dialect = (*DialectPostgres)(nil)
 
 // DialectPostgres is the PostgreSQL dialect.
-type DialectPostgres struct{}
+type DialectPostgres struct {
+	fieldConverters map[string]FieldConverter
+}
 
 // MigrateQuery converts a PostgreSQL query into an RDS statement.
 func (d *DialectPostgres) MigrateQuery(query string, args []driver.NamedValue) (*rdsdata.ExecuteStatementInput, error) {
@@ -69,5 +71,8 @@ func (d *DialectPostgres) IsIsolationLevelSupported(level sql.IsolationLevel) bo
 }
 
 func (d *DialectPostgres) GetFieldConverter(columnType string) FieldConverter {
+	if conv, ok := lookupFieldConverter(d.fieldConverters, EnginePostgres, columnType); ok {
+		return conv
+	}
 	return convertDefault
 }
@@ -35,11 +35,15 @@ func (d *Driver) Open(dsn string) (driver.Conn, error) {
 }
 
 // OpenConnector returns a new connector.
+// It returns an error if the DSN is invalid. See Config.Validate.
 func (d *Driver) OpenConnector(dsn string) (driver.Connector, error) {
 	cfg, err := ParseDSN(dsn)
 	if err != nil {
 		return nil, err
 	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
 	connector := newConnector(d, cfg)
 	return connector, nil
 }
@@ -0,0 +1,349 @@
+package rdsdata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsretry "github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata"
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata/types"
+	"github.com/aws/smithy-go"
+)
+
+// ErrTransactionEndpointFailed is returned when the endpoint that owns a transaction fails.
+// The transaction is not moved to another endpoint, because its state lives in the failed cluster.
+var ErrTransactionEndpointFailed = errors.New("rdsdata: the endpoint of the transaction failed, and the transaction can't fail over")
+
+// ErrNoEndpointAvailable is returned when all the endpoints are failing.
+var ErrNoEndpointAvailable = errors.New("rdsdata: no endpoint is available")
+
+// Endpoint is a Data API endpoint to fail over to, e.g. a secondary cluster of Aurora Global Database.
+type Endpoint struct {
+	// AWSRegion is the AWS region of the endpoint.
+	// If it is empty, the region of ResourceArn is used.
+	AWSRegion string
+
+	// ResourceArn is the ARN of the cluster in the region.
+	ResourceArn string
+
+	// SecretArn is the ARN of the secret in the region, e.g. a replica of the primary secret.
+	// If it is empty, the secret of the primary is used.
+	SecretArn string
+
+	// Client is the RDS Data API client for the region.
+	// If it is nil, a client is created from the default AWS config and AWSRegion.
+	Client Client
+}
+
+// region returns AWSRegion, or the region of ResourceArn if it is empty.
+func (e *Endpoint) region() string {
+	if e.AWSRegion != "" {
+		return e.AWSRegion
+	}
+	return (&Config{ResourceArn: e.ResourceArn}).region()
+}
+
+// compile time type check
+var _ Client = (*failoverClient)(nil)
+
+// failoverClient is a Client that sends the calls to the first available endpoint.
+// The endpoints that fail with endpoint-level errors are skipped by the circuit breakers,
+// and probed with pings until they recover.
+type failoverClient struct {
+	endpoints []*endpointState
+	interval  time.Duration
+
+	mu sync.Mutex
+	// txs maps the transaction IDs to the endpoints that began them.
+	txs map[string]*failoverTx
+	// sweptAt is the time when the expired transactions were removed from txs.
+	sweptAt time.Time
+}
+
+// transactionIdleTimeout is the time after which the Data API rolls back an idle transaction.
+// The transactions that are not used for this time are forgotten, e.g. the abandoned ones.
+const transactionIdleTimeout = 3 * time.Minute
+
+// failoverTx is a transaction of failoverClient.
+type failoverTx struct {
+	endpoint *endpointState
+	usedAt   time.Time
+}
+
+// endpointState is an endpoint with its circuit breaker.
+type endpointState struct {
+	Endpoint
+
+	mu       sync.Mutex
+	open     bool
+	probedAt time.Time
+}
+
+// newFailoverClient returns a new client that fails over the endpoints in order.
+func newFailoverClient(endpoints []Endpoint, interval time.Duration) *failoverClient {
+	states := make([]*endpointState, 0, len(endpoints))
+	for _, e := range endpoints {
+		states = append(states, &endpointState{Endpoint: e})
+	}
+	return &failoverClient{
+		endpoints: states,
+		interval:  interval,
+		txs:       map[string]*failoverTx{},
+	}
+}
+
+// trip opens the circuit breaker of the endpoint.
+func (e *endpointState) trip() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.open {
+		e.open = true
+		e.probedAt = time.Now()
+	}
+}
+
+// claimProbe reports whether the endpoint can be used:
+// the circuit breaker is closed, or the caller should probe it.
+func (e *endpointState) claimProbe(now time.Time, interval time.Duration) (usable, probe bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.open {
+		return true, false
+	}
+	if now.Sub(e.probedAt) < interval {
+		return false, false
+	}
+	e.probedAt = now
+	return true, true
+}
+
+// probe pings the endpoint, and closes the circuit breaker if it recovers.
+func (e *endpointState) probe(ctx context.Context, secretArn *string) bool {
+	_, err := e.Client.ExecuteStatement(ctx, &rdsdata.ExecuteStatementInput{
+		ResourceArn: aws.String(e.ResourceArn),
+		SecretArn:   e.secretArn(secretArn),
+		Sql:         aws.String("/* ping */ SELECT 1"),
+	})
+	if err != nil {
+		return false
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.open = false
+	return true
+}
+
+func (e *endpointState) secretArn(secretArn *string) *string {
+	if e.SecretArn != "" {
+		return aws.String(e.SecretArn)
+	}
+	return secretArn
+}
+
+// do calls f on the first available endpoint, and fails over to the next endpoint on endpoint-level errors.
+// The failed endpoint is skipped by the circuit breaker in any case,
+// but the call moves to the next endpoint only if canFailOver reports that it is safe to send it again.
+// A throttled call may move to the next endpoint too, but it doesn't trip the circuit breaker,
+// because throttling says nothing about the health of the endpoint.
+func (c *failoverClient) do(ctx context.Context, secretArn *string, canFailOver func(err error) bool, f func(e *endpointState) error) error {
+	now := time.Now()
+	var lastErr error
+	for _, e := range c.endpoints {
+		usable, probe := e.claimProbe(now, c.interval)
+		if !usable {
+			continue
+		}
+		if probe && !e.probe(ctx, secretArn) {
+			continue
+		}
+		err := f(e)
+		if err == nil {
+			return nil
+		}
+		if isEndpointError(err) {
+			e.trip()
+		} else if !isThrottleError(err) {
+			return err
+		}
+		if !canFailOver(err) {
+			return err
+		}
+		lastErr = err
+	}
+	if lastErr != nil {
+		return lastErr
+	}
+	return ErrNoEndpointAvailable
+}
+
+// endpointOf returns the endpoint that began the transaction.
+func (c *failoverClient) endpointOf(txID *string) (*endpointState, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	tx, ok := c.txs[aws.ToString(txID)]
+	if !ok {
+		return nil, fmt.Errorf("rdsdata: unknown transaction %q", aws.ToString(txID))
+	}
+	tx.usedAt = time.Now()
+	return tx.endpoint, nil
+}
+
+// beginTransaction records the endpoint of the transaction, and forgets the expired transactions.
+func (c *failoverClient) beginTransaction(txID *string, e *endpointState) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	if now.Sub(c.sweptAt) >= transactionIdleTimeout {
+		for id, tx := range c.txs {
+			if now.Sub(tx.usedAt) >= transactionIdleTimeout {
+				delete(c.txs, id)
+			}
+		}
+		c.sweptAt = now
+	}
+	c.txs[aws.ToString(txID)] = &failoverTx{endpoint: e, usedAt: now}
+}
+
+// inTransaction calls f on the endpoint of the transaction without failing over.
+func (c *failoverClient) inTransaction(txID *string, f func(e *endpointState) error) error {
+	e, err := c.endpointOf(txID)
+	if err != nil {
+		return err
+	}
+	if err := f(e); err != nil {
+		if isEndpointError(err) {
+			e.trip()
+			return fmt.Errorf("%w: %w", ErrTransactionEndpointFailed, err)
+		}
+		return err
+	}
+	return nil
+}
+
+func (c *failoverClient) endTransaction(txID *string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.txs, aws.ToString(txID))
+}
+
+// ExecuteStatement implements Client.
+func (c *failoverClient) ExecuteStatement(ctx context.Context, in *rdsdata.ExecuteStatementInput, optFns ...func(*rdsdata.Options)) (*rdsdata.ExecuteStatementOutput, error) {
+	var out *rdsdata.ExecuteStatementOutput
+	f := func(e *endpointState) error {
+		params := *in
+		params.ResourceArn = aws.String(e.ResourceArn)
+		params.SecretArn = e.secretArn(in.SecretArn)
+		var err error
+		out, err = e.Client.ExecuteStatement(ctx, &params, optFns...)
+		return err
+	}
+	if in.TransactionId != nil {
+		return out, c.inTransaction(in.TransactionId, f)
+	}
+
+	// the statement may have been executed on the failed endpoint,
+	// so it is sent again only if it is idempotent or it has never run.
+	idempotent := optionsFromContext(ctx).idempotent
+	canFailOver := func(err error) bool {
+		return idempotent || isNotExecutedError(err)
+	}
+	return out, c.do(ctx, in.SecretArn, canFailOver, f)
+}
+
+// BeginTransaction implements Client.
+func (c *failoverClient) BeginTransaction(ctx context.Context, in *rdsdata.BeginTransactionInput, optFns ...func(*rdsdata.Options)) (*rdsdata.BeginTransactionOutput, error) {
+	// beginning a transaction changes no data, so it is always safe to fail over.
+	var out *rdsdata.BeginTransactionOutput
+	err := c.do(ctx, in.SecretArn, func(error) bool { return true }, func(e *endpointState) error {
+		params := *in
+		params.ResourceArn = aws.String(e.ResourceArn)
+		params.SecretArn = e.secretArn(in.SecretArn)
+		var err error
+		out, err = e.Client.BeginTransaction(ctx, &params, optFns...)
+		if err != nil {
+			return err
+		}
+		c.beginTransaction(out.TransactionId, e)
+		return nil
+	})
+	return out, err
+}
+
+// CommitTransaction implements Client.
+func (c *failoverClient) CommitTransaction(ctx context.Context, in *rdsdata.CommitTransactionInput, optFns ...func(*rdsdata.Options)) (*rdsdata.CommitTransactionOutput, error) {
+	var out *rdsdata.CommitTransactionOutput
+	err := c.inTransaction(in.TransactionId, func(e *endpointState) error {
+		params := *in
+		params.ResourceArn = aws.String(e.ResourceArn)
+		params.SecretArn = e.secretArn(in.SecretArn)
+		var err error
+		out, err = e.Client.CommitTransaction(ctx, &params, optFns...)
+		return err
+	})
+	// the transaction is finished even if the call fails.
+	c.endTransaction(in.TransactionId)
+	return out, err
+}
+
+// RollbackTransaction implements Client.
+func (c *failoverClient) RollbackTransaction(ctx context.Context, in *rdsdata.RollbackTransactionInput, optFns ...func(*rdsdata.Options)) (*rdsdata.RollbackTransactionOutput, error) {
+	var out *rdsdata.RollbackTransactionOutput
+	err := c.inTransaction(in.TransactionId, func(e *endpointState) error {
+		params := *in
+		params.ResourceArn = aws.String(e.ResourceArn)
+		params.SecretArn = e.secretArn(in.SecretArn)
+		var err error
+		out, err = e.Client.RollbackTransaction(ctx, &params, optFns...)
+		return err
+	})
+	// the transaction is finished even if the call fails.
+	c.endTransaction(in.TransactionId)
+	return out, err
+}
+
+// isEndpointError reports whether the error means that the endpoint is unavailable,
+// rather than the call is invalid.
+func isEndpointError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		// the caller gave up, which says nothing about the endpoint.
+		return false
+	}
+	var internalErr *types.InternalServerErrorException
+	var unavailableErr *types.ServiceUnavailableError
+	var dbUnavailableErr *types.DatabaseUnavailableException
+	var stateErr *types.InvalidResourceStateException
+	var netErr net.Error
+	return errors.As(err, &internalErr) ||
+		errors.As(err, &unavailableErr) ||
+		errors.As(err, &dbUnavailableErr) ||
+		errors.As(err, &stateErr) ||
+		errors.As(err, &netErr)
+}
+
+// isNotExecutedError reports whether the endpoint error guarantees that the statement has not been executed:
+// the database is unavailable, the call is throttled, or the connection failed before the request was sent.
+// The other endpoint errors, e.g. internal server errors and timeouts, may happen after the statement has run.
+func isNotExecutedError(err error) bool {
+	var dbUnavailableErr *types.DatabaseUnavailableException
+	var opErr *net.OpError
+	var dnsErr *net.DNSError
+	return errors.As(err, &dbUnavailableErr) ||
+		isThrottleError(err) ||
+		(errors.As(err, &opErr) && opErr.Op == "dial") ||
+		errors.As(err, &dnsErr)
+}
+
+// isThrottleError reports whether the Data API rejected the call by throttling.
+func isThrottleError(err error) bool {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	_, ok := awsretry.DefaultThrottleErrorCodes[apiErr.ErrorCode()]
+	return ok
+}
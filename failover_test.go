@@ -0,0 +1,305 @@
+package rdsdata
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata"
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata/types"
+	"github.com/aws/smithy-go"
+)
+
+const (
+	primaryArn   = "arn:aws:rds:us-east-1:123456789012:cluster:primary"
+	secondaryArn = "arn:aws:rds:us-west-2:123456789012:cluster:secondary"
+)
+
+// failoverMock is a client of a region that can be made unavailable.
+type failoverMock struct {
+	region      string
+	unavailable bool
+	calls       []string
+
+	// err is the error of the unavailable region.
+	// The default is DatabaseUnavailableException.
+	err error
+}
+
+func (m *failoverMock) client() Client {
+	err := func() error {
+		if !m.unavailable {
+			return nil
+		}
+		if m.err != nil {
+			return m.err
+		}
+		return &types.DatabaseUnavailableException{Message: aws.String("database unavailable")}
+	}
+	return &awsClientMock{
+		ExecuteStatementFunc: func(ctx context.Context, input *rdsdata.ExecuteStatementInput, optFns ...func(*rdsdata.Options)) (*rdsdata.ExecuteStatementOutput, error) {
+			m.calls = append(m.calls, aws.ToString(input.Sql)+" @"+aws.ToString(input.ResourceArn))
+			return &rdsdata.ExecuteStatementOutput{}, err()
+		},
+		BeginTransactionFunc: func(ctx context.Context, input *rdsdata.BeginTransactionInput, optFns ...func(*rdsdata.Options)) (*rdsdata.BeginTransactionOutput, error) {
+			m.calls = append(m.calls, "BEGIN @"+aws.ToString(input.ResourceArn))
+			return &rdsdata.BeginTransactionOutput{TransactionId: aws.String(m.region)}, err()
+		},
+		CommitTransactionFunc: func(ctx context.Context, input *rdsdata.CommitTransactionInput, optFns ...func(*rdsdata.Options)) (*rdsdata.CommitTransactionOutput, error) {
+			m.calls = append(m.calls, "COMMIT @"+aws.ToString(input.ResourceArn))
+			return &rdsdata.CommitTransactionOutput{}, err()
+		},
+		RollbackTransactionFunc: func(ctx context.Context, input *rdsdata.RollbackTransactionInput, optFns ...func(*rdsdata.Options)) (*rdsdata.RollbackTransactionOutput, error) {
+			m.calls = append(m.calls, "ROLLBACK @"+aws.ToString(input.ResourceArn))
+			return &rdsdata.RollbackTransactionOutput{}, err()
+		},
+	}
+}
+
+func openFailoverDB(t *testing.T, primary, secondary *failoverMock, interval time.Duration) *sql.DB {
+	t.Helper()
+	db := sql.OpenDB(NewConnector(&Config{
+		ResourceArn: primaryArn,
+		SecretArn:   "arn:aws:secretsmanager:us-east-1:123456789012:secret:primary",
+		Engine:      EngineMySQL,
+		Client:      primary.client(),
+		Failover: []Endpoint{
+			{
+				ResourceArn: secondaryArn,
+				SecretArn:   "arn:aws:secretsmanager:us-west-2:123456789012:secret:secondary",
+				Client:      secondary.client(),
+			},
+		},
+		FailoverProbeInterval: interval,
+	}))
+	t.Cleanup(func() { db.Close() })
+	db.SetMaxOpenConns(1)
+	return db
+}
+
+func TestFailover(t *testing.T) {
+	primary := &failoverMock{region: "us-east-1", unavailable: true}
+	secondary := &failoverMock{region: "us-west-2"}
+	db := openFailoverDB(t, primary, secondary, time.Hour)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if _, err := db.ExecContext(ctx, "DELETE FROM users"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the circuit breaker skips the primary in the second call.
+	want := []string{"DELETE FROM users @" + primaryArn}
+	if fmt.Sprint(primary.calls) != fmt.Sprint(want) {
+		t.Errorf("unexpected primary calls: %q", primary.calls)
+	}
+	want = []string{"DELETE FROM users @" + secondaryArn, "DELETE FROM users @" + secondaryArn}
+	if fmt.Sprint(secondary.calls) != fmt.Sprint(want) {
+		t.Errorf("unexpected secondary calls: %q", secondary.calls)
+	}
+}
+
+func TestFailover_Recover(t *testing.T) {
+	primary := &failoverMock{region: "us-east-1", unavailable: true}
+	secondary := &failoverMock{region: "us-west-2"}
+	db := openFailoverDB(t, primary, secondary, time.Nanosecond)
+	ctx := context.Background()
+
+	if _, err := db.ExecContext(ctx, "DELETE FROM users"); err != nil {
+		t.Fatal(err)
+	}
+	primary.unavailable = false
+	primary.calls = nil
+	time.Sleep(time.Millisecond)
+	if _, err := db.ExecContext(ctx, "DELETE FROM posts"); err != nil {
+		t.Fatal(err)
+	}
+
+	// the primary is probed, and used again.
+	want := []string{"/* ping */ SELECT 1 @" + primaryArn, "DELETE FROM posts @" + primaryArn}
+	if fmt.Sprint(primary.calls) != fmt.Sprint(want) {
+		t.Errorf("unexpected primary calls: %q", primary.calls)
+	}
+}
+
+func TestFailover_Transaction(t *testing.T) {
+	primary := &failoverMock{region: "us-east-1"}
+	secondary := &failoverMock{region: "us-west-2"}
+	db := openFailoverDB(t, primary, secondary, time.Hour)
+	ctx := context.Background()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	primary.unavailable = true
+	_, err = tx.ExecContext(ctx, "DELETE FROM users")
+	if !errors.Is(err, ErrTransactionEndpointFailed) {
+		t.Errorf("want ErrTransactionEndpointFailed, got %v", err)
+	}
+	_ = tx.Rollback()
+
+	if len(secondary.calls) != 0 {
+		t.Errorf("the transaction moved to the secondary: %q", secondary.calls)
+	}
+}
+
+func TestFailover_MayBeExecuted(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		idempotent bool
+		failover   bool
+	}{
+		{
+			name:     "service unavailable",
+			err:      &types.ServiceUnavailableError{Message: aws.String("service unavailable")},
+			failover: false,
+		},
+		{
+			name:       "idempotent",
+			err:        &types.ServiceUnavailableError{Message: aws.String("service unavailable")},
+			idempotent: true,
+			failover:   true,
+		},
+		{
+			name:     "throttled",
+			err:      &smithy.GenericAPIError{Code: "ThrottlingException", Message: "rate exceeded"},
+			failover: true,
+		},
+		{
+			name:     "connection refused",
+			err:      &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED},
+			failover: true,
+		},
+		{
+			name:     "connection reset",
+			err:      &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET},
+			failover: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary := &failoverMock{region: "us-east-1", unavailable: true, err: tt.err}
+			secondary := &failoverMock{region: "us-west-2"}
+			db := openFailoverDB(t, primary, secondary, time.Hour)
+			ctx := context.Background()
+			if tt.idempotent {
+				ctx = WithOptions(ctx, Idempotent())
+			}
+
+			_, err := db.ExecContext(ctx, "UPDATE counters SET n = n + 1")
+			if tt.failover {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(secondary.calls) != 1 {
+					t.Errorf("unexpected secondary calls: %q", secondary.calls)
+				}
+				return
+			}
+			if !errors.Is(err, tt.err) {
+				t.Errorf("want %v, got %v", tt.err, err)
+			}
+			if len(secondary.calls) != 0 {
+				t.Errorf("the statement that may have been executed failed over: %q", secondary.calls)
+			}
+		})
+	}
+}
+
+func TestFailover_Throttled(t *testing.T) {
+	primary := &failoverMock{
+		region:      "us-east-1",
+		unavailable: true,
+		err:         &smithy.GenericAPIError{Code: "ThrottlingException", Message: "rate exceeded"},
+	}
+	secondary := &failoverMock{region: "us-west-2"}
+	db := openFailoverDB(t, primary, secondary, time.Hour)
+	ctx := context.Background()
+
+	// the throttled statement is sent to the secondary.
+	if _, err := db.ExecContext(ctx, "DELETE FROM users"); err != nil {
+		t.Fatal(err)
+	}
+
+	// but the primary is still used.
+	primary.unavailable = false
+	if _, err := db.ExecContext(ctx, "DELETE FROM posts"); err != nil {
+		t.Fatal(err)
+	}
+
+	wantPrimary := []string{"DELETE FROM users @" + primaryArn, "DELETE FROM posts @" + primaryArn}
+	if fmt.Sprint(primary.calls) != fmt.Sprint(wantPrimary) {
+		t.Errorf("unexpected primary calls:\n got: %q\nwant: %q", primary.calls, wantPrimary)
+	}
+	wantSecondary := []string{"DELETE FROM users @" + secondaryArn}
+	if fmt.Sprint(secondary.calls) != fmt.Sprint(wantSecondary) {
+		t.Errorf("unexpected secondary calls:\n got: %q\nwant: %q", secondary.calls, wantSecondary)
+	}
+}
+
+func TestFailover_EndTransaction(t *testing.T) {
+	primary := &failoverMock{region: "us-east-1"}
+	client := newFailoverClient([]Endpoint{{ResourceArn: primaryArn, Client: primary.client()}}, time.Hour)
+	ctx := context.Background()
+
+	// the transaction is forgotten even if the commit fails.
+	out, err := client.BeginTransaction(ctx, &rdsdata.BeginTransactionInput{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	primary.unavailable = true
+	primary.err = &types.BadRequestException{Message: aws.String("transaction is aborted")}
+	if _, err := client.CommitTransaction(ctx, &rdsdata.CommitTransactionInput{TransactionId: out.TransactionId}); err == nil {
+		t.Fatal("want error, got nil")
+	}
+	if len(client.txs) != 0 {
+		t.Errorf("the failed transaction is not forgotten: %v", client.txs)
+	}
+
+	// the idle transactions expire.
+	primary.unavailable = false
+	if _, err := client.BeginTransaction(ctx, &rdsdata.BeginTransactionInput{}); err != nil {
+		t.Fatal(err)
+	}
+	client.txs["us-east-1"].usedAt = time.Now().Add(-transactionIdleTimeout)
+	client.sweptAt = time.Time{}
+	client.beginTransaction(aws.String("another"), client.endpoints[0])
+	if _, ok := client.txs["us-east-1"]; ok {
+		t.Error("the idle transaction is not forgotten")
+	}
+	if _, ok := client.txs["another"]; !ok {
+		t.Error("the new transaction is forgotten")
+	}
+}
+
+func TestFailover_StatementError(t *testing.T) {
+	secondary := &failoverMock{region: "us-west-2"}
+	db := sql.OpenDB(NewConnector(&Config{
+		ResourceArn: primaryArn,
+		SecretArn:   "arn:aws:secretsmanager:us-east-1:123456789012:secret:primary",
+		Engine:      EngineMySQL,
+		Client: &awsClientMock{
+			ExecuteStatementFunc: func(ctx context.Context, input *rdsdata.ExecuteStatementInput, optFns ...func(*rdsdata.Options)) (*rdsdata.ExecuteStatementOutput, error) {
+				return nil, &types.BadRequestException{Message: aws.String("syntax error")}
+			},
+		},
+		Failover: []Endpoint{{ResourceArn: secondaryArn, Client: secondary.client()}},
+	}))
+	defer db.Close()
+
+	if _, err := db.ExecContext(context.Background(), "DELETE"); err == nil {
+		t.Fatal("expected error, but got nil")
+	}
+	if len(secondary.calls) != 0 {
+		t.Errorf("unexpected failover: %q", secondary.calls)
+	}
+}
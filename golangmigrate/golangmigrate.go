@@ -0,0 +1,427 @@
+// Package golangmigrate provides a database driver of golang-migrate for the RDS Data API.
+//
+// Importing the package registers the driver under the "rdsdata" scheme:
+//
+//	import (
+//		"github.com/golang-migrate/migrate/v4"
+//		_ "github.com/golang-migrate/migrate/v4/source/file"
+//		_ "github.com/shogo82148/go-rdsdata/golangmigrate"
+//	)
+//
+//	m, err := migrate.New("file://migrations", "rdsdata://?resource_arn=...&secret_arn=...&database=app")
+//
+// The URL is parsed by rdsdata.ParseDSN, except for the following parameters of golang-migrate:
+//
+//	x-migrations-table  the name of the version table (default: schema_migrations)
+//
+// The migration files may contain multiple statements.
+// They are split by migrate.SplitStatements of go-rdsdata and executed one by one,
+// because the Data API executes only one statement per call.
+// On PostgreSQL, each migration runs in a Data API transaction,
+// unless the file contains the line "-- rdsdata:no-transaction".
+//
+// The lock is taken in a Data API transaction, which pins the database session until it ends:
+// GET_LOCK on MySQL, and pg_try_advisory_xact_lock on PostgreSQL.
+// The transaction-level advisory lock of PostgreSQL is used instead of pg_advisory_lock,
+// so that the lock never outlives the transaction even if the Data API aborts it.
+// The locks are tried without blocking for up to 10 seconds,
+// and Lock returns database.ErrLocked if another migration holds the lock.
+package golangmigrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	nurl "net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/shogo82148/go-rdsdata"
+	rdsdatamigrate "github.com/shogo82148/go-rdsdata/migrate"
+	"go.uber.org/atomic"
+)
+
+func init() {
+	database.Register("rdsdata", &Driver{})
+}
+
+// DefaultMigrationsTable is the default name of the version table.
+const DefaultMigrationsTable = "schema_migrations"
+
+// lockTimeout is the time to wait for the lock held by another migration.
+const lockTimeout = 10 * time.Second
+
+// lockPollInterval is the interval of the attempts to take the lock.
+// The locks are taken without blocking, because the Data API limits the time of a call.
+const lockPollInterval = time.Second
+
+// keepAliveInterval is the interval of the statements that keep the lock transaction alive.
+// The Data API aborts transactions that are idle for three minutes.
+const keepAliveInterval = time.Minute
+
+// Config is the configuration of Driver.
+type Config struct {
+	// MigrationsTable is the name of the version table.
+	// The default is DefaultMigrationsTable.
+	MigrationsTable string
+
+	// DatabaseName is the name of the database, which is used to generate the lock ID.
+	// If it is empty, the current database is queried.
+	DatabaseName string
+}
+
+// compile time type check
+var _ database.Driver = (*Driver)(nil)
+
+// Driver is a database driver of golang-migrate.
+type Driver struct {
+	db       *sql.DB
+	config   *Config
+	engine   rdsdata.Engine
+	postgres bool
+	lockID   string
+
+	lockTimeout  time.Duration
+	pollInterval time.Duration
+
+	isLocked atomic.Bool
+	lockTx   *sql.Tx
+	stop     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// WithInstance returns a new driver that uses db, which must be opened by the rdsdata driver.
+// Close of the driver closes db.
+func WithInstance(db *sql.DB, config *Config) (database.Driver, error) {
+	ctx := context.Background()
+	if config == nil {
+		config = &Config{}
+	}
+	cfg := *config
+	if cfg.MigrationsTable == "" {
+		cfg.MigrationsTable = DefaultMigrationsTable
+	}
+
+	engine, err := rdsdatamigrate.DetectEngine(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	postgres := engine == rdsdata.EnginePostgres
+
+	if cfg.DatabaseName == "" {
+		query := "SELECT DATABASE()"
+		if postgres {
+			query = "SELECT current_database()"
+		}
+		var name sql.NullString
+		if err := db.QueryRowContext(ctx, query).Scan(&name); err != nil {
+			return nil, &database.Error{OrigErr: err, Query: []byte(query)}
+		}
+		cfg.DatabaseName = name.String
+	}
+	lockID, err := database.GenerateAdvisoryLockId(cfg.DatabaseName, cfg.MigrationsTable)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &Driver{
+		db:       db,
+		config:   &cfg,
+		engine:   engine,
+		postgres: postgres,
+		lockID:   lockID,
+
+		lockTimeout:  lockTimeout,
+		pollInterval: lockPollInterval,
+	}
+	if err := d.ensureVersionTable(ctx); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Open implements database.Driver.
+func (d *Driver) Open(url string) (database.Driver, error) {
+	cfg, config, err := parseURL(url)
+	if err != nil {
+		return nil, err
+	}
+	db := sql.OpenDB(rdsdata.NewConnector(cfg))
+	driver, err := WithInstance(db, config)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return driver, nil
+}
+
+// parseURL parses the URL into the configs of the rdsdata driver and Driver.
+func parseURL(url string) (*rdsdata.Config, *Config, error) {
+	u, err := nurl.Parse(url)
+	if err != nil {
+		return nil, nil, err
+	}
+	cfg, err := rdsdata.ParseDSN(migrate.FilterCustomQuery(u).String())
+	if err != nil {
+		return nil, nil, err
+	}
+	config := &Config{
+		MigrationsTable: u.Query().Get("x-migrations-table"),
+		DatabaseName:    cfg.Database,
+	}
+	return cfg, config, nil
+}
+
+// Close implements database.Driver.
+func (d *Driver) Close() error {
+	return d.db.Close()
+}
+
+// Lock implements database.Driver.
+func (d *Driver) Lock() error {
+	return database.CasRestoreOnErr(&d.isLocked, false, true, database.ErrLocked, func() error {
+		ctx := context.Background()
+		tx, err := d.db.BeginTx(ctx, nil)
+		if err != nil {
+			return &database.Error{OrigErr: err, Err: "transaction start failed"}
+		}
+
+		deadline := time.Now().Add(d.lockTimeout)
+		for {
+			ok, err := d.tryLock(ctx, tx)
+			if err != nil {
+				_ = tx.Rollback()
+				return err
+			}
+			if ok {
+				break
+			}
+			if !time.Now().Before(deadline) {
+				_ = tx.Rollback()
+				return database.ErrLocked
+			}
+			time.Sleep(d.pollInterval)
+		}
+
+		d.lockTx = tx
+		d.stop = make(chan struct{})
+		d.wg.Add(1)
+		go d.keepAlive(tx, d.stop)
+		return nil
+	})
+}
+
+// tryLock takes the advisory lock in the transaction without blocking.
+func (d *Driver) tryLock(ctx context.Context, tx *sql.Tx) (bool, error) {
+	if d.postgres {
+		// the lock is released when the transaction ends.
+		id, err := strconv.ParseInt(d.lockID, 10, 64)
+		if err != nil {
+			return false, err
+		}
+		query := "SELECT pg_try_advisory_xact_lock($1)"
+		var ok bool
+		if err := tx.QueryRowContext(ctx, query, id).Scan(&ok); err != nil {
+			return false, &database.Error{OrigErr: err, Err: "try lock failed", Query: []byte(query)}
+		}
+		return ok, nil
+	}
+
+	// GET_LOCK returns 1 if the lock is obtained, 0 if it is held by another session, and NULL on an error.
+	query := "SELECT GET_LOCK(?, 0)"
+	var ok sql.NullInt64
+	if err := tx.QueryRowContext(ctx, query, d.lockID).Scan(&ok); err != nil {
+		return false, &database.Error{OrigErr: err, Err: "try lock failed", Query: []byte(query)}
+	}
+	if !ok.Valid {
+		return false, &database.Error{Err: "try lock failed", Query: []byte(query)}
+	}
+	return ok.Int64 == 1, nil
+}
+
+// keepAlive keeps the lock transaction alive while the migrations run.
+func (d *Driver) keepAlive(tx *sql.Tx, stop <-chan struct{}) {
+	defer d.wg.Done()
+	ticker := time.NewTicker(keepAliveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			_, _ = tx.Exec("SELECT 1")
+		}
+	}
+}
+
+// Unlock implements database.Driver.
+func (d *Driver) Unlock() error {
+	return database.CasRestoreOnErr(&d.isLocked, true, false, database.ErrNotLocked, func() error {
+		close(d.stop)
+		d.wg.Wait()
+		tx := d.lockTx
+		d.lockTx = nil
+
+		var err error
+		if !d.postgres {
+			// MySQL locks are bound to the session rather than the transaction,
+			// so they must be released explicitly before the session returns to the pool.
+			query := "SELECT RELEASE_LOCK(?)"
+			if _, execErr := tx.Exec(query, d.lockID); execErr != nil {
+				err = &database.Error{OrigErr: execErr, Query: []byte(query)}
+			}
+		}
+		if rollbackErr := tx.Rollback(); err == nil && rollbackErr != nil {
+			err = &database.Error{OrigErr: rollbackErr, Err: "transaction rollback failed"}
+		}
+		return err
+	})
+}
+
+// Run implements database.Driver.
+func (d *Driver) Run(migration io.Reader) error {
+	data, err := io.ReadAll(migration)
+	if err != nil {
+		return err
+	}
+	query := string(data)
+	stmts, err := rdsdatamigrate.SplitStatements(query, d.engine)
+	if err != nil {
+		return &database.Error{OrigErr: err, Err: "migration failed", Query: data}
+	}
+
+	ctx := context.Background()
+	if !d.postgres || rdsdatamigrate.HasNoTransaction(query) {
+		// MySQL commits DDL statements implicitly, so transactions make no sense.
+		for _, stmt := range stmts {
+			if _, err := d.db.ExecContext(ctx, stmt); err != nil {
+				return &database.Error{OrigErr: err, Err: "migration failed", Query: []byte(stmt)}
+			}
+		}
+		return nil
+	}
+
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return &database.Error{OrigErr: err, Err: "transaction start failed"}
+	}
+	defer tx.Rollback()
+	for _, stmt := range stmts {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return &database.Error{OrigErr: err, Err: "migration failed", Query: []byte(stmt)}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return &database.Error{OrigErr: err, Err: "transaction commit failed"}
+	}
+	return nil
+}
+
+// SetVersion implements database.Driver.
+func (d *Driver) SetVersion(version int, dirty bool) error {
+	ctx := context.Background()
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return &database.Error{OrigErr: err, Err: "transaction start failed"}
+	}
+	defer tx.Rollback()
+
+	query := "DELETE FROM " + d.quotedTable()
+	if _, err := tx.ExecContext(ctx, query); err != nil {
+		return &database.Error{OrigErr: err, Query: []byte(query)}
+	}
+
+	// Also re-write the schema version for nil dirty versions to prevent
+	// empty schema version for failed down migration on the first migration,
+	// as the drivers of golang-migrate do.
+	if version >= 0 || (version == database.NilVersion && dirty) {
+		query := "INSERT INTO " + d.quotedTable() + " (version, dirty) VALUES (?, ?)"
+		if d.postgres {
+			query = "INSERT INTO " + d.quotedTable() + " (version, dirty) VALUES ($1, $2)"
+		}
+		if _, err := tx.ExecContext(ctx, query, version, dirty); err != nil {
+			return &database.Error{OrigErr: err, Query: []byte(query)}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return &database.Error{OrigErr: err, Err: "transaction commit failed"}
+	}
+	return nil
+}
+
+// Version implements database.Driver.
+func (d *Driver) Version() (version int, dirty bool, err error) {
+	query := "SELECT version, dirty FROM " + d.quotedTable() + " LIMIT 1"
+	err = d.db.QueryRowContext(context.Background(), query).Scan(&version, &dirty)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return database.NilVersion, false, nil
+	case err != nil:
+		return 0, false, &database.Error{OrigErr: err, Query: []byte(query)}
+	}
+	return version, dirty, nil
+}
+
+// Drop implements database.Driver.
+func (d *Driver) Drop() error {
+	ctx := context.Background()
+	query := "SHOW TABLES"
+	if d.postgres {
+		query = "SELECT table_name FROM information_schema.tables WHERE table_schema = (SELECT current_schema()) AND table_type = 'BASE TABLE'"
+	}
+	rows, err := d.db.QueryContext(ctx, query)
+	if err != nil {
+		return &database.Error{OrigErr: err, Query: []byte(query)}
+	}
+	defer rows.Close()
+
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+		tables = append(tables, d.quoteIdent(name))
+	}
+	if err := rows.Err(); err != nil {
+		return &database.Error{OrigErr: err, Query: []byte(query)}
+	}
+	if len(tables) == 0 {
+		return nil
+	}
+
+	// drop the tables by one statement, so that the foreign keys between them don't matter.
+	query = "DROP TABLE IF EXISTS " + strings.Join(tables, ", ")
+	if d.postgres {
+		query += " CASCADE"
+	}
+	if _, err := d.db.ExecContext(ctx, query); err != nil {
+		return &database.Error{OrigErr: err, Query: []byte(query)}
+	}
+	return nil
+}
+
+func (d *Driver) ensureVersionTable(ctx context.Context) error {
+	query := "CREATE TABLE IF NOT EXISTS " + d.quotedTable() + " (version BIGINT NOT NULL PRIMARY KEY, dirty BOOLEAN NOT NULL)"
+	if _, err := d.db.ExecContext(ctx, query); err != nil {
+		return &database.Error{OrigErr: err, Query: []byte(query)}
+	}
+	return nil
+}
+
+func (d *Driver) quotedTable() string {
+	return d.quoteIdent(d.config.MigrationsTable)
+}
+
+func (d *Driver) quoteIdent(name string) string {
+	if d.postgres {
+		return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+	}
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
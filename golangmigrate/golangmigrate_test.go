@@ -0,0 +1,240 @@
+package golangmigrate
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata/types"
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/shogo82148/go-rdsdata/rdsdatatest"
+)
+
+func open(t *testing.T, fake *rdsdatatest.Fake) database.Driver {
+	t.Helper()
+	if fake.Version == rdsdatatest.VersionPostgres {
+		fake.ExpectStatement(`CREATE TABLE IF NOT EXISTS "schema_migrations" (version BIGINT NOT NULL PRIMARY KEY, dirty BOOLEAN NOT NULL)`)
+	} else {
+		fake.ExpectStatement("CREATE TABLE IF NOT EXISTS `schema_migrations` (version BIGINT NOT NULL PRIMARY KEY, dirty BOOLEAN NOT NULL)")
+	}
+	d, err := WithInstance(fake.OpenDB(nil), &Config{DatabaseName: "app"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		d.Close()
+		if err := fake.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	})
+	return d
+}
+
+func TestParseURL(t *testing.T) {
+	cfg, config, err := parseURL("rdsdata://?resource_arn=arn:aws:rds:us-east-1:123456789012:cluster:test&secret_arn=arn:aws:secretsmanager:us-east-1:123456789012:secret:test&database=app&x-migrations-table=versions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.ResourceArn != "arn:aws:rds:us-east-1:123456789012:cluster:test" {
+		t.Errorf("unexpected resource arn: %q", cfg.ResourceArn)
+	}
+	if cfg.Database != "app" {
+		t.Errorf("unexpected database: %q", cfg.Database)
+	}
+	if config.MigrationsTable != "versions" {
+		t.Errorf("unexpected migrations table: %q", config.MigrationsTable)
+	}
+	if config.DatabaseName != "app" {
+		t.Errorf("unexpected database name: %q", config.DatabaseName)
+	}
+}
+
+func TestRegistered(t *testing.T) {
+	for _, name := range database.List() {
+		if name == "rdsdata" {
+			return
+		}
+	}
+	t.Error("rdsdata is not registered")
+}
+
+func TestVersion(t *testing.T) {
+	fake := rdsdatatest.New()
+	d := open(t, fake)
+	fake.ExpectStatement("SELECT version, dirty FROM `schema_migrations` LIMIT 1").
+		WillReturnRecords([]types.ColumnMetadata{
+			rdsdatatest.Column("version", "BIGINT"),
+			rdsdatatest.Column("dirty", "BIT"),
+		})
+	fake.ExpectStatement("SELECT version, dirty FROM `schema_migrations` LIMIT 1").
+		WillReturnRecords(
+			[]types.ColumnMetadata{
+				rdsdatatest.Column("version", "BIGINT"),
+				rdsdatatest.Column("dirty", "BIT"),
+			},
+			[]types.Field{rdsdatatest.Long(3), rdsdatatest.Bool(true)},
+		)
+
+	version, dirty, err := d.Version()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != database.NilVersion || dirty {
+		t.Errorf("unexpected version: %d, %t", version, dirty)
+	}
+
+	version, dirty, err = d.Version()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != 3 || !dirty {
+		t.Errorf("unexpected version: %d, %t", version, dirty)
+	}
+}
+
+func TestSetVersion(t *testing.T) {
+	fake := rdsdatatest.New()
+	fake.Version = rdsdatatest.VersionPostgres
+	d := open(t, fake)
+	fake.ExpectBegin()
+	fake.ExpectStatement(`DELETE FROM "schema_migrations"`).InTransaction()
+	fake.ExpectStatement(`INSERT INTO "schema_migrations" (version, dirty) VALUES (:1, :2)`).
+		WithParameters(
+			rdsdatatest.Param("1", rdsdatatest.Long(2)),
+			rdsdatatest.Param("2", rdsdatatest.Bool(true)),
+		).
+		InTransaction()
+	fake.ExpectCommit()
+
+	// NilVersion that is not dirty is recorded as no rows.
+	fake.ExpectBegin()
+	fake.ExpectStatement(`DELETE FROM "schema_migrations"`).InTransaction()
+	fake.ExpectCommit()
+
+	if err := d.SetVersion(2, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.SetVersion(database.NilVersion, false); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLock_MySQL(t *testing.T) {
+	fake := rdsdatatest.New()
+	d := open(t, fake)
+	lockID, err := database.GenerateAdvisoryLockId("app", "schema_migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake.ExpectBegin().WillReturnTransactionID("lock")
+	fake.ExpectStatement("SELECT GET_LOCK(:1, 0)").
+		WithParameters(rdsdatatest.Param("1", rdsdatatest.String(lockID))).
+		InTransaction().
+		WillReturnRecords([]types.ColumnMetadata{rdsdatatest.Column("GET_LOCK", "BIGINT")}, []types.Field{rdsdatatest.Long(1)})
+	fake.ExpectStatement("SELECT RELEASE_LOCK(:1)").InTransaction()
+	fake.ExpectRollback()
+
+	if err := d.Lock(); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Lock(); !errors.Is(err, database.ErrLocked) {
+		t.Errorf("want ErrLocked, got %v", err)
+	}
+	if err := d.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Unlock(); !errors.Is(err, database.ErrNotLocked) {
+		t.Errorf("want ErrNotLocked, got %v", err)
+	}
+}
+
+func TestLock_Timeout(t *testing.T) {
+	fake := rdsdatatest.New()
+	d := open(t, fake)
+	d.(*Driver).lockTimeout = 0
+	fake.ExpectBegin()
+	fake.ExpectStatement("SELECT GET_LOCK(:1, 0)").InTransaction().
+		WillReturnRecords([]types.ColumnMetadata{rdsdatatest.Column("GET_LOCK", "BIGINT")}, []types.Field{rdsdatatest.Long(0)})
+	fake.ExpectRollback()
+
+	if err := d.Lock(); !errors.Is(err, database.ErrLocked) {
+		t.Errorf("want ErrLocked, got %v", err)
+	}
+}
+
+func TestLock_Postgres(t *testing.T) {
+	fake := rdsdatatest.New()
+	fake.Version = rdsdatatest.VersionPostgres
+	d := open(t, fake)
+	d.(*Driver).pollInterval = time.Millisecond
+	fake.ExpectBegin().WillReturnTransactionID("lock")
+	// the lock is held by another migration at first.
+	fake.ExpectStatement("SELECT pg_try_advisory_xact_lock(:1)").InTransaction().
+		WillReturnRecords([]types.ColumnMetadata{rdsdatatest.Column("pg_try_advisory_xact_lock", "bool")}, []types.Field{rdsdatatest.Bool(false)})
+	fake.ExpectStatement("SELECT pg_try_advisory_xact_lock(:1)").InTransaction().
+		WillReturnRecords([]types.ColumnMetadata{rdsdatatest.Column("pg_try_advisory_xact_lock", "bool")}, []types.Field{rdsdatatest.Bool(true)})
+	fake.ExpectRollback()
+
+	if err := d.Lock(); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRun_Postgres(t *testing.T) {
+	fake := rdsdatatest.New()
+	fake.Version = rdsdatatest.VersionPostgres
+	d := open(t, fake)
+	fake.ExpectBegin()
+	fake.ExpectStatement("CREATE TABLE users (id BIGINT PRIMARY KEY)").InTransaction()
+	fake.ExpectStatement("CREATE FUNCTION one() RETURNS int AS $$ BEGIN RETURN 1; END; $$ LANGUAGE plpgsql").InTransaction()
+	fake.ExpectCommit()
+
+	// the directive disables the transaction.
+	fake.ExpectStatement("-- rdsdata:no-transaction\nCREATE INDEX CONCURRENTLY users_id ON users (id)").OutsideTransaction()
+
+	err := d.Run(strings.NewReader("CREATE TABLE users (id BIGINT PRIMARY KEY);\n" +
+		"CREATE FUNCTION one() RETURNS int AS $$ BEGIN RETURN 1; END; $$ LANGUAGE plpgsql;\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = d.Run(strings.NewReader("-- rdsdata:no-transaction\nCREATE INDEX CONCURRENTLY users_id ON users (id);\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRun_MySQL(t *testing.T) {
+	fake := rdsdatatest.New()
+	d := open(t, fake)
+	fake.ExpectStatement("CREATE TABLE users (id BIGINT PRIMARY KEY)").OutsideTransaction()
+	fake.ExpectStatement("INSERT INTO users VALUES (1)").OutsideTransaction().
+		WillReturnError(errors.New("duplicate entry"))
+
+	err := d.Run(strings.NewReader("CREATE TABLE users (id BIGINT PRIMARY KEY);\nINSERT INTO users VALUES (1);\nSELECT 1;\n"))
+	var dbErr *database.Error
+	if !errors.As(err, &dbErr) {
+		t.Fatalf("want database.Error, got %v", err)
+	}
+	if string(dbErr.Query) != "INSERT INTO users VALUES (1)" {
+		t.Errorf("unexpected query: %q", dbErr.Query)
+	}
+}
+
+func TestDrop(t *testing.T) {
+	fake := rdsdatatest.New()
+	d := open(t, fake)
+	fake.ExpectStatement("SHOW TABLES").WillReturnRecords(
+		[]types.ColumnMetadata{rdsdatatest.Column("Tables_in_app", "VARCHAR")},
+		[]types.Field{rdsdatatest.String("schema_migrations")},
+		[]types.Field{rdsdatatest.String("users")},
+	)
+	fake.ExpectStatement("DROP TABLE IF EXISTS `schema_migrations`, `users`")
+
+	if err := d.Drop(); err != nil {
+		t.Fatal(err)
+	}
+}
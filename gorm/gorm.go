@@ -0,0 +1,305 @@
+// Package gorm provides a GORM dialector for the RDS Data API.
+//
+//	db, err := gorm.Open(rdsdatagorm.Open("rdsdata://?resource_arn=...&secret_arn=...&database=app"), &gorm.Config{})
+//
+// The dialector connects through rdsdata.Connector, and delegates the clause builders,
+// the data types and the migrator to the MySQL or PostgreSQL dialector of GORM,
+// depending on the engine that the driver detected.
+//
+// The Data API executes only one statement per call,
+// so Exec without arguments splits the SQL into statements and executes them one by one.
+// The errors of the Data API are translated into the errors of GORM, such as gorm.ErrDuplicatedKey,
+// when the TranslateError option of GORM is enabled.
+package gorm
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata/types"
+	rdsdatadriver "github.com/shogo82148/go-rdsdata"
+	"github.com/shogo82148/go-rdsdata/migrate"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	gormapi "gorm.io/gorm"
+)
+
+// compile time type check
+var _ gormapi.Dialector = (*Dialector)(nil)
+var _ gormapi.SavePointerDialectorInterface = (*Dialector)(nil)
+var _ gormapi.ErrorTranslator = (*Dialector)(nil)
+
+// Config is the configuration of Dialector.
+type Config struct {
+	// DSN is the data source name parsed by rdsdata.ParseDSN.
+	// It is used if both Connector and Conn are nil.
+	DSN string
+
+	// Connector is the connector of the RDS Data API driver.
+	// It is used if Conn is nil.
+	Connector *rdsdatadriver.Connector
+
+	// Conn is the connection pool opened by the RDS Data API driver.
+	Conn *sql.DB
+
+	// MySQL configures the MySQL dialector of GORM.
+	// Its DSN and Conn are ignored.
+	MySQL mysql.Config
+
+	// Postgres configures the PostgreSQL dialector of GORM.
+	// Its DSN and Conn are ignored.
+	Postgres postgres.Config
+}
+
+// Dialector is a GORM dialector for the RDS Data API.
+// The methods that are not overridden are delegated to the dialector of the detected engine,
+// so they are available after Initialize.
+type Dialector struct {
+	*Config
+	gormapi.Dialector
+
+	postgres bool
+}
+
+// Open returns a new dialector that connects to the DSN.
+func Open(dsn string) gormapi.Dialector {
+	return &Dialector{Config: &Config{DSN: dsn}}
+}
+
+// New returns a new dialector with the config.
+func New(config Config) gormapi.Dialector {
+	return &Dialector{Config: &config}
+}
+
+// Name returns the name of the dialector of the detected engine: "mysql" or "postgres".
+// It returns "rdsdata" before Initialize.
+func (d *Dialector) Name() string {
+	if d.Dialector == nil {
+		return "rdsdata"
+	}
+	return d.Dialector.Name()
+}
+
+// Initialize connects to the database, and initializes the dialector of the detected engine.
+func (d *Dialector) Initialize(db *gormapi.DB) error {
+	ctx := context.Background()
+	sqlDB := d.Conn
+	if sqlDB == nil {
+		connector := d.Connector
+		if connector == nil {
+			cfg, err := rdsdatadriver.ParseDSN(d.DSN)
+			if err != nil {
+				return err
+			}
+			if err := cfg.Validate(); err != nil {
+				return err
+			}
+			connector = rdsdatadriver.NewConnector(cfg)
+		}
+		sqlDB = sql.OpenDB(connector)
+	}
+
+	engine, err := migrate.DetectEngine(ctx, sqlDB)
+	if err != nil {
+		return err
+	}
+	pool := &connPool{db: sqlDB, engine: engine}
+
+	switch engine {
+	case rdsdatadriver.EngineMySQL:
+		cfg := d.MySQL
+		cfg.DSN = ""
+		cfg.Conn = pool
+		d.Dialector = mysql.New(cfg)
+	case rdsdatadriver.EnginePostgres:
+		cfg := d.Postgres
+		cfg.DSN = ""
+		cfg.Conn = pool
+		d.Dialector = postgres.New(cfg)
+		d.postgres = true
+	}
+	return d.Dialector.Initialize(db)
+}
+
+// SavePoint implements gorm.SavePointerDialectorInterface.
+func (d *Dialector) SavePoint(tx *gormapi.DB, name string) error {
+	return d.Dialector.(gormapi.SavePointerDialectorInterface).SavePoint(tx, name)
+}
+
+// RollbackTo implements gorm.SavePointerDialectorInterface.
+func (d *Dialector) RollbackTo(tx *gormapi.DB, name string) error {
+	return d.Dialector.(gormapi.SavePointerDialectorInterface).RollbackTo(tx, name)
+}
+
+// sqlStateRegex extracts the SQLSTATE that the Data API appends to the messages of PostgreSQL errors.
+var sqlStateRegex = regexp.MustCompile(`;\s*SQLState:\s*([0-9A-Z]{5})\s*$`)
+
+// Translate implements gorm.ErrorTranslator.
+// The Data API returns the errors of the database as DatabaseErrorException,
+// which has only the message. The error codes are recovered from it.
+func (d *Dialector) Translate(err error) error {
+	var dbErr *types.DatabaseErrorException
+	if !errors.As(err, &dbErr) {
+		return err
+	}
+	msg := aws.ToString(dbErr.Message)
+
+	if d.postgres {
+		m := sqlStateRegex.FindStringSubmatch(msg)
+		if m == nil {
+			return err
+		}
+		switch m[1] {
+		case "23505": // unique_violation
+			return gormapi.ErrDuplicatedKey
+		case "23503": // foreign_key_violation
+			return gormapi.ErrForeignKeyViolated
+		case "23514": // check_violation
+			return gormapi.ErrCheckConstraintViolated
+		}
+		return err
+	}
+
+	switch {
+	case strings.HasPrefix(msg, "Duplicate entry "): // ER_DUP_ENTRY
+		return gormapi.ErrDuplicatedKey
+	case strings.HasPrefix(msg, "Cannot add or update a child row: a foreign key constraint fails"), // ER_NO_REFERENCED_ROW_2
+		strings.HasPrefix(msg, "Cannot delete or update a parent row: a foreign key constraint fails"): // ER_ROW_IS_REFERENCED_2
+		return gormapi.ErrForeignKeyViolated
+	case strings.HasPrefix(msg, "Check constraint ") && strings.HasSuffix(msg, " is violated."): // ER_CHECK_CONSTRAINT_VIOLATED
+		return gormapi.ErrCheckConstraintViolated
+	}
+	return err
+}
+
+// execer is *sql.DB or *sql.Tx.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// execMulti executes the statements in the query one by one,
+// because the Data API doesn't support multiple statements in a call.
+// The query is split only if it has no arguments,
+// because the placeholders can't be distributed to the statements reliably.
+func execMulti(ctx context.Context, db execer, engine rdsdatadriver.Engine, query string, args []any) (sql.Result, error) {
+	if len(args) > 0 {
+		return db.ExecContext(ctx, query, args...)
+	}
+	stmts, err := migrate.SplitStatements(query, engine)
+	if err != nil || len(stmts) <= 1 {
+		// leave the error to the database.
+		return db.ExecContext(ctx, query)
+	}
+
+	var ret multiResult
+	for _, stmt := range stmts {
+		result, err := db.ExecContext(ctx, stmt)
+		if err != nil {
+			return nil, err
+		}
+		ret.results = append(ret.results, result)
+	}
+	return &ret, nil
+}
+
+// multiResult is the result of multiple statements.
+type multiResult struct {
+	results []sql.Result
+}
+
+// LastInsertId returns the ID of the last statement.
+func (r *multiResult) LastInsertId() (int64, error) {
+	return r.results[len(r.results)-1].LastInsertId()
+}
+
+// RowsAffected returns the sum of the affected rows.
+func (r *multiResult) RowsAffected() (int64, error) {
+	var sum int64
+	for _, result := range r.results {
+		n, err := result.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		sum += n
+	}
+	return sum, nil
+}
+
+// compile time type check
+var _ gormapi.ConnPool = (*connPool)(nil)
+var _ gormapi.ConnPoolBeginner = (*connPool)(nil)
+var _ gormapi.GetDBConnector = (*connPool)(nil)
+var _ gormapi.ConnPool = (*txPool)(nil)
+var _ gormapi.TxCommitter = (*txPool)(nil)
+
+// connPool is the connection pool that GORM uses.
+type connPool struct {
+	db     *sql.DB
+	engine rdsdatadriver.Engine
+}
+
+func (p *connPool) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return p.db.PrepareContext(ctx, query)
+}
+
+func (p *connPool) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return execMulti(ctx, p.db, p.engine, query, args)
+}
+
+func (p *connPool) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return p.db.QueryContext(ctx, query, args...)
+}
+
+func (p *connPool) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return p.db.QueryRowContext(ctx, query, args...)
+}
+
+func (p *connPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (gormapi.ConnPool, error) {
+	tx, err := p.db.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &txPool{tx: tx, engine: p.engine}, nil
+}
+
+func (p *connPool) GetDBConn() (*sql.DB, error) {
+	return p.db, nil
+}
+
+func (p *connPool) Ping() error {
+	return p.db.Ping()
+}
+
+// txPool is the connection pool in a transaction.
+type txPool struct {
+	tx     *sql.Tx
+	engine rdsdatadriver.Engine
+}
+
+func (p *txPool) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return p.tx.PrepareContext(ctx, query)
+}
+
+func (p *txPool) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return execMulti(ctx, p.tx, p.engine, query, args)
+}
+
+func (p *txPool) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return p.tx.QueryContext(ctx, query, args...)
+}
+
+func (p *txPool) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return p.tx.QueryRowContext(ctx, query, args...)
+}
+
+func (p *txPool) Commit() error {
+	return p.tx.Commit()
+}
+
+func (p *txPool) Rollback() error {
+	return p.tx.Rollback()
+}
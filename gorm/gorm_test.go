@@ -0,0 +1,154 @@
+package gorm
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata/types"
+	"github.com/shogo82148/go-rdsdata/rdsdatatest"
+	gormapi "gorm.io/gorm"
+)
+
+type User struct {
+	ID   int64
+	Name string
+}
+
+func open(t *testing.T, fake *rdsdatatest.Fake) *gormapi.DB {
+	t.Helper()
+	db, err := gormapi.Open(New(Config{Conn: fake.OpenDB(nil)}), &gormapi.Config{
+		SkipDefaultTransaction: true,
+		TranslateError:         true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := fake.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	})
+	return db
+}
+
+func TestName(t *testing.T) {
+	if got := Open("").Name(); got != "rdsdata" {
+		t.Errorf("unexpected name: %q", got)
+	}
+
+	fake := rdsdatatest.New()
+	db := open(t, fake)
+	if got := db.Dialector.Name(); got != "mysql" {
+		t.Errorf("unexpected name: %q", got)
+	}
+
+	fake = rdsdatatest.New()
+	fake.Version = rdsdatatest.VersionPostgres
+	db = open(t, fake)
+	if got := db.Dialector.Name(); got != "postgres" {
+		t.Errorf("unexpected name: %q", got)
+	}
+}
+
+func TestOpen_InvalidDSN(t *testing.T) {
+	// the resource ARN is missing.
+	dsn := "rdsdata://?secret_arn=arn:aws:secretsmanager:us-east-1:123456789012:secret:secret"
+	_, err := gormapi.Open(Open(dsn), &gormapi.Config{})
+	if err == nil || !strings.Contains(err.Error(), "resource_arn is required") {
+		t.Errorf("want the validation error, got %v", err)
+	}
+}
+
+func TestCreate_MySQL(t *testing.T) {
+	fake := rdsdatatest.New()
+	db := open(t, fake)
+	fake.ExpectStatement("INSERT INTO `users` (`name`) VALUES (:1)").
+		WithParameters(rdsdatatest.Param("1", rdsdatatest.String("alice"))).
+		WillReturnResult(1, rdsdatatest.Long(42))
+
+	user := User{Name: "alice"}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	if user.ID != 42 {
+		t.Errorf("unexpected id: %d", user.ID)
+	}
+}
+
+func TestCreate_Postgres(t *testing.T) {
+	fake := rdsdatatest.New()
+	fake.Version = rdsdatatest.VersionPostgres
+	db := open(t, fake)
+	fake.ExpectStatement(`INSERT INTO "users" ("name") VALUES (:1) RETURNING "id"`).
+		WithParameters(rdsdatatest.Param("1", rdsdatatest.String("alice"))).
+		WillReturnRecords([]types.ColumnMetadata{rdsdatatest.Column("id", "int8")}, []types.Field{rdsdatatest.Long(42)})
+
+	user := User{Name: "alice"}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	if user.ID != 42 {
+		t.Errorf("unexpected id: %d", user.ID)
+	}
+}
+
+func TestExec_MultiStatements(t *testing.T) {
+	fake := rdsdatatest.New()
+	db := open(t, fake)
+	fake.ExpectStatement("DELETE FROM users WHERE name = 'a;b'").WillReturnResult(2)
+	fake.ExpectStatement("DELETE FROM posts").WillReturnResult(3)
+
+	result := db.Exec("DELETE FROM users WHERE name = 'a;b'; DELETE FROM posts;")
+	if result.Error != nil {
+		t.Fatal(result.Error)
+	}
+	if result.RowsAffected != 5 {
+		t.Errorf("unexpected rows affected: %d", result.RowsAffected)
+	}
+}
+
+func TestTransaction(t *testing.T) {
+	fake := rdsdatatest.New()
+	db := open(t, fake)
+	fake.ExpectBegin()
+	fake.ExpectStatement("UPDATE users SET name = 'a'").InTransaction().WillReturnResult(1)
+	fake.ExpectStatement("UPDATE posts SET title = 'b'").InTransaction().WillReturnResult(1)
+	fake.ExpectCommit()
+
+	err := db.Transaction(func(tx *gormapi.DB) error {
+		return tx.Exec("UPDATE users SET name = 'a'; UPDATE posts SET title = 'b'").Error
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	tests := []struct {
+		postgres bool
+		msg      string
+		want     error
+	}{
+		{false, "Duplicate entry 'alice' for key 'users.name'", gormapi.ErrDuplicatedKey},
+		{false, "Cannot add or update a child row: a foreign key constraint fails (`app`.`posts`)", gormapi.ErrForeignKeyViolated},
+		{false, "Check constraint 'users_chk_1' is violated.", gormapi.ErrCheckConstraintViolated},
+		{true, `ERROR: duplicate key value violates unique constraint "users_name_key"; SQLState: 23505`, gormapi.ErrDuplicatedKey},
+		{true, `ERROR: insert or update on table "posts" violates foreign key constraint "posts_user_id_fkey"; SQLState: 23503`, gormapi.ErrForeignKeyViolated},
+		{true, `ERROR: new row for relation "users" violates check constraint "users_age_check"; SQLState: 23514`, gormapi.ErrCheckConstraintViolated},
+	}
+	for _, tt := range tests {
+		d := &Dialector{Config: &Config{}, postgres: tt.postgres}
+		err := &types.DatabaseErrorException{Message: aws.String(tt.msg)}
+		if got := d.Translate(err); !errors.Is(got, tt.want) {
+			t.Errorf("Translate(%q) = %v, want %v", tt.msg, got, tt.want)
+		}
+	}
+
+	d := &Dialector{Config: &Config{}}
+	err := errors.New("other error")
+	if got := d.Translate(err); got != err {
+		t.Errorf("unexpected error: %v", got)
+	}
+}
@@ -0,0 +1,166 @@
+package rdsdata
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/shogo82148/go-rdsdata/internal/sqllex"
+)
+
+// StatementNotAllowedError is returned when a statement is rejected by Config.ReadOnly or Config.StatementAllowlist.
+// The statements are checked before any call to the Data API.
+type StatementNotAllowedError struct {
+	// Query is the rejected statement.
+	Query string
+
+	// Fingerprint is the fingerprint of the statement.
+	Fingerprint string
+
+	// ReadOnly reports whether the statement is rejected by the read-only mode.
+	// Otherwise, it is not in the allowlist.
+	ReadOnly bool
+
+	// Keyword is the keyword that makes the statement a write in the read-only mode, e.g. "UPDATE".
+	Keyword string
+}
+
+func (e *StatementNotAllowedError) Error() string {
+	if e.ReadOnly {
+		return fmt.Sprintf("rdsdata: %s is not allowed in read-only mode: %s", e.Keyword, e.Fingerprint)
+	}
+	return fmt.Sprintf("rdsdata: the statement is not in the allowlist: %s", e.Fingerprint)
+}
+
+// readOnlyStatements are the first keywords of the statements that can be read-only.
+var readOnlyStatements = []string{"SELECT", "SHOW", "DESCRIBE", "DESC", "EXPLAIN", "WITH", "VALUES", "TABLE"}
+
+// writeStatements are the keywords that start a data-modifying statement.
+// They are rejected in the statement positions of a read-only statement,
+// e.g. the bodies of the CTEs, the main statement after the CTEs and the statement of EXPLAIN.
+var writeStatements = []string{"INSERT", "UPDATE", "DELETE", "MERGE", "REPLACE"}
+
+// checkStatement checks the statement against the read-only mode and the allowlist.
+func (c *Conn) checkStatement(query string) error {
+	cfg := c.connector.cfg
+	if !cfg.ReadOnly && cfg.StatementAllowlist == nil {
+		return nil
+	}
+
+	tokens := sqllex.Code(c.engine.syntax(), query)
+	if cfg.ReadOnly {
+		if keyword, ok := classifyReadOnly(tokens); !ok {
+			return &StatementNotAllowedError{
+				Query:       query,
+				Fingerprint: fingerprint(tokens),
+				ReadOnly:    true,
+				Keyword:     keyword,
+			}
+		}
+	}
+	if cfg.StatementAllowlist != nil {
+		fp := fingerprint(tokens)
+		if !slices.Contains(cfg.StatementAllowlist, fp) {
+			return &StatementNotAllowedError{
+				Query:       query,
+				Fingerprint: fp,
+			}
+		}
+	}
+	return nil
+}
+
+// classifyReadOnly reports whether the statement is read-only.
+// If not, it returns the keyword that makes the statement a write.
+// It is conservative: EXPLAIN of a write, SELECT INTO and the locking reads are rejected too.
+// The keywords are matched only where they can start a statement or a clause,
+// so the columns and the functions such as comment and replace(name, 'a', 'b') are allowed.
+// The functions that write, such as nextval, are not detected.
+// The statements after the first one are rejected, whatever they are.
+func classifyReadOnly(tokens []sqllex.Token) (string, bool) {
+	start := 0
+	for start < len(tokens) && tokens[start].Text == "(" {
+		start++
+	}
+	if start == len(tokens) {
+		return "", true
+	}
+	first := strings.ToUpper(tokens[start].Text)
+	if tokens[start].Kind != sqllex.Word || !slices.Contains(readOnlyStatements, first) {
+		return first, false
+	}
+	explain := first == "EXPLAIN" || first == "DESCRIBE" || first == "DESC"
+
+	// word returns the upper-cased keyword at i, or "" if it is not a keyword.
+	word := func(i int) string {
+		if i >= len(tokens) || tokens[i].Kind != sqllex.Word {
+			return ""
+		}
+		return strings.ToUpper(tokens[i].Text)
+	}
+	for i := start + 1; i < len(tokens); i++ {
+		if prev := tokens[i-1]; prev.Kind == sqllex.Operator && prev.Text == ";" && tokens[i].Text != ";" {
+			// another statement follows, e.g. SELECT 1; DROP TABLE users.
+			return strings.ToUpper(tokens[i].Text), false
+		}
+		w := word(i)
+		switch {
+		case w == "":
+		case slices.Contains(writeStatements, w):
+			if i+1 < len(tokens) && tokens[i+1].Text == "(" {
+				// a function call, e.g. replace(name, 'a', 'b').
+				continue
+			}
+			if prev := tokens[i-1].Text; explain || prev == "(" || prev == ")" {
+				return w, false
+			}
+		case w == "INTO":
+			// SELECT ... INTO creates a table or writes a file.
+			return w, false
+		case w == "FOR":
+			// FOR UPDATE, FOR NO KEY UPDATE, FOR SHARE and FOR KEY SHARE.
+			j := i + 1
+			for word(j) == "NO" || word(j) == "KEY" {
+				j++
+			}
+			if lock := word(j); lock == "UPDATE" || lock == "SHARE" {
+				return lock, false
+			}
+		case w == "LOCK":
+			// LOCK IN SHARE MODE of MySQL.
+			if word(i+1) == "IN" {
+				return w, false
+			}
+		}
+	}
+	return "", true
+}
+
+// Fingerprint returns the fingerprint of the statement for Config.StatementAllowlist.
+// The comments are removed, the literals and the placeholders are replaced with "?",
+// the keywords and the identifiers that are not quoted are lower-cased, and the tokens are separated by single spaces.
+// For example, "SELECT * FROM users WHERE id = 42" and "select *  from users where id = ?"
+// have the same fingerprint "select * from users where id = ?".
+func Fingerprint(engine Engine, query string) string {
+	return fingerprint(sqllex.Code(engine.syntax(), query))
+}
+
+func fingerprint(tokens []sqllex.Token) string {
+	parts := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		switch token.Kind {
+		case sqllex.Word:
+			parts = append(parts, strings.ToLower(token.Text))
+		case sqllex.String, sqllex.Number, sqllex.Placeholder:
+			parts = append(parts, "?")
+		default:
+			parts = append(parts, token.Text)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// syntax returns the lexical syntax of the engine.
+func (e Engine) syntax() sqllex.Syntax {
+	return sqllex.ForEngine(string(e))
+}
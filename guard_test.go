@@ -0,0 +1,195 @@
+package rdsdata
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata"
+	"github.com/shogo82148/go-rdsdata/internal/sqllex"
+)
+
+func TestClassifyReadOnly(t *testing.T) {
+	tests := []struct {
+		engine  Engine
+		query   string
+		keyword string
+		want    bool
+	}{
+		{EngineMySQL, "SELECT * FROM users", "", true},
+		{EngineMySQL, "  select 1", "", true},
+		{EngineMySQL, "/* list */ SELECT * FROM users", "", true},
+		{EngineMySQL, "# list\nSELECT * FROM users", "", true},
+		{EngineMySQL, "SHOW TABLES", "", true},
+		{EngineMySQL, "SELECT * FROM users WHERE name = 'DELETE'", "", true},
+		{EngineMySQL, `SELECT * FROM users WHERE name = "it\"s DROP"`, "", true},
+		{EngineMySQL, "SELECT `update` FROM users", "", true},
+		{EngineMySQL, "UPDATE users SET name = 'a'", "UPDATE", false},
+		{EngineMySQL, "/* SELECT */ DELETE FROM users", "DELETE", false},
+		{EngineMySQL, "SELECT * FROM users FOR UPDATE", "UPDATE", false},
+		{EngineMySQL, "SELECT * FROM users LOCK IN SHARE MODE", "LOCK", false},
+		{EngineMySQL, "SELECT 1 INTO @x", "INTO", false},
+		{EngineMySQL, "CREATE TABLE t (id INT)", "CREATE", false},
+		{EngineMySQL, "SET GLOBAL read_only = 0", "SET", false},
+		{EnginePostgres, "WITH t AS (DELETE FROM users RETURNING *) SELECT * FROM t", "DELETE", false},
+		{EnginePostgres, "WITH t AS (SELECT 1) SELECT * FROM t", "", true},
+		{EnginePostgres, "SELECT $$DROP TABLE users$$", "", true},
+		{EnginePostgres, `SELECT "delete" FROM users`, "", true},
+		{EnginePostgres, "SELECT E'it\\'s DELETE'", "", true},
+		{EnginePostgres, "/* outer /* inner */ DELETE */ SELECT 1", "", true},
+		{EnginePostgres, "EXPLAIN ANALYZE DELETE FROM users", "DELETE", false},
+		{EnginePostgres, "TABLE users", "", true},
+		{EnginePostgres, "", "", true},
+
+		// the columns and the functions that have the names of keywords.
+		{EngineMySQL, "SELECT comment FROM posts", "", true},
+		{EngineMySQL, "SELECT replace(name, 'a', 'b') FROM users", "", true},
+		{EngineMySQL, "SELECT `lock`, share, do, call FROM t", "", true},
+		{EnginePostgres, "SELECT comment, share, lock FROM posts WHERE do = 1", "", true},
+		{EnginePostgres, "SELECT substring(name FROM 1 FOR 3) FROM users", "", true},
+		{EngineMySQL, "(SELECT 1) UNION (SELECT 2)", "", true},
+
+		// the writes in the statement positions.
+		{EngineMySQL, "SELECT * FROM users\nFOR\tUPDATE", "UPDATE", false},
+		{EnginePostgres, "SELECT * FROM users FOR NO KEY UPDATE", "UPDATE", false},
+		{EnginePostgres, "SELECT * FROM users FOR SHARE", "SHARE", false},
+		{EnginePostgres, "WITH t AS MATERIALIZED (UPDATE users SET a = 1 RETURNING *) SELECT * FROM t", "UPDATE", false},
+		{EnginePostgres, "WITH t AS (SELECT 1) INSERT INTO users SELECT * FROM t", "INSERT", false},
+		{EngineMySQL, "EXPLAIN FORMAT=JSON REPLACE INTO users VALUES (1)", "REPLACE", false},
+		{EngineMySQL, "SELECT 1 /*!50000 FOR UPDATE */", "UPDATE", false},
+		{EnginePostgres, "SELECT * INTO\nbackup FROM users", "INTO", false},
+
+		// multiple statements.
+		{EngineMySQL, "SELECT 1;", "", true},
+		{EngineMySQL, "SELECT 1; DROP TABLE users", "DROP", false},
+		{EngineMySQL, "SELECT 1; DELETE FROM users", "DELETE", false},
+		{EngineMySQL, "SELECT 1;\nUPDATE t SET a=1", "UPDATE", false},
+		{EnginePostgres, "SELECT 1;; SELECT 2", "SELECT", false},
+		{EnginePostgres, "SELECT ';'; -- DROP TABLE users", "", true},
+	}
+	for _, tt := range tests {
+		keyword, got := classifyReadOnly(sqllex.Code(tt.engine.syntax(), tt.query))
+		if got != tt.want || keyword != tt.keyword {
+			t.Errorf("classifyReadOnly(%s, %q) = %q, %v, want %q, %v", tt.engine, tt.query, keyword, got, tt.keyword, tt.want)
+		}
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	tests := []struct {
+		engine Engine
+		query  string
+		want   string
+	}{
+		{EngineMySQL, "SELECT * FROM users WHERE id = 42", "select * from users where id = ?"},
+		{EngineMySQL, "select *\n  from users -- comment\n where id = ?", "select * from users where id = ?"},
+		{EngineMySQL, "SELECT * FROM `Users` WHERE name = 'alice' AND score > 1.5e-3", "select * from `Users` where name = ? and score > ?"},
+		{EnginePostgres, "SELECT * FROM users WHERE id = $1", "select * from users where id = ?"},
+		{EnginePostgres, "SELECT id::text FROM users WHERE name = :name", "select id :: text from users where name = ?"},
+		{EnginePostgres, `SELECT * FROM "Users" WHERE body = $tag$it's$tag$`, `select * from "Users" where body = ?`},
+	}
+	for _, tt := range tests {
+		if got := Fingerprint(tt.engine, tt.query); got != tt.want {
+			t.Errorf("Fingerprint(%s, %q) = %q, want %q", tt.engine, tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	var calls []string
+	client := &awsClientMock{
+		ExecuteStatementFunc: func(ctx context.Context, input *rdsdata.ExecuteStatementInput, optFns ...func(*rdsdata.Options)) (*rdsdata.ExecuteStatementOutput, error) {
+			call := aws.ToString(input.Sql)
+			if input.TransactionId != nil {
+				call += " @" + aws.ToString(input.TransactionId)
+			}
+			calls = append(calls, call)
+			return &rdsdata.ExecuteStatementOutput{}, nil
+		},
+		BeginTransactionFunc: func(ctx context.Context, input *rdsdata.BeginTransactionInput, optFns ...func(*rdsdata.Options)) (*rdsdata.BeginTransactionOutput, error) {
+			calls = append(calls, "BEGIN")
+			return &rdsdata.BeginTransactionOutput{TransactionId: aws.String("tx")}, nil
+		},
+		CommitTransactionFunc: func(ctx context.Context, input *rdsdata.CommitTransactionInput, optFns ...func(*rdsdata.Options)) (*rdsdata.CommitTransactionOutput, error) {
+			calls = append(calls, "COMMIT")
+			return &rdsdata.CommitTransactionOutput{}, nil
+		},
+	}
+	db := sql.OpenDB(NewConnector(&Config{
+		ResourceArn: "arn:aws:rds:us-east-1:123456789012:cluster:cluster",
+		SecretArn:   "arn:aws:secretsmanager:us-east-1:123456789012:secret:secretArn",
+		Engine:      EngineMySQL,
+		ReadOnly:    true,
+		Client:      client,
+	}))
+	defer db.Close()
+	ctx := context.Background()
+
+	_, err := db.ExecContext(ctx, "DELETE FROM users WHERE id = ?", 1)
+	var notAllowed *StatementNotAllowedError
+	if !errors.As(err, &notAllowed) {
+		t.Fatalf("want StatementNotAllowedError, got %v", err)
+	}
+	if !notAllowed.ReadOnly || notAllowed.Keyword != "DELETE" || notAllowed.Fingerprint != "delete from users where id = ?" {
+		t.Errorf("unexpected error: %#v", notAllowed)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := tx.QueryContext(ctx, "SELECT * FROM users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"BEGIN", "START TRANSACTION READ ONLY @tx", "SELECT * FROM users @tx", "COMMIT"}
+	if fmt.Sprint(calls) != fmt.Sprint(want) {
+		t.Errorf("unexpected calls:\n got: %q\nwant: %q", calls, want)
+	}
+}
+
+func TestStatementAllowlist(t *testing.T) {
+	var calls []string
+	db := sql.OpenDB(NewConnector(&Config{
+		ResourceArn:        "arn:aws:rds:us-east-1:123456789012:cluster:cluster",
+		SecretArn:          "arn:aws:secretsmanager:us-east-1:123456789012:secret:secretArn",
+		Engine:             EnginePostgres,
+		StatementAllowlist: []string{"select * from users where id = ?"},
+		Client: &awsClientMock{
+			ExecuteStatementFunc: func(ctx context.Context, input *rdsdata.ExecuteStatementInput, optFns ...func(*rdsdata.Options)) (*rdsdata.ExecuteStatementOutput, error) {
+				calls = append(calls, aws.ToString(input.Sql))
+				return &rdsdata.ExecuteStatementOutput{}, nil
+			},
+		},
+	}))
+	defer db.Close()
+	ctx := context.Background()
+
+	rows, err := db.QueryContext(ctx, "SELECT * FROM users WHERE id = $1", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+
+	_, err = db.QueryContext(ctx, "SELECT * FROM users WHERE id = 1 OR 1 = 1")
+	var notAllowed *StatementNotAllowedError
+	if !errors.As(err, &notAllowed) {
+		t.Fatalf("want StatementNotAllowedError, got %v", err)
+	}
+	if notAllowed.ReadOnly || notAllowed.Fingerprint != "select * from users where id = ? or ? = ?" {
+		t.Errorf("unexpected error: %#v", notAllowed)
+	}
+
+	want := []string{"SELECT * FROM users WHERE id = :1"}
+	if fmt.Sprint(calls) != fmt.Sprint(want) {
+		t.Errorf("unexpected calls:\n got: %q\nwant: %q", calls, want)
+	}
+}
@@ -0,0 +1,310 @@
+package rdsdata
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata"
+)
+
+// Hooks is the interface for observing the Data API calls issued by the driver.
+// It can be used to build logging, metrics and tracing adapters.
+//
+// The context returned by a Before* method is passed to the matching After* method,
+// so that the implementation can carry state (e.g. a span) between them.
+type Hooks interface {
+	// BeforeQuery is called before executing a statement that returns rows.
+	BeforeQuery(ctx context.Context, in *rdsdata.ExecuteStatementInput) context.Context
+
+	// AfterQuery is called after executing a statement that returns rows.
+	AfterQuery(ctx context.Context, in *rdsdata.ExecuteStatementInput, info *HookInfo)
+
+	// BeforeExec is called before executing a statement that doesn't return rows.
+	BeforeExec(ctx context.Context, in *rdsdata.ExecuteStatementInput) context.Context
+
+	// AfterExec is called after executing a statement that doesn't return rows.
+	AfterExec(ctx context.Context, in *rdsdata.ExecuteStatementInput, info *HookInfo)
+
+	// BeforeBegin is called before beginning a transaction.
+	BeforeBegin(ctx context.Context, in *rdsdata.BeginTransactionInput) context.Context
+
+	// AfterBegin is called after beginning a transaction.
+	AfterBegin(ctx context.Context, in *rdsdata.BeginTransactionInput, info *HookInfo)
+
+	// BeforeCommit is called before committing a transaction.
+	BeforeCommit(ctx context.Context, in *rdsdata.CommitTransactionInput) context.Context
+
+	// AfterCommit is called after committing a transaction.
+	AfterCommit(ctx context.Context, in *rdsdata.CommitTransactionInput, info *HookInfo)
+
+	// BeforeRollback is called before rolling back a transaction.
+	BeforeRollback(ctx context.Context, in *rdsdata.RollbackTransactionInput) context.Context
+
+	// AfterRollback is called after rolling back a transaction.
+	AfterRollback(ctx context.Context, in *rdsdata.RollbackTransactionInput, info *HookInfo)
+}
+
+// HookInfo is the information about a finished Data API call.
+type HookInfo struct {
+	// TransactionID is the ID of the transaction that the call belongs to.
+	// It is empty if the call is not in a transaction.
+	TransactionID string
+
+	// Duration is the time taken by the call.
+	Duration time.Duration
+
+	// Attempt is the number of the attempt, starting from 1.
+	// It is greater than 1 when the driver retries the call, e.g. while the cluster is resuming.
+	Attempt int
+
+	// Records is the number of records returned by the call.
+	Records int
+
+	// RowsAffected is the number of records updated by the call.
+	RowsAffected int64
+
+	// Output is the raw response of ExecuteStatement.
+	// It is nil for the transaction calls and for failed calls.
+	Output *rdsdata.ExecuteStatementOutput
+
+	// Err is the error returned by the call.
+	Err error
+}
+
+// NopHooks is a Hooks that does nothing.
+// It is useful for embedding in a Hooks implementation that handles only some of the calls.
+type NopHooks struct{}
+
+var _ Hooks = NopHooks{}
+
+func (NopHooks) BeforeQuery(ctx context.Context, in *rdsdata.ExecuteStatementInput) context.Context {
+	return ctx
+}
+
+func (NopHooks) AfterQuery(ctx context.Context, in *rdsdata.ExecuteStatementInput, info *HookInfo) {}
+
+func (NopHooks) BeforeExec(ctx context.Context, in *rdsdata.ExecuteStatementInput) context.Context {
+	return ctx
+}
+
+func (NopHooks) AfterExec(ctx context.Context, in *rdsdata.ExecuteStatementInput, info *HookInfo) {}
+
+func (NopHooks) BeforeBegin(ctx context.Context, in *rdsdata.BeginTransactionInput) context.Context {
+	return ctx
+}
+
+func (NopHooks) AfterBegin(ctx context.Context, in *rdsdata.BeginTransactionInput, info *HookInfo) {}
+
+func (NopHooks) BeforeCommit(ctx context.Context, in *rdsdata.CommitTransactionInput) context.Context {
+	return ctx
+}
+
+func (NopHooks) AfterCommit(ctx context.Context, in *rdsdata.CommitTransactionInput, info *HookInfo) {
+}
+
+func (NopHooks) BeforeRollback(ctx context.Context, in *rdsdata.RollbackTransactionInput) context.Context {
+	return ctx
+}
+
+func (NopHooks) AfterRollback(ctx context.Context, in *rdsdata.RollbackTransactionInput, info *HookInfo) {
+}
+
+// MultiHooks returns a Hooks that calls all the given hooks in order.
+// The After* methods are called in reverse order.
+func MultiHooks(hooks ...Hooks) Hooks {
+	list := make(multiHooks, 0, len(hooks))
+	for _, h := range hooks {
+		if h == nil {
+			continue
+		}
+		if m, ok := h.(multiHooks); ok {
+			list = append(list, m...)
+			continue
+		}
+		list = append(list, h)
+	}
+	return list
+}
+
+type multiHooks []Hooks
+
+func (m multiHooks) BeforeQuery(ctx context.Context, in *rdsdata.ExecuteStatementInput) context.Context {
+	for _, h := range m {
+		ctx = h.BeforeQuery(ctx, in)
+	}
+	return ctx
+}
+
+func (m multiHooks) AfterQuery(ctx context.Context, in *rdsdata.ExecuteStatementInput, info *HookInfo) {
+	for i := len(m) - 1; i >= 0; i-- {
+		m[i].AfterQuery(ctx, in, info)
+	}
+}
+
+func (m multiHooks) BeforeExec(ctx context.Context, in *rdsdata.ExecuteStatementInput) context.Context {
+	for _, h := range m {
+		ctx = h.BeforeExec(ctx, in)
+	}
+	return ctx
+}
+
+func (m multiHooks) AfterExec(ctx context.Context, in *rdsdata.ExecuteStatementInput, info *HookInfo) {
+	for i := len(m) - 1; i >= 0; i-- {
+		m[i].AfterExec(ctx, in, info)
+	}
+}
+
+func (m multiHooks) BeforeBegin(ctx context.Context, in *rdsdata.BeginTransactionInput) context.Context {
+	for _, h := range m {
+		ctx = h.BeforeBegin(ctx, in)
+	}
+	return ctx
+}
+
+func (m multiHooks) AfterBegin(ctx context.Context, in *rdsdata.BeginTransactionInput, info *HookInfo) {
+	for i := len(m) - 1; i >= 0; i-- {
+		m[i].AfterBegin(ctx, in, info)
+	}
+}
+
+func (m multiHooks) BeforeCommit(ctx context.Context, in *rdsdata.CommitTransactionInput) context.Context {
+	for _, h := range m {
+		ctx = h.BeforeCommit(ctx, in)
+	}
+	return ctx
+}
+
+func (m multiHooks) AfterCommit(ctx context.Context, in *rdsdata.CommitTransactionInput, info *HookInfo) {
+	for i := len(m) - 1; i >= 0; i-- {
+		m[i].AfterCommit(ctx, in, info)
+	}
+}
+
+func (m multiHooks) BeforeRollback(ctx context.Context, in *rdsdata.RollbackTransactionInput) context.Context {
+	for _, h := range m {
+		ctx = h.BeforeRollback(ctx, in)
+	}
+	return ctx
+}
+
+func (m multiHooks) AfterRollback(ctx context.Context, in *rdsdata.RollbackTransactionInput, info *HookInfo) {
+	for i := len(m) - 1; i >= 0; i-- {
+		m[i].AfterRollback(ctx, in, info)
+	}
+}
+
+type attemptKey struct{}
+
+// withAttempt returns a new context that carries the attempt number of a retried call.
+func withAttempt(ctx context.Context, attempt int) context.Context {
+	return context.WithValue(ctx, attemptKey{}, attempt)
+}
+
+func attemptFromContext(ctx context.Context) int {
+	if attempt, ok := ctx.Value(attemptKey{}).(int); ok {
+		return attempt
+	}
+	return 1
+}
+
+// callKind is the kind of an ExecuteStatement call.
+type callKind int
+
+const (
+	callQuery callKind = iota
+	callExec
+)
+
+// executeStatement calls the ExecuteStatement API with the hooks.
+func (c *Conn) executeStatement(ctx context.Context, kind callKind, in *rdsdata.ExecuteStatementInput, optFns ...func(*rdsdata.Options)) (*rdsdata.ExecuteStatementOutput, error) {
+	hooks := c.connector.hooks
+	if hooks == nil {
+		return c.client.ExecuteStatement(ctx, in, optFns...)
+	}
+
+	if kind == callQuery {
+		ctx = hooks.BeforeQuery(ctx, in)
+	} else {
+		ctx = hooks.BeforeExec(ctx, in)
+	}
+	start := time.Now()
+	out, err := c.client.ExecuteStatement(ctx, in, optFns...)
+	info := &HookInfo{
+		TransactionID: aws.ToString(in.TransactionId),
+		Duration:      time.Since(start),
+		Attempt:       attemptFromContext(ctx),
+		Err:           err,
+	}
+	if out != nil {
+		info.Records = len(out.Records)
+		info.RowsAffected = out.NumberOfRecordsUpdated
+		info.Output = out
+	}
+	if kind == callQuery {
+		hooks.AfterQuery(ctx, in, info)
+	} else {
+		hooks.AfterExec(ctx, in, info)
+	}
+	return out, err
+}
+
+// beginTransaction calls the BeginTransaction API with the hooks.
+func (c *Conn) beginTransaction(ctx context.Context, in *rdsdata.BeginTransactionInput) (*rdsdata.BeginTransactionOutput, error) {
+	hooks := c.connector.hooks
+	if hooks == nil {
+		return c.client.BeginTransaction(ctx, in)
+	}
+
+	ctx = hooks.BeforeBegin(ctx, in)
+	start := time.Now()
+	out, err := c.client.BeginTransaction(ctx, in)
+	info := &HookInfo{
+		Duration: time.Since(start),
+		Attempt:  attemptFromContext(ctx),
+		Err:      err,
+	}
+	if out != nil {
+		info.TransactionID = aws.ToString(out.TransactionId)
+	}
+	hooks.AfterBegin(ctx, in, info)
+	return out, err
+}
+
+// commitTransaction calls the CommitTransaction API with the hooks.
+func (c *Conn) commitTransaction(ctx context.Context, in *rdsdata.CommitTransactionInput) (*rdsdata.CommitTransactionOutput, error) {
+	hooks := c.connector.hooks
+	if hooks == nil {
+		return c.client.CommitTransaction(ctx, in)
+	}
+
+	ctx = hooks.BeforeCommit(ctx, in)
+	start := time.Now()
+	out, err := c.client.CommitTransaction(ctx, in)
+	hooks.AfterCommit(ctx, in, &HookInfo{
+		TransactionID: aws.ToString(in.TransactionId),
+		Duration:      time.Since(start),
+		Attempt:       attemptFromContext(ctx),
+		Err:           err,
+	})
+	return out, err
+}
+
+// rollbackTransaction calls the RollbackTransaction API with the hooks.
+func (c *Conn) rollbackTransaction(ctx context.Context, in *rdsdata.RollbackTransactionInput) (*rdsdata.RollbackTransactionOutput, error) {
+	hooks := c.connector.hooks
+	if hooks == nil {
+		return c.client.RollbackTransaction(ctx, in)
+	}
+
+	ctx = hooks.BeforeRollback(ctx, in)
+	start := time.Now()
+	out, err := c.client.RollbackTransaction(ctx, in)
+	hooks.AfterRollback(ctx, in, &HookInfo{
+		TransactionID: aws.ToString(in.TransactionId),
+		Duration:      time.Since(start),
+		Attempt:       attemptFromContext(ctx),
+		Err:           err,
+	})
+	return out, err
+}
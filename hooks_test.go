@@ -0,0 +1,172 @@
+package rdsdata
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata"
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata/types"
+)
+
+type recordHooks struct {
+	NopHooks
+	calls []string
+	infos []*HookInfo
+}
+
+func (h *recordHooks) BeforeQuery(ctx context.Context, in *rdsdata.ExecuteStatementInput) context.Context {
+	h.calls = append(h.calls, "BeforeQuery: "+aws.ToString(in.Sql))
+	return ctx
+}
+
+func (h *recordHooks) AfterQuery(ctx context.Context, in *rdsdata.ExecuteStatementInput, info *HookInfo) {
+	h.calls = append(h.calls, "AfterQuery: "+aws.ToString(in.Sql))
+	h.infos = append(h.infos, info)
+}
+
+func (h *recordHooks) BeforeExec(ctx context.Context, in *rdsdata.ExecuteStatementInput) context.Context {
+	h.calls = append(h.calls, "BeforeExec: "+aws.ToString(in.Sql))
+	return ctx
+}
+
+func (h *recordHooks) AfterExec(ctx context.Context, in *rdsdata.ExecuteStatementInput, info *HookInfo) {
+	h.calls = append(h.calls, "AfterExec: "+aws.ToString(in.Sql))
+	h.infos = append(h.infos, info)
+}
+
+func (h *recordHooks) BeforeBegin(ctx context.Context, in *rdsdata.BeginTransactionInput) context.Context {
+	h.calls = append(h.calls, "BeforeBegin")
+	return ctx
+}
+
+func (h *recordHooks) AfterBegin(ctx context.Context, in *rdsdata.BeginTransactionInput, info *HookInfo) {
+	h.calls = append(h.calls, "AfterBegin")
+	h.infos = append(h.infos, info)
+}
+
+func (h *recordHooks) BeforeCommit(ctx context.Context, in *rdsdata.CommitTransactionInput) context.Context {
+	h.calls = append(h.calls, "BeforeCommit")
+	return ctx
+}
+
+func (h *recordHooks) AfterCommit(ctx context.Context, in *rdsdata.CommitTransactionInput, info *HookInfo) {
+	h.calls = append(h.calls, "AfterCommit")
+	h.infos = append(h.infos, info)
+}
+
+func TestHooks(t *testing.T) {
+	errExec := errors.New("exec error")
+	client := &awsClientMock{
+		BeginTransactionFunc: func(ctx context.Context, input *rdsdata.BeginTransactionInput, optFns ...func(*rdsdata.Options)) (*rdsdata.BeginTransactionOutput, error) {
+			return &rdsdata.BeginTransactionOutput{
+				TransactionId: aws.String("transactionId"),
+			}, nil
+		},
+		ExecuteStatementFunc: func(ctx context.Context, input *rdsdata.ExecuteStatementInput, optFns ...func(*rdsdata.Options)) (*rdsdata.ExecuteStatementOutput, error) {
+			switch aws.ToString(input.Sql) {
+			case "SELECT 1":
+				return &rdsdata.ExecuteStatementOutput{
+					Records: [][]types.Field{
+						{&types.FieldMemberLongValue{Value: 1}},
+					},
+				}, nil
+			case "UPDATE test SET a = 1":
+				return &rdsdata.ExecuteStatementOutput{
+					NumberOfRecordsUpdated: 3,
+				}, nil
+			}
+			return nil, errExec
+		},
+		CommitTransactionFunc: func(ctx context.Context, input *rdsdata.CommitTransactionInput, optFns ...func(*rdsdata.Options)) (*rdsdata.CommitTransactionOutput, error) {
+			return &rdsdata.CommitTransactionOutput{}, nil
+		},
+	}
+	hooks := &recordHooks{}
+	conn := &Conn{
+		client: client,
+		connector: &Connector{
+			cfg:   &Config{},
+			hooks: hooks,
+		},
+		dialect: &DialectMySQL{},
+	}
+
+	ctx := context.Background()
+	tx, err := conn.BeginTx(ctx, driver.TxOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.QueryContext(ctx, "SELECT 1", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.ExecContext(ctx, "UPDATE test SET a = 1", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.ExecContext(ctx, "INVALID", nil); !errors.Is(err, errExec) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	wantCalls := []string{
+		"BeforeBegin", "AfterBegin",
+		"BeforeQuery: SELECT 1", "AfterQuery: SELECT 1",
+		"BeforeExec: UPDATE test SET a = 1", "AfterExec: UPDATE test SET a = 1",
+		"BeforeExec: INVALID", "AfterExec: INVALID",
+		"BeforeCommit", "AfterCommit",
+	}
+	if !slices.Equal(hooks.calls, wantCalls) {
+		t.Errorf("unexpected calls: %v, want %v", hooks.calls, wantCalls)
+	}
+
+	for i, info := range hooks.infos {
+		if info.TransactionID != "transactionId" {
+			t.Errorf("%d: unexpected TransactionID: %q", i, info.TransactionID)
+		}
+	}
+	if hooks.infos[1].Records != 1 {
+		t.Errorf("unexpected Records: %d, want 1", hooks.infos[1].Records)
+	}
+	if hooks.infos[2].RowsAffected != 3 {
+		t.Errorf("unexpected RowsAffected: %d, want 3", hooks.infos[2].RowsAffected)
+	}
+	if !errors.Is(hooks.infos[3].Err, errExec) {
+		t.Errorf("unexpected Err: %v", hooks.infos[3].Err)
+	}
+}
+
+func TestMultiHooks(t *testing.T) {
+	var calls []string
+	h1 := &orderHooks{name: "h1", calls: &calls}
+	h2 := &orderHooks{name: "h2", calls: &calls}
+	hooks := MultiHooks(h1, nil, h2)
+
+	in := &rdsdata.ExecuteStatementInput{}
+	ctx := hooks.BeforeQuery(context.Background(), in)
+	hooks.AfterQuery(ctx, in, &HookInfo{})
+
+	want := []string{"h1 before", "h2 before", "h2 after", "h1 after"}
+	if !slices.Equal(calls, want) {
+		t.Errorf("unexpected calls: %v, want %v", calls, want)
+	}
+}
+
+type orderHooks struct {
+	NopHooks
+	name  string
+	calls *[]string
+}
+
+func (h *orderHooks) BeforeQuery(ctx context.Context, in *rdsdata.ExecuteStatementInput) context.Context {
+	*h.calls = append(*h.calls, h.name+" before")
+	return ctx
+}
+
+func (h *orderHooks) AfterQuery(ctx context.Context, in *rdsdata.ExecuteStatementInput, info *HookInfo) {
+	*h.calls = append(*h.calls, h.name+" after")
+}
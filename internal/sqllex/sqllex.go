@@ -0,0 +1,356 @@
+// Package sqllex splits SQL into tokens, following the lexical rules of MySQL and PostgreSQL.
+//
+// It is shared by the driver and the tools of this repository,
+// so that they agree on where the strings, the identifiers and the comments are.
+package sqllex
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Syntax is the lexical syntax of the SQL.
+type Syntax int
+
+const (
+	// PostgreSQL has nested block comments, double-quoted identifiers,
+	// E'...' strings with backslash escapes, dollar-quoted strings and $1 placeholders.
+	PostgreSQL Syntax = iota
+
+	// MySQL has # comments, conditional comments such as /*!50000 ... */, backquoted identifiers,
+	// double-quoted strings, backslash escapes and ? placeholders.
+	MySQL
+)
+
+// ForEngine returns the syntax of the engine, e.g. "mysql" or "postgres".
+// The engines other than MySQL are assumed to be PostgreSQL.
+func ForEngine(engine string) Syntax {
+	if engine == "mysql" {
+		return MySQL
+	}
+	return PostgreSQL
+}
+
+// Kind is the kind of a token.
+type Kind int
+
+const (
+	// Space is a sequence of white spaces.
+	Space Kind = iota
+
+	// Comment is a line comment or a block comment.
+	Comment
+
+	// ConditionalComment is the opening "/*!50000" or the closing "*/" of a MySQL conditional comment.
+	// MySQL executes the content, so the tokens between them are code.
+	ConditionalComment
+
+	// Word is a keyword or an identifier that is not quoted.
+	Word
+
+	// QuotedIdentifier is a quoted identifier, e.g. `name` or "name".
+	QuotedIdentifier
+
+	// String is a string literal, including E'...' and dollar-quoted strings.
+	String
+
+	// Number is a numeric literal.
+	Number
+
+	// Placeholder is a placeholder, e.g. ?, $1 or :name.
+	Placeholder
+
+	// Operator is an operator or a punctuation, e.g. "(", ";" or "::".
+	Operator
+)
+
+var kindNames = [...]string{
+	Space:              "Space",
+	Comment:            "Comment",
+	ConditionalComment: "ConditionalComment",
+	Word:               "Word",
+	QuotedIdentifier:   "QuotedIdentifier",
+	String:             "String",
+	Number:             "Number",
+	Placeholder:        "Placeholder",
+	Operator:           "Operator",
+}
+
+func (k Kind) String() string {
+	if 0 <= k && int(k) < len(kindNames) {
+		return kindNames[k]
+	}
+	return "Kind(" + strconv.Itoa(int(k)) + ")"
+}
+
+// Token is a token of SQL.
+type Token struct {
+	Kind Kind
+	Text string
+
+	// Pos is the byte offset of the token in the source.
+	Pos int
+
+	// Unterminated reports whether the string, the quoted identifier or the block comment is not terminated.
+	Unterminated bool
+}
+
+// IsCode reports whether the token is code, neither a space nor a comment.
+func (t Token) IsCode() bool {
+	return t.Kind >= Word
+}
+
+// IsWord reports whether the token is the keyword, case-insensitively.
+func (t Token) IsWord(keyword string) bool {
+	return t.Kind == Word && strings.EqualFold(t.Text, keyword)
+}
+
+// End returns the byte offset just after the token.
+func (t Token) End() int {
+	return t.Pos + len(t.Text)
+}
+
+// Scanner reads the tokens of SQL one by one.
+type Scanner struct {
+	syntax      Syntax
+	src         string
+	pos         int
+	conditional bool // in a MySQL conditional comment
+}
+
+// NewScanner returns a new Scanner that reads src.
+func NewScanner(syntax Syntax, src string) *Scanner {
+	return &Scanner{syntax: syntax, src: src}
+}
+
+// Pos returns the byte offset of the next token.
+func (s *Scanner) Pos() int {
+	return s.pos
+}
+
+// Seek moves the scanner to the byte offset pos, e.g. to skip a client-side directive.
+func (s *Scanner) Seek(pos int) {
+	s.pos = min(pos, len(s.src))
+}
+
+// Next returns the next token.
+// It returns false at the end of the source.
+func (s *Scanner) Next() (Token, bool) {
+	src, start := s.src, s.pos
+	if start >= len(src) {
+		return Token{}, false
+	}
+	mysql := s.syntax == MySQL
+	c := src[start]
+	kind, end, ok := Operator, start+1, true
+
+	switch {
+	case isSpace(c):
+		kind = Space
+		for end < len(src) && isSpace(src[end]) {
+			end++
+		}
+
+	case s.conditional && strings.HasPrefix(src[start:], "*/"):
+		kind, end = ConditionalComment, start+2
+		s.conditional = false
+
+	case strings.HasPrefix(src[start:], "--") && (!mysql || start+2 == len(src) || isSpace(src[start+2])),
+		mysql && c == '#':
+		// MySQL requires a space after "--".
+		kind = Comment
+		end = start + strings.IndexByte(src[start:]+"\n", '\n')
+
+	case mysql && !s.conditional && (strings.HasPrefix(src[start:], "/*!") || strings.HasPrefix(src[start:], "/*M!")):
+		kind = ConditionalComment
+		end = start + strings.IndexByte(src[start:], '!') + 1
+		for end < len(src) && isDigit(src[end]) {
+			end++
+		}
+		s.conditional = true
+
+	case strings.HasPrefix(src[start:], "/*"):
+		kind = Comment
+		end, ok = skipBlockComment(src, start, !mysql)
+
+	case c == '\'':
+		kind = String
+		end, ok = skipQuoted(src, start, '\'', mysql)
+
+	case !mysql && (c == 'E' || c == 'e') && start+1 < len(src) && src[start+1] == '\'':
+		kind = String
+		end, ok = skipQuoted(src, start+1, '\'', true)
+
+	case c == '"':
+		kind = QuotedIdentifier
+		if mysql {
+			kind = String
+		}
+		end, ok = skipQuoted(src, start, '"', mysql)
+
+	case mysql && c == '`':
+		kind = QuotedIdentifier
+		end, ok = skipQuoted(src, start, '`', false)
+
+	case !mysql && c == '$':
+		if e, dollarOK, isDollar := skipDollarQuoted(src, start); isDollar {
+			kind, end, ok = String, e, dollarOK
+			break
+		}
+		for end < len(src) && isDigit(src[end]) {
+			end++
+		}
+		if end > start+1 {
+			kind = Placeholder
+		}
+
+	case mysql && c == '?':
+		kind = Placeholder
+
+	case c == ':' && start+1 < len(src) && src[start+1] == ':':
+		// the type cast of PostgreSQL.
+		end = start + 2
+
+	case c == ':' && start+1 < len(src) && isWordChar(src[start+1]):
+		// the named parameters of the Data API.
+		kind = Placeholder
+		for end < len(src) && isWordChar(src[end]) {
+			end++
+		}
+
+	case isDigit(c) || (c == '.' && start+1 < len(src) && isDigit(src[start+1])):
+		kind = Number
+		for end < len(src) && (isWordChar(src[end]) || src[end] == '.' ||
+			((src[end] == '+' || src[end] == '-') && (src[end-1] == 'e' || src[end-1] == 'E'))) {
+			end++
+		}
+
+	case isWordChar(c) || c >= 0x80:
+		kind = Word
+		for end < len(src) && (isWordChar(src[end]) || src[end] >= 0x80 || src[end] == '$') {
+			end++
+		}
+	}
+
+	s.pos = end
+	return Token{Kind: kind, Text: src[start:end], Pos: start, Unterminated: !ok}, true
+}
+
+// Tokenize returns all the tokens of src, including the spaces and the comments.
+func Tokenize(syntax Syntax, src string) []Token {
+	var tokens []Token
+	s := NewScanner(syntax, src)
+	for {
+		token, ok := s.Next()
+		if !ok {
+			return tokens
+		}
+		tokens = append(tokens, token)
+	}
+}
+
+// Code returns the tokens of src that are code, skipping the spaces and the comments.
+func Code(syntax Syntax, src string) []Token {
+	var tokens []Token
+	s := NewScanner(syntax, src)
+	for {
+		token, ok := s.Next()
+		if !ok {
+			return tokens
+		}
+		if token.IsCode() {
+			tokens = append(tokens, token)
+		}
+	}
+}
+
+// TrimLeadingComments returns src without the leading spaces and comments.
+// MySQL conditional comments are kept, because they are executed.
+func TrimLeadingComments(syntax Syntax, src string) string {
+	s := NewScanner(syntax, src)
+	for {
+		token, ok := s.Next()
+		if !ok {
+			return ""
+		}
+		if token.Kind != Space && token.Kind != Comment {
+			return src[token.Pos:]
+		}
+	}
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == '\f' || c == '\v'
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func isWordChar(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || isDigit(c) || c == '_'
+}
+
+// skipBlockComment returns the position after the block comment at i.
+func skipBlockComment(src string, i int, nested bool) (int, bool) {
+	depth := 0
+	for i < len(src) {
+		switch {
+		case strings.HasPrefix(src[i:], "/*"):
+			if depth == 0 || nested {
+				depth++
+			}
+			i += 2
+		case strings.HasPrefix(src[i:], "*/"):
+			depth--
+			i += 2
+			if depth == 0 {
+				return i, true
+			}
+		default:
+			i++
+		}
+	}
+	return len(src), false
+}
+
+// skipQuoted returns the position after the quoted string at i.
+// The quote is escaped by doubling it, or by a backslash if backslash is true.
+func skipQuoted(src string, i int, quote byte, backslash bool) (int, bool) {
+	i++
+	for i < len(src) {
+		switch src[i] {
+		case '\\':
+			if backslash {
+				i += 2
+				continue
+			}
+		case quote:
+			if i+1 < len(src) && src[i+1] == quote {
+				i += 2
+				continue
+			}
+			return i + 1, true
+		}
+		i++
+	}
+	return len(src), false
+}
+
+// skipDollarQuoted returns the position after the dollar-quoted string of PostgreSQL at i, e.g. $tag$...$tag$.
+// isDollar is false if src[i:] doesn't start a dollar-quoted string, e.g. a $1 placeholder.
+func skipDollarQuoted(src string, i int) (end int, ok, isDollar bool) {
+	j := i + 1
+	for j < len(src) && isWordChar(src[j]) && !(j == i+1 && isDigit(src[j])) {
+		j++
+	}
+	if j >= len(src) || src[j] != '$' {
+		return 0, false, false
+	}
+	tag := src[i : j+1]
+	k := strings.Index(src[j+1:], tag)
+	if k < 0 {
+		return len(src), false, true
+	}
+	return j + 1 + k + len(tag), true, true
+}
@@ -0,0 +1,97 @@
+package sqllex
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		syntax Syntax
+		src    string
+		want   string
+	}{
+		{
+			MySQL,
+			"SELECT `a``b`, \"it\\\"s\", 'it''s' FROM t -- comment\n# comment\nWHERE id = ?",
+			"Word(SELECT) QuotedIdentifier(`a``b`) Operator(,) String(\"it\\\"s\") Operator(,) String('it''s') Word(FROM) Word(t) Comment(-- comment) Comment(# comment) Word(WHERE) Word(id) Operator(=) Placeholder(?)",
+		},
+		{
+			// "--" without a space is an operator of MySQL.
+			MySQL,
+			"SELECT 1--1",
+			"Word(SELECT) Number(1) Operator(-) Operator(-) Number(1)",
+		},
+		{
+			MySQL,
+			"/*!50000 SELECT */ 1 /* a /* b */",
+			"ConditionalComment(/*!50000) Word(SELECT) ConditionalComment(*/) Number(1) Comment(/* a /* b */)",
+		},
+		{
+			PostgreSQL,
+			`SELECT "a""b", E'it\'s', 'a\', $$x;y$$, $tag$$x$$tag$, $1, :name, id::text FROM t--comment`,
+			`Word(SELECT) QuotedIdentifier("a""b") Operator(,) String(E'it\'s') Operator(,) String('a\') Operator(,) String($$x;y$$) Operator(,) String($tag$$x$$tag$) Operator(,) Placeholder($1) Operator(,) Placeholder(:name) Operator(,) Word(id) Operator(::) Word(text) Word(FROM) Word(t) Comment(--comment)`,
+		},
+		{
+			PostgreSQL,
+			"/* a /* b */ c */ SELECT 1.5e-3, a$b",
+			"Comment(/* a /* b */ c */) Word(SELECT) Number(1.5e-3) Operator(,) Word(a$b)",
+		},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, token := range Tokenize(tt.syntax, tt.src) {
+			if token.Kind == Space {
+				continue
+			}
+			if tt.src[token.Pos:token.End()] != token.Text {
+				t.Errorf("unexpected position of %q: %d", token.Text, token.Pos)
+			}
+			got = append(got, fmt.Sprintf("%s(%s)", token.Kind, token.Text))
+		}
+		if strings.Join(got, " ") != tt.want {
+			t.Errorf("Tokenize(%q)\n got: %s\nwant: %s", tt.src, strings.Join(got, " "), tt.want)
+		}
+	}
+}
+
+func TestTokenize_Unterminated(t *testing.T) {
+	tests := []struct {
+		syntax Syntax
+		src    string
+	}{
+		{MySQL, "SELECT 'abc"},
+		{MySQL, `SELECT 'abc\'`},
+		{MySQL, "SELECT `abc"},
+		{MySQL, "SELECT 1 /* abc"},
+		{PostgreSQL, `SELECT "abc`},
+		{PostgreSQL, "SELECT $tag$abc$ta"},
+		{PostgreSQL, "SELECT 1 /* a /* b */"},
+	}
+	for _, tt := range tests {
+		tokens := Tokenize(tt.syntax, tt.src)
+		last := tokens[len(tokens)-1]
+		if !last.Unterminated || last.End() != len(tt.src) {
+			t.Errorf("Tokenize(%q): want unterminated token, got %#v", tt.src, last)
+		}
+	}
+}
+
+func TestTrimLeadingComments(t *testing.T) {
+	tests := []struct {
+		syntax Syntax
+		src    string
+		want   string
+	}{
+		{PostgreSQL, "  -- comment\n/* a /* b */ c */ SELECT 1", "SELECT 1"},
+		{MySQL, "# comment\n/* a */ SELECT 1", "SELECT 1"},
+		{MySQL, "/* a */ /*!40101 SET NAMES utf8 */", "/*!40101 SET NAMES utf8 */"},
+		{MySQL, "-- comment", ""},
+	}
+	for _, tt := range tests {
+		if got := TrimLeadingComments(tt.syntax, tt.src); got != tt.want {
+			t.Errorf("TrimLeadingComments(%q) = %q, want %q", tt.src, got, tt.want)
+		}
+	}
+}
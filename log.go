@@ -0,0 +1,157 @@
+package rdsdata
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata"
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata/types"
+)
+
+// redactedValue is logged instead of the redacted parameter values.
+const redactedValue = "[REDACTED]"
+
+// logHooks is a Hooks that writes structured logs of the Data API calls.
+type logHooks struct {
+	logger          *slog.Logger
+	slowThreshold   time.Duration
+	redactParameter func(name string) bool
+}
+
+var _ Hooks = (*logHooks)(nil)
+
+func newLogHooks(cfg *Config) *logHooks {
+	return &logHooks{
+		logger:          cfg.Logger,
+		slowThreshold:   cfg.SlowQueryThreshold,
+		redactParameter: cfg.RedactParameter,
+	}
+}
+
+func (h *logHooks) BeforeQuery(ctx context.Context, in *rdsdata.ExecuteStatementInput) context.Context {
+	return ctx
+}
+
+func (h *logHooks) AfterQuery(ctx context.Context, in *rdsdata.ExecuteStatementInput, info *HookInfo) {
+	h.logStatement(ctx, "rdsdata: query", in, info)
+}
+
+func (h *logHooks) BeforeExec(ctx context.Context, in *rdsdata.ExecuteStatementInput) context.Context {
+	return ctx
+}
+
+func (h *logHooks) AfterExec(ctx context.Context, in *rdsdata.ExecuteStatementInput, info *HookInfo) {
+	h.logStatement(ctx, "rdsdata: exec", in, info)
+}
+
+func (h *logHooks) BeforeBegin(ctx context.Context, in *rdsdata.BeginTransactionInput) context.Context {
+	return ctx
+}
+
+func (h *logHooks) AfterBegin(ctx context.Context, in *rdsdata.BeginTransactionInput, info *HookInfo) {
+	h.logTransaction(ctx, "rdsdata: begin", info)
+}
+
+func (h *logHooks) BeforeCommit(ctx context.Context, in *rdsdata.CommitTransactionInput) context.Context {
+	return ctx
+}
+
+func (h *logHooks) AfterCommit(ctx context.Context, in *rdsdata.CommitTransactionInput, info *HookInfo) {
+	h.logTransaction(ctx, "rdsdata: commit", info)
+}
+
+func (h *logHooks) BeforeRollback(ctx context.Context, in *rdsdata.RollbackTransactionInput) context.Context {
+	return ctx
+}
+
+func (h *logHooks) AfterRollback(ctx context.Context, in *rdsdata.RollbackTransactionInput, info *HookInfo) {
+	h.logTransaction(ctx, "rdsdata: rollback", info)
+}
+
+func (h *logHooks) level(info *HookInfo) slog.Level {
+	if info.Err != nil {
+		return slog.LevelError
+	}
+	if h.slowThreshold > 0 && info.Duration >= h.slowThreshold {
+		return slog.LevelWarn
+	}
+	return slog.LevelDebug
+}
+
+func (h *logHooks) logStatement(ctx context.Context, msg string, in *rdsdata.ExecuteStatementInput, info *HookInfo) {
+	level := h.level(info)
+	if !h.logger.Enabled(ctx, level) {
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.String("sql", aws.ToString(in.Sql)),
+	}
+	if len(in.Parameters) > 0 {
+		params := make([]any, 0, len(in.Parameters))
+		for _, param := range in.Parameters {
+			params = append(params, h.parameterAttr(param))
+		}
+		attrs = append(attrs, slog.Group("params", params...))
+	}
+	attrs = append(attrs, slog.Duration("duration", info.Duration))
+	if info.TransactionID != "" {
+		attrs = append(attrs, slog.String("transaction_id", info.TransactionID))
+	}
+	if info.Output != nil {
+		attrs = append(attrs,
+			slog.Int("records", info.Records),
+			slog.Int64("rows_affected", info.RowsAffected),
+		)
+	}
+	if info.Err != nil {
+		attrs = append(attrs, slog.Any("error", info.Err))
+	}
+	h.logger.LogAttrs(ctx, level, msg, attrs...)
+}
+
+func (h *logHooks) logTransaction(ctx context.Context, msg string, info *HookInfo) {
+	level := h.level(info)
+	if !h.logger.Enabled(ctx, level) {
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.Duration("duration", info.Duration),
+	}
+	if info.TransactionID != "" {
+		attrs = append(attrs, slog.String("transaction_id", info.TransactionID))
+	}
+	if info.Err != nil {
+		attrs = append(attrs, slog.Any("error", info.Err))
+	}
+	h.logger.LogAttrs(ctx, level, msg, attrs...)
+}
+
+// parameterAttr returns the attribute of the parameter.
+// The value is redacted unless RedactParameter says otherwise.
+func (h *logHooks) parameterAttr(param types.SqlParameter) slog.Attr {
+	name := aws.ToString(param.Name)
+	if h.redactParameter == nil || h.redactParameter(name) {
+		return slog.String(name, redactedValue)
+	}
+
+	switch v := param.Value.(type) {
+	case *types.FieldMemberLongValue:
+		return slog.Int64(name, v.Value)
+	case *types.FieldMemberDoubleValue:
+		return slog.Float64(name, v.Value)
+	case *types.FieldMemberBooleanValue:
+		return slog.Bool(name, v.Value)
+	case *types.FieldMemberStringValue:
+		return slog.String(name, v.Value)
+	case *types.FieldMemberBlobValue:
+		return slog.Any(name, v.Value)
+	case *types.FieldMemberIsNull:
+		return slog.Any(name, nil)
+	default:
+		return slog.Any(name, v)
+	}
+}
@@ -0,0 +1,129 @@
+package rdsdata
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata"
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata/types"
+)
+
+func newTestLogHooks(buf *bytes.Buffer, cfg *Config) *logHooks {
+	cfg.Logger = slog.New(slog.NewJSONHandler(buf, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+	return newLogHooks(cfg)
+}
+
+func TestLogHooks(t *testing.T) {
+	in := &rdsdata.ExecuteStatementInput{
+		Sql: aws.String("UPDATE users SET name = :name WHERE id = :id"),
+		Parameters: []types.SqlParameter{
+			{Name: aws.String("name"), Value: &types.FieldMemberStringValue{Value: "alice"}},
+			{Name: aws.String("id"), Value: &types.FieldMemberLongValue{Value: 42}},
+		},
+	}
+
+	t.Run("redact all parameters by default", func(t *testing.T) {
+		var buf bytes.Buffer
+		hooks := newTestLogHooks(&buf, &Config{})
+		hooks.AfterExec(context.Background(), in, &HookInfo{
+			TransactionID: "transactionId",
+			Duration:      time.Millisecond,
+			RowsAffected:  1,
+			Output:        &rdsdata.ExecuteStatementOutput{NumberOfRecordsUpdated: 1},
+		})
+
+		var got map[string]any
+		if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		if got["level"] != "DEBUG" {
+			t.Errorf("unexpected level: %v", got["level"])
+		}
+		if got["sql"] != "UPDATE users SET name = :name WHERE id = :id" {
+			t.Errorf("unexpected sql: %v", got["sql"])
+		}
+		params := got["params"].(map[string]any)
+		if params["name"] != redactedValue || params["id"] != redactedValue {
+			t.Errorf("unexpected params: %v", params)
+		}
+		if got["rows_affected"] != 1.0 {
+			t.Errorf("unexpected rows_affected: %v", got["rows_affected"])
+		}
+		if got["transaction_id"] != "transactionId" {
+			t.Errorf("unexpected transaction_id: %v", got["transaction_id"])
+		}
+	})
+
+	t.Run("redact by predicate", func(t *testing.T) {
+		var buf bytes.Buffer
+		hooks := newTestLogHooks(&buf, &Config{
+			RedactParameter: func(name string) bool {
+				return name == "name"
+			},
+		})
+		hooks.AfterExec(context.Background(), in, &HookInfo{})
+
+		var got map[string]any
+		if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		params := got["params"].(map[string]any)
+		if params["name"] != redactedValue {
+			t.Errorf("unexpected name: %v", params["name"])
+		}
+		if params["id"] != 42.0 {
+			t.Errorf("unexpected id: %v", params["id"])
+		}
+	})
+
+	t.Run("slow query", func(t *testing.T) {
+		var buf bytes.Buffer
+		hooks := newTestLogHooks(&buf, &Config{
+			SlowQueryThreshold: 100 * time.Millisecond,
+		})
+		hooks.AfterQuery(context.Background(), in, &HookInfo{
+			Duration: 200 * time.Millisecond,
+		})
+
+		var got map[string]any
+		if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		if got["level"] != "WARN" {
+			t.Errorf("unexpected level: %v", got["level"])
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		var buf bytes.Buffer
+		hooks := newTestLogHooks(&buf, &Config{})
+		hooks.AfterQuery(context.Background(), in, &HookInfo{
+			Err: errors.New("something wrong"),
+		})
+
+		var got map[string]any
+		if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		if got["level"] != "ERROR" {
+			t.Errorf("unexpected level: %v", got["level"])
+		}
+		if got["error"] != "something wrong" {
+			t.Errorf("unexpected error: %v", got["error"])
+		}
+	})
+}
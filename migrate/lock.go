@@ -0,0 +1,123 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"hash/fnv"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/shogo82148/go-rdsdata"
+)
+
+// advisoryLock is an advisory lock held in a Data API transaction.
+// The Data API runs each call outside transactions in an arbitrary session,
+// so the lock is bound to the transaction, which pins its session until it ends.
+type advisoryLock struct {
+	m      *Migrator
+	engine rdsdata.Engine
+	tx     *sql.Tx
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// lock takes the advisory lock, waiting for the lock timeout.
+func (m *Migrator) lock(ctx context.Context, e rdsdata.Engine) (*advisoryLock, error) {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	l := &advisoryLock{
+		m:      m,
+		engine: e,
+		tx:     tx,
+		stop:   make(chan struct{}),
+	}
+
+	deadline := time.Now().Add(m.lockTimeout)
+	for {
+		ok, err := l.tryLock(ctx)
+		if err != nil {
+			_ = tx.Rollback()
+			return nil, err
+		}
+		if ok {
+			break
+		}
+		if !time.Now().Before(deadline) {
+			_ = tx.Rollback()
+			return nil, ErrLocked
+		}
+		m.log(ctx, "migrate: waiting for the lock", slog.String("lock", m.lockName))
+		timer := time.NewTimer(m.pollInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			_ = tx.Rollback()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	// keep the transaction alive while the migrations run.
+	l.wg.Add(1)
+	go l.keepAlive()
+	return l, nil
+}
+
+func (l *advisoryLock) tryLock(ctx context.Context) (bool, error) {
+	if l.engine == rdsdata.EnginePostgres {
+		// the lock is released when the transaction ends.
+		var ok bool
+		err := l.tx.QueryRowContext(ctx, "SELECT pg_try_advisory_xact_lock($1)", lockKey(l.m.lockName)).Scan(&ok)
+		return ok, err
+	}
+
+	// GET_LOCK returns 1 if the lock is obtained, 0 if it times out, and NULL on an error.
+	var ok sql.NullInt64
+	err := l.tx.QueryRowContext(ctx, "SELECT GET_LOCK(?, 0)", l.m.lockName).Scan(&ok)
+	return ok.Valid && ok.Int64 == 1, err
+}
+
+func (l *advisoryLock) keepAlive() {
+	defer l.wg.Done()
+	ticker := time.NewTicker(l.m.keepAliveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			if _, err := l.tx.Exec("SELECT 1"); err != nil {
+				l.m.log(context.Background(), "migrate: failed to keep the lock alive", slog.String("error", err.Error()))
+			}
+		}
+	}
+}
+
+// unlock releases the lock.
+func (l *advisoryLock) unlock() error {
+	close(l.stop)
+	l.wg.Wait()
+
+	if l.engine == rdsdata.EnginePostgres {
+		return l.tx.Rollback()
+	}
+
+	// MySQL locks are bound to the session rather than the transaction,
+	// so they must be released explicitly before the session returns to the pool.
+	_, err := l.tx.Exec("SELECT RELEASE_LOCK(?)", l.m.lockName)
+	if rollbackErr := l.tx.Rollback(); err == nil {
+		err = rollbackErr
+	}
+	return err
+}
+
+// lockKey converts the lock name into the key of PostgreSQL advisory locks.
+func lockKey(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	return int64(h.Sum64())
+}
@@ -0,0 +1,643 @@
+// Package migrate applies schema migrations through the RDS Data API.
+//
+// A migration is a pair of SQL files named {version}_{name}.up.sql and {version}_{name}.down.sql.
+// The files may contain multiple statements; they are split and executed one by one,
+// because the Data API executes only one statement per call.
+//
+// The applied versions are recorded in a version table (schema_migrations by default).
+// On PostgreSQL, each migration runs in a Data API transaction together with the update of the version table,
+// unless the up or down file contains the line
+//
+//	-- rdsdata:no-transaction
+//
+// which is needed for the statements that can't run in a transaction, such as CREATE INDEX CONCURRENTLY.
+// On MySQL, DDL statements commit implicitly, so the migrations run without transactions.
+// The migration is marked as dirty while it runs, and a failed migration must be fixed by hand and then resolved by Force.
+//
+// While the migrations run, Migrator holds an advisory lock in a separate Data API transaction,
+// so concurrent deploys don't race.
+package migrate
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shogo82148/go-rdsdata"
+)
+
+// ErrLocked is returned when the lock is held by another process until the lock timeout.
+var ErrLocked = errors.New("migrate: the lock is held by another process")
+
+// ErrNoChange is returned by Down when no migration is applied.
+var ErrNoChange = errors.New("migrate: no change")
+
+// DirtyError is returned when a previous migration failed halfway.
+// Fix the database by hand, and then call Force.
+type DirtyError struct {
+	Version int64
+}
+
+func (e *DirtyError) Error() string {
+	return fmt.Sprintf("migrate: version %d is dirty; fix the database and force the version", e.Version)
+}
+
+// noTransaction is the directive to run the migration without a transaction.
+const noTransaction = "-- rdsdata:no-transaction"
+
+// Migration is a schema migration.
+type Migration struct {
+	// Version is the version of the migration. It must be positive.
+	Version int64
+
+	// Name is the description of the migration.
+	Name string
+
+	// Up is the SQL to apply the migration.
+	Up string
+
+	// Down is the SQL to revert the migration.
+	// If it is empty, the migration can't be reverted.
+	Down string
+}
+
+var fileRegex = regexp.MustCompile(`^([0-9]+)_(.+)\.(up|down)\.sql$`)
+
+// Load reads the migrations from the root directory of fsys.
+// Files that don't match {version}_{name}.up.sql or {version}_{name}.down.sql are ignored.
+func Load(fsys fs.FS) ([]*Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := map[int64]*Migration{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		m := fileRegex.FindStringSubmatch(entry.Name())
+		if m == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(m[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migrate: invalid version %q: %w", entry.Name(), err)
+		}
+		data, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		mig, ok := migrations[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: m[2]}
+			migrations[version] = mig
+		} else if mig.Name != m[2] {
+			return nil, fmt.Errorf("migrate: duplicated version %d: %q and %q", version, mig.Name, m[2])
+		}
+		if m[3] == "up" {
+			mig.Up = string(data)
+		} else {
+			mig.Down = string(data)
+		}
+	}
+
+	ret := make([]*Migration, 0, len(migrations))
+	for _, mig := range migrations {
+		if mig.Up == "" {
+			return nil, fmt.Errorf("migrate: the up migration of version %d is missing", mig.Version)
+		}
+		ret = append(ret, mig)
+	}
+	slices.SortFunc(ret, func(a, b *Migration) int {
+		return cmp.Compare(a.Version, b.Version)
+	})
+	return ret, nil
+}
+
+// Status is the status of a migration.
+type Status struct {
+	Version int64
+	Name    string
+
+	// Applied reports whether the migration is recorded in the version table.
+	Applied bool
+
+	// Dirty reports whether the migration failed halfway.
+	Dirty bool
+}
+
+// Option configures Migrator.
+type Option func(*Migrator)
+
+// WithTable sets the name of the version table.
+// The default is "schema_migrations".
+func WithTable(table string) Option {
+	return func(m *Migrator) {
+		m.table = table
+	}
+}
+
+// WithLockName sets the name of the advisory lock.
+// The default is "rdsdata-migrate:" followed by the table name.
+func WithLockName(name string) Option {
+	return func(m *Migrator) {
+		m.lockName = name
+	}
+}
+
+// WithLockTimeout sets how long to wait for the lock held by another process.
+// The default is one minute. Zero tries only once.
+func WithLockTimeout(timeout time.Duration) Option {
+	return func(m *Migrator) {
+		m.lockTimeout = timeout
+	}
+}
+
+// WithLogger sets the logger that reports the progress.
+// If it is nil, no logs are written.
+func WithLogger(logger *slog.Logger) Option {
+	return func(m *Migrator) {
+		m.logger = logger
+	}
+}
+
+// Migrator applies the migrations.
+type Migrator struct {
+	db         *sql.DB
+	migrations []*Migration
+
+	table       string
+	lockName    string
+	lockTimeout time.Duration
+	logger      *slog.Logger
+
+	// keepAliveInterval is the interval of the statements that keep the lock transaction alive.
+	// The Data API aborts transactions that are idle for three minutes.
+	keepAliveInterval time.Duration
+
+	// pollInterval is the interval of trying to take the lock.
+	pollInterval time.Duration
+}
+
+var identRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// New returns a new Migrator.
+// db must be opened by the rdsdata driver.
+func New(db *sql.DB, migrations []*Migration, opts ...Option) (*Migrator, error) {
+	m := &Migrator{
+		db:                db,
+		table:             "schema_migrations",
+		lockTimeout:       time.Minute,
+		keepAliveInterval: time.Minute,
+		pollInterval:      time.Second,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	if !identRegex.MatchString(m.table) {
+		return nil, fmt.Errorf("migrate: invalid table name: %q", m.table)
+	}
+	if m.lockName == "" {
+		m.lockName = "rdsdata-migrate:" + m.table
+	}
+
+	m.migrations = slices.Clone(migrations)
+	slices.SortFunc(m.migrations, func(a, b *Migration) int {
+		return cmp.Compare(a.Version, b.Version)
+	})
+	for i, mig := range m.migrations {
+		if mig.Version <= 0 {
+			return nil, fmt.Errorf("migrate: invalid version: %d", mig.Version)
+		}
+		if i > 0 && m.migrations[i-1].Version == mig.Version {
+			return nil, fmt.Errorf("migrate: duplicated version: %d", mig.Version)
+		}
+	}
+	return m, nil
+}
+
+// Up applies all the migrations that are not applied yet, in ascending order of the versions.
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.withLock(ctx, func(e rdsdata.Engine) error {
+		applied, err := m.applied(ctx)
+		if err != nil {
+			return err
+		}
+		for _, mig := range m.migrations {
+			if _, ok := applied[mig.Version]; ok {
+				continue
+			}
+			if err := m.run(ctx, e, mig, true); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Down reverts the latest steps migrations, in descending order of the versions.
+// steps must be positive.
+// It returns ErrNoChange if no migration is applied.
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	if steps <= 0 {
+		return fmt.Errorf("migrate: invalid steps: %d", steps)
+	}
+	return m.withLock(ctx, func(e rdsdata.Engine) error {
+		applied, err := m.applied(ctx)
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			return ErrNoChange
+		}
+
+		versions := make([]int64, 0, len(applied))
+		for v := range applied {
+			versions = append(versions, v)
+		}
+		slices.SortFunc(versions, func(a, b int64) int {
+			return cmp.Compare(b, a)
+		})
+		for _, v := range versions[:min(steps, len(versions))] {
+			mig := m.find(v)
+			if mig == nil {
+				return fmt.Errorf("migrate: the migration of version %d is not found", v)
+			}
+			if mig.Down == "" {
+				return fmt.Errorf("migrate: the down migration of version %d is missing", v)
+			}
+			if err := m.run(ctx, e, mig, false); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Status returns the status of the migrations.
+// The versions that are recorded in the version table but unknown to Migrator are also returned.
+// It only reads the database: if the version table doesn't exist yet, no migration is applied.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	e, err := DetectEngine(ctx, m.db)
+	if err != nil {
+		return nil, err
+	}
+	exists, err := m.tableExists(ctx, e)
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []Status
+	if exists {
+		ret, err = m.appliedStatus(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, mig := range m.migrations {
+		if !slices.ContainsFunc(ret, func(s Status) bool { return s.Version == mig.Version }) {
+			ret = append(ret, Status{Version: mig.Version, Name: mig.Name})
+		}
+	}
+	slices.SortFunc(ret, func(a, b Status) int {
+		return cmp.Compare(a.Version, b.Version)
+	})
+	return ret, nil
+}
+
+// Force records that the migrations up to and including version are applied and the others are not,
+// without running them. It also clears the dirty flag.
+// Zero means that no migration is applied.
+func (m *Migrator) Force(ctx context.Context, version int64) error {
+	return m.withLock(ctx, func(e rdsdata.Engine) error {
+		tx, err := m.db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		if _, err := tx.ExecContext(ctx, rebind(e, "DELETE FROM "+m.table+" WHERE version > ? OR dirty"), version); err != nil {
+			return err
+		}
+		rows, err := tx.QueryContext(ctx, "SELECT version FROM "+m.table)
+		if err != nil {
+			return err
+		}
+		applied, err := scanVersions(rows)
+		if err != nil {
+			return err
+		}
+		for _, mig := range m.migrations {
+			if mig.Version > version {
+				break
+			}
+			if slices.Contains(applied, mig.Version) {
+				continue
+			}
+			if err := m.insertVersion(ctx, e, tx, mig, false); err != nil {
+				return err
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		m.log(ctx, "migrate: forced the version", slog.Int64("version", version))
+		return nil
+	})
+}
+
+// withLock takes the lock, makes sure that the version table exists, and calls f.
+func (m *Migrator) withLock(ctx context.Context, f func(e rdsdata.Engine) error) error {
+	e, err := DetectEngine(ctx, m.db)
+	if err != nil {
+		return err
+	}
+	l, err := m.lock(ctx, e)
+	if err != nil {
+		return err
+	}
+	err = m.createTable(ctx)
+	if err == nil {
+		err = f(e)
+	}
+	if unlockErr := l.unlock(); err == nil {
+		err = unlockErr
+	}
+	return err
+}
+
+// appliedStatus returns the status of the migrations recorded in the version table.
+func (m *Migrator) appliedStatus(ctx context.Context) ([]Status, error) {
+	rows, err := m.db.QueryContext(ctx, "SELECT version, name, dirty FROM "+m.table+" ORDER BY version")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ret []Status
+	for rows.Next() {
+		var s Status
+		if err := rows.Scan(&s.Version, &s.Name, &s.Dirty); err != nil {
+			return nil, err
+		}
+		s.Applied = true
+		ret = append(ret, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// tableExists reports whether the version table exists.
+func (m *Migrator) tableExists(ctx context.Context, e rdsdata.Engine) (bool, error) {
+	if e == rdsdata.EnginePostgres {
+		// to_regclass follows the search_path, as the other queries do.
+		var exists bool
+		err := m.db.QueryRowContext(ctx, "SELECT to_regclass($1) IS NOT NULL", m.table).Scan(&exists)
+		return exists, err
+	}
+
+	schema, table, ok := strings.Cut(m.table, ".")
+	if !ok {
+		schema, table = "", m.table
+	}
+	var n int64
+	err := m.db.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = COALESCE(NULLIF(?, ''), DATABASE()) AND table_name = ?",
+		schema, table,
+	).Scan(&n)
+	return n > 0, err
+}
+
+func (m *Migrator) createTable(ctx context.Context) error {
+	_, err := m.db.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS "+m.table+" ("+
+		"version BIGINT NOT NULL PRIMARY KEY, "+
+		"name VARCHAR(255) NOT NULL, "+
+		"dirty BOOLEAN NOT NULL, "+
+		"applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP)")
+	return err
+}
+
+// applied returns the set of the applied versions.
+// It returns DirtyError if any migration is dirty.
+func (m *Migrator) applied(ctx context.Context) (map[int64]struct{}, error) {
+	var dirty sql.NullInt64
+	err := m.db.QueryRowContext(ctx, "SELECT MIN(version) FROM "+m.table+" WHERE dirty").Scan(&dirty)
+	if err != nil {
+		return nil, err
+	}
+	if dirty.Valid {
+		return nil, &DirtyError{Version: dirty.Int64}
+	}
+
+	rows, err := m.db.QueryContext(ctx, "SELECT version FROM "+m.table)
+	if err != nil {
+		return nil, err
+	}
+	versions, err := scanVersions(rows)
+	if err != nil {
+		return nil, err
+	}
+	ret := make(map[int64]struct{}, len(versions))
+	for _, v := range versions {
+		ret[v] = struct{}{}
+	}
+	return ret, nil
+}
+
+func scanVersions(rows *sql.Rows) ([]int64, error) {
+	defer rows.Close()
+	var ret []int64
+	for rows.Next() {
+		var v int64
+		if err := rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		ret = append(ret, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+func (m *Migrator) find(version int64) *Migration {
+	for _, mig := range m.migrations {
+		if mig.Version == version {
+			return mig
+		}
+	}
+	return nil
+}
+
+// run applies or reverts the migration.
+func (m *Migrator) run(ctx context.Context, e rdsdata.Engine, mig *Migration, up bool) error {
+	query, direction := mig.Up, "up"
+	if !up {
+		query, direction = mig.Down, "down"
+	}
+	stmts, err := splitStatements(query, e)
+	if err != nil {
+		return fmt.Errorf("migrate: %d_%s.%s.sql: %w", mig.Version, mig.Name, direction, err)
+	}
+
+	m.log(ctx, "migrate: running", slog.Int64("version", mig.Version), slog.String("name", mig.Name), slog.String("direction", direction))
+	start := time.Now()
+	if e == rdsdata.EnginePostgres && !HasNoTransaction(query) {
+		err = m.runInTransaction(ctx, e, mig, stmts, up)
+	} else {
+		err = m.runWithoutTransaction(ctx, e, mig, stmts, up)
+	}
+	if err != nil {
+		return fmt.Errorf("migrate: %d_%s.%s.sql: %w", mig.Version, mig.Name, direction, err)
+	}
+	m.log(ctx, "migrate: finished", slog.Int64("version", mig.Version), slog.String("name", mig.Name), slog.String("direction", direction), slog.Duration("duration", time.Since(start)))
+	return nil
+}
+
+func (m *Migrator) runInTransaction(ctx context.Context, e rdsdata.Engine, mig *Migration, stmts []string, up bool) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, stmt := range stmts {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	if up {
+		err = m.insertVersion(ctx, e, tx, mig, false)
+	} else {
+		err = m.deleteVersion(ctx, e, tx, mig.Version)
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// runWithoutTransaction runs the statements one by one.
+// The migration is marked as dirty while it runs, so a failure is detected by the next run.
+func (m *Migrator) runWithoutTransaction(ctx context.Context, e rdsdata.Engine, mig *Migration, stmts []string, up bool) error {
+	var err error
+	if up {
+		err = m.insertVersion(ctx, e, m.db, mig, true)
+	} else {
+		err = m.setDirty(ctx, e, m.db, mig.Version, true)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, stmt := range stmts {
+		if _, err := m.db.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+
+	if up {
+		return m.setDirty(ctx, e, m.db, mig.Version, false)
+	}
+	return m.deleteVersion(ctx, e, m.db, mig.Version)
+}
+
+// execer is *sql.DB or *sql.Tx.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+func (m *Migrator) insertVersion(ctx context.Context, e rdsdata.Engine, db execer, mig *Migration, dirty bool) error {
+	_, err := db.ExecContext(ctx, rebind(e, "INSERT INTO "+m.table+" (version, name, dirty) VALUES (?, ?, ?)"), mig.Version, mig.Name, dirty)
+	return err
+}
+
+func (m *Migrator) setDirty(ctx context.Context, e rdsdata.Engine, db execer, version int64, dirty bool) error {
+	_, err := db.ExecContext(ctx, rebind(e, "UPDATE "+m.table+" SET dirty = ? WHERE version = ?"), dirty, version)
+	return err
+}
+
+func (m *Migrator) deleteVersion(ctx context.Context, e rdsdata.Engine, db execer, version int64) error {
+	_, err := db.ExecContext(ctx, rebind(e, "DELETE FROM "+m.table+" WHERE version = ?"), version)
+	return err
+}
+
+// HasNoTransaction reports whether the migration has the directive "-- rdsdata:no-transaction" on a line by itself.
+// The migrations with the directive run without a transaction.
+func HasNoTransaction(query string) bool {
+	for _, line := range strings.Split(query, "\n") {
+		if strings.TrimSpace(line) == noTransaction {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Migrator) log(ctx context.Context, msg string, attrs ...slog.Attr) {
+	if m.logger == nil {
+		return
+	}
+	m.logger.LogAttrs(ctx, slog.LevelInfo, msg, attrs...)
+}
+
+// rebind converts the ? placeholders into the placeholders of the engine.
+// The queries of Migrator contain no question marks other than the placeholders.
+func rebind(e rdsdata.Engine, query string) string {
+	if e != rdsdata.EnginePostgres {
+		return query
+	}
+	var buf strings.Builder
+	n := 0
+	for _, ch := range query {
+		if ch == '?' {
+			n++
+			buf.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		buf.WriteRune(ch)
+	}
+	return buf.String()
+}
+
+// DetectEngine returns the engine that the rdsdata driver of db detected on connect.
+// It returns an error if db doesn't use the rdsdata driver,
+// or the engine is neither MySQL nor PostgreSQL, e.g. the engine of a custom dialect is unknown.
+func DetectEngine(ctx context.Context, db *sql.DB) (rdsdata.Engine, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	return DetectConnEngine(conn)
+}
+
+// DetectConnEngine is like DetectEngine, but it uses the connection c.
+func DetectConnEngine(c *sql.Conn) (rdsdata.Engine, error) {
+	var e rdsdata.Engine
+	err := c.Raw(func(driverConn any) error {
+		dc, ok := driverConn.(rdsdata.DialectConn)
+		if !ok {
+			return fmt.Errorf("migrate: unsupported driver: %T", driverConn)
+		}
+		e = dc.Engine()
+		if e != rdsdata.EngineMySQL && e != rdsdata.EnginePostgres {
+			return fmt.Errorf("migrate: unsupported engine of the dialect %T", dc.Dialect())
+		}
+		return nil
+	})
+	return e, err
+}
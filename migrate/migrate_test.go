@@ -0,0 +1,450 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata/types"
+	"github.com/shogo82148/go-rdsdata"
+	"github.com/shogo82148/go-rdsdata/rdsdatatest"
+)
+
+const createTable = "CREATE TABLE IF NOT EXISTS schema_migrations (" +
+	"version BIGINT NOT NULL PRIMARY KEY, " +
+	"name VARCHAR(255) NOT NULL, " +
+	"dirty BOOLEAN NOT NULL, " +
+	"applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP)"
+
+var testMigrations = []*Migration{
+	{
+		Version: 1,
+		Name:    "create_users",
+		Up:      "CREATE TABLE users (id BIGINT PRIMARY KEY);\nCREATE INDEX users_id ON users (id);\n",
+		Down:    "DROP TABLE users;\n",
+	},
+	{
+		Version: 2,
+		Name:    "add_name",
+		Up:      "ALTER TABLE users ADD COLUMN name TEXT;\n",
+		Down:    "ALTER TABLE users DROP COLUMN name;\n",
+	},
+}
+
+// expectApplied registers the expectations of reading the version table.
+func expectApplied(fake *rdsdatatest.Fake, dirty types.Field, versions ...int64) {
+	fake.ExpectStatement(createTable).OutsideTransaction()
+	fake.ExpectStatement("SELECT MIN(version) FROM schema_migrations WHERE dirty").
+		WillReturnRecords([]types.ColumnMetadata{rdsdatatest.Column("MIN(version)", "BIGINT")}, []types.Field{dirty})
+	if _, ok := dirty.(*types.FieldMemberIsNull); !ok {
+		return
+	}
+	records := make([][]types.Field, len(versions))
+	for i, v := range versions {
+		records[i] = []types.Field{rdsdatatest.Long(v)}
+	}
+	fake.ExpectStatement("SELECT version FROM schema_migrations").
+		WillReturnRecords([]types.ColumnMetadata{rdsdatatest.Column("version", "BIGINT")}, records...)
+}
+
+func expectPostgresLock(fake *rdsdatatest.Fake) {
+	fake.ExpectBegin().WillReturnTransactionID("lock")
+	fake.ExpectStatement("SELECT pg_try_advisory_xact_lock(:1)").
+		WithParameters(rdsdatatest.Param("1", rdsdatatest.Long(lockKey("rdsdata-migrate:schema_migrations")))).
+		InTransaction().
+		WillReturnRecords([]types.ColumnMetadata{rdsdatatest.Column("pg_try_advisory_xact_lock", "bool")}, []types.Field{rdsdatatest.Bool(true)})
+}
+
+func expectMySQLLock(fake *rdsdatatest.Fake, result int64) {
+	fake.ExpectBegin().WillReturnTransactionID("lock")
+	fake.ExpectStatement("SELECT GET_LOCK(:1, 0)").
+		WithParameters(rdsdatatest.Param("1", rdsdatatest.String("rdsdata-migrate:schema_migrations"))).
+		InTransaction().
+		WillReturnRecords([]types.ColumnMetadata{rdsdatatest.Column("GET_LOCK", "BIGINT")}, []types.Field{rdsdatatest.Long(result)})
+}
+
+func expectMySQLUnlock(fake *rdsdatatest.Fake) {
+	fake.ExpectStatement("SELECT RELEASE_LOCK(:1)").InTransaction().WillReturnResult(0)
+	fake.ExpectRollback()
+}
+
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"2_add_name.up.sql":       {Data: []byte("ALTER TABLE users ADD COLUMN name TEXT;\n")},
+		"2_add_name.down.sql":     {Data: []byte("ALTER TABLE users DROP COLUMN name;\n")},
+		"1_create_users.up.sql":   {Data: []byte("CREATE TABLE users (id BIGINT PRIMARY KEY);\n")},
+		"README.md":               {Data: []byte("# migrations\n")},
+		"10_no_down.up.sql":       {Data: []byte("SELECT 1;\n")},
+		"old/3_ignored.up.sql":    {Data: []byte("SELECT 1;\n")},
+		"1_create_users.down.sql": {Data: []byte("DROP TABLE users;\n")},
+	}
+	migrations, err := Load(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) != 3 {
+		t.Fatalf("unexpected migrations: %d", len(migrations))
+	}
+	want := []struct {
+		version int64
+		name    string
+		down    bool
+	}{
+		{1, "create_users", true},
+		{2, "add_name", true},
+		{10, "no_down", false},
+	}
+	for i, w := range want {
+		got := migrations[i]
+		if got.Version != w.version || got.Name != w.name || (got.Down != "") != w.down {
+			t.Errorf("migration %d: got %+v, want %+v", i, got, w)
+		}
+	}
+}
+
+func TestLoad_Error(t *testing.T) {
+	tests := []struct {
+		name string
+		fsys fstest.MapFS
+	}{
+		{
+			name: "duplicated version",
+			fsys: fstest.MapFS{
+				"1_a.up.sql": {Data: []byte("SELECT 1;")},
+				"1_b.up.sql": {Data: []byte("SELECT 1;")},
+			},
+		},
+		{
+			name: "missing up",
+			fsys: fstest.MapFS{
+				"1_a.down.sql": {Data: []byte("SELECT 1;")},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Load(tt.fsys); err == nil {
+				t.Error("want error, got nil")
+			}
+		})
+	}
+}
+
+func TestNew_InvalidTable(t *testing.T) {
+	fake := rdsdatatest.New()
+	db := fake.OpenDB(nil)
+	defer db.Close()
+
+	if _, err := New(db, testMigrations, WithTable("users; DROP TABLE users")); err == nil {
+		t.Error("want error, got nil")
+	}
+}
+
+func TestUp_Postgres(t *testing.T) {
+	fake := rdsdatatest.New()
+	fake.Version = rdsdatatest.VersionPostgres
+	expectPostgresLock(fake)
+	expectApplied(fake, rdsdatatest.Null(), 1)
+
+	// each migration runs in a transaction.
+	fake.ExpectBegin().WillReturnTransactionID("migration")
+	fake.ExpectStatement("ALTER TABLE users ADD COLUMN name TEXT").InTransaction()
+	fake.ExpectStatement("INSERT INTO schema_migrations (version, name, dirty) VALUES (:1, :2, :3)").
+		WithParameters(
+			rdsdatatest.Param("1", rdsdatatest.Long(2)),
+			rdsdatatest.Param("2", rdsdatatest.String("add_name")),
+			rdsdatatest.Param("3", rdsdatatest.Bool(false)),
+		).
+		InTransaction()
+	fake.ExpectCommit()
+
+	// release the lock.
+	fake.ExpectRollback()
+
+	db := fake.OpenDB(nil)
+	defer db.Close()
+	m, err := New(db, testMigrations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := fake.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestUp_MySQL(t *testing.T) {
+	fake := rdsdatatest.New()
+	expectMySQLLock(fake, 1)
+	expectApplied(fake, rdsdatatest.Null())
+
+	// the migrations run without transactions, and are marked as dirty while they run.
+	fake.ExpectStatement("INSERT INTO schema_migrations (version, name, dirty) VALUES (:1, :2, :3)").
+		WithParameters(
+			rdsdatatest.Param("1", rdsdatatest.Long(1)),
+			rdsdatatest.Param("2", rdsdatatest.String("create_users")),
+			rdsdatatest.Param("3", rdsdatatest.Long(1)),
+		).
+		OutsideTransaction()
+	fake.ExpectStatement("CREATE TABLE users (id BIGINT PRIMARY KEY)").OutsideTransaction()
+	fake.ExpectStatement("CREATE INDEX users_id ON users (id)").OutsideTransaction()
+	fake.ExpectStatement("UPDATE schema_migrations SET dirty = :1 WHERE version = :2").
+		WithParameters(
+			rdsdatatest.Param("1", rdsdatatest.Long(0)),
+			rdsdatatest.Param("2", rdsdatatest.Long(1)),
+		)
+	fake.ExpectStatement("INSERT INTO schema_migrations (version, name, dirty) VALUES (:1, :2, :3)")
+	fake.ExpectStatement("ALTER TABLE users ADD COLUMN name TEXT").OutsideTransaction()
+	fake.ExpectStatement("UPDATE schema_migrations SET dirty = :1 WHERE version = :2")
+	expectMySQLUnlock(fake)
+
+	db := fake.OpenDB(nil)
+	defer db.Close()
+	m, err := New(db, testMigrations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := fake.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestUp_Dirty(t *testing.T) {
+	fake := rdsdatatest.New()
+	expectMySQLLock(fake, 1)
+	expectApplied(fake, rdsdatatest.Long(2))
+	expectMySQLUnlock(fake)
+
+	db := fake.OpenDB(nil)
+	defer db.Close()
+	m, err := New(db, testMigrations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = m.Up(context.Background())
+	var dirty *DirtyError
+	if !errors.As(err, &dirty) {
+		t.Fatalf("want DirtyError, got %v", err)
+	}
+	if dirty.Version != 2 {
+		t.Errorf("unexpected version: %d", dirty.Version)
+	}
+	if err := fake.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestUp_Locked(t *testing.T) {
+	fake := rdsdatatest.New()
+	expectMySQLLock(fake, 0)
+	fake.ExpectStatement("SELECT GET_LOCK(:1, 0)").InTransaction().
+		WillReturnRecords([]types.ColumnMetadata{rdsdatatest.Column("GET_LOCK", "BIGINT")}, []types.Field{rdsdatatest.Long(0)})
+	fake.ExpectRollback()
+
+	db := fake.OpenDB(nil)
+	defer db.Close()
+	m, err := New(db, testMigrations, WithLockTimeout(5*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.pollInterval = 10 * time.Millisecond
+	if err := m.Up(context.Background()); !errors.Is(err, ErrLocked) {
+		t.Errorf("want ErrLocked, got %v", err)
+	}
+	if err := fake.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDown_Postgres(t *testing.T) {
+	fake := rdsdatatest.New()
+	fake.Version = rdsdatatest.VersionPostgres
+	expectPostgresLock(fake)
+	expectApplied(fake, rdsdatatest.Null(), 1, 2)
+	fake.ExpectBegin().WillReturnTransactionID("migration")
+	fake.ExpectStatement("ALTER TABLE users DROP COLUMN name").InTransaction()
+	fake.ExpectStatement("DELETE FROM schema_migrations WHERE version = :1").
+		WithParameters(rdsdatatest.Param("1", rdsdatatest.Long(2))).
+		InTransaction()
+	fake.ExpectCommit()
+	fake.ExpectRollback()
+
+	db := fake.OpenDB(nil)
+	defer db.Close()
+	m, err := New(db, testMigrations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Down(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := fake.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestUp_Error(t *testing.T) {
+	fake := rdsdatatest.New()
+	fake.Version = rdsdatatest.VersionPostgres
+	expectPostgresLock(fake)
+	expectApplied(fake, rdsdatatest.Null())
+	fake.ExpectBegin().WillReturnTransactionID("migration")
+	fake.ExpectStatement("CREATE TABLE users (id BIGINT PRIMARY KEY)").InTransaction().
+		WillReturnError(errors.New("relation \"users\" already exists"))
+	fake.ExpectRollback()
+	fake.ExpectRollback()
+
+	db := fake.OpenDB(nil)
+	defer db.Close()
+	m, err := New(db, testMigrations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = m.Up(context.Background())
+	if err == nil || err.Error() != `migrate: 1_create_users.up.sql: relation "users" already exists` {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := fake.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDown_InvalidSteps(t *testing.T) {
+	fake := rdsdatatest.New()
+	db := fake.OpenDB(nil)
+	defer db.Close()
+	m, err := New(db, testMigrations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, steps := range []int{0, -1} {
+		if err := m.Down(context.Background(), steps); err == nil {
+			t.Errorf("Down(%d): want error, got nil", steps)
+		}
+	}
+	if err := fake.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	fake := rdsdatatest.New()
+	fake.ExpectStatement("SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = COALESCE(NULLIF(:1, ''), DATABASE()) AND table_name = :2").
+		WithParameters(
+			rdsdatatest.Param("1", rdsdatatest.String("")),
+			rdsdatatest.Param("2", rdsdatatest.String("schema_migrations")),
+		).
+		WillReturnRecords([]types.ColumnMetadata{rdsdatatest.Column("COUNT(*)", "BIGINT")}, []types.Field{rdsdatatest.Long(1)})
+	fake.ExpectStatement("SELECT version, name, dirty FROM schema_migrations ORDER BY version").
+		WillReturnRecords(
+			[]types.ColumnMetadata{
+				rdsdatatest.Column("version", "BIGINT"),
+				rdsdatatest.Column("name", "VARCHAR"),
+				rdsdatatest.Column("dirty", "BIT"),
+			},
+			[]types.Field{rdsdatatest.Long(1), rdsdatatest.String("create_users"), rdsdatatest.Bool(false)},
+		)
+
+	db := fake.OpenDB(nil)
+	defer db.Close()
+	m, err := New(db, testMigrations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := m.Status(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Status{
+		{Version: 1, Name: "create_users", Applied: true},
+		{Version: 2, Name: "add_name"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("unexpected status: %+v", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("status %d: got %+v, want %+v", i, got[i], want[i])
+		}
+	}
+	if err := fake.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestStatus_NoTable(t *testing.T) {
+	// Status doesn't create the version table.
+	fake := rdsdatatest.New()
+	fake.Version = rdsdatatest.VersionPostgres
+	fake.ExpectStatement("SELECT to_regclass(:1) IS NOT NULL").
+		WithParameters(rdsdatatest.Param("1", rdsdatatest.String("schema_migrations"))).
+		WillReturnRecords([]types.ColumnMetadata{rdsdatatest.Column("?column?", "bool")}, []types.Field{rdsdatatest.Bool(false)})
+
+	db := fake.OpenDB(nil)
+	defer db.Close()
+	m, err := New(db, testMigrations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := m.Status(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Status{
+		{Version: 1, Name: "create_users"},
+		{Version: 2, Name: "add_name"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("unexpected status: %+v", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("status %d: got %+v, want %+v", i, got[i], want[i])
+		}
+	}
+	if err := fake.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestHasNoTransaction(t *testing.T) {
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{"-- rdsdata:no-transaction\nCREATE INDEX CONCURRENTLY idx ON users (name);", true},
+		{"CREATE INDEX CONCURRENTLY idx ON users (name);\n  -- rdsdata:no-transaction  \n", true},
+		{"CREATE INDEX idx ON users (name); -- rdsdata:no-transaction", false},
+		{"CREATE INDEX idx ON users (name);", false},
+	}
+	for _, tt := range tests {
+		if got := HasNoTransaction(tt.query); got != tt.want {
+			t.Errorf("HasNoTransaction(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestDetectEngine(t *testing.T) {
+	for _, want := range []rdsdata.Engine{rdsdata.EngineMySQL, rdsdata.EnginePostgres} {
+		fake := rdsdatatest.New()
+		if want == rdsdata.EnginePostgres {
+			fake.Version = rdsdatatest.VersionPostgres
+		}
+		db := fake.OpenDB(nil)
+		got, err := DetectEngine(context.Background(), db)
+		db.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("DetectEngine() = %q, want %q", got, want)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package migrate
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/shogo82148/go-rdsdata"
+	"github.com/shogo82148/go-rdsdata/internal/sqllex"
+)
+
+// SplitStatements splits the SQL into the statements in the syntax of the engine,
+// in the same way as Migrator does.
+// It is useful to run multi-statement scripts through the Data API,
+// which executes only one statement per call.
+func SplitStatements(query string, engine rdsdata.Engine) ([]string, error) {
+	return splitStatements(query, engine)
+}
+
+// splitStatements splits the migration into the statements,
+// because the Data API executes only one statement per call.
+//
+// Semicolons in quoted strings, quoted identifiers and comments are ignored.
+// PostgreSQL dollar-quoted strings are kept as is, so the bodies of functions can contain semicolons.
+// For MySQL, the DELIMITER directive changes the terminator as the mysql command does,
+// which is needed to define triggers and stored procedures.
+// Statements that consist only of comments are dropped.
+func splitStatements(input string, engine rdsdata.Engine) ([]string, error) {
+	mysql := engine == rdsdata.EngineMySQL
+	var stmts []string
+	delimiter := ";"
+	start := 0
+	hasCode := false
+	flush := func(end int) {
+		if hasCode {
+			stmts = append(stmts, strings.TrimSpace(input[start:end]))
+		}
+		hasCode = false
+	}
+
+	s := sqllex.NewScanner(sqllex.ForEngine(string(engine)), input)
+	for {
+		pos := s.Pos()
+		if mysql && !hasCode && isLineStart(input, pos) && hasPrefixFold(input[pos:], "DELIMITER") {
+			// the DELIMITER directive is interpreted by the client, and is not sent to the server.
+			end := pos + strings.IndexByte(input[pos:]+"\n", '\n')
+			fields := strings.Fields(input[pos:end])
+			if len(fields) != 2 || !strings.EqualFold(fields[0], "DELIMITER") {
+				return nil, errors.New("migrate: invalid DELIMITER directive")
+			}
+			delimiter = fields[1]
+			s.Seek(end)
+			start = end
+			continue
+		}
+
+		token, ok := s.Next()
+		if !ok {
+			break
+		}
+		switch {
+		case token.Unterminated && token.Kind == sqllex.Comment:
+			return nil, errors.New("migrate: unterminated block comment")
+		case token.Unterminated && strings.HasPrefix(token.Text, "$"):
+			return nil, errors.New("migrate: unterminated dollar-quoted string")
+		case token.Unterminated:
+			return nil, errors.New("migrate: unterminated quoted string")
+		case (token.Kind == sqllex.Operator || token.Kind == sqllex.Word) && strings.HasPrefix(input[pos:], delimiter):
+			flush(pos)
+			s.Seek(pos + len(delimiter))
+			start = s.Pos()
+		case token.IsCode():
+			hasCode = true
+		}
+	}
+	flush(len(input))
+	return stmts, nil
+}
+
+// isLineStart reports whether input[i] is the first non-space character of the line.
+func isLineStart(input string, i int) bool {
+	for j := i - 1; j >= 0; j-- {
+		if input[j] == '\n' {
+			return true
+		}
+		if !isSpace(input[j]) {
+			return false
+		}
+	}
+	return true
+}
+
+func hasPrefixFold(s, prefix string) bool {
+	return len(s) > len(prefix) && strings.EqualFold(s[:len(prefix)], prefix) && isSpace(s[len(prefix)])
+}
+
+func isSpace(ch byte) bool {
+	return ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r' || ch == '\f' || ch == '\v'
+}
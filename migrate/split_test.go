@@ -0,0 +1,113 @@
+package migrate
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/shogo82148/go-rdsdata"
+)
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		name   string
+		engine rdsdata.Engine
+		input  string
+		want   []string
+	}{
+		{
+			name:   "simple",
+			engine: rdsdata.EngineMySQL,
+			input:  "CREATE TABLE a (id INT);\nCREATE TABLE b (id INT);\n",
+			want:   []string{"CREATE TABLE a (id INT)", "CREATE TABLE b (id INT)"},
+		},
+		{
+			name:   "without the last semicolon",
+			engine: rdsdata.EngineMySQL,
+			input:  "SELECT 1; SELECT 2",
+			want:   []string{"SELECT 1", "SELECT 2"},
+		},
+		{
+			name:   "quoted",
+			engine: rdsdata.EngineMySQL,
+			input:  "INSERT INTO a VALUES (';', \"it\\\"s;\", `;`); SELECT 1;",
+			want:   []string{"INSERT INTO a VALUES (';', \"it\\\"s;\", `;`)", "SELECT 1"},
+		},
+		{
+			name:   "comments",
+			engine: rdsdata.EngineMySQL,
+			input:  "-- comment;\n# comment;\n/* comment; */\nSELECT 1;\n-- trailing comment\n",
+			want:   []string{"-- comment;\n# comment;\n/* comment; */\nSELECT 1"},
+		},
+		{
+			name:   "delimiter",
+			engine: rdsdata.EngineMySQL,
+			input: "DELIMITER //\n" +
+				"CREATE TRIGGER t BEFORE INSERT ON a FOR EACH ROW BEGIN SET NEW.x = 1; END//\n" +
+				"DELIMITER ;\n" +
+				"SELECT 1;\n",
+			want: []string{
+				"CREATE TRIGGER t BEFORE INSERT ON a FOR EACH ROW BEGIN SET NEW.x = 1; END",
+				"SELECT 1",
+			},
+		},
+		{
+			name:   "dollar quoted",
+			engine: rdsdata.EnginePostgres,
+			input: "CREATE FUNCTION f() RETURNS int AS $body$ BEGIN RETURN 1; END; $body$ LANGUAGE plpgsql;\n" +
+				"SELECT $$;$$;",
+			want: []string{
+				"CREATE FUNCTION f() RETURNS int AS $body$ BEGIN RETURN 1; END; $body$ LANGUAGE plpgsql",
+				"SELECT $$;$$",
+			},
+		},
+		{
+			name:   "postgres backslash",
+			engine: rdsdata.EnginePostgres,
+			input:  `SELECT 'C:\'; SELECT 1;`,
+			want:   []string{`SELECT 'C:\'`, "SELECT 1"},
+		},
+		{
+			name:   "postgres nested comments",
+			engine: rdsdata.EnginePostgres,
+			input:  "/* outer /* inner; */ still comment; */ SELECT 1;",
+			want:   []string{"/* outer /* inner; */ still comment; */ SELECT 1"},
+		},
+		{
+			name:   "postgres hash",
+			engine: rdsdata.EnginePostgres,
+			input:  "SELECT '{}'::jsonb # '{a}'; SELECT 1;",
+			want:   []string{"SELECT '{}'::jsonb # '{a}'", "SELECT 1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := splitStatements(tt.input, tt.engine)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitStatements_Error(t *testing.T) {
+	tests := []struct {
+		name   string
+		engine rdsdata.Engine
+		input  string
+	}{
+		{"unterminated string", rdsdata.EngineMySQL, "SELECT 'abc;"},
+		{"unterminated comment", rdsdata.EngineMySQL, "SELECT 1; /* comment"},
+		{"unterminated dollar quoted", rdsdata.EnginePostgres, "SELECT $tag$ abc;"},
+		{"invalid delimiter", rdsdata.EngineMySQL, "DELIMITER\t\nSELECT 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := splitStatements(tt.input, tt.engine); err == nil {
+				t.Error("want error, got nil")
+			}
+		})
+	}
+}
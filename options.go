@@ -0,0 +1,118 @@
+package rdsdata
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata"
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata/types"
+)
+
+// Option is an option of the statements, which is set by WithOptions.
+type Option func(*callOptions)
+
+// callOptions are the options of the statements executed with a context.
+type callOptions struct {
+	database             *string
+	schema               *string
+	resultSetOptions     *types.ResultSetOptions
+	continueAfterTimeout *bool
+	timeout              *time.Duration
+	idempotent           bool
+	clientOptions        []func(*rdsdata.Options)
+}
+
+type callOptionsKey struct{}
+
+// WithOptions returns a new context that overrides the options of the statements executed with it.
+// The options are added to the options that ctx already has.
+//
+//	ctx = rdsdata.WithOptions(ctx, rdsdata.Database("reports"), rdsdata.Timeout(5*time.Minute))
+//	rows, err := db.QueryContext(ctx, "SELECT ...")
+func WithOptions(ctx context.Context, opts ...Option) context.Context {
+	o := optionsFromContext(ctx)
+	o.clientOptions = slices.Clone(o.clientOptions)
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return context.WithValue(ctx, callOptionsKey{}, o)
+}
+
+// optionsFromContext returns the options set by WithOptions.
+func optionsFromContext(ctx context.Context) callOptions {
+	o, _ := ctx.Value(callOptionsKey{}).(callOptions)
+	return o
+}
+
+// Database overrides Config.Database.
+// A transaction is bound to the database of the context passed to BeginTx,
+// and the statements in it fail if they override it with another database.
+func Database(name string) Option {
+	return func(o *callOptions) {
+		o.database = &name
+	}
+}
+
+// Schema overrides Config.Schema.
+// The empty name disables it, e.g. for the statements that can't run in a transaction.
+func Schema(name string) Option {
+	return func(o *callOptions) {
+		o.schema = &name
+	}
+}
+
+// ResultSetOptions sets the options of the result sets,
+// e.g. the return types of DECIMAL and BIGINT values.
+func ResultSetOptions(opts types.ResultSetOptions) Option {
+	return func(o *callOptions) {
+		o.resultSetOptions = &opts
+	}
+}
+
+// ContinueAfterTimeout overrides Config.ContinueAfterTimeout.
+func ContinueAfterTimeout(continueAfterTimeout bool) Option {
+	return func(o *callOptions) {
+		o.continueAfterTimeout = &continueAfterTimeout
+	}
+}
+
+// Timeout overrides Config.Timeout.
+// Zero disables the timeout.
+func Timeout(timeout time.Duration) Option {
+	return func(o *callOptions) {
+		o.timeout = &timeout
+	}
+}
+
+// Idempotent marks the statements as idempotent, i.e. safe to execute more than once.
+// Config.Failover sends an idempotent statement to the next endpoint on any endpoint-level error.
+// The other statements fail over only if the error guarantees that they have not been executed,
+// e.g. the database is unavailable or the call is throttled.
+func Idempotent() Option {
+	return func(o *callOptions) {
+		o.idempotent = true
+	}
+}
+
+// ClientOptions adds the options of the RDS Data API client for the calls,
+// e.g. the retryer or the middlewares.
+func ClientOptions(optFns ...func(*rdsdata.Options)) Option {
+	return func(o *callOptions) {
+		o.clientOptions = append(o.clientOptions, optFns...)
+	}
+}
+
+// apply sets the options to the input.
+// The schema is not set, because the Data API doesn't support it; see Stmt.executeInSchema.
+func (o *callOptions) apply(in *rdsdata.ExecuteStatementInput) {
+	if o.database != nil {
+		in.Database = o.database
+	}
+	if o.resultSetOptions != nil {
+		in.ResultSetOptions = o.resultSetOptions
+	}
+	if o.continueAfterTimeout != nil {
+		in.ContinueAfterTimeout = *o.continueAfterTimeout
+	}
+}
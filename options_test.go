@@ -0,0 +1,124 @@
+package rdsdata
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata"
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata/types"
+)
+
+func TestWithOptions(t *testing.T) {
+	var got *rdsdata.ExecuteStatementInput
+	var gotOptions rdsdata.Options
+	db := sql.OpenDB(NewConnector(&Config{
+		ResourceArn: "arn:aws:rds:us-east-1:123456789012:cluster:cluster",
+		SecretArn:   "arn:aws:secretsmanager:us-east-1:123456789012:secret:secretArn",
+		Database:    "app",
+		Engine:      EngineMySQL,
+		Client: &awsClientMock{
+			ExecuteStatementFunc: func(ctx context.Context, input *rdsdata.ExecuteStatementInput, optFns ...func(*rdsdata.Options)) (*rdsdata.ExecuteStatementOutput, error) {
+				got = input
+				gotOptions = rdsdata.Options{}
+				for _, fn := range optFns {
+					fn(&gotOptions)
+				}
+				return &rdsdata.ExecuteStatementOutput{}, nil
+			},
+		},
+	}))
+	defer db.Close()
+
+	t.Run("default", func(t *testing.T) {
+		if _, err := db.ExecContext(context.Background(), "DELETE FROM users"); err != nil {
+			t.Fatal(err)
+		}
+		if aws.ToString(got.Database) != "app" {
+			t.Errorf("unexpected Database: %q", aws.ToString(got.Database))
+		}
+		if got.Schema != nil || got.ResultSetOptions != nil || got.ContinueAfterTimeout {
+			t.Errorf("unexpected input: %#v", got)
+		}
+	})
+
+	t.Run("overrides", func(t *testing.T) {
+		ctx := WithOptions(context.Background(), Database("reports"))
+		ctx = WithOptions(ctx,
+			ResultSetOptions(types.ResultSetOptions{DecimalReturnType: types.DecimalReturnTypeString}),
+			ContinueAfterTimeout(true),
+			Timeout(time.Minute),
+			ClientOptions(func(o *rdsdata.Options) { o.Region = "us-west-2" }),
+		)
+		if _, err := db.ExecContext(ctx, "DELETE FROM users"); err != nil {
+			t.Fatal(err)
+		}
+		if aws.ToString(got.Database) != "reports" {
+			t.Errorf("unexpected Database: %q", aws.ToString(got.Database))
+		}
+		if got.ResultSetOptions == nil || got.ResultSetOptions.DecimalReturnType != types.DecimalReturnTypeString {
+			t.Errorf("unexpected ResultSetOptions: %#v", got.ResultSetOptions)
+		}
+		if !got.ContinueAfterTimeout {
+			t.Error("want ContinueAfterTimeout")
+		}
+		if gotOptions.Region != "us-west-2" {
+			t.Errorf("unexpected client options: %#v", gotOptions)
+		}
+		if timeout := statementTimeout(ctx, &Config{}); timeout != time.Minute {
+			t.Errorf("unexpected timeout: %s", timeout)
+		}
+	})
+}
+
+func TestWithOptions_Transaction(t *testing.T) {
+	var got []string
+	db := sql.OpenDB(NewConnector(&Config{
+		ResourceArn: "arn:aws:rds:us-east-1:123456789012:cluster:cluster",
+		SecretArn:   "arn:aws:secretsmanager:us-east-1:123456789012:secret:secretArn",
+		Database:    "app",
+		Engine:      EngineMySQL,
+		Client: &awsClientMock{
+			BeginTransactionFunc: func(ctx context.Context, input *rdsdata.BeginTransactionInput, optFns ...func(*rdsdata.Options)) (*rdsdata.BeginTransactionOutput, error) {
+				got = append(got, "BEGIN @"+aws.ToString(input.Database))
+				return &rdsdata.BeginTransactionOutput{TransactionId: aws.String("tx")}, nil
+			},
+			ExecuteStatementFunc: func(ctx context.Context, input *rdsdata.ExecuteStatementInput, optFns ...func(*rdsdata.Options)) (*rdsdata.ExecuteStatementOutput, error) {
+				got = append(got, aws.ToString(input.Sql)+" @"+aws.ToString(input.Database))
+				return &rdsdata.ExecuteStatementOutput{}, nil
+			},
+			CommitTransactionFunc: func(ctx context.Context, input *rdsdata.CommitTransactionInput, optFns ...func(*rdsdata.Options)) (*rdsdata.CommitTransactionOutput, error) {
+				got = append(got, "COMMIT")
+				return &rdsdata.CommitTransactionOutput{}, nil
+			},
+		},
+	}))
+	defer db.Close()
+
+	ctx := WithOptions(context.Background(), Database("reports"))
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.ExecContext(context.Background(), "DELETE FROM users"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM posts"); err != nil {
+		t.Fatal(err)
+	}
+	// the statements can't switch the database of the transaction.
+	if _, err := tx.ExecContext(WithOptions(ctx, Database("app")), "DELETE FROM tags"); err == nil {
+		t.Error("want error, got nil")
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"BEGIN @reports", "DELETE FROM users @reports", "DELETE FROM posts @reports", "COMMIT"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("unexpected calls: %q, want %q", got, want)
+	}
+}
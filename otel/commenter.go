@@ -0,0 +1,18 @@
+package otel
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// CommentTags returns the traceparent and tracestate tags of the span in ctx
+// in the W3C Trace Context format, which sqlcommenter uses.
+// It is intended for rdsdata.Commenter.Tags:
+//
+//	cfg.Commenter = &rdsdata.Commenter{Application: "app", Tags: otel.CommentTags}
+func CommentTags(ctx context.Context) map[string]string {
+	carrier := propagation.MapCarrier{}
+	propagation.TraceContext{}.Inject(ctx, carrier)
+	return carrier
+}
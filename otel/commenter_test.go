@@ -0,0 +1,28 @@
+package otel
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestCommentTags(t *testing.T) {
+	traceID, _ := trace.TraceIDFromHex("0af7651916cd43dd8448eb211c80319c")
+	spanID, _ := trace.SpanIDFromHex("b7ad6b7169203331")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	}))
+
+	tags := CommentTags(ctx)
+	want := "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"
+	if tags["traceparent"] != want {
+		t.Errorf("unexpected traceparent: %q, want %q", tags["traceparent"], want)
+	}
+
+	if tags := CommentTags(context.Background()); len(tags) != 0 {
+		t.Errorf("unexpected tags without a span: %v", tags)
+	}
+}
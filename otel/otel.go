@@ -0,0 +1,303 @@
+// Package otel provides OpenTelemetry tracing and metrics for the RDS Data API driver.
+package otel
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata"
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata/types"
+	rdsdatadriver "github.com/shogo82148/go-rdsdata"
+	otelapi "go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/shogo82148/go-rdsdata/otel"
+
+// Attribute keys specific to the RDS Data API.
+const (
+	// ResourceArnKey is the ARN of the Aurora cluster.
+	ResourceArnKey = attribute.Key("aws.rds_data.resource_arn")
+
+	// TransactionIDKey is the ID of the Data API transaction.
+	TransactionIDKey = attribute.Key("aws.rds_data.transaction_id")
+
+	// AttemptKey is the number of the attempt, starting from 1.
+	AttemptKey = attribute.Key("aws.rds_data.attempt")
+
+	// RecordsKey is the number of records returned.
+	RecordsKey = attribute.Key("aws.rds_data.records")
+
+	// RowsAffectedKey is the number of records updated.
+	RowsAffectedKey = attribute.Key("aws.rds_data.rows_affected")
+)
+
+// Option is an option for NewHooks and NewConnector.
+type Option func(*config)
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	meterProvider  metric.MeterProvider
+	system         attribute.KeyValue
+	engine         rdsdatadriver.Engine
+	redact         func(query string) string
+}
+
+// WithTracerProvider sets the tracer provider.
+// The default is the global tracer provider.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tracerProvider = provider
+	}
+}
+
+// WithMeterProvider sets the meter provider.
+// The default is the global meter provider.
+func WithMeterProvider(provider metric.MeterProvider) Option {
+	return func(c *config) {
+		c.meterProvider = provider
+	}
+}
+
+// WithDBSystem sets the value of the db.system attribute, e.g. "mysql" or "postgresql".
+// The default is "other_sql".
+func WithDBSystem(system string) Option {
+	return func(c *config) {
+		c.system = semconv.DBSystemKey.String(system)
+	}
+}
+
+// WithEngine sets the engine of the cluster, which the default redactor uses to find the literals.
+// NewConnector uses Config.Engine by default.
+func WithEngine(engine rdsdatadriver.Engine) Option {
+	return func(c *config) {
+		c.engine = engine
+	}
+}
+
+// WithStatementRedactor sets the function that redacts the db.statement attribute.
+// The default is RedactLiterals with the engine set by WithEngine.
+// If redact returns an empty string, the db.statement attribute is omitted.
+func WithStatementRedactor(redact func(query string) string) Option {
+	return func(c *config) {
+		c.redact = redact
+	}
+}
+
+// NewConnector returns a new connector that is instrumented with OpenTelemetry.
+func NewConnector(cfg *rdsdatadriver.Config, opts ...Option) (*rdsdatadriver.Connector, error) {
+	hooks, err := NewHooks(append([]Option{WithEngine(cfg.Engine)}, opts...)...)
+	if err != nil {
+		return nil, err
+	}
+	cfg = cfg.Clone()
+	cfg.Hooks = rdsdatadriver.MultiHooks(cfg.Hooks, hooks)
+	return rdsdatadriver.NewConnector(cfg), nil
+}
+
+// NewHooks returns a new rdsdata.Hooks that emits spans and metrics.
+func NewHooks(opts ...Option) (rdsdatadriver.Hooks, error) {
+	c := &config{
+		tracerProvider: otelapi.GetTracerProvider(),
+		meterProvider:  otelapi.GetMeterProvider(),
+		system:         semconv.DBSystemOtherSQL,
+	}
+	c.redact = func(query string) string {
+		return RedactLiterals(c.engine, query)
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	meter := c.meterProvider.Meter(instrumentationName)
+	duration, err := meter.Float64Histogram(
+		"db.client.operation.duration",
+		metric.WithDescription("Duration of the Data API calls."),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("otel: failed to create the duration histogram: %w", err)
+	}
+	size, err := meter.Int64Histogram(
+		"aws.rds_data.response.size",
+		metric.WithDescription("Estimated size of the records returned by the Data API calls."),
+		metric.WithUnit("By"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("otel: failed to create the response size histogram: %w", err)
+	}
+
+	return &hooks{
+		tracer:   c.tracerProvider.Tracer(instrumentationName),
+		system:   c.system,
+		redact:   c.redact,
+		duration: duration,
+		size:     size,
+	}, nil
+}
+
+type hooks struct {
+	tracer   trace.Tracer
+	system   attribute.KeyValue
+	redact   func(query string) string
+	duration metric.Float64Histogram
+	size     metric.Int64Histogram
+}
+
+var _ rdsdatadriver.Hooks = (*hooks)(nil)
+
+func (h *hooks) BeforeQuery(ctx context.Context, in *rdsdata.ExecuteStatementInput) context.Context {
+	return h.startStatement(ctx, "Query", in)
+}
+
+func (h *hooks) AfterQuery(ctx context.Context, in *rdsdata.ExecuteStatementInput, info *rdsdatadriver.HookInfo) {
+	h.end(ctx, "Query", info)
+}
+
+func (h *hooks) BeforeExec(ctx context.Context, in *rdsdata.ExecuteStatementInput) context.Context {
+	return h.startStatement(ctx, "Exec", in)
+}
+
+func (h *hooks) AfterExec(ctx context.Context, in *rdsdata.ExecuteStatementInput, info *rdsdatadriver.HookInfo) {
+	h.end(ctx, "Exec", info)
+}
+
+func (h *hooks) BeforeBegin(ctx context.Context, in *rdsdata.BeginTransactionInput) context.Context {
+	return h.start(ctx, "BeginTransaction",
+		semconv.DBName(aws.ToString(in.Database)),
+		ResourceArnKey.String(aws.ToString(in.ResourceArn)),
+	)
+}
+
+func (h *hooks) AfterBegin(ctx context.Context, in *rdsdata.BeginTransactionInput, info *rdsdatadriver.HookInfo) {
+	h.end(ctx, "BeginTransaction", info)
+}
+
+func (h *hooks) BeforeCommit(ctx context.Context, in *rdsdata.CommitTransactionInput) context.Context {
+	return h.start(ctx, "CommitTransaction",
+		ResourceArnKey.String(aws.ToString(in.ResourceArn)),
+	)
+}
+
+func (h *hooks) AfterCommit(ctx context.Context, in *rdsdata.CommitTransactionInput, info *rdsdatadriver.HookInfo) {
+	h.end(ctx, "CommitTransaction", info)
+}
+
+func (h *hooks) BeforeRollback(ctx context.Context, in *rdsdata.RollbackTransactionInput) context.Context {
+	return h.start(ctx, "RollbackTransaction",
+		ResourceArnKey.String(aws.ToString(in.ResourceArn)),
+	)
+}
+
+func (h *hooks) AfterRollback(ctx context.Context, in *rdsdata.RollbackTransactionInput, info *rdsdatadriver.HookInfo) {
+	h.end(ctx, "RollbackTransaction", info)
+}
+
+func (h *hooks) startStatement(ctx context.Context, operation string, in *rdsdata.ExecuteStatementInput) context.Context {
+	attrs := []attribute.KeyValue{
+		semconv.DBName(aws.ToString(in.Database)),
+		ResourceArnKey.String(aws.ToString(in.ResourceArn)),
+	}
+	if h.redact != nil {
+		if stmt := h.redact(aws.ToString(in.Sql)); stmt != "" {
+			attrs = append(attrs, semconv.DBStatement(stmt))
+		}
+	}
+	return h.start(ctx, operation, attrs...)
+}
+
+func (h *hooks) start(ctx context.Context, operation string, attrs ...attribute.KeyValue) context.Context {
+	attrs = append(attrs, h.system, semconv.DBOperation(operation))
+	ctx, _ = h.tracer.Start(ctx, "rdsdata."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+	return ctx
+}
+
+func (h *hooks) end(ctx context.Context, operation string, info *rdsdatadriver.HookInfo) {
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(AttemptKey.Int(info.Attempt))
+	if info.TransactionID != "" {
+		span.SetAttributes(TransactionIDKey.String(info.TransactionID))
+	}
+	if info.Output != nil {
+		span.SetAttributes(
+			RecordsKey.Int(info.Records),
+			RowsAffectedKey.Int64(info.RowsAffected),
+		)
+	}
+
+	metricAttrs := []attribute.KeyValue{h.system, semconv.DBOperation(operation)}
+	if info.Err != nil {
+		errType := semconv.ErrorTypeKey.String(fmt.Sprintf("%T", info.Err))
+		span.SetAttributes(errType)
+		span.RecordError(info.Err)
+		span.SetStatus(codes.Error, info.Err.Error())
+		metricAttrs = append(metricAttrs, errType)
+	}
+	span.End()
+
+	opt := metric.WithAttributes(metricAttrs...)
+	h.duration.Record(ctx, info.Duration.Seconds(), opt)
+	if info.Output != nil {
+		h.size.Record(ctx, responseSize(info.Output), opt)
+	}
+}
+
+// responseSize estimates the size of the records in bytes.
+func responseSize(out *rdsdata.ExecuteStatementOutput) int64 {
+	var size int64
+	if out.FormattedRecords != nil {
+		size += int64(len(*out.FormattedRecords))
+	}
+	for _, row := range out.Records {
+		for _, field := range row {
+			size += fieldSize(field)
+		}
+	}
+	return size
+}
+
+func fieldSize(field types.Field) int64 {
+	switch v := field.(type) {
+	case *types.FieldMemberStringValue:
+		return int64(len(v.Value))
+	case *types.FieldMemberBlobValue:
+		return int64(len(v.Value))
+	case *types.FieldMemberLongValue, *types.FieldMemberDoubleValue:
+		return 8
+	case *types.FieldMemberBooleanValue, *types.FieldMemberIsNull:
+		return 1
+	case *types.FieldMemberArrayValue:
+		return arraySize(v.Value)
+	default:
+		return 0
+	}
+}
+
+func arraySize(array types.ArrayValue) int64 {
+	var size int64
+	switch v := array.(type) {
+	case *types.ArrayValueMemberStringValues:
+		for _, s := range v.Value {
+			size += int64(len(s))
+		}
+	case *types.ArrayValueMemberLongValues:
+		size = int64(len(v.Value)) * 8
+	case *types.ArrayValueMemberDoubleValues:
+		size = int64(len(v.Value)) * 8
+	case *types.ArrayValueMemberBooleanValues:
+		size = int64(len(v.Value))
+	case *types.ArrayValueMemberArrayValues:
+		for _, a := range v.Value {
+			size += arraySize(a)
+		}
+	}
+	return size
+}
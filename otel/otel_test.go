@@ -0,0 +1,126 @@
+package otel
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata"
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata/types"
+	rdsdatadriver "github.com/shogo82148/go-rdsdata"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestHooks(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	hooks, err := NewHooks(
+		WithTracerProvider(tp),
+		WithMeterProvider(mp),
+		WithDBSystem("mysql"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	in := &rdsdata.ExecuteStatementInput{
+		ResourceArn:   aws.String("arn:aws:rds:us-east-1:123456789012:cluster:test"),
+		Database:      aws.String("database"),
+		Sql:           aws.String("SELECT * FROM users WHERE name = 'alice' AND id = :id"),
+		TransactionId: aws.String("transactionId"),
+	}
+	ctx := hooks.BeforeQuery(context.Background(), in)
+	hooks.AfterQuery(ctx, in, &rdsdatadriver.HookInfo{
+		TransactionID: "transactionId",
+		Duration:      100 * time.Millisecond,
+		Attempt:       2,
+		Records:       1,
+		Output: &rdsdata.ExecuteStatementOutput{
+			Records: [][]types.Field{
+				{&types.FieldMemberStringValue{Value: "alice"}},
+			},
+		},
+	})
+
+	errExec := errors.New("exec error")
+	ctx = hooks.BeforeExec(context.Background(), in)
+	hooks.AfterExec(ctx, in, &rdsdatadriver.HookInfo{
+		Attempt: 1,
+		Err:     errExec,
+	})
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("unexpected number of spans: %d, want 2", len(spans))
+	}
+
+	query := spans[0]
+	if query.Name() != "rdsdata.Query" {
+		t.Errorf("unexpected span name: %s", query.Name())
+	}
+	attrs := attribute.NewSet(query.Attributes()...)
+	want := map[attribute.Key]attribute.Value{
+		"db.system":                   attribute.StringValue("mysql"),
+		"db.name":                     attribute.StringValue("database"),
+		"db.statement":                attribute.StringValue("SELECT * FROM users WHERE name = ? AND id = :id"),
+		"aws.rds_data.resource_arn":   attribute.StringValue("arn:aws:rds:us-east-1:123456789012:cluster:test"),
+		"aws.rds_data.transaction_id": attribute.StringValue("transactionId"),
+		"aws.rds_data.attempt":        attribute.IntValue(2),
+		"aws.rds_data.records":        attribute.IntValue(1),
+		"aws.rds_data.rows_affected":  attribute.Int64Value(0),
+	}
+	for k, v := range want {
+		got, ok := attrs.Value(k)
+		if !ok {
+			t.Errorf("attribute %s is missing", k)
+			continue
+		}
+		if got != v {
+			t.Errorf("unexpected attribute %s: %v, want %v", k, got.Emit(), v.Emit())
+		}
+	}
+
+	exec := spans[1]
+	if exec.Status().Code != codes.Error {
+		t.Errorf("unexpected status: %v", exec.Status())
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	metrics := map[string]metricdata.Metrics{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			metrics[m.Name] = m
+		}
+	}
+	duration, ok := metrics["db.client.operation.duration"].Data.(metricdata.Histogram[float64])
+	if !ok {
+		t.Fatal("db.client.operation.duration is missing")
+	}
+	var count uint64
+	for _, dp := range duration.DataPoints {
+		count += dp.Count
+	}
+	if count != 2 {
+		t.Errorf("unexpected count of durations: %d, want 2", count)
+	}
+	size, ok := metrics["aws.rds_data.response.size"].Data.(metricdata.Histogram[int64])
+	if !ok {
+		t.Fatal("aws.rds_data.response.size is missing")
+	}
+	if len(size.DataPoints) != 1 || size.DataPoints[0].Sum != 5 {
+		t.Errorf("unexpected response size: %v", size.DataPoints)
+	}
+}
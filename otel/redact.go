@@ -0,0 +1,50 @@
+package otel
+
+import (
+	"strings"
+
+	rdsdatadriver "github.com/shogo82148/go-rdsdata"
+	"github.com/shogo82148/go-rdsdata/internal/sqllex"
+)
+
+// RedactLiterals replaces the string and numeric literals in the query with "?",
+// following the lexical rules of the engine, e.g. the dollar-quoted strings of PostgreSQL
+// and the backslash escapes of MySQL.
+// Placeholders (e.g. :name, $1), quoted identifiers and comments are kept as is.
+// If the engine is unknown, e.g. EngineAuto, the literals of both MySQL and PostgreSQL are redacted,
+// which may redact the double-quoted identifiers too.
+func RedactLiterals(engine rdsdatadriver.Engine, query string) string {
+	switch engine {
+	case rdsdatadriver.EngineMySQL:
+		return redactLiterals(query, sqllex.MySQL)
+	case rdsdatadriver.EnginePostgres:
+		return redactLiterals(query, sqllex.PostgreSQL)
+	}
+	return redactLiterals(query, sqllex.MySQL, sqllex.PostgreSQL)
+}
+
+// redactLiterals replaces the bytes that are literals in any of the syntaxes with "?".
+func redactLiterals(query string, syntaxes ...sqllex.Syntax) string {
+	literal := make([]bool, len(query))
+	for _, syntax := range syntaxes {
+		for _, token := range sqllex.Tokenize(syntax, query) {
+			if token.Kind == sqllex.String || token.Kind == sqllex.Number {
+				for i := token.Pos; i < token.End(); i++ {
+					literal[i] = true
+				}
+			}
+		}
+	}
+
+	var buf strings.Builder
+	buf.Grow(len(query))
+	for i := 0; i < len(query); i++ {
+		switch {
+		case !literal[i]:
+			buf.WriteByte(query[i])
+		case i == 0 || !literal[i-1]:
+			buf.WriteByte('?')
+		}
+	}
+	return buf.String()
+}
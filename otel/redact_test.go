@@ -0,0 +1,59 @@
+package otel
+
+import (
+	"testing"
+
+	rdsdata "github.com/shogo82148/go-rdsdata"
+)
+
+func TestRedactLiterals(t *testing.T) {
+	testCases := []struct {
+		engine rdsdata.Engine
+		query  string
+		want   string
+	}{
+		{
+			engine: rdsdata.EngineMySQL,
+			query:  "SELECT 1",
+			want:   "SELECT ?",
+		},
+		{
+			engine: rdsdata.EngineMySQL,
+			query:  "SELECT * FROM t1 WHERE name = 'alice' AND age > 20.5",
+			want:   "SELECT * FROM t1 WHERE name = ? AND age > ?",
+		},
+		{
+			engine: rdsdata.EnginePostgres,
+			query:  "SELECT * FROM t WHERE id = :1 OR id = $2",
+			want:   "SELECT * FROM t WHERE id = :1 OR id = $2",
+		},
+		{
+			engine: rdsdata.EngineMySQL,
+			query:  "SELECT 'it''s', 'a\\'b', \"c\" FROM `table1`",
+			want:   "SELECT ?, ?, ? FROM `table1`",
+		},
+		{
+			engine: rdsdata.EngineMySQL,
+			query:  "/* ping */ SELECT 1 -- comment 2\n",
+			want:   "/* ping */ SELECT ? -- comment 2\n",
+		},
+		{
+			engine: rdsdata.EnginePostgres,
+			query:  "SELECT $$secret$$, $tag$it's $$secret$$ too$tag$, E'it\\'s secret' FROM \"t\" WHERE id = $1",
+			want:   "SELECT ?, ?, ? FROM \"t\" WHERE id = $1",
+		},
+		{
+			// the engine is unknown: the literals of both engines are redacted.
+			engine: rdsdata.EngineAuto,
+			query:  "SELECT $$secret$$, 'it\\'s secret', \"secret\"",
+			want:   "SELECT ?, ?",
+		},
+	}
+
+	for _, tc := range testCases {
+		got := RedactLiterals(tc.engine, tc.query)
+		if got != tc.want {
+			t.Errorf("RedactLiterals(%s, %q) = %q, want %q", tc.engine, tc.query, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,274 @@
+package rdsdatatest
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata/types"
+	"github.com/aws/smithy-go"
+)
+
+// jsonField is the JSON representation of types.Field.
+type jsonField struct {
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// jsonArray is the JSON representation of types.ArrayValue.
+type jsonArray struct {
+	Type   string          `json:"type"`
+	Values json.RawMessage `json:"values"`
+}
+
+func encodeField(field types.Field) (*jsonField, error) {
+	var typ string
+	var value any
+	switch v := field.(type) {
+	case *types.FieldMemberLongValue:
+		typ, value = "long", v.Value
+	case *types.FieldMemberDoubleValue:
+		typ, value = "double", v.Value
+	case *types.FieldMemberBooleanValue:
+		typ, value = "boolean", v.Value
+	case *types.FieldMemberStringValue:
+		typ, value = "string", v.Value
+	case *types.FieldMemberBlobValue:
+		typ, value = "blob", v.Value
+	case *types.FieldMemberIsNull:
+		return &jsonField{Type: "null"}, nil
+	case *types.FieldMemberArrayValue:
+		array, err := encodeArray(v.Value)
+		if err != nil {
+			return nil, err
+		}
+		typ, value = "array", array
+	default:
+		return nil, fmt.Errorf("rdsdatatest: unsupported field type: %T", v)
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return &jsonField{Type: typ, Value: data}, nil
+}
+
+func decodeField(field *jsonField) (types.Field, error) {
+	switch field.Type {
+	case "long":
+		var v int64
+		err := json.Unmarshal(field.Value, &v)
+		return &types.FieldMemberLongValue{Value: v}, err
+	case "double":
+		var v float64
+		err := json.Unmarshal(field.Value, &v)
+		return &types.FieldMemberDoubleValue{Value: v}, err
+	case "boolean":
+		var v bool
+		err := json.Unmarshal(field.Value, &v)
+		return &types.FieldMemberBooleanValue{Value: v}, err
+	case "string":
+		var v string
+		err := json.Unmarshal(field.Value, &v)
+		return &types.FieldMemberStringValue{Value: v}, err
+	case "blob":
+		var v []byte
+		err := json.Unmarshal(field.Value, &v)
+		return &types.FieldMemberBlobValue{Value: v}, err
+	case "null":
+		return &types.FieldMemberIsNull{Value: true}, nil
+	case "array":
+		var v jsonArray
+		if err := json.Unmarshal(field.Value, &v); err != nil {
+			return nil, err
+		}
+		array, err := decodeArray(&v)
+		if err != nil {
+			return nil, err
+		}
+		return &types.FieldMemberArrayValue{Value: array}, nil
+	}
+	return nil, fmt.Errorf("rdsdatatest: unknown field type: %q", field.Type)
+}
+
+func encodeArray(array types.ArrayValue) (*jsonArray, error) {
+	var typ string
+	var values any
+	switch v := array.(type) {
+	case *types.ArrayValueMemberLongValues:
+		typ, values = "long", v.Value
+	case *types.ArrayValueMemberDoubleValues:
+		typ, values = "double", v.Value
+	case *types.ArrayValueMemberBooleanValues:
+		typ, values = "boolean", v.Value
+	case *types.ArrayValueMemberStringValues:
+		typ, values = "string", v.Value
+	case *types.ArrayValueMemberArrayValues:
+		arrays := make([]*jsonArray, 0, len(v.Value))
+		for _, a := range v.Value {
+			encoded, err := encodeArray(a)
+			if err != nil {
+				return nil, err
+			}
+			arrays = append(arrays, encoded)
+		}
+		typ, values = "array", arrays
+	default:
+		return nil, fmt.Errorf("rdsdatatest: unsupported array type: %T", v)
+	}
+
+	data, err := json.Marshal(values)
+	if err != nil {
+		return nil, err
+	}
+	return &jsonArray{Type: typ, Values: data}, nil
+}
+
+func decodeArray(array *jsonArray) (types.ArrayValue, error) {
+	switch array.Type {
+	case "long":
+		var v []int64
+		err := json.Unmarshal(array.Values, &v)
+		return &types.ArrayValueMemberLongValues{Value: v}, err
+	case "double":
+		var v []float64
+		err := json.Unmarshal(array.Values, &v)
+		return &types.ArrayValueMemberDoubleValues{Value: v}, err
+	case "boolean":
+		var v []bool
+		err := json.Unmarshal(array.Values, &v)
+		return &types.ArrayValueMemberBooleanValues{Value: v}, err
+	case "string":
+		var v []string
+		err := json.Unmarshal(array.Values, &v)
+		return &types.ArrayValueMemberStringValues{Value: v}, err
+	case "array":
+		var arrays []*jsonArray
+		if err := json.Unmarshal(array.Values, &arrays); err != nil {
+			return nil, err
+		}
+		values := make([]types.ArrayValue, 0, len(arrays))
+		for _, a := range arrays {
+			decoded, err := decodeArray(a)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, decoded)
+		}
+		return &types.ArrayValueMemberArrayValues{Value: values}, nil
+	}
+	return nil, fmt.Errorf("rdsdatatest: unknown array type: %q", array.Type)
+}
+
+func encodeFields(fields []types.Field) ([]*jsonField, error) {
+	if fields == nil {
+		return nil, nil
+	}
+	ret := make([]*jsonField, 0, len(fields))
+	for _, f := range fields {
+		encoded, err := encodeField(f)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, encoded)
+	}
+	return ret, nil
+}
+
+func decodeFields(fields []*jsonField) ([]types.Field, error) {
+	if fields == nil {
+		return nil, nil
+	}
+	ret := make([]types.Field, 0, len(fields))
+	for _, f := range fields {
+		decoded, err := decodeField(f)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, decoded)
+	}
+	return ret, nil
+}
+
+// jsonParameter is the JSON representation of types.SqlParameter.
+type jsonParameter struct {
+	Name     string         `json:"name"`
+	TypeHint types.TypeHint `json:"typeHint,omitempty"`
+	Value    *jsonField     `json:"value"`
+}
+
+func encodeParameters(params []types.SqlParameter) ([]*jsonParameter, error) {
+	ret := make([]*jsonParameter, 0, len(params))
+	for _, p := range params {
+		value, err := encodeField(p.Value)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, &jsonParameter{
+			Name:     aws.ToString(p.Name),
+			TypeHint: p.TypeHint,
+			Value:    value,
+		})
+	}
+	return ret, nil
+}
+
+// jsonError is the JSON representation of an error returned by the Data API.
+type jsonError struct {
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message"`
+}
+
+func encodeError(err error) *jsonError {
+	if err == nil {
+		return nil
+	}
+	if apiErr, ok := err.(smithy.APIError); ok {
+		return &jsonError{
+			Code:    apiErr.ErrorCode(),
+			Message: apiErr.ErrorMessage(),
+		}
+	}
+	return &jsonError{
+		Message: err.Error(),
+	}
+}
+
+func decodeError(e *jsonError) error {
+	if e == nil {
+		return nil
+	}
+	msg := aws.String(e.Message)
+	switch e.Code {
+	case "":
+		return &smithy.GenericAPIError{Message: e.Message}
+	case "AccessDeniedException":
+		return &types.AccessDeniedException{Message: msg}
+	case "BadRequestException":
+		return &types.BadRequestException{Message: msg}
+	case "DatabaseErrorException":
+		return &types.DatabaseErrorException{Message: msg}
+	case "DatabaseNotFoundException":
+		return &types.DatabaseNotFoundException{Message: msg}
+	case "DatabaseResumingException":
+		return &types.DatabaseResumingException{Message: msg}
+	case "DatabaseUnavailableException":
+		return &types.DatabaseUnavailableException{Message: msg}
+	case "ForbiddenException":
+		return &types.ForbiddenException{Message: msg}
+	case "HttpEndpointNotEnabledException":
+		return &types.HttpEndpointNotEnabledException{Message: msg}
+	case "InternalServerErrorException":
+		return &types.InternalServerErrorException{Message: msg}
+	case "InvalidSecretException":
+		return &types.InvalidSecretException{Message: msg}
+	case "SecretsErrorException":
+		return &types.SecretsErrorException{Message: msg}
+	case "StatementTimeoutException":
+		return &types.StatementTimeoutException{Message: msg}
+	case "TransactionNotFoundException":
+		return &types.TransactionNotFoundException{Message: msg}
+	}
+	return &smithy.GenericAPIError{Code: e.Code, Message: e.Message}
+}
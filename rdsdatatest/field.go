@@ -0,0 +1,54 @@
+package rdsdatatest
+
+import (
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata/types"
+)
+
+// Long returns a field of the long value.
+func Long(v int64) types.Field {
+	return &types.FieldMemberLongValue{Value: v}
+}
+
+// Double returns a field of the double value.
+func Double(v float64) types.Field {
+	return &types.FieldMemberDoubleValue{Value: v}
+}
+
+// Bool returns a field of the boolean value.
+func Bool(v bool) types.Field {
+	return &types.FieldMemberBooleanValue{Value: v}
+}
+
+// String returns a field of the string value.
+func String(v string) types.Field {
+	return &types.FieldMemberStringValue{Value: v}
+}
+
+// Blob returns a field of the blob value.
+func Blob(v []byte) types.Field {
+	return &types.FieldMemberBlobValue{Value: v}
+}
+
+// Null returns a field of NULL.
+func Null() types.Field {
+	return &types.FieldMemberIsNull{Value: true}
+}
+
+// Param returns a named SQL parameter.
+func Param(name string, value types.Field) types.SqlParameter {
+	return types.SqlParameter{
+		Name:  aws.String(name),
+		Value: value,
+	}
+}
+
+// Column returns a column metadata with the label and the type name.
+// typeName is the database-specific type name, e.g. "VARCHAR", "BIGINT UNSIGNED" or "int8".
+func Column(label, typeName string) types.ColumnMetadata {
+	return types.ColumnMetadata{
+		Name:     aws.String(label),
+		Label:    aws.String(label),
+		TypeName: aws.String(typeName),
+	}
+}
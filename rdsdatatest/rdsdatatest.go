@@ -0,0 +1,503 @@
+// Package rdsdatatest provides a programmable fake of the RDS Data API client
+// for unit-testing code that uses the rdsdata driver without AWS.
+//
+//	fake := rdsdatatest.New()
+//	fake.ExpectStatement("SELECT id, name FROM users WHERE id = :1").
+//		WithParameters(rdsdatatest.Param("1", rdsdatatest.Long(42))).
+//		WillReturnRecords(
+//			[]types.ColumnMetadata{
+//				rdsdatatest.Column("id", "BIGINT"),
+//				rdsdatatest.Column("name", "VARCHAR"),
+//			},
+//			[]types.Field{rdsdatatest.Long(42), rdsdatatest.String("alice")},
+//		)
+//
+//	db := fake.OpenDB(nil)
+//	defer db.Close()
+//	// ... run the code under test ...
+//
+//	if err := fake.ExpectationsWereMet(); err != nil {
+//		t.Error(err)
+//	}
+package rdsdatatest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata"
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata/types"
+	rdsdatadriver "github.com/shogo82148/go-rdsdata"
+)
+
+// Default ARNs used by OpenDB.
+const (
+	DefaultResourceArn = "arn:aws:rds:us-east-1:123456789012:cluster:rdsdatatest"
+	DefaultSecretArn   = "arn:aws:secretsmanager:us-east-1:123456789012:secret:rdsdatatest-AbCdEf"
+)
+
+// Versions returned for the engine detection query.
+const (
+	VersionMySQL    = "8.0.32"
+	VersionPostgres = "PostgreSQL 16.1 on x86_64-pc-linux-gnu"
+)
+
+// compile time type check
+var _ rdsdatadriver.Client = (*Fake)(nil)
+
+// Fake is a programmable fake of the RDS Data API client.
+// The expectations must be met in the order they are registered.
+// The SQL is compared without the comments appended by rdsdata.Commenter,
+// whose tags such as traceparent differ between the runs.
+// It is safe for concurrent use.
+type Fake struct {
+	// Version is the response to "SELECT VERSION()",
+	// which the driver sends to detect the database engine.
+	// The default is VersionMySQL.
+	Version string
+
+	mu           sync.Mutex
+	expectations []expectation
+	transactions map[string]bool
+	seq          int
+}
+
+// New returns a new fake client.
+func New() *Fake {
+	return &Fake{
+		Version:      VersionMySQL,
+		transactions: map[string]bool{},
+	}
+}
+
+// OpenDB returns a new *sql.DB that uses the fake client.
+// cfg may be nil. The ARNs are filled with the default values if they are empty.
+func (f *Fake) OpenDB(cfg *rdsdatadriver.Config) *sql.DB {
+	if cfg == nil {
+		cfg = &rdsdatadriver.Config{}
+	} else {
+		cfg = cfg.Clone()
+	}
+	cfg.Client = f
+	if cfg.ResourceArn == "" {
+		cfg.ResourceArn = DefaultResourceArn
+	}
+	if cfg.SecretArn == "" {
+		cfg.SecretArn = DefaultSecretArn
+	}
+	return sql.OpenDB(rdsdatadriver.NewConnector(cfg))
+}
+
+// ExpectationsWereMet returns an error if any expectation is not met.
+func (f *Fake) ExpectationsWereMet() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var errs []error
+	for _, e := range f.expectations {
+		if !e.triggered() {
+			errs = append(errs, fmt.Errorf("rdsdatatest: expectation is not met: %s", e))
+		}
+	}
+	for id, open := range f.transactions {
+		if open {
+			errs = append(errs, fmt.Errorf("rdsdatatest: transaction %q is not finished", id))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ExpectStatement registers an expectation of ExecuteStatement.
+// The SQL is compared with the statement sent by the driver after collapsing whitespace,
+// so the ordinal placeholders must be written in the translated form (e.g. ":1").
+func (f *Fake) ExpectStatement(sql string) *ExpectedStatement {
+	e := &ExpectedStatement{sql: sql}
+	f.add(e)
+	return e
+}
+
+// ExpectBegin registers an expectation of BeginTransaction.
+func (f *Fake) ExpectBegin() *ExpectedBegin {
+	e := &ExpectedBegin{}
+	f.add(e)
+	return e
+}
+
+// ExpectCommit registers an expectation of CommitTransaction.
+func (f *Fake) ExpectCommit() *ExpectedCommit {
+	e := &ExpectedCommit{}
+	f.add(e)
+	return e
+}
+
+// ExpectRollback registers an expectation of RollbackTransaction.
+func (f *Fake) ExpectRollback() *ExpectedRollback {
+	e := &ExpectedRollback{}
+	f.add(e)
+	return e
+}
+
+func (f *Fake) add(e expectation) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.expectations = append(f.expectations, e)
+}
+
+// next returns the next expectation that is not triggered yet.
+func (f *Fake) next() expectation {
+	for _, e := range f.expectations {
+		if !e.triggered() {
+			return e
+		}
+	}
+	return nil
+}
+
+// checkTransaction returns an error if the transaction is not open.
+func (f *Fake) checkTransaction(id *string) error {
+	if id == nil {
+		return nil
+	}
+	if !f.transactions[*id] {
+		return &types.TransactionNotFoundException{
+			Message: aws.String(fmt.Sprintf("rdsdatatest: transaction %q is not found", *id)),
+		}
+	}
+	return nil
+}
+
+// ExecuteStatement implements rdsdata.Client.
+func (f *Fake) ExecuteStatement(ctx context.Context, in *rdsdata.ExecuteStatementInput, optFns ...func(*rdsdata.Options)) (*rdsdata.ExecuteStatementOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.checkTransaction(in.TransactionId); err != nil {
+		return nil, err
+	}
+
+	// answer the queries issued by the driver itself.
+	switch stripSQLComment(aws.ToString(in.Sql)) {
+	case "SELECT VERSION()":
+		return &rdsdata.ExecuteStatementOutput{
+			ColumnMetadata: []types.ColumnMetadata{Column("VERSION()", "VARCHAR")},
+			Records:        [][]types.Field{{String(f.Version)}},
+		}, nil
+	case "/* ping */ SELECT 1":
+		return &rdsdata.ExecuteStatementOutput{
+			ColumnMetadata: []types.ColumnMetadata{Column("1", "BIGINT")},
+			Records:        [][]types.Field{{Long(1)}},
+		}, nil
+	}
+
+	e, ok := f.next().(*ExpectedStatement)
+	if !ok {
+		return nil, f.unexpected("ExecuteStatement " + strconv.Quote(aws.ToString(in.Sql)))
+	}
+	if err := e.match(in); err != nil {
+		return nil, err
+	}
+	e.done = true
+	if e.err != nil {
+		return nil, e.err
+	}
+	return e.output(), nil
+}
+
+// BeginTransaction implements rdsdata.Client.
+func (f *Fake) BeginTransaction(ctx context.Context, in *rdsdata.BeginTransactionInput, optFns ...func(*rdsdata.Options)) (*rdsdata.BeginTransactionOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	e, ok := f.next().(*ExpectedBegin)
+	if !ok {
+		return nil, f.unexpected("BeginTransaction")
+	}
+	e.done = true
+	if e.err != nil {
+		return nil, e.err
+	}
+
+	id := e.id
+	if id == "" {
+		f.seq++
+		id = "transaction-" + strconv.Itoa(f.seq)
+	}
+	f.transactions[id] = true
+	return &rdsdata.BeginTransactionOutput{
+		TransactionId: aws.String(id),
+	}, nil
+}
+
+// CommitTransaction implements rdsdata.Client.
+func (f *Fake) CommitTransaction(ctx context.Context, in *rdsdata.CommitTransactionInput, optFns ...func(*rdsdata.Options)) (*rdsdata.CommitTransactionOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.checkTransaction(in.TransactionId); err != nil {
+		return nil, err
+	}
+	e, ok := f.next().(*ExpectedCommit)
+	if !ok {
+		return nil, f.unexpected("CommitTransaction")
+	}
+	e.done = true
+	if e.err != nil {
+		// the transaction is still open, e.g. for a rollback after the failed commit.
+		return nil, e.err
+	}
+	f.transactions[aws.ToString(in.TransactionId)] = false
+	return &rdsdata.CommitTransactionOutput{
+		TransactionStatus: aws.String("Transaction Committed"),
+	}, nil
+}
+
+// RollbackTransaction implements rdsdata.Client.
+func (f *Fake) RollbackTransaction(ctx context.Context, in *rdsdata.RollbackTransactionInput, optFns ...func(*rdsdata.Options)) (*rdsdata.RollbackTransactionOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.checkTransaction(in.TransactionId); err != nil {
+		return nil, err
+	}
+	e, ok := f.next().(*ExpectedRollback)
+	if !ok {
+		return nil, f.unexpected("RollbackTransaction")
+	}
+	e.done = true
+	if e.err != nil {
+		// the transaction is still open.
+		return nil, e.err
+	}
+	f.transactions[aws.ToString(in.TransactionId)] = false
+	return &rdsdata.RollbackTransactionOutput{
+		TransactionStatus: aws.String("Rollback Complete"),
+	}, nil
+}
+
+func (f *Fake) unexpected(call string) error {
+	next := f.next()
+	if next == nil {
+		return fmt.Errorf("rdsdatatest: unexpected call %s: all expectations were already met", call)
+	}
+	return fmt.Errorf("rdsdatatest: unexpected call %s: next expectation is %s", call, next)
+}
+
+type expectation interface {
+	fmt.Stringer
+	triggered() bool
+}
+
+type txState int
+
+const (
+	txAny txState = iota
+	txInside
+	txOutside
+)
+
+// ExpectedStatement is an expectation of ExecuteStatement.
+type ExpectedStatement struct {
+	sql         string
+	params      []types.SqlParameter
+	checkParams bool
+	tx          txState
+	out         *rdsdata.ExecuteStatementOutput
+	err         error
+	done        bool
+}
+
+// WithParameters sets the expected parameters.
+// The TypeHint is compared only if it is set in the expectation.
+func (e *ExpectedStatement) WithParameters(params ...types.SqlParameter) *ExpectedStatement {
+	e.params = params
+	e.checkParams = true
+	return e
+}
+
+// InTransaction expects that the statement is executed in a transaction.
+func (e *ExpectedStatement) InTransaction() *ExpectedStatement {
+	e.tx = txInside
+	return e
+}
+
+// OutsideTransaction expects that the statement is executed outside of transactions.
+func (e *ExpectedStatement) OutsideTransaction() *ExpectedStatement {
+	e.tx = txOutside
+	return e
+}
+
+// WillReturnRecords sets the result set of the statement.
+func (e *ExpectedStatement) WillReturnRecords(columns []types.ColumnMetadata, records ...[]types.Field) *ExpectedStatement {
+	e.out = &rdsdata.ExecuteStatementOutput{
+		ColumnMetadata: columns,
+		Records:        records,
+	}
+	return e
+}
+
+// WillReturnResult sets the number of updated records and the generated fields.
+func (e *ExpectedStatement) WillReturnResult(rowsAffected int64, generatedFields ...types.Field) *ExpectedStatement {
+	e.out = &rdsdata.ExecuteStatementOutput{
+		NumberOfRecordsUpdated: rowsAffected,
+		GeneratedFields:        generatedFields,
+	}
+	return e
+}
+
+// WillReturnOutput sets the raw output of the statement.
+func (e *ExpectedStatement) WillReturnOutput(out *rdsdata.ExecuteStatementOutput) *ExpectedStatement {
+	e.out = out
+	return e
+}
+
+// WillReturnError sets the error of the statement.
+func (e *ExpectedStatement) WillReturnError(err error) *ExpectedStatement {
+	e.err = err
+	return e
+}
+
+func (e *ExpectedStatement) String() string {
+	return "ExecuteStatement " + strconv.Quote(e.sql)
+}
+
+func (e *ExpectedStatement) triggered() bool {
+	return e.done
+}
+
+func (e *ExpectedStatement) output() *rdsdata.ExecuteStatementOutput {
+	if e.out == nil {
+		return &rdsdata.ExecuteStatementOutput{}
+	}
+	return e.out
+}
+
+func (e *ExpectedStatement) match(in *rdsdata.ExecuteStatementInput) error {
+	got := aws.ToString(in.Sql)
+	if normalize(stripSQLComment(got)) != normalize(stripSQLComment(e.sql)) {
+		return fmt.Errorf("rdsdatatest: unexpected SQL: %q, want %q", got, e.sql)
+	}
+
+	switch e.tx {
+	case txInside:
+		if in.TransactionId == nil {
+			return fmt.Errorf("rdsdatatest: %q is expected to be executed in a transaction", e.sql)
+		}
+	case txOutside:
+		if in.TransactionId != nil {
+			return fmt.Errorf("rdsdatatest: %q is expected to be executed outside of transactions", e.sql)
+		}
+	}
+
+	if !e.checkParams {
+		return nil
+	}
+	if len(in.Parameters) != len(e.params) {
+		return fmt.Errorf("rdsdatatest: unexpected number of parameters for %q: %d, want %d", e.sql, len(in.Parameters), len(e.params))
+	}
+	for i, want := range e.params {
+		got := in.Parameters[i]
+		if aws.ToString(got.Name) != aws.ToString(want.Name) {
+			return fmt.Errorf("rdsdatatest: unexpected name of parameter #%d: %q, want %q", i, aws.ToString(got.Name), aws.ToString(want.Name))
+		}
+		if want.TypeHint != "" && got.TypeHint != want.TypeHint {
+			return fmt.Errorf("rdsdatatest: unexpected type hint of parameter %q: %q, want %q", aws.ToString(want.Name), got.TypeHint, want.TypeHint)
+		}
+		if !reflect.DeepEqual(got.Value, want.Value) {
+			return fmt.Errorf("rdsdatatest: unexpected value of parameter %q: %#v, want %#v", aws.ToString(want.Name), got.Value, want.Value)
+		}
+	}
+	return nil
+}
+
+// ExpectedBegin is an expectation of BeginTransaction.
+type ExpectedBegin struct {
+	id   string
+	err  error
+	done bool
+}
+
+// WillReturnTransactionID sets the ID of the new transaction.
+// If it is not set, a unique ID is generated.
+func (e *ExpectedBegin) WillReturnTransactionID(id string) *ExpectedBegin {
+	e.id = id
+	return e
+}
+
+// WillReturnError sets the error of BeginTransaction.
+func (e *ExpectedBegin) WillReturnError(err error) *ExpectedBegin {
+	e.err = err
+	return e
+}
+
+func (e *ExpectedBegin) String() string {
+	return "BeginTransaction"
+}
+
+func (e *ExpectedBegin) triggered() bool {
+	return e.done
+}
+
+// ExpectedCommit is an expectation of CommitTransaction.
+type ExpectedCommit struct {
+	err  error
+	done bool
+}
+
+// WillReturnError sets the error of CommitTransaction.
+// The transaction is kept open, so that it can be rolled back.
+func (e *ExpectedCommit) WillReturnError(err error) *ExpectedCommit {
+	e.err = err
+	return e
+}
+
+func (e *ExpectedCommit) String() string {
+	return "CommitTransaction"
+}
+
+func (e *ExpectedCommit) triggered() bool {
+	return e.done
+}
+
+// ExpectedRollback is an expectation of RollbackTransaction.
+type ExpectedRollback struct {
+	err  error
+	done bool
+}
+
+// WillReturnError sets the error of RollbackTransaction.
+// The transaction is kept open.
+func (e *ExpectedRollback) WillReturnError(err error) *ExpectedRollback {
+	e.err = err
+	return e
+}
+
+func (e *ExpectedRollback) String() string {
+	return "RollbackTransaction"
+}
+
+func (e *ExpectedRollback) triggered() bool {
+	return e.done
+}
+
+// normalize collapses the whitespace in the SQL.
+func normalize(sql string) string {
+	return strings.Join(strings.Fields(sql), " ")
+}
+
+// sqlCommentPattern matches the sqlcommenter comment that rdsdata.Commenter appends to the statement,
+// e.g. " /*application='app',traceparent='00-...'*/".
+// The keys and the values are URL-encoded, so they contain no quotes, spaces or comment delimiters.
+var sqlCommentPattern = regexp.MustCompile(`\s*/\*[^\s'=*/]+='[^\s'*/]*'(?:,[^\s'=*/]+='[^\s'*/]*')*\*/([;\s]*)$`)
+
+// stripSQLComment removes the sqlcommenter comment from the SQL.
+func stripSQLComment(sql string) string {
+	return sqlCommentPattern.ReplaceAllString(sql, "$1")
+}
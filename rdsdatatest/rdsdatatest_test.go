@@ -0,0 +1,200 @@
+package rdsdatatest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata"
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata/types"
+	rdsdatadriver "github.com/shogo82148/go-rdsdata"
+)
+
+func TestFake(t *testing.T) {
+	fake := New()
+	fake.ExpectStatement("SELECT id, name FROM users WHERE id = :1").
+		WithParameters(Param("1", Long(42))).
+		OutsideTransaction().
+		WillReturnRecords(
+			[]types.ColumnMetadata{
+				Column("id", "BIGINT"),
+				Column("name", "VARCHAR"),
+			},
+			[]types.Field{Long(42), String("alice")},
+		)
+	fake.ExpectBegin().WillReturnTransactionID("tx1")
+	fake.ExpectStatement("UPDATE users SET name = :1 WHERE id = :2").
+		WithParameters(Param("1", String("bob")), Param("2", Long(42))).
+		InTransaction().
+		WillReturnResult(1)
+	fake.ExpectCommit()
+
+	db := fake.OpenDB(nil)
+	defer db.Close()
+	ctx := context.Background()
+
+	var id int64
+	var name string
+	if err := db.QueryRowContext(ctx, "SELECT id, name FROM users WHERE id = ?", 42).Scan(&id, &name); err != nil {
+		t.Fatal(err)
+	}
+	if id != 42 || name != "alice" {
+		t.Errorf("unexpected row: %d, %q", id, name)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := tx.ExecContext(ctx, "UPDATE users SET name = ? WHERE id = ?", "bob", 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := result.RowsAffected(); n != 1 {
+		t.Errorf("unexpected rows affected: %d", n)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := fake.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFake_Unexpected(t *testing.T) {
+	fake := New()
+	fake.ExpectStatement("SELECT 1").WillReturnRecords(nil)
+	fake.ExpectStatement("SELECT 2").WithParameters(Param("1", Long(1)))
+
+	db := fake.OpenDB(nil)
+	defer db.Close()
+	ctx := context.Background()
+
+	if _, err := db.ExecContext(ctx, "SELECT 3"); err == nil {
+		t.Error("expected error, but got nil")
+	}
+	if _, err := db.ExecContext(ctx, "SELECT 1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.ExecContext(ctx, "SELECT 2", 2); err == nil {
+		t.Error("expected error, but got nil")
+	}
+	if err := fake.ExpectationsWereMet(); err == nil {
+		t.Error("expected error, but got nil")
+	}
+}
+
+func TestFake_Rollback(t *testing.T) {
+	errExec := errors.New("exec error")
+
+	fake := New()
+	fake.Version = VersionPostgres
+	fake.ExpectBegin()
+	fake.ExpectStatement("DELETE FROM users").InTransaction().WillReturnError(errExec)
+	fake.ExpectRollback()
+
+	db := fake.OpenDB(nil)
+	defer db.Close()
+	ctx := context.Background()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM users"); !errors.Is(err, errExec) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	if err := fake.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFake_CommitError(t *testing.T) {
+	errCommit := errors.New("commit error")
+
+	fake := New()
+	fake.ExpectBegin().WillReturnTransactionID("tx1")
+	fake.ExpectCommit().WillReturnError(errCommit)
+	fake.ExpectRollback()
+
+	ctx := context.Background()
+	out, err := fake.BeginTransaction(ctx, &rdsdata.BeginTransactionInput{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fake.CommitTransaction(ctx, &rdsdata.CommitTransactionInput{TransactionId: out.TransactionId}); !errors.Is(err, errCommit) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	// the transaction is still open after the failed commit.
+	if _, err := fake.RollbackTransaction(ctx, &rdsdata.RollbackTransactionInput{TransactionId: out.TransactionId}); err != nil {
+		t.Fatal(err)
+	}
+	if err := fake.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFake_Commenter(t *testing.T) {
+	fake := New()
+	fake.ExpectStatement("SELECT name FROM users WHERE id = :1").
+		WithParameters(Param("1", Long(42))).
+		WillReturnRecords(
+			[]types.ColumnMetadata{Column("name", "VARCHAR")},
+			[]types.Field{String("alice")},
+		)
+
+	db := fake.OpenDB(&rdsdatadriver.Config{
+		Commenter: &rdsdatadriver.Commenter{
+			Application: "app",
+			Tags: func(ctx context.Context) map[string]string {
+				return map[string]string{"traceparent": "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"}
+			},
+		},
+	})
+	defer db.Close()
+
+	var name string
+	if err := db.QueryRowContext(context.Background(), "SELECT name FROM users WHERE id = ?", 42).Scan(&name); err != nil {
+		t.Fatal(err)
+	}
+	if name != "alice" {
+		t.Errorf("unexpected name: %q", name)
+	}
+	if err := fake.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestStripSQLComment(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want string
+	}{
+		{
+			sql:  "SELECT 1 /*application='app',traceparent='00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01'*/",
+			want: "SELECT 1",
+		},
+		{
+			sql:  "DELETE FROM users /*route='%2Fusers%2F%7Bid%7D'*/;\n",
+			want: "DELETE FROM users;\n",
+		},
+		{
+			// the comments written by the users are kept.
+			sql:  "/* ping */ SELECT 1",
+			want: "/* ping */ SELECT 1",
+		},
+		{
+			sql:  "SELECT 1 /* keep this */",
+			want: "SELECT 1 /* keep this */",
+		},
+	}
+	for _, tt := range tests {
+		if got := stripSQLComment(tt.sql); got != tt.want {
+			t.Errorf("stripSQLComment(%q) = %q, want %q", tt.sql, got, tt.want)
+		}
+	}
+}
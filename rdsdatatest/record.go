@@ -0,0 +1,358 @@
+package rdsdatatest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata"
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata/types"
+	rdsdatadriver "github.com/shogo82148/go-rdsdata"
+)
+
+// compile time type check
+var _ rdsdatadriver.Client = (*Recorder)(nil)
+var _ rdsdatadriver.Client = (*Replayer)(nil)
+
+// Operation names in the golden file.
+const (
+	opExecuteStatement    = "ExecuteStatement"
+	opBeginTransaction    = "BeginTransaction"
+	opCommitTransaction   = "CommitTransaction"
+	opRollbackTransaction = "RollbackTransaction"
+)
+
+// golden is the format of the golden file.
+type golden struct {
+	Interactions []*interaction `json:"interactions"`
+}
+
+// interaction is a pair of a request and its response.
+type interaction struct {
+	Operation string     `json:"operation"`
+	Request   *request   `json:"request"`
+	Response  *response  `json:"response,omitempty"`
+	Error     *jsonError `json:"error,omitempty"`
+
+	used bool
+}
+
+type request struct {
+	Sql           string           `json:"sql,omitempty"`
+	Parameters    []*jsonParameter `json:"parameters,omitempty"`
+	Database      string           `json:"database,omitempty"`
+	TransactionID string           `json:"transactionId,omitempty"`
+}
+
+type response struct {
+	ColumnMetadata         []types.ColumnMetadata `json:"columnMetadata,omitempty"`
+	Records                [][]*jsonField         `json:"records,omitempty"`
+	GeneratedFields        []*jsonField           `json:"generatedFields,omitempty"`
+	NumberOfRecordsUpdated int64                  `json:"numberOfRecordsUpdated,omitempty"`
+	FormattedRecords       string                 `json:"formattedRecords,omitempty"`
+	TransactionID          string                 `json:"transactionId,omitempty"`
+}
+
+// Recorder is a Client that records the requests and the responses of the underlying client.
+// Call Save to write them into a golden file, which Replayer serves back.
+type Recorder struct {
+	client rdsdatadriver.Client
+
+	mu           sync.Mutex
+	interactions []*interaction
+	err          error
+}
+
+// NewRecorder returns a new Recorder that wraps client.
+func NewRecorder(client rdsdatadriver.Client) *Recorder {
+	return &Recorder{
+		client: client,
+	}
+}
+
+// Save writes the recorded interactions into the golden file.
+func (r *Recorder) Save(path string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return r.err
+	}
+
+	data, err := json.MarshalIndent(&golden{Interactions: r.interactions}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+func (r *Recorder) record(i *interaction, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err != nil {
+		r.err = errors.Join(r.err, err)
+		return
+	}
+	r.interactions = append(r.interactions, i)
+}
+
+// ExecuteStatement implements rdsdata.Client.
+func (r *Recorder) ExecuteStatement(ctx context.Context, in *rdsdata.ExecuteStatementInput, optFns ...func(*rdsdata.Options)) (*rdsdata.ExecuteStatementOutput, error) {
+	out, err := r.client.ExecuteStatement(ctx, in, optFns...)
+
+	req, encErr := newExecuteStatementRequest(in)
+	if encErr != nil {
+		r.record(nil, encErr)
+		return out, err
+	}
+	i := &interaction{
+		Operation: opExecuteStatement,
+		Request:   req,
+		Error:     encodeError(err),
+	}
+	if out != nil {
+		i.Response, encErr = newExecuteStatementResponse(out)
+	}
+	r.record(i, encErr)
+	return out, err
+}
+
+// BeginTransaction implements rdsdata.Client.
+func (r *Recorder) BeginTransaction(ctx context.Context, in *rdsdata.BeginTransactionInput, optFns ...func(*rdsdata.Options)) (*rdsdata.BeginTransactionOutput, error) {
+	out, err := r.client.BeginTransaction(ctx, in, optFns...)
+	i := &interaction{
+		Operation: opBeginTransaction,
+		Request: &request{
+			Database: aws.ToString(in.Database),
+		},
+		Error: encodeError(err),
+	}
+	if out != nil {
+		i.Response = &response{
+			TransactionID: aws.ToString(out.TransactionId),
+		}
+	}
+	r.record(i, nil)
+	return out, err
+}
+
+// CommitTransaction implements rdsdata.Client.
+func (r *Recorder) CommitTransaction(ctx context.Context, in *rdsdata.CommitTransactionInput, optFns ...func(*rdsdata.Options)) (*rdsdata.CommitTransactionOutput, error) {
+	out, err := r.client.CommitTransaction(ctx, in, optFns...)
+	r.record(&interaction{
+		Operation: opCommitTransaction,
+		Request: &request{
+			TransactionID: aws.ToString(in.TransactionId),
+		},
+		Error: encodeError(err),
+	}, nil)
+	return out, err
+}
+
+// RollbackTransaction implements rdsdata.Client.
+func (r *Recorder) RollbackTransaction(ctx context.Context, in *rdsdata.RollbackTransactionInput, optFns ...func(*rdsdata.Options)) (*rdsdata.RollbackTransactionOutput, error) {
+	out, err := r.client.RollbackTransaction(ctx, in, optFns...)
+	r.record(&interaction{
+		Operation: opRollbackTransaction,
+		Request: &request{
+			TransactionID: aws.ToString(in.TransactionId),
+		},
+		Error: encodeError(err),
+	}, nil)
+	return out, err
+}
+
+func newExecuteStatementRequest(in *rdsdata.ExecuteStatementInput) (*request, error) {
+	params, err := encodeParameters(in.Parameters)
+	if err != nil {
+		return nil, err
+	}
+	return &request{
+		Sql:           stripSQLComment(aws.ToString(in.Sql)),
+		Parameters:    params,
+		Database:      aws.ToString(in.Database),
+		TransactionID: aws.ToString(in.TransactionId),
+	}, nil
+}
+
+func newExecuteStatementResponse(out *rdsdata.ExecuteStatementOutput) (*response, error) {
+	records := make([][]*jsonField, 0, len(out.Records))
+	for _, row := range out.Records {
+		encoded, err := encodeFields(row)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, encoded)
+	}
+	generated, err := encodeFields(out.GeneratedFields)
+	if err != nil {
+		return nil, err
+	}
+	return &response{
+		ColumnMetadata:         out.ColumnMetadata,
+		Records:                records,
+		GeneratedFields:        generated,
+		NumberOfRecordsUpdated: out.NumberOfRecordsUpdated,
+		FormattedRecords:       aws.ToString(out.FormattedRecords),
+	}, nil
+}
+
+// Replayer is a Client that serves the interactions recorded by Recorder.
+// A request is matched with the first unused interaction of the same operation
+// that has the same SQL, parameters, database and transaction ID.
+// The comments appended by rdsdata.Commenter are not recorded nor compared,
+// because their tags such as traceparent differ between the runs.
+// It returns an error if no interaction matches.
+// It is safe for concurrent use.
+type Replayer struct {
+	mu           sync.Mutex
+	interactions []*interaction
+}
+
+// NewReplayer returns a new Replayer that serves the golden file.
+func NewReplayer(path string) (*Replayer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var g golden
+	if err := json.Unmarshal(data, &g); err != nil {
+		return nil, fmt.Errorf("rdsdatatest: failed to parse %s: %w", path, err)
+	}
+	return &Replayer{
+		interactions: g.Interactions,
+	}, nil
+}
+
+// ExpectationsWereMet returns an error if any recorded interaction is not replayed.
+func (r *Replayer) ExpectationsWereMet() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var errs []error
+	for _, i := range r.interactions {
+		if !i.used {
+			errs = append(errs, fmt.Errorf("rdsdatatest: interaction is not replayed: %s %s", i.Operation, strconv.Quote(i.Request.Sql)))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// find returns the first unused interaction that matches the request.
+func (r *Replayer) find(op string, req *request) (*interaction, error) {
+	// compare the compact JSON representations,
+	// because the raw values in the golden file may be indented.
+	want, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, i := range r.interactions {
+		if i.used || i.Operation != op {
+			continue
+		}
+		got, err := json.Marshal(i.Request)
+		if err != nil {
+			return nil, err
+		}
+		if bytes.Equal(got, want) {
+			i.used = true
+			return i, nil
+		}
+	}
+	return nil, fmt.Errorf("rdsdatatest: no recorded interaction matches %s %s", op, want)
+}
+
+// ExecuteStatement implements rdsdata.Client.
+func (r *Replayer) ExecuteStatement(ctx context.Context, in *rdsdata.ExecuteStatementInput, optFns ...func(*rdsdata.Options)) (*rdsdata.ExecuteStatementOutput, error) {
+	req, err := newExecuteStatementRequest(in)
+	if err != nil {
+		return nil, err
+	}
+	i, err := r.find(opExecuteStatement, req)
+	if err != nil {
+		return nil, err
+	}
+	if i.Error != nil {
+		return nil, decodeError(i.Error)
+	}
+
+	out := &rdsdata.ExecuteStatementOutput{}
+	if resp := i.Response; resp != nil {
+		out.ColumnMetadata = resp.ColumnMetadata
+		out.NumberOfRecordsUpdated = resp.NumberOfRecordsUpdated
+		if resp.FormattedRecords != "" {
+			out.FormattedRecords = aws.String(resp.FormattedRecords)
+		}
+		for _, row := range resp.Records {
+			decoded, err := decodeFields(row)
+			if err != nil {
+				return nil, err
+			}
+			out.Records = append(out.Records, decoded)
+		}
+		out.GeneratedFields, err = decodeFields(resp.GeneratedFields)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// BeginTransaction implements rdsdata.Client.
+func (r *Replayer) BeginTransaction(ctx context.Context, in *rdsdata.BeginTransactionInput, optFns ...func(*rdsdata.Options)) (*rdsdata.BeginTransactionOutput, error) {
+	i, err := r.find(opBeginTransaction, &request{
+		Database: aws.ToString(in.Database),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if i.Error != nil {
+		return nil, decodeError(i.Error)
+	}
+	out := &rdsdata.BeginTransactionOutput{}
+	if i.Response != nil {
+		out.TransactionId = aws.String(i.Response.TransactionID)
+	}
+	return out, nil
+}
+
+// CommitTransaction implements rdsdata.Client.
+func (r *Replayer) CommitTransaction(ctx context.Context, in *rdsdata.CommitTransactionInput, optFns ...func(*rdsdata.Options)) (*rdsdata.CommitTransactionOutput, error) {
+	i, err := r.find(opCommitTransaction, &request{
+		TransactionID: aws.ToString(in.TransactionId),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if i.Error != nil {
+		return nil, decodeError(i.Error)
+	}
+	return &rdsdata.CommitTransactionOutput{
+		TransactionStatus: aws.String("Transaction Committed"),
+	}, nil
+}
+
+// RollbackTransaction implements rdsdata.Client.
+func (r *Replayer) RollbackTransaction(ctx context.Context, in *rdsdata.RollbackTransactionInput, optFns ...func(*rdsdata.Options)) (*rdsdata.RollbackTransactionOutput, error) {
+	i, err := r.find(opRollbackTransaction, &request{
+		TransactionID: aws.ToString(in.TransactionId),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if i.Error != nil {
+		return nil, decodeError(i.Error)
+	}
+	return &rdsdata.RollbackTransactionOutput{
+		TransactionStatus: aws.String("Rollback Complete"),
+	}, nil
+}
@@ -0,0 +1,161 @@
+package rdsdatatest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata"
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata/types"
+	rdsdatadriver "github.com/shogo82148/go-rdsdata"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	golden := filepath.Join(t.TempDir(), "golden.json")
+
+	run := func(client rdsdatadriver.Client, name string) error {
+		db := sql.OpenDB(rdsdatadriver.NewConnector(&rdsdatadriver.Config{
+			ResourceArn: DefaultResourceArn,
+			SecretArn:   DefaultSecretArn,
+			Client:      client,
+		}))
+		defer db.Close()
+		ctx := context.Background()
+
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		var tags []byte
+		if err := tx.QueryRowContext(ctx, "SELECT tags FROM users WHERE name = ?", name).Scan(&tags); err != nil {
+			return err
+		}
+		if string(tags) != "admin" {
+			t.Errorf("unexpected tags: %q", tags)
+		}
+		if _, err := tx.ExecContext(ctx, "UPDATE users SET tags = ? WHERE name = ?", []byte("user"), name); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}
+
+	// record
+	fake := New()
+	fake.ExpectBegin()
+	fake.ExpectStatement("SELECT tags FROM users WHERE name = :1").
+		WillReturnRecords(
+			[]types.ColumnMetadata{Column("tags", "VARCHAR")},
+			[]types.Field{String("admin")},
+		)
+	fake.ExpectStatement("UPDATE users SET tags = :1 WHERE name = :2").WillReturnResult(1)
+	fake.ExpectCommit()
+	recorder := NewRecorder(fake)
+	if err := run(recorder, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if err := recorder.Save(golden); err != nil {
+		t.Fatal(err)
+	}
+
+	// replay
+	replayer, err := NewReplayer(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := run(replayer, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if err := replayer.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+
+	// mismatch of parameters
+	replayer, err = NewReplayer(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := run(replayer, "bob"); err == nil {
+		t.Error("expected error, but got nil")
+	}
+}
+
+func TestReplayError(t *testing.T) {
+	golden := filepath.Join(t.TempDir(), "golden.json")
+
+	fake := New()
+	fake.ExpectStatement("SELECT 1").WillReturnError(&types.DatabaseResumingException{})
+	recorder := NewRecorder(fake)
+	in := &rdsdata.ExecuteStatementInput{Sql: aws.String("SELECT 1")}
+	if _, err := recorder.ExecuteStatement(context.Background(), in); err == nil {
+		t.Fatal("expected error, but got nil")
+	}
+	if err := recorder.Save(golden); err != nil {
+		t.Fatal(err)
+	}
+
+	replayer, err := NewReplayer(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = replayer.ExecuteStatement(context.Background(), in)
+	var resuming *types.DatabaseResumingException
+	if !errors.As(err, &resuming) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestRecordAndReplay_Commenter(t *testing.T) {
+	golden := filepath.Join(t.TempDir(), "golden.json")
+
+	run := func(client rdsdatadriver.Client, traceparent string) error {
+		db := sql.OpenDB(rdsdatadriver.NewConnector(&rdsdatadriver.Config{
+			ResourceArn: DefaultResourceArn,
+			SecretArn:   DefaultSecretArn,
+			Client:      client,
+			Commenter:   &rdsdatadriver.Commenter{Application: "app"},
+		}))
+		defer db.Close()
+		ctx := rdsdatadriver.WithCommentTags(context.Background(), map[string]string{"traceparent": traceparent})
+
+		var name string
+		if err := db.QueryRowContext(ctx, "SELECT name FROM users WHERE id = ?", 42).Scan(&name); err != nil {
+			return err
+		}
+		if name != "alice" {
+			t.Errorf("unexpected name: %q", name)
+		}
+		return nil
+	}
+
+	// record
+	fake := New()
+	fake.ExpectStatement("SELECT name FROM users WHERE id = :1").
+		WillReturnRecords(
+			[]types.ColumnMetadata{Column("name", "VARCHAR")},
+			[]types.Field{String("alice")},
+		)
+	recorder := NewRecorder(fake)
+	if err := run(recorder, "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"); err != nil {
+		t.Fatal(err)
+	}
+	if err := recorder.Save(golden); err != nil {
+		t.Fatal(err)
+	}
+
+	// replay with another trace context
+	replayer, err := NewReplayer(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := run(replayer, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"); err != nil {
+		t.Fatal(err)
+	}
+	if err := replayer.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
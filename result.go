@@ -3,36 +3,86 @@ package rdsdata
 import (
 	"database/sql/driver"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/rdsdata"
 	"github.com/aws/aws-sdk-go-v2/service/rdsdata/types"
 )
 
 // compile time type check
 var _ driver.Result = (*Result)(nil)
+var _ GeneratedFieldsResult = (*Result)(nil)
+
+// GeneratedFieldsResult is the interface implemented by the results of the driver.
+// database/sql hides the driver.Result, so it can be reached by executing the statement through sql.Conn.Raw:
+//
+//	err := conn.Raw(func(driverConn any) error {
+//		result, err := driverConn.(driver.ExecerContext).ExecContext(ctx, query, args)
+//		if err != nil {
+//			return err
+//		}
+//		fields := result.(rdsdata.GeneratedFieldsResult).RawGeneratedFields()
+//		// ...
+//	})
+type GeneratedFieldsResult interface {
+	driver.Result
+
+	// RawGeneratedFields returns the generated fields of every statement.
+	// On MySQL, it has an entry for each statement that generated fields.
+	// On PostgreSQL, it has an entry for each row returned by the RETURNING clause that the driver appended.
+	RawGeneratedFields() [][]types.Field
+
+	// GeneratedFields returns the generated fields converted by the field converters of the dialect,
+	// in the same order as RawGeneratedFields.
+	GeneratedFields() ([][]driver.Value, error)
+}
 
 // newResult creates a new result.
-func newResult(results []*rdsdata.ExecuteStatementOutput) *Result {
+// If returning is true, the records are the rows returned by the RETURNING clause that the driver appended,
+// and they are used as the generated fields.
+func newResult(dialect Dialect, results []*rdsdata.ExecuteStatementOutput, returning bool) *Result {
 	var rowsAffected int64
-	var lastInsertID int64
+	var generatedFields [][]types.Field
+	var generatedColumns [][]types.ColumnMetadata
 	for _, result := range results {
 		rowsAffected += result.NumberOfRecordsUpdated
-		if len(result.GeneratedFields) == 1 {
-			field := result.GeneratedFields[0]
-			if fv, ok := field.(*types.FieldMemberLongValue); ok {
-				lastInsertID = fv.Value
+		if len(result.GeneratedFields) > 0 {
+			// the Data API returns no metadata for the generated fields.
+			generatedFields = append(generatedFields, result.GeneratedFields)
+			generatedColumns = append(generatedColumns, nil)
+		}
+		if returning {
+			for _, record := range result.Records {
+				generatedFields = append(generatedFields, record)
+				generatedColumns = append(generatedColumns, result.ColumnMetadata)
 			}
 		}
 	}
+
+	var lastInsertID int64
+	for _, fields := range generatedFields {
+		if len(fields) != 1 {
+			continue
+		}
+		if fv, ok := fields[0].(*types.FieldMemberLongValue); ok {
+			lastInsertID = fv.Value
+		}
+	}
 	return &Result{
-		rowsAffected: rowsAffected,
-		lastInsertID: lastInsertID,
+		dialect:          dialect,
+		rowsAffected:     rowsAffected,
+		lastInsertID:     lastInsertID,
+		generatedFields:  generatedFields,
+		generatedColumns: generatedColumns,
 	}
 }
 
 // Result is the result of a query.
 type Result struct {
-	rowsAffected int64
-	lastInsertID int64
+	dialect          Dialect
+	rowsAffected     int64
+	lastInsertID     int64
+	generatedFields  [][]types.Field
+	generatedColumns [][]types.ColumnMetadata
 }
 
 // RowsAffected returns the number of rows affected.
@@ -41,6 +91,36 @@ func (r *Result) RowsAffected() (int64, error) {
 }
 
 // LastInsertId returns the last inserted ID.
+// It is the last generated field of the statements that generated a single integer.
 func (r *Result) LastInsertId() (int64, error) {
 	return r.lastInsertID, nil
 }
+
+// RawGeneratedFields implements GeneratedFieldsResult.
+func (r *Result) RawGeneratedFields() [][]types.Field {
+	return r.generatedFields
+}
+
+// GeneratedFields implements GeneratedFieldsResult.
+// The fields of the RETURNING clause are converted by the types of their columns,
+// and the generated fields of MySQL, which have no metadata, are converted by the default converter.
+func (r *Result) GeneratedFields() ([][]driver.Value, error) {
+	ret := make([][]driver.Value, 0, len(r.generatedFields))
+	for i, fields := range r.generatedFields {
+		columns := r.generatedColumns[i]
+		values := make([]driver.Value, len(fields))
+		for j, field := range fields {
+			var typeName string
+			if j < len(columns) {
+				typeName = aws.ToString(columns[j].TypeName)
+			}
+			v, err := r.dialect.GetFieldConverter(typeName)(field)
+			if err != nil {
+				return nil, err
+			}
+			values[j] = v
+		}
+		ret = append(ret, values)
+	}
+	return ret, nil
+}
@@ -1,8 +1,11 @@
 package rdsdata
 
 import (
+	"database/sql/driver"
+	"reflect"
 	"testing"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/rdsdata"
 	"github.com/aws/aws-sdk-go-v2/service/rdsdata/types"
 )
@@ -20,7 +23,7 @@ func TestNewResult(t *testing.T) {
 				NumberOfRecordsUpdated: 3,
 			},
 		}
-		result := newResult(results)
+		result := newResult(&DialectPostgres{}, results, false)
 
 		rowsAffected, err := result.RowsAffected()
 		if err != nil {
@@ -49,7 +52,7 @@ func TestNewResult(t *testing.T) {
 				},
 			},
 		}
-		result := newResult(results)
+		result := newResult(&DialectPostgres{}, results, false)
 
 		rowsAffected, err := result.RowsAffected()
 		if err != nil {
@@ -67,4 +70,76 @@ func TestNewResult(t *testing.T) {
 			t.Errorf("unexpected lastInsertID: %d, want 42", lastInsetID)
 		}
 	})
+	t.Run("generated fields", func(t *testing.T) {
+		results := []*rdsdata.ExecuteStatementOutput{
+			{
+				GeneratedFields: []types.Field{&types.FieldMemberLongValue{Value: 1}},
+			},
+			{
+				GeneratedFields: []types.Field{&types.FieldMemberLongValue{Value: 2}},
+			},
+			{
+				NumberOfRecordsUpdated: 1,
+			},
+		}
+		result := newResult(&DialectPostgres{}, results, false)
+
+		lastInsetID, err := result.LastInsertId()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if lastInsetID != 2 {
+			t.Errorf("unexpected lastInsertID: %d, want 2", lastInsetID)
+		}
+		if fields := result.RawGeneratedFields(); len(fields) != 2 {
+			t.Errorf("unexpected generated fields: %v", fields)
+		}
+	})
+
+	t.Run("returning", func(t *testing.T) {
+		results := []*rdsdata.ExecuteStatementOutput{
+			{
+				NumberOfRecordsUpdated: 2,
+				Records: [][]types.Field{
+					{&types.FieldMemberLongValue{Value: 10}},
+					{&types.FieldMemberLongValue{Value: 11}},
+				},
+			},
+		}
+		result := newResult(&DialectPostgres{}, results, true)
+
+		lastInsetID, err := result.LastInsertId()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if lastInsetID != 11 {
+			t.Errorf("unexpected lastInsertID: %d, want 11", lastInsetID)
+		}
+		if fields := result.RawGeneratedFields(); len(fields) != 2 {
+			t.Errorf("unexpected generated fields: %v", fields)
+		}
+	})
+	t.Run("typed generated fields", func(t *testing.T) {
+		results := []*rdsdata.ExecuteStatementOutput{
+			{
+				ColumnMetadata: []types.ColumnMetadata{
+					{Name: aws.String("id"), TypeName: aws.String("BIGINT UNSIGNED")},
+					{Name: aws.String("name"), TypeName: aws.String("VARCHAR")},
+				},
+				Records: [][]types.Field{
+					{&types.FieldMemberLongValue{Value: 10}, &types.FieldMemberStringValue{Value: "alice"}},
+				},
+			},
+		}
+		result := newResult(&DialectMySQL{}, results, true)
+
+		fields, err := result.GeneratedFields()
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := [][]driver.Value{{uint64(10), []byte("alice")}}
+		if !reflect.DeepEqual(fields, want) {
+			t.Errorf("unexpected generated fields: %#v, want %#v", fields, want)
+		}
+	})
 }
@@ -0,0 +1,447 @@
+package rdsdata
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shogo82148/go-rdsdata/internal/sqllex"
+)
+
+// compile time type check
+var _ driver.Connector = (*RoutingConnector)(nil)
+var _ driver.Conn = (*RoutingConn)(nil)
+var _ driver.ConnPrepareContext = (*RoutingConn)(nil)
+var _ driver.ConnBeginTx = (*RoutingConn)(nil)
+var _ driver.Pinger = (*RoutingConn)(nil)
+var _ driver.ExecerContext = (*RoutingConn)(nil)
+var _ driver.QueryerContext = (*RoutingConn)(nil)
+
+// Reader is a reader cluster of RoutingConnector.
+type Reader struct {
+	// Config is the configuration of the reader cluster.
+	Config *Config
+
+	// Weight is the relative weight to select the reader.
+	// Zero is treated as 1.
+	Weight int
+}
+
+// RoutingConfig is the configuration of RoutingConnector.
+type RoutingConfig struct {
+	// Writer is the configuration of the writer cluster.
+	Writer *Config
+
+	// Readers are the reader clusters.
+	Readers []Reader
+
+	// RouteSelects sends the SELECT statements outside transactions to the readers.
+	// If it is false, only the read-only transactions are sent to the readers.
+	// SELECT ... FOR UPDATE and similar locking reads are always sent to the writer.
+	RouteSelects bool
+
+	// ReadOnlyFunctions are the names of the functions that the SELECT statements may call
+	// to be sent to the readers by RouteSelects, e.g. "count", "coalesce" and "now".
+	// The statements that call any other function are sent to the writer,
+	// because functions such as nextval and pg_advisory_lock write or take locks.
+	// The names are case-insensitive, and the schema names are ignored.
+	ReadOnlyFunctions []string
+
+	// HealthCheckInterval is the interval to ping a reader before using it.
+	// The readers that fail the ping are skipped until the next check.
+	// The default is 30 seconds.
+	HealthCheckInterval time.Duration
+}
+
+// RoutingConnector is a connector that routes the statements to a writer cluster and reader clusters.
+// Read-only transactions, and optionally plain SELECT statements, are sent to a reader
+// selected by the weights among the healthy readers.
+// Everything else is sent to the writer, which is also the fallback when no reader is healthy.
+// Use WithWriter to force a statement onto the writer.
+type RoutingConnector struct {
+	driver       *Driver
+	writer       *Connector
+	readers      []*reader
+	routeSelects bool
+	functions    []string
+	interval     time.Duration
+}
+
+// reader is the state of a reader cluster.
+type reader struct {
+	connector *Connector
+	weight    int
+
+	mu        sync.Mutex
+	healthy   bool
+	checkedAt time.Time
+}
+
+// NewRoutingConnector returns a new routing connector.
+func NewRoutingConnector(cfg *RoutingConfig) (*RoutingConnector, error) {
+	if cfg.Writer == nil {
+		return nil, errors.New("rdsdata: the writer is required")
+	}
+	if err := cfg.Writer.Validate(); err != nil {
+		return nil, err
+	}
+	driver := NewDriver()
+	readers := make([]*reader, 0, len(cfg.Readers))
+	for i, r := range cfg.Readers {
+		if r.Config == nil {
+			return nil, fmt.Errorf("rdsdata: the config of the reader %d is required", i)
+		}
+		if err := r.Config.Validate(); err != nil {
+			return nil, fmt.Errorf("rdsdata: reader %d: %w", i, err)
+		}
+		if r.Weight < 0 {
+			return nil, fmt.Errorf("rdsdata: the weight of the reader %d is negative", i)
+		}
+		weight := r.Weight
+		if weight == 0 {
+			weight = 1
+		}
+		readers = append(readers, &reader{
+			connector: newConnector(driver, r.Config),
+			weight:    weight,
+			healthy:   true,
+		})
+	}
+	functions := make([]string, 0, len(cfg.ReadOnlyFunctions))
+	for _, name := range cfg.ReadOnlyFunctions {
+		functions = append(functions, strings.ToLower(name))
+	}
+	interval := cfg.HealthCheckInterval
+	if interval == 0 {
+		interval = 30 * time.Second
+	}
+	return &RoutingConnector{
+		driver:       driver,
+		writer:       newConnector(driver, cfg.Writer),
+		readers:      readers,
+		routeSelects: cfg.RouteSelects,
+		functions:    functions,
+		interval:     interval,
+	}, nil
+}
+
+// Connect connects to the writer.
+// The readers are connected when they are used first.
+func (c *RoutingConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	writer, err := c.writer.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &RoutingConn{
+		connector: c,
+		writer:    writer.(*Conn),
+		readers:   make([]*Conn, len(c.readers)),
+	}, nil
+}
+
+func (c *RoutingConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// available reports whether the reader can be selected: it is healthy, or it is time to check it again.
+func (r *reader) available(now time.Time, interval time.Duration) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.healthy || now.Sub(r.checkedAt) >= interval
+}
+
+// claimCheck reports whether the caller should check the health of the reader.
+// Only one caller checks it in an interval.
+func (r *reader) claimCheck(now time.Time, interval time.Duration) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if now.Sub(r.checkedAt) < interval {
+		return false
+	}
+	r.checkedAt = now
+	return true
+}
+
+// report records the result of the health check.
+func (r *reader) report(healthy bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.healthy = healthy
+}
+
+// RoutingConn is a connection of RoutingConnector.
+type RoutingConn struct {
+	connector *RoutingConnector
+	writer    *Conn
+	readers   []*Conn
+
+	// tx is the connection of the current transaction.
+	tx *Conn
+}
+
+type writerKey struct{}
+
+// WithWriter returns a new context that forces the statements and the transactions onto the writer of RoutingConnector.
+func WithWriter(ctx context.Context) context.Context {
+	return context.WithValue(ctx, writerKey{}, true)
+}
+
+func isWriterForced(ctx context.Context) bool {
+	forced, _ := ctx.Value(writerKey{}).(bool)
+	return forced
+}
+
+// Dialect returns the dialect of the writer.
+// It can be accessed via sql.Conn.Raw.
+func (c *RoutingConn) Dialect() Dialect {
+	return c.writer.Dialect()
+}
+
+// Engine returns the database engine of the writer.
+// It can be accessed via sql.Conn.Raw.
+func (c *RoutingConn) Engine() Engine {
+	return c.writer.Engine()
+}
+
+// ServerVersion returns the server version of the writer.
+// It can be accessed via sql.Conn.Raw.
+func (c *RoutingConn) ServerVersion() string {
+	return c.writer.ServerVersion()
+}
+
+// reader returns the connection to a healthy reader selected by the weights.
+// It returns the writer if no reader is available.
+func (c *RoutingConn) reader(ctx context.Context) *Conn {
+	now := time.Now()
+	interval := c.connector.interval
+	skipped := make([]bool, len(c.connector.readers))
+	for {
+		var candidates []int
+		total := 0
+		for i, r := range c.connector.readers {
+			if !skipped[i] && r.available(now, interval) {
+				candidates = append(candidates, i)
+				total += r.weight
+			}
+		}
+		if total == 0 {
+			return c.writer
+		}
+
+		n := rand.IntN(total)
+		idx := candidates[len(candidates)-1]
+		for _, i := range candidates {
+			w := c.connector.readers[i].weight
+			if n < w {
+				idx = i
+				break
+			}
+			n -= w
+		}
+
+		if conn, ok := c.connectReader(ctx, idx, now); ok {
+			return conn
+		}
+		skipped[idx] = true
+	}
+}
+
+// connectReader returns the connection to the reader, checking its health if it is time.
+func (c *RoutingConn) connectReader(ctx context.Context, idx int, now time.Time) (*Conn, bool) {
+	r := c.connector.readers[idx]
+	conn := c.readers[idx]
+	if conn == nil {
+		dc, err := r.connector.Connect(ctx)
+		if err != nil {
+			r.mu.Lock()
+			r.healthy = false
+			r.checkedAt = now
+			r.mu.Unlock()
+			return nil, false
+		}
+		conn = dc.(*Conn)
+		c.readers[idx] = conn
+	}
+	if r.claimCheck(now, c.connector.interval) {
+		r.report(conn.Ping(ctx) == nil)
+	}
+	return conn, r.available(now, c.connector.interval)
+}
+
+// Prepare prepares a query.
+func (c *RoutingConn) Prepare(query string) (driver.Stmt, error) {
+	return &routingStmt{conn: c, query: query}, nil
+}
+
+// PrepareContext prepares a query.
+// The statement is routed when it is executed.
+func (c *RoutingConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	return &routingStmt{conn: c, query: query}, nil
+}
+
+// Close closes the connection.
+func (c *RoutingConn) Close() error {
+	return nil
+}
+
+// Begin begins a transaction on the writer.
+func (c *RoutingConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+// BeginTx begins a transaction.
+// Read-only transactions are sent to a reader.
+func (c *RoutingConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	conn := c.writer
+	if opts.ReadOnly && !isWriterForced(ctx) {
+		conn = c.reader(ctx)
+	}
+	tx, err := conn.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	c.tx = conn
+	return &routingTx{tx: tx, conn: c}, nil
+}
+
+// ExecContext executes a query on the writer, or in the current transaction.
+func (c *RoutingConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	conn := c.writer
+	if c.tx != nil {
+		conn = c.tx
+	}
+	return conn.ExecContext(ctx, query, args)
+}
+
+// QueryContext executes a query.
+// If RoutingConfig.RouteSelects is set, plain SELECT statements outside transactions are sent to a reader,
+// unless they call functions that are not in RoutingConfig.ReadOnlyFunctions.
+func (c *RoutingConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	conn := c.writer
+	switch {
+	case c.tx != nil:
+		conn = c.tx
+	case c.connector.routeSelects && !isWriterForced(ctx) && isReadOnlyQuery(c.writer.engine, query, c.connector.functions):
+		conn = c.reader(ctx)
+	}
+	return conn.QueryContext(ctx, query, args)
+}
+
+// Ping pings the writer.
+func (c *RoutingConn) Ping(ctx context.Context) error {
+	return c.writer.Ping(ctx)
+}
+
+// isReadOnlyQuery reports whether the query is a SELECT statement that can be sent to a reader:
+// it has no data-modifying CTEs, SELECT INTO or locking reads, and calls only the functions.
+func isReadOnlyQuery(engine Engine, query string, functions []string) bool {
+	tokens := sqllex.Code(engine.syntax(), query)
+	if len(tokens) == 0 || !tokens[0].IsWord("SELECT") {
+		return false
+	}
+	if _, ok := classifyReadOnly(tokens); !ok {
+		return false
+	}
+	for i, token := range tokens[:len(tokens)-1] {
+		if !isFunctionCall(tokens, i) {
+			continue
+		}
+		if !slices.Contains(functions, strings.ToLower(token.Text)) {
+			return false
+		}
+	}
+	return true
+}
+
+// notFunctions are the keywords that can be followed by "(".
+var notFunctions = []string{
+	"ALL", "AND", "ANY", "ARRAY", "AS", "BETWEEN", "BY", "CASE", "DISTINCT", "ELSE", "EXCEPT", "EXISTS",
+	"FILTER", "FROM", "GROUP", "HAVING", "ILIKE", "IN", "INTERSECT", "IS", "JOIN", "LATERAL", "LIKE", "LIMIT",
+	"MATERIALIZED", "NOT", "OFFSET", "ON", "OR", "OVER", "ROW", "SELECT", "SOME", "THEN", "UNION", "USING",
+	"VALUES", "WHEN", "WHERE", "WINDOW", "WITH",
+}
+
+// isFunctionCall reports whether tokens[i] is the name of a called function.
+// The type modifiers such as numeric(10, 2) in casts are not function calls.
+func isFunctionCall(tokens []sqllex.Token, i int) bool {
+	if tokens[i].Kind != sqllex.Word || i+1 >= len(tokens) || tokens[i+1].Text != "(" {
+		return false
+	}
+	if slices.Contains(notFunctions, strings.ToUpper(tokens[i].Text)) {
+		return false
+	}
+	if i > 0 && (tokens[i-1].Text == "::" || tokens[i-1].IsWord("AS")) {
+		return false
+	}
+	return true
+}
+
+// routingTx is a transaction of RoutingConn.
+type routingTx struct {
+	tx   driver.Tx
+	conn *RoutingConn
+}
+
+func (tx *routingTx) Commit() error {
+	err := tx.tx.Commit()
+	tx.done()
+	return err
+}
+
+func (tx *routingTx) Rollback() error {
+	err := tx.tx.Rollback()
+	tx.done()
+	return err
+}
+
+// done detaches the transaction from the connections.
+// database/sql considers the transaction finished once Commit or Rollback is called, even if they fail,
+// so the following statements must not use the transaction.
+func (tx *routingTx) done() {
+	if c := tx.conn; c.tx != nil {
+		c.tx.tx = nil
+		c.tx = nil
+	}
+}
+
+// routingStmt is a prepared statement of RoutingConn.
+type routingStmt struct {
+	conn  *RoutingConn
+	query string
+}
+
+// Close closes the statement.
+func (s *routingStmt) Close() error {
+	return nil
+}
+
+// NumInput returns the number of placeholder parameters.
+func (s *routingStmt) NumInput() int {
+	return -1
+}
+
+// Exec executes a query that doesn't return rows, such as an INSERT or UPDATE.
+func (s *routingStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), convertOrdinal(args))
+}
+
+// ExecContext executes a query that doesn't return rows, such as an INSERT or UPDATE.
+func (s *routingStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return s.conn.ExecContext(ctx, s.query, args)
+}
+
+// Query executes a query that may return rows, such as a SELECT.
+func (s *routingStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), convertOrdinal(args))
+}
+
+// QueryContext executes a query that may return rows, such as a SELECT.
+func (s *routingStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return s.conn.QueryContext(ctx, s.query, args)
+}
@@ -0,0 +1,243 @@
+package rdsdata
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata"
+)
+
+// routingClient returns a client that records the statements as "cluster: SQL".
+func routingClient(cluster string, calls *[]string, pingErr error) Client {
+	return &awsClientMock{
+		ExecuteStatementFunc: func(ctx context.Context, input *rdsdata.ExecuteStatementInput, optFns ...func(*rdsdata.Options)) (*rdsdata.ExecuteStatementOutput, error) {
+			sql := aws.ToString(input.Sql)
+			if sql == "/* ping */ SELECT 1" {
+				return &rdsdata.ExecuteStatementOutput{}, pingErr
+			}
+			*calls = append(*calls, cluster+": "+sql)
+			return &rdsdata.ExecuteStatementOutput{}, nil
+		},
+		BeginTransactionFunc: func(ctx context.Context, input *rdsdata.BeginTransactionInput, optFns ...func(*rdsdata.Options)) (*rdsdata.BeginTransactionOutput, error) {
+			*calls = append(*calls, cluster+": BEGIN")
+			return &rdsdata.BeginTransactionOutput{TransactionId: aws.String("tx")}, nil
+		},
+		CommitTransactionFunc: func(ctx context.Context, input *rdsdata.CommitTransactionInput, optFns ...func(*rdsdata.Options)) (*rdsdata.CommitTransactionOutput, error) {
+			*calls = append(*calls, cluster+": COMMIT")
+			return &rdsdata.CommitTransactionOutput{}, nil
+		},
+	}
+}
+
+func routingConfig(cluster string, client Client) *Config {
+	return &Config{
+		ResourceArn: "arn:aws:rds:us-east-1:123456789012:cluster:" + cluster,
+		SecretArn:   "arn:aws:secretsmanager:us-east-1:123456789012:secret:" + cluster,
+		Engine:      EngineMySQL,
+		Client:      client,
+	}
+}
+
+func TestRoutingConnector(t *testing.T) {
+	var calls []string
+	c, err := NewRoutingConnector(&RoutingConfig{
+		Writer: routingConfig("writer", routingClient("writer", &calls, nil)),
+		Readers: []Reader{
+			{Config: routingConfig("reader", routingClient("reader", &calls, nil))},
+		},
+		RouteSelects: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := sql.OpenDB(c)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	ctx := context.Background()
+	if _, err := db.ExecContext(ctx, "UPDATE users SET name = 'a'"); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := db.QueryContext(ctx, "/* list */ SELECT * FROM users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+	rows, err = db.QueryContext(ctx, "SELECT * FROM users FOR UPDATE")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+	rows, err = db.QueryContext(WithWriter(ctx), "SELECT * FROM posts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.ExecContext(ctx, "SELECT 1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.ExecContext(ctx, "DELETE FROM users"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"writer: UPDATE users SET name = 'a'",
+		"reader: /* list */ SELECT * FROM users",
+		"writer: SELECT * FROM users FOR UPDATE",
+		"writer: SELECT * FROM posts",
+		"reader: BEGIN",
+		"reader: START TRANSACTION READ ONLY",
+		"reader: SELECT 1",
+		"reader: COMMIT",
+		"writer: DELETE FROM users",
+	}
+	if fmt.Sprint(calls) != fmt.Sprint(want) {
+		t.Errorf("unexpected calls:\n got: %q\nwant: %q", calls, want)
+	}
+}
+
+func TestRoutingConnector_CommitError(t *testing.T) {
+	var calls []string
+	client := &awsClientMock{
+		ExecuteStatementFunc: func(ctx context.Context, input *rdsdata.ExecuteStatementInput, optFns ...func(*rdsdata.Options)) (*rdsdata.ExecuteStatementOutput, error) {
+			call := aws.ToString(input.Sql)
+			if input.TransactionId != nil {
+				call += " @" + aws.ToString(input.TransactionId)
+			}
+			calls = append(calls, call)
+			return &rdsdata.ExecuteStatementOutput{}, nil
+		},
+		BeginTransactionFunc: func(ctx context.Context, input *rdsdata.BeginTransactionInput, optFns ...func(*rdsdata.Options)) (*rdsdata.BeginTransactionOutput, error) {
+			calls = append(calls, "BEGIN")
+			return &rdsdata.BeginTransactionOutput{TransactionId: aws.String("tx")}, nil
+		},
+		CommitTransactionFunc: func(ctx context.Context, input *rdsdata.CommitTransactionInput, optFns ...func(*rdsdata.Options)) (*rdsdata.CommitTransactionOutput, error) {
+			calls = append(calls, "COMMIT")
+			return nil, errors.New("commit failed")
+		},
+	}
+	c, err := NewRoutingConnector(&RoutingConfig{
+		Writer: routingConfig("writer", client),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := sql.OpenDB(c)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	ctx := context.Background()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE users SET name = 'a'"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err == nil {
+		t.Fatal("want error, got nil")
+	}
+
+	// the failed transaction is finished, and the connection doesn't use it anymore.
+	if _, err := db.ExecContext(ctx, "DELETE FROM users"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"BEGIN",
+		"UPDATE users SET name = 'a' @tx",
+		"COMMIT",
+		"DELETE FROM users",
+	}
+	if fmt.Sprint(calls) != fmt.Sprint(want) {
+		t.Errorf("unexpected calls:\n got: %q\nwant: %q", calls, want)
+	}
+}
+
+func TestRoutingConnector_UnhealthyReader(t *testing.T) {
+	var calls []string
+	c, err := NewRoutingConnector(&RoutingConfig{
+		Writer: routingConfig("writer", routingClient("writer", &calls, nil)),
+		Readers: []Reader{
+			{Config: routingConfig("reader", routingClient("reader", &calls, errors.New("unavailable"))), Weight: 10},
+		},
+		RouteSelects: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := sql.OpenDB(c)
+	defer db.Close()
+
+	rows, err := db.QueryContext(context.Background(), "SELECT * FROM users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+
+	want := []string{"writer: SELECT * FROM users"}
+	if fmt.Sprint(calls) != fmt.Sprint(want) {
+		t.Errorf("unexpected calls:\n got: %q\nwant: %q", calls, want)
+	}
+}
+
+func TestNewRoutingConnector_Invalid(t *testing.T) {
+	if _, err := NewRoutingConnector(&RoutingConfig{}); err == nil {
+		t.Error("expected error, but got nil")
+	}
+
+	var calls []string
+	_, err := NewRoutingConnector(&RoutingConfig{
+		Writer: routingConfig("writer", routingClient("writer", &calls, nil)),
+		Readers: []Reader{
+			{Config: routingConfig("reader", routingClient("reader", &calls, nil)), Weight: -1},
+		},
+	})
+	if err == nil {
+		t.Error("expected error, but got nil")
+	}
+}
+
+func TestIsReadOnlyQuery(t *testing.T) {
+	functions := []string{"count", "coalesce"}
+	tests := []struct {
+		engine Engine
+		query  string
+		want   bool
+	}{
+		{EngineMySQL, "SELECT 1", true},
+		{EngineMySQL, "  select * from users", true},
+		{EngineMySQL, "-- comment\nSELECT 1", true},
+		{EngineMySQL, "/* comment */ SELECT 1", true},
+		{EngineMySQL, "SELECT COUNT(*), coalesce(name, '') FROM users WHERE id IN (1, 2)", true},
+		{EngineMySQL, "SELECT CAST(id AS CHAR(10)) FROM users", false},
+		{EngineMySQL, "SELECT * FROM users FOR UPDATE", false},
+		{EngineMySQL, "SELECT * FROM users\nFOR UPDATE", false},
+		{EnginePostgres, "SELECT * FROM users FOR SHARE", false},
+		{EngineMySQL, "SELECT * FROM users LOCK IN SHARE MODE", false},
+		{EngineMySQL, "SELECT 1 INTO @x", false},
+		{EnginePostgres, "SELECT *\tINTO backup FROM users", false},
+		{EngineMySQL, "INSERT INTO users VALUES (1)", false},
+		{EnginePostgres, "WITH t AS (DELETE FROM users RETURNING *) SELECT * FROM t", false},
+		{EnginePostgres, "SELECT nextval('s')", false},
+		{EnginePostgres, "SELECT pg_catalog.pg_advisory_lock(1)", false},
+		{EnginePostgres, "SELECT 'nextval(1)', id::numeric(10, 2) FROM users", true},
+	}
+	for _, tt := range tests {
+		if got := isReadOnlyQuery(tt.engine, tt.query, functions); got != tt.want {
+			t.Errorf("isReadOnlyQuery(%s, %q) = %v, want %v", tt.engine, tt.query, got, tt.want)
+		}
+	}
+}
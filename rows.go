@@ -3,14 +3,19 @@ package rdsdata
 import (
 	"database/sql/driver"
 	"io"
+	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/rdsdata"
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata/types"
 )
 
 // compile time type check
 var _ driver.Rows = (*Rows)(nil)
 var _ driver.RowsNextResultSet = (*Rows)(nil)
+var _ driver.RowsColumnTypeDatabaseTypeName = (*Rows)(nil)
+var _ driver.RowsColumnTypeNullable = (*Rows)(nil)
+var _ driver.RowsColumnTypePrecisionScale = (*Rows)(nil)
 
 type Rows struct {
 	results        []*rdsdata.ExecuteStatementOutput
@@ -86,3 +91,65 @@ func (r *Rows) setResultIndex(index int) {
 		r.columnNames[i] = aws.ToString(col.Label)
 	}
 }
+
+// ColumnTypeDatabaseTypeName returns the database system type name of the column,
+// which is ColumnMetadata.TypeName of the Data API, e.g. "BIGINT UNSIGNED" for MySQL and "int8" for PostgreSQL.
+// It is reported by sql.ColumnType.DatabaseTypeName.
+func (r *Rows) ColumnTypeDatabaseTypeName(index int) string {
+	return aws.ToString(r.columnMetadata()[index].TypeName)
+}
+
+// ColumnTypeNullable reports whether the column may be null.
+// ok is false if the Data API doesn't know it.
+// It is reported by sql.ColumnType.Nullable.
+func (r *Rows) ColumnTypeNullable(index int) (nullable, ok bool) {
+	// The values of Nullable follow java.sql.ResultSetMetaData:
+	// 0 (columnNoNulls), 1 (columnNullable) and 2 (columnNullableUnknown).
+	switch r.columnMetadata()[index].Nullable {
+	case 0:
+		return false, true
+	case 1:
+		return true, true
+	default:
+		return false, false
+	}
+}
+
+// ColumnTypePrecisionScale returns the precision and scale for decimal types.
+// ok is false for the other types.
+// It is reported by sql.ColumnType.DecimalSize.
+func (r *Rows) ColumnTypePrecisionScale(index int) (precision, scale int64, ok bool) {
+	col := r.columnMetadata()[index]
+	switch strings.ToUpper(aws.ToString(col.TypeName)) {
+	case "DECIMAL", "NUMERIC", "DECIMAL UNSIGNED":
+		return int64(col.Precision), int64(col.Scale), true
+	}
+	return 0, 0, false
+}
+
+// ColumnMetadata returns the raw metadata of the columns in the current result set.
+// database/sql hides the driver.Rows, so it can be reached by querying through sql.Conn.Raw:
+//
+//	err := conn.Raw(func(driverConn any) error {
+//		rows, err := driverConn.(driver.QueryerContext).QueryContext(ctx, query, args)
+//		if err != nil {
+//			return err
+//		}
+//		defer rows.Close()
+//		metadata := rows.(*rdsdata.Rows).ColumnMetadata()
+//		// ...
+//	})
+func (r *Rows) ColumnMetadata() []types.ColumnMetadata {
+	return r.columnMetadata()
+}
+
+// Result returns the result of the statements that produced the rows,
+// e.g. the number of updated records of an UPDATE statement executed by QueryContext.
+// It can be reached in the same way as ColumnMetadata.
+func (r *Rows) Result() *Result {
+	return newResult(r.dialect, r.results, false)
+}
+
+func (r *Rows) columnMetadata() []types.ColumnMetadata {
+	return r.results[r.resultPosition].ColumnMetadata
+}
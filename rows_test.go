@@ -0,0 +1,107 @@
+package rdsdata
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata"
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata/types"
+)
+
+func openRowsTestDB(t *testing.T, out *rdsdata.ExecuteStatementOutput) *sql.DB {
+	t.Helper()
+	client := &awsClientMock{
+		ExecuteStatementFunc: func(ctx context.Context, input *rdsdata.ExecuteStatementInput, optFns ...func(*rdsdata.Options)) (*rdsdata.ExecuteStatementOutput, error) {
+			return out, nil
+		},
+	}
+	db := sql.OpenDB(NewConnector(&Config{
+		ResourceArn: "arn:aws:rds:us-east-1:123456789012:cluster:cluster",
+		SecretArn:   "arn:aws:secretsmanager:us-east-1:123456789012:secret:secretArn",
+		Engine:      EngineMySQL,
+		Client:      client,
+	}))
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestRows_ColumnTypes(t *testing.T) {
+	db := openRowsTestDB(t, &rdsdata.ExecuteStatementOutput{
+		ColumnMetadata: []types.ColumnMetadata{
+			{Label: aws.String("id"), TypeName: aws.String("BIGINT UNSIGNED"), Nullable: 0},
+			{Label: aws.String("price"), TypeName: aws.String("DECIMAL"), Precision: 10, Scale: 2, Nullable: 1},
+			{Label: aws.String("name"), TypeName: aws.String("VARCHAR"), Nullable: 2},
+		},
+	})
+
+	rows, err := db.QueryContext(context.Background(), "SELECT id, price, name FROM items")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	columns, err := rows.ColumnTypes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		typeName         string
+		nullable, nullOK bool
+		precision, scale int64
+		decimalOK        bool
+	}{
+		{"BIGINT UNSIGNED", false, true, 0, 0, false},
+		{"DECIMAL", true, true, 10, 2, true},
+		{"VARCHAR", false, false, 0, 0, false},
+	}
+	for i, tt := range tests {
+		col := columns[i]
+		if got := col.DatabaseTypeName(); got != tt.typeName {
+			t.Errorf("%s: unexpected DatabaseTypeName: %q, want %q", col.Name(), got, tt.typeName)
+		}
+		if nullable, ok := col.Nullable(); nullable != tt.nullable || ok != tt.nullOK {
+			t.Errorf("%s: unexpected Nullable: %v, %v, want %v, %v", col.Name(), nullable, ok, tt.nullable, tt.nullOK)
+		}
+		if precision, scale, ok := col.DecimalSize(); precision != tt.precision || scale != tt.scale || ok != tt.decimalOK {
+			t.Errorf("%s: unexpected DecimalSize: %d, %d, %v, want %d, %d, %v", col.Name(), precision, scale, ok, tt.precision, tt.scale, tt.decimalOK)
+		}
+	}
+}
+
+func TestRows_ColumnMetadataAndResult(t *testing.T) {
+	db := openRowsTestDB(t, &rdsdata.ExecuteStatementOutput{
+		NumberOfRecordsUpdated: 3,
+	})
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	err = conn.Raw(func(driverConn any) error {
+		r, err := driverConn.(driver.QueryerContext).QueryContext(ctx, "UPDATE users SET name = 'a'", nil)
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		rows := r.(*Rows)
+		if metadata := rows.ColumnMetadata(); len(metadata) != 0 {
+			t.Errorf("unexpected metadata: %v", metadata)
+		}
+		affected, err := rows.Result().RowsAffected()
+		if err != nil {
+			return err
+		}
+		if affected != 3 {
+			t.Errorf("unexpected rows affected: %d, want 3", affected)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,127 @@
+package rdsdata
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata"
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata/types"
+)
+
+// ErrSchemaNotSupported is returned when a schema is set for an engine other than PostgreSQL.
+// MySQL has no schemas apart from the databases, so use Config.Database or the Database option instead.
+var ErrSchemaNotSupported = errors.New("rdsdata: schema is supported only on PostgreSQL")
+
+// executeInSchema executes the statement with the search_path of the schema.
+//
+// The Data API doesn't support the Schema parameter, and it doesn't keep the session between the calls,
+// so the search_path is set locally in the transaction of the statement by set_config.
+// Outside of a transaction, the statement is wrapped in a new transaction,
+// which costs three extra calls: begin, set_config and commit.
+// They are skipped if the schema is the default schema of the database,
+// which is looked up once for each database of the connection.
+func (s *Stmt) executeInSchema(ctx context.Context, kind callKind, input *rdsdata.ExecuteStatementInput, schema string, opts *callOptions) (*rdsdata.ExecuteStatementOutput, error) {
+	if s.conn.engine != EnginePostgres {
+		return nil, ErrSchemaNotSupported
+	}
+
+	tx := s.conn.tx
+	current := ""
+	if tx != nil {
+		current = tx.searchPath
+	}
+	if current == "" {
+		def, err := s.defaultSchema(ctx, input, opts)
+		if err != nil {
+			return nil, err
+		}
+		current = def
+	}
+	if current == schema {
+		return s.execute(ctx, kind, input, opts)
+	}
+
+	owned := tx == nil
+	if owned {
+		t, err := s.conn.BeginTx(ctx, driver.TxOptions{})
+		if err != nil {
+			return nil, err
+		}
+		tx = t.(*Tx)
+		defer func() {
+			if !tx.done {
+				_ = tx.Rollback()
+				// the transaction is abandoned even if the rollback fails,
+				// so that the following statements don't use it.
+				s.conn.tx = nil
+			}
+		}()
+	}
+
+	set := &rdsdata.ExecuteStatementInput{
+		ResourceArn:   input.ResourceArn,
+		Database:      input.Database,
+		TransactionId: tx.id,
+		Sql:           aws.String("SELECT set_config('search_path', :search_path, true)"),
+		Parameters: []types.SqlParameter{
+			{
+				Name:  aws.String("search_path"),
+				Value: &types.FieldMemberStringValue{Value: quoteIdentifier(schema)},
+			},
+		},
+	}
+	if _, err := s.execute(ctx, callExec, set, opts); err != nil {
+		return nil, err
+	}
+	tx.searchPath = schema
+
+	input.TransactionId = tx.id
+	out, err := s.execute(ctx, kind, input, opts)
+	if err != nil {
+		return nil, err
+	}
+	if owned {
+		if err := tx.Commit(); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// defaultSchema returns the default schema of the database of the input,
+// which is the first existing schema in the default search_path.
+// It is empty if no schema in the search_path exists.
+func (s *Stmt) defaultSchema(ctx context.Context, input *rdsdata.ExecuteStatementInput, opts *callOptions) (string, error) {
+	database := aws.ToString(input.Database)
+	if schema, ok := s.conn.defaultSchemas[database]; ok {
+		return schema, nil
+	}
+
+	out, err := s.execute(ctx, callQuery, &rdsdata.ExecuteStatementInput{
+		ResourceArn: input.ResourceArn,
+		Database:    input.Database,
+		Sql:         aws.String("SELECT current_schema()"),
+	}, opts)
+	if err != nil {
+		return "", err
+	}
+	var schema string
+	if len(out.Records) > 0 && len(out.Records[0]) > 0 {
+		if v, ok := out.Records[0][0].(*types.FieldMemberStringValue); ok {
+			schema = v.Value
+		}
+	}
+	if s.conn.defaultSchemas == nil {
+		s.conn.defaultSchemas = map[string]string{}
+	}
+	s.conn.defaultSchemas[database] = schema
+	return schema, nil
+}
+
+// quoteIdentifier quotes the identifier of PostgreSQL.
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
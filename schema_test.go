@@ -0,0 +1,216 @@
+package rdsdata
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata"
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata/types"
+)
+
+// schemaClient returns a client that records the calls.
+func schemaClient(calls *[]string) Client {
+	return &awsClientMock{
+		ExecuteStatementFunc: func(ctx context.Context, input *rdsdata.ExecuteStatementInput, optFns ...func(*rdsdata.Options)) (*rdsdata.ExecuteStatementOutput, error) {
+			call := aws.ToString(input.Sql)
+			for _, p := range input.Parameters {
+				if v, ok := p.Value.(*types.FieldMemberStringValue); ok {
+					call += fmt.Sprintf(" [%s=%s]", aws.ToString(p.Name), v.Value)
+				}
+			}
+			if input.TransactionId != nil {
+				call += " @" + aws.ToString(input.TransactionId)
+			}
+			*calls = append(*calls, call)
+			if call == "SELECT current_schema()" {
+				return &rdsdata.ExecuteStatementOutput{
+					Records: [][]types.Field{{&types.FieldMemberStringValue{Value: "public"}}},
+				}, nil
+			}
+			return &rdsdata.ExecuteStatementOutput{}, nil
+		},
+		BeginTransactionFunc: func(ctx context.Context, input *rdsdata.BeginTransactionInput, optFns ...func(*rdsdata.Options)) (*rdsdata.BeginTransactionOutput, error) {
+			*calls = append(*calls, "BEGIN")
+			return &rdsdata.BeginTransactionOutput{TransactionId: aws.String("tx")}, nil
+		},
+		CommitTransactionFunc: func(ctx context.Context, input *rdsdata.CommitTransactionInput, optFns ...func(*rdsdata.Options)) (*rdsdata.CommitTransactionOutput, error) {
+			*calls = append(*calls, "COMMIT")
+			return &rdsdata.CommitTransactionOutput{}, nil
+		},
+		RollbackTransactionFunc: func(ctx context.Context, input *rdsdata.RollbackTransactionInput, optFns ...func(*rdsdata.Options)) (*rdsdata.RollbackTransactionOutput, error) {
+			*calls = append(*calls, "ROLLBACK")
+			return &rdsdata.RollbackTransactionOutput{}, nil
+		},
+	}
+}
+
+func openSchemaDB(t *testing.T, engine Engine, calls *[]string) *sql.DB {
+	t.Helper()
+	db := sql.OpenDB(NewConnector(&Config{
+		ResourceArn: "arn:aws:rds:us-east-1:123456789012:cluster:cluster",
+		SecretArn:   "arn:aws:secretsmanager:us-east-1:123456789012:secret:secretArn",
+		Engine:      engine,
+		Schema:      "tenant1",
+		Client:      schemaClient(calls),
+	}))
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestSchema(t *testing.T) {
+	t.Run("outside of a transaction", func(t *testing.T) {
+		var calls []string
+		db := openSchemaDB(t, EnginePostgres, &calls)
+		ctx := WithOptions(context.Background(), Schema(`tenant"2`))
+		for i := 0; i < 2; i++ {
+			if _, err := db.ExecContext(ctx, "DELETE FROM users"); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		// the default schema is looked up once.
+		want := []string{
+			"SELECT current_schema()",
+			"BEGIN",
+			`SELECT set_config('search_path', :search_path, true) [search_path="tenant""2"] @tx`,
+			"DELETE FROM users @tx",
+			"COMMIT",
+			"BEGIN",
+			`SELECT set_config('search_path', :search_path, true) [search_path="tenant""2"] @tx`,
+			"DELETE FROM users @tx",
+			"COMMIT",
+		}
+		if fmt.Sprint(calls) != fmt.Sprint(want) {
+			t.Errorf("unexpected calls:\n got: %q\nwant: %q", calls, want)
+		}
+	})
+
+	t.Run("in a transaction", func(t *testing.T) {
+		var calls []string
+		db := openSchemaDB(t, EnginePostgres, &calls)
+		ctx := context.Background()
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 2; i++ {
+			if _, err := tx.ExecContext(ctx, "DELETE FROM users"); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			t.Fatal(err)
+		}
+
+		// search_path is set once in the transaction.
+		want := []string{
+			"BEGIN",
+			"SELECT current_schema()",
+			`SELECT set_config('search_path', :search_path, true) [search_path="tenant1"] @tx`,
+			"DELETE FROM users @tx",
+			"DELETE FROM users @tx",
+			"COMMIT",
+		}
+		if fmt.Sprint(calls) != fmt.Sprint(want) {
+			t.Errorf("unexpected calls:\n got: %q\nwant: %q", calls, want)
+		}
+	})
+
+	t.Run("default schema", func(t *testing.T) {
+		var calls []string
+		db := openSchemaDB(t, EnginePostgres, &calls)
+		ctx := WithOptions(context.Background(), Schema("public"))
+		for i := 0; i < 2; i++ {
+			if _, err := db.ExecContext(ctx, "DELETE FROM users"); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		want := []string{
+			"SELECT current_schema()",
+			"DELETE FROM users",
+			"DELETE FROM users",
+		}
+		if fmt.Sprint(calls) != fmt.Sprint(want) {
+			t.Errorf("unexpected calls:\n got: %q\nwant: %q", calls, want)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		// the statements that can't run in a transaction disable the schema.
+		var calls []string
+		db := openSchemaDB(t, EnginePostgres, &calls)
+		ctx := WithOptions(context.Background(), Schema(""))
+		if _, err := db.ExecContext(ctx, "CREATE INDEX CONCURRENTLY users_name ON tenant1.users (name)"); err != nil {
+			t.Fatal(err)
+		}
+
+		want := []string{"CREATE INDEX CONCURRENTLY users_name ON tenant1.users (name)"}
+		if fmt.Sprint(calls) != fmt.Sprint(want) {
+			t.Errorf("unexpected calls:\n got: %q\nwant: %q", calls, want)
+		}
+	})
+
+	t.Run("failed rollback", func(t *testing.T) {
+		var calls []string
+		client := schemaClient(&calls).(*awsClientMock)
+		execute := client.ExecuteStatementFunc
+		client.ExecuteStatementFunc = func(ctx context.Context, input *rdsdata.ExecuteStatementInput, optFns ...func(*rdsdata.Options)) (*rdsdata.ExecuteStatementOutput, error) {
+			out, err := execute(ctx, input, optFns...)
+			if aws.ToString(input.Sql) == "DELETE FROM users" {
+				return nil, errors.New("statement failed")
+			}
+			return out, err
+		}
+		client.RollbackTransactionFunc = func(ctx context.Context, input *rdsdata.RollbackTransactionInput, optFns ...func(*rdsdata.Options)) (*rdsdata.RollbackTransactionOutput, error) {
+			calls = append(calls, "ROLLBACK")
+			return nil, errors.New("rollback failed")
+		}
+		db := sql.OpenDB(NewConnector(&Config{
+			ResourceArn: "arn:aws:rds:us-east-1:123456789012:cluster:cluster",
+			SecretArn:   "arn:aws:secretsmanager:us-east-1:123456789012:secret:secretArn",
+			Engine:      EnginePostgres,
+			Schema:      "tenant1",
+			Client:      client,
+		}))
+		defer db.Close()
+		db.SetMaxOpenConns(1)
+		ctx := context.Background()
+
+		if _, err := db.ExecContext(ctx, "DELETE FROM users"); err == nil {
+			t.Fatal("want error, got nil")
+		}
+		// the next statement doesn't use the abandoned transaction.
+		if _, err := db.ExecContext(WithOptions(ctx, Schema("")), "SELECT 1"); err != nil {
+			t.Fatal(err)
+		}
+
+		want := []string{
+			"SELECT current_schema()",
+			"BEGIN",
+			`SELECT set_config('search_path', :search_path, true) [search_path="tenant1"] @tx`,
+			"DELETE FROM users @tx",
+			"ROLLBACK",
+			"SELECT 1",
+		}
+		if fmt.Sprint(calls) != fmt.Sprint(want) {
+			t.Errorf("unexpected calls:\n got: %q\nwant: %q", calls, want)
+		}
+	})
+
+	t.Run("mysql", func(t *testing.T) {
+		var calls []string
+		db := openSchemaDB(t, EngineMySQL, &calls)
+		_, err := db.ExecContext(context.Background(), "DELETE FROM users")
+		if !errors.Is(err, ErrSchemaNotSupported) {
+			t.Errorf("want ErrSchemaNotSupported, got %v", err)
+		}
+		if len(calls) != 0 {
+			t.Errorf("unexpected calls: %q", calls)
+		}
+	})
+}
@@ -0,0 +1,196 @@
+package rdsdata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata/types"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/shogo82148/go-retry/v2"
+)
+
+// SecretsManagerClient is the interface that captures methods of the Secrets Manager client required by the driver.
+// *secretsmanager.Client satisfies it.
+type SecretsManagerClient interface {
+	DescribeSecret(ctx context.Context, in *secretsmanager.DescribeSecretInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.DescribeSecretOutput, error)
+}
+
+// SecretProvider provides the secret used by the Data API calls.
+// It is called for every statement and transaction,
+// so it can switch the secret at runtime, or pick the secret from the context to run as a different database user.
+// The statements in a transaction use the secret that began the transaction.
+type SecretProvider interface {
+	// SecretArn returns the ARN or the name of the secret for the call.
+	SecretArn(ctx context.Context) (string, error)
+}
+
+// SecretProviderFunc is an adapter to allow the use of ordinary functions as SecretProvider.
+type SecretProviderFunc func(ctx context.Context) (string, error)
+
+// SecretArn implements SecretProvider.
+func (f SecretProviderFunc) SecretArn(ctx context.Context) (string, error) {
+	return f(ctx)
+}
+
+// isSecretArn reports whether the secret is specified by the ARN, not by the name.
+func isSecretArn(secret string) bool {
+	return strings.HasPrefix(secret, "arn:")
+}
+
+// secretArn returns the ARN of the secret for the call.
+// The names of the secrets are resolved through Secrets Manager once.
+func (c *Connector) secretArn(ctx context.Context) (string, error) {
+	secret := c.cfg.SecretArn
+	if c.cfg.SecretProvider != nil {
+		var err error
+		secret, err = c.cfg.SecretProvider.SecretArn(ctx)
+		if err != nil {
+			return "", err
+		}
+	}
+	if secret == "" || isSecretArn(secret) {
+		// the Data API reports the empty secret.
+		return secret, nil
+	}
+
+	c.secretsMu.Lock()
+	defer c.secretsMu.Unlock()
+	if arn, ok := c.secrets[secret]; ok {
+		return arn, nil
+	}
+
+	client, err := c.newSecretsManagerClient(ctx)
+	if err != nil {
+		return "", err
+	}
+	out, err := client.DescribeSecret(ctx, &secretsmanager.DescribeSecretInput{
+		SecretId: aws.String(secret),
+	})
+	if err != nil {
+		return "", fmt.Errorf("rdsdata: failed to resolve the secret %q: %w", secret, err)
+	}
+	arn := aws.ToString(out.ARN)
+	if arn == "" {
+		return "", fmt.Errorf("rdsdata: failed to resolve the secret %q", secret)
+	}
+	if c.secrets == nil {
+		c.secrets = map[string]string{}
+	}
+	c.secrets[secret] = arn
+	return arn, nil
+}
+
+// newSecretsManagerClient returns the client for resolving the names of the secrets.
+// It must be called with c.secretsMu held.
+func (c *Connector) newSecretsManagerClient(ctx context.Context) (SecretsManagerClient, error) {
+	if c.cfg.SecretsManagerClient != nil {
+		return c.cfg.SecretsManagerClient, nil
+	}
+	if c.secretsManager != nil {
+		return c.secretsManager, nil
+	}
+	awsConfig, err := config.LoadDefaultConfig(ctx, config.WithRegion(c.cfg.region()))
+	if err != nil {
+		return nil, err
+	}
+	c.secretsManager = secretsmanager.NewFromConfig(awsConfig, func(o *secretsmanager.Options) {
+		if c.cfg.SecretsManagerEndpoint != "" {
+			o.BaseEndpoint = aws.String(c.cfg.SecretsManagerEndpoint)
+		}
+	})
+	return c.secretsManager, nil
+}
+
+// withSecret calls f with the ARN of the secret for the call.
+//
+// The Data API always authenticates with the AWSCURRENT version of the secret.
+// While the secret is rotated, the password of the database user is changed to the AWSPENDING version
+// before it is promoted to AWSCURRENT, so the authentication may fail for a moment.
+// If the authentication fails outside a transaction, withSecret retries f once
+// if the SecretProvider has switched to another secret,
+// or if Secrets Manager reports that the secret is being rotated: it waits until
+// the AWSPENDING version is promoted to AWSCURRENT, as long as ctx allows.
+// The other authentication errors, e.g. a wrong password that is not rotated, are returned as is.
+// The calls in a transaction are not retried, because the transaction is bound to the secret.
+func (c *Conn) withSecret(ctx context.Context, f func(ctx context.Context, secretArn string) error) error {
+	if c.tx != nil {
+		return f(ctx, c.tx.secretArn)
+	}
+
+	start := time.Now()
+	secretArn, err := c.connector.secretArn(ctx)
+	if err != nil {
+		return err
+	}
+	err = f(ctx, secretArn)
+	if err == nil || !isAuthError(err) || c.connector.policy == nil {
+		return err
+	}
+
+	next, nextErr := c.connector.secretArn(ctx)
+	if nextErr != nil {
+		return err
+	}
+	if next == secretArn && !c.connector.waitRotation(ctx, secretArn, start) {
+		return err
+	}
+	return f(withAttempt(ctx, 2), next)
+}
+
+// errRotationPending is returned while the AWSPENDING version of the secret is not promoted yet.
+var errRotationPending = errors.New("rdsdata: the rotation of the secret is pending")
+
+// waitRotation waits for the rotation of the secret to finish.
+// It reports whether the secret has been rotated since the time,
+// so that the Data API authenticates with the new AWSCURRENT version.
+// The wait is bounded by the retry policy and ctx.
+func (c *Connector) waitRotation(ctx context.Context, secretArn string, since time.Time) bool {
+	c.secretsMu.Lock()
+	client, err := c.newSecretsManagerClient(ctx)
+	c.secretsMu.Unlock()
+	if err != nil {
+		return false
+	}
+
+	var rotated bool
+	err = c.policy.Do(ctx, func() error {
+		out, err := client.DescribeSecret(ctx, &secretsmanager.DescribeSecretInput{
+			SecretId: aws.String(secretArn),
+		})
+		if err != nil {
+			return retry.MarkPermanent(err)
+		}
+		for _, stages := range out.VersionIdsToStages {
+			if slices.Contains(stages, "AWSPENDING") && !slices.Contains(stages, "AWSCURRENT") {
+				return errRotationPending
+			}
+		}
+		rotated = out.LastRotatedDate != nil && out.LastRotatedDate.After(since)
+		return nil
+	})
+	return err == nil && rotated
+}
+
+// isAuthError reports whether the database rejected the credentials in the secret.
+func isAuthError(err error) bool {
+	var msg string
+	var badRequest *types.BadRequestException
+	var dbErr *types.DatabaseErrorException
+	switch {
+	case errors.As(err, &badRequest):
+		msg = aws.ToString(badRequest.Message)
+	case errors.As(err, &dbErr):
+		msg = aws.ToString(dbErr.Message)
+	default:
+		return false
+	}
+	return strings.Contains(msg, "Access denied for user") || // MySQL: ER_ACCESS_DENIED_ERROR
+		strings.Contains(msg, "password authentication failed") || // PostgreSQL: invalid_password
+		strings.Contains(msg, "SQLState: 28P01")
+}
@@ -0,0 +1,347 @@
+package rdsdata
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata"
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata/types"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/shogo82148/go-retry/v2"
+)
+
+const (
+	testSecretArn  = "arn:aws:secretsmanager:us-east-1:123456789012:secret:app-AbCdEf"
+	testSecretArn2 = "arn:aws:secretsmanager:us-east-1:123456789012:secret:admin-AbCdEf"
+)
+
+type secretsManagerMock struct {
+	calls int
+
+	// versions are the responses for testSecretArn in order.
+	// The last one is repeated.
+	versions []*secretsmanager.DescribeSecretOutput
+}
+
+func (mock *secretsManagerMock) DescribeSecret(ctx context.Context, in *secretsmanager.DescribeSecretInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.DescribeSecretOutput, error) {
+	mock.calls++
+	switch aws.ToString(in.SecretId) {
+	case testSecretArn:
+		if len(mock.versions) == 0 {
+			break
+		}
+		out := mock.versions[0]
+		if len(mock.versions) > 1 {
+			mock.versions = mock.versions[1:]
+		}
+		return out, nil
+	case "app":
+		return &secretsmanager.DescribeSecretOutput{ARN: aws.String(testSecretArn)}, nil
+	case "admin":
+		return &secretsmanager.DescribeSecretOutput{ARN: aws.String(testSecretArn2)}, nil
+	}
+	return nil, errors.New("secret not found")
+}
+
+func newTestConn(cfg *Config, client Client) *Conn {
+	return &Conn{
+		client: client,
+		connector: &Connector{
+			cfg: cfg,
+			policy: &retry.Policy{
+				MinDelay: time.Millisecond,
+				MaxDelay: time.Millisecond,
+				MaxCount: 3,
+			},
+		},
+		dialect: &DialectMySQL{},
+	}
+}
+
+func TestConnector_secretArn(t *testing.T) {
+	t.Run("resolves the name once", func(t *testing.T) {
+		sm := &secretsManagerMock{}
+		c := &Connector{cfg: &Config{SecretArn: "app", SecretsManagerClient: sm}}
+		for i := 0; i < 2; i++ {
+			arn, err := c.secretArn(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if arn != testSecretArn {
+				t.Errorf("unexpected ARN: %q", arn)
+			}
+		}
+		if sm.calls != 1 {
+			t.Errorf("unexpected calls: %d", sm.calls)
+		}
+	})
+
+	t.Run("unknown name", func(t *testing.T) {
+		c := &Connector{cfg: &Config{SecretArn: "unknown", SecretsManagerClient: &secretsManagerMock{}}}
+		if _, err := c.secretArn(context.Background()); err == nil {
+			t.Fatal("expected error, but got nil")
+		}
+	})
+
+	t.Run("ARN", func(t *testing.T) {
+		sm := &secretsManagerMock{}
+		c := &Connector{cfg: &Config{SecretArn: testSecretArn, SecretsManagerClient: sm}}
+		arn, err := c.secretArn(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if arn != testSecretArn {
+			t.Errorf("unexpected ARN: %q", arn)
+		}
+		if sm.calls != 0 {
+			t.Errorf("unexpected calls: %d", sm.calls)
+		}
+	})
+}
+
+type secretKey struct{}
+
+func TestConn_SecretProvider(t *testing.T) {
+	var got []string
+	client := &awsClientMock{
+		ExecuteStatementFunc: func(ctx context.Context, input *rdsdata.ExecuteStatementInput, optFns ...func(*rdsdata.Options)) (*rdsdata.ExecuteStatementOutput, error) {
+			got = append(got, aws.ToString(input.SecretArn))
+			return &rdsdata.ExecuteStatementOutput{}, nil
+		},
+	}
+	conn := newTestConn(&Config{
+		SecretsManagerClient: &secretsManagerMock{},
+		SecretProvider: SecretProviderFunc(func(ctx context.Context) (string, error) {
+			if name, ok := ctx.Value(secretKey{}).(string); ok {
+				return name, nil
+			}
+			return "app", nil
+		}),
+	}, client)
+
+	ctx := context.Background()
+	if _, err := conn.ExecContext(ctx, "SELECT 1", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.ExecContext(context.WithValue(ctx, secretKey{}, "admin"), "SELECT 1", nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0] != testSecretArn || got[1] != testSecretArn2 {
+		t.Errorf("unexpected secrets: %v", got)
+	}
+}
+
+func TestConn_withSecret_AuthError(t *testing.T) {
+	current := testSecretArn
+	var attempts int
+	client := &awsClientMock{
+		ExecuteStatementFunc: func(ctx context.Context, input *rdsdata.ExecuteStatementInput, optFns ...func(*rdsdata.Options)) (*rdsdata.ExecuteStatementOutput, error) {
+			attempts++
+			if aws.ToString(input.SecretArn) != testSecretArn2 {
+				// the password has been changed to the AWSPENDING version.
+				current = testSecretArn2
+				return nil, errAccessDenied
+			}
+			return &rdsdata.ExecuteStatementOutput{}, nil
+		},
+	}
+	conn := newTestConn(&Config{
+		SecretProvider: SecretProviderFunc(func(ctx context.Context) (string, error) {
+			return current, nil
+		}),
+	}, client)
+
+	if _, err := conn.ExecContext(context.Background(), "SELECT 1", nil); err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 2 {
+		t.Errorf("unexpected attempts: %d", attempts)
+	}
+}
+
+var errAccessDenied = &types.BadRequestException{
+	Message: aws.String("Database error code: 1045. Message: Access denied for user 'app'@'10.0.0.1' (using password: YES)"),
+}
+
+func TestConn_withSecret_Rotation(t *testing.T) {
+	pending := &secretsmanager.DescribeSecretOutput{
+		ARN: aws.String(testSecretArn),
+		VersionIdsToStages: map[string][]string{
+			"v1": {"AWSCURRENT"},
+			"v2": {"AWSPENDING"},
+		},
+	}
+	rotated := &secretsmanager.DescribeSecretOutput{
+		ARN: aws.String(testSecretArn),
+		VersionIdsToStages: map[string][]string{
+			"v1": {"AWSPREVIOUS"},
+			"v2": {"AWSCURRENT", "AWSPENDING"},
+		},
+		LastRotatedDate: aws.Time(time.Now().Add(time.Hour)),
+	}
+	notRotated := &secretsmanager.DescribeSecretOutput{
+		ARN: aws.String(testSecretArn),
+		VersionIdsToStages: map[string][]string{
+			"v1": {"AWSCURRENT"},
+		},
+		LastRotatedDate: aws.Time(time.Now().Add(-time.Hour)),
+	}
+
+	tests := []struct {
+		name     string
+		versions []*secretsmanager.DescribeSecretOutput
+		attempts int
+		err      bool
+	}{
+		{
+			name:     "waits for the pending version",
+			versions: []*secretsmanager.DescribeSecretOutput{pending, pending, rotated},
+			attempts: 2,
+		},
+		{
+			name:     "rotated after the call",
+			versions: []*secretsmanager.DescribeSecretOutput{rotated},
+			attempts: 2,
+		},
+		{
+			name:     "not rotated",
+			versions: []*secretsmanager.DescribeSecretOutput{notRotated},
+			attempts: 1,
+			err:      true,
+		},
+		{
+			// the retry policy gives up.
+			name:     "pending forever",
+			versions: []*secretsmanager.DescribeSecretOutput{pending},
+			attempts: 1,
+			err:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sm := &secretsManagerMock{versions: tt.versions}
+			var attempts int
+			client := &awsClientMock{
+				ExecuteStatementFunc: func(ctx context.Context, input *rdsdata.ExecuteStatementInput, optFns ...func(*rdsdata.Options)) (*rdsdata.ExecuteStatementOutput, error) {
+					attempts++
+					if attempts == 1 {
+						return nil, errAccessDenied
+					}
+					return &rdsdata.ExecuteStatementOutput{}, nil
+				},
+			}
+			conn := newTestConn(&Config{SecretArn: testSecretArn, SecretsManagerClient: sm}, client)
+
+			_, err := conn.ExecContext(context.Background(), "SELECT 1", nil)
+			if (err != nil) != tt.err {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if attempts != tt.attempts {
+				t.Errorf("unexpected attempts: %d", attempts)
+			}
+		})
+	}
+}
+
+func TestConn_withSecret_RotationCanceled(t *testing.T) {
+	sm := &secretsManagerMock{
+		versions: []*secretsmanager.DescribeSecretOutput{
+			{
+				ARN: aws.String(testSecretArn),
+				VersionIdsToStages: map[string][]string{
+					"v1": {"AWSCURRENT"},
+					"v2": {"AWSPENDING"},
+				},
+			},
+		},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var attempts int
+	client := &awsClientMock{
+		ExecuteStatementFunc: func(ctx context.Context, input *rdsdata.ExecuteStatementInput, optFns ...func(*rdsdata.Options)) (*rdsdata.ExecuteStatementOutput, error) {
+			attempts++
+			cancel()
+			return nil, errAccessDenied
+		},
+	}
+	conn := newTestConn(&Config{SecretArn: testSecretArn, SecretsManagerClient: sm}, client)
+	conn.connector.policy = &retry.Policy{
+		MinDelay: time.Hour,
+		MaxDelay: time.Hour,
+	}
+
+	// the caller's context bounds the wait for the rotation.
+	if _, err := conn.ExecContext(ctx, "SELECT 1", nil); !errors.Is(err, errAccessDenied) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if attempts != 1 {
+		t.Errorf("unexpected attempts: %d", attempts)
+	}
+}
+
+func TestConn_withSecret_OtherError(t *testing.T) {
+	var attempts int
+	client := &awsClientMock{
+		ExecuteStatementFunc: func(ctx context.Context, input *rdsdata.ExecuteStatementInput, optFns ...func(*rdsdata.Options)) (*rdsdata.ExecuteStatementOutput, error) {
+			attempts++
+			return nil, &types.BadRequestException{Message: aws.String("Database error code: 1064. Message: You have an error in your SQL syntax")}
+		},
+	}
+	conn := newTestConn(&Config{SecretArn: testSecretArn}, client)
+
+	if _, err := conn.ExecContext(context.Background(), "SELECT", nil); err == nil {
+		t.Fatal("expected error, but got nil")
+	}
+	if attempts != 1 {
+		t.Errorf("unexpected attempts: %d", attempts)
+	}
+}
+
+func TestConn_BeginTx_Secret(t *testing.T) {
+	secret := testSecretArn
+	var got []string
+	client := &awsClientMock{
+		BeginTransactionFunc: func(ctx context.Context, input *rdsdata.BeginTransactionInput, optFns ...func(*rdsdata.Options)) (*rdsdata.BeginTransactionOutput, error) {
+			got = append(got, aws.ToString(input.SecretArn))
+			return &rdsdata.BeginTransactionOutput{TransactionId: aws.String("tx")}, nil
+		},
+		ExecuteStatementFunc: func(ctx context.Context, input *rdsdata.ExecuteStatementInput, optFns ...func(*rdsdata.Options)) (*rdsdata.ExecuteStatementOutput, error) {
+			got = append(got, aws.ToString(input.SecretArn))
+			return &rdsdata.ExecuteStatementOutput{}, nil
+		},
+		CommitTransactionFunc: func(ctx context.Context, input *rdsdata.CommitTransactionInput, optFns ...func(*rdsdata.Options)) (*rdsdata.CommitTransactionOutput, error) {
+			got = append(got, aws.ToString(input.SecretArn))
+			return &rdsdata.CommitTransactionOutput{}, nil
+		},
+	}
+	conn := newTestConn(&Config{
+		SecretProvider: SecretProviderFunc(func(ctx context.Context) (string, error) {
+			return secret, nil
+		}),
+	}, client)
+
+	ctx := context.Background()
+	tx, err := conn.BeginTx(ctx, driver.TxOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the transaction keeps the secret that began it.
+	secret = testSecretArn2
+	if _, err := conn.ExecContext(ctx, "SELECT 1", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	for _, arn := range got {
+		if arn != testSecretArn {
+			t.Errorf("unexpected secrets: %v", got)
+			break
+		}
+	}
+}
@@ -2,9 +2,14 @@ package rdsdata
 
 import (
 	"context"
+	"database/sql"
 	"database/sql/driver"
+	"fmt"
+	"slices"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/rdsdata"
+	"github.com/shogo82148/go-rdsdata/internal/sqllex"
 )
 
 // compile time type check
@@ -34,16 +39,101 @@ func (s *Stmt) Exec(args []driver.Value) (driver.Result, error) {
 }
 
 // ExecContext executes a query that doesn't return rows, such as an INSERT or UPDATE.
+// The INSERT statements get a RETURNING clause of the column of the "returning" named argument,
+// or Config.Returning on PostgreSQL, unless they already have one.
+// The empty column, e.g. NoReturning, disables the clause.
+// The returned rows are available as the generated fields of the result.
 func (s *Stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	args, column, err := s.returningColumn(args, true)
+	if err != nil {
+		return nil, err
+	}
+
 	output := make([]*rdsdata.ExecuteStatementOutput, 0, len(s.queries))
+	var returning bool
 	for _, query := range s.queries {
-		out, err := s.executeStatement(ctx, query, args)
+		query, ok := withReturning(query, column)
+		returning = returning || ok
+		out, err := s.executeStatement(ctx, callExec, query, args)
 		if err != nil {
 			return nil, err
 		}
 		output = append(output, out)
 	}
-	return newResult(output), nil
+	return newResult(s.conn.dialect, output, returning), nil
+}
+
+// returningNamedArg is the name of the argument that sets the column of the RETURNING clause.
+const returningNamedArg = "returning"
+
+// NoReturning is the argument that disables Config.Returning for the statement,
+// e.g. an INSERT into a table without the column:
+//
+//	db.ExecContext(ctx, "INSERT INTO logs (msg) VALUES ($1)", rdsdata.NoReturning, msg)
+//
+// It is the same as sql.Named("returning", "").
+var NoReturning = sql.Named(returningNamedArg, "")
+
+// returningColumn removes the "returning" named argument from args,
+// and returns the column of the RETURNING clause.
+// If useConfig is true, Config.Returning is used on PostgreSQL without the argument.
+// The argument is an error on MySQL, which has no RETURNING clause.
+func (s *Stmt) returningColumn(args []driver.NamedValue, useConfig bool) ([]driver.NamedValue, string, error) {
+	var column string
+	if useConfig && s.conn.engine == EnginePostgres {
+		column = s.conn.connector.cfg.Returning
+	}
+
+	idx := slices.IndexFunc(args, func(arg driver.NamedValue) bool {
+		return arg.Name == returningNamedArg
+	})
+	if idx < 0 {
+		return args, column, nil
+	}
+	if s.conn.engine != EnginePostgres {
+		return nil, "", fmt.Errorf("rdsdata: the %q argument is supported only on PostgreSQL", returningNamedArg)
+	}
+	v, ok := args[idx].Value.(string)
+	if !ok {
+		return nil, "", fmt.Errorf("rdsdata: the %q argument must be a string, got %T", returningNamedArg, args[idx].Value)
+	}
+
+	// renumber the rest of the arguments, because their placeholders don't count the named argument.
+	rest := make([]driver.NamedValue, 0, len(args)-1)
+	for i, arg := range args {
+		if i == idx {
+			continue
+		}
+		if arg.Name == "" {
+			arg.Ordinal = len(rest) + 1
+		}
+		rest = append(rest, arg)
+	}
+	return rest, v, nil
+}
+
+// withReturning appends the RETURNING clause of the column to the INSERT statement of PostgreSQL.
+// It reports whether the statement returns the rows of the RETURNING clause,
+// which is also true if the statement already has the clause.
+func withReturning(query, column string) (string, bool) {
+	if column == "" {
+		return query, false
+	}
+	tokens := sqllex.Code(sqllex.PostgreSQL, query)
+	if len(tokens) == 0 || !tokens[0].IsWord("INSERT") {
+		return query, false
+	}
+	if slices.ContainsFunc(tokens, func(token sqllex.Token) bool { return token.IsWord("RETURNING") }) {
+		return query, true
+	}
+
+	// insert the clause before the trailing semicolons and comments.
+	last := len(tokens) - 1
+	for last > 0 && tokens[last].Text == ";" {
+		last--
+	}
+	end := tokens[last].End()
+	return query[:end] + " RETURNING " + column + query[end:], true
 }
 
 // Query executes a query that may return rows, such as a SELECT.
@@ -53,10 +143,18 @@ func (s *Stmt) Query(args []driver.Value) (driver.Rows, error) {
 }
 
 // QueryContext executes a query that may return rows, such as a SELECT.
+// The INSERT statements get a RETURNING clause of the columns of the "returning" named argument,
+// e.g. sql.Named("returning", "*") returns the inserted rows on PostgreSQL.
 func (s *Stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	args, column, err := s.returningColumn(args, false)
+	if err != nil {
+		return nil, err
+	}
+
 	output := make([]*rdsdata.ExecuteStatementOutput, 0, len(s.queries))
 	for _, query := range s.queries {
-		out, err := s.executeStatement(ctx, query, args)
+		query, _ := withReturning(query, column)
+		out, err := s.executeStatement(ctx, callQuery, query, args)
 		if err != nil {
 			return nil, err
 		}
@@ -77,18 +175,51 @@ func convertOrdinal(values []driver.Value) []driver.NamedValue {
 	return namedValues
 }
 
-func (s *Stmt) executeStatement(ctx context.Context, query string, args []driver.NamedValue) (*rdsdata.ExecuteStatementOutput, error) {
+func (s *Stmt) executeStatement(ctx context.Context, kind callKind, query string, args []driver.NamedValue) (*rdsdata.ExecuteStatementOutput, error) {
 	input, err := s.conn.dialect.MigrateQuery(query, args)
 	if err != nil {
 		return nil, err
 	}
+	if commenter := s.conn.connector.cfg.Commenter; commenter != nil {
+		// the comment is appended after the placeholders are converted,
+		// so that the dialect doesn't parse it.
+		input.Sql = aws.String(commenter.comment(ctx, aws.ToString(input.Sql)))
+	}
 
 	input.ResourceArn = &s.conn.connector.cfg.ResourceArn
-	input.SecretArn = &s.conn.connector.cfg.SecretArn
 	input.Database = &s.conn.connector.cfg.Database
 	input.IncludeResultMetadata = true
-	if s.conn.tx != nil {
-		input.TransactionId = s.conn.tx.id
+	input.ContinueAfterTimeout = s.conn.connector.cfg.ContinueAfterTimeout
+	opts := optionsFromContext(ctx)
+	if tx := s.conn.tx; tx != nil {
+		if opts.database != nil && *opts.database != tx.database {
+			return nil, fmt.Errorf("rdsdata: the database %q differs from the database %q of the transaction", *opts.database, tx.database)
+		}
+		input.TransactionId = tx.id
+		input.Database = &tx.database
+	}
+	opts.apply(input)
+
+	schema := s.conn.connector.cfg.Schema
+	if opts.schema != nil {
+		schema = *opts.schema
+	}
+	if schema != "" {
+		return s.executeInSchema(ctx, kind, input, schema, &opts)
 	}
-	return s.conn.client.ExecuteStatement(ctx, input)
+	return s.execute(ctx, kind, input, &opts)
+}
+
+// execute calls the ExecuteStatement API with the timeout and the secret.
+func (s *Stmt) execute(ctx context.Context, kind callKind, input *rdsdata.ExecuteStatementInput, opts *callOptions) (*rdsdata.ExecuteStatementOutput, error) {
+	var out *rdsdata.ExecuteStatementOutput
+	err := withStatementTimeout(ctx, s.conn.connector.cfg, input.ContinueAfterTimeout, func(ctx context.Context) error {
+		return s.conn.withSecret(ctx, func(ctx context.Context, secretArn string) error {
+			input.SecretArn = aws.String(secretArn)
+			var err error
+			out, err = s.conn.executeStatement(ctx, kind, input, opts.clientOptions...)
+			return err
+		})
+	})
+	return out, err
 }
@@ -0,0 +1,90 @@
+package rdsdata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata/types"
+)
+
+// TimeoutError is returned when a statement times out,
+// either by the timeout of the driver or by the synchronous timeout of the Data API.
+type TimeoutError struct {
+	// Timeout is the timeout of the driver that expired.
+	// It is zero if the Data API timed out.
+	Timeout time.Duration
+
+	// MayBeRunning reports whether the statement may still be running on the server.
+	// It is true if the driver gave up waiting for the response,
+	// or the Data API timed out with ContinueAfterTimeout.
+	MayBeRunning bool
+
+	// Err is the underlying error.
+	Err error
+}
+
+func (e *TimeoutError) Error() string {
+	state := "the statement was canceled"
+	if e.MayBeRunning {
+		state = "the statement may still be running"
+	}
+	if e.Timeout > 0 {
+		return fmt.Sprintf("rdsdata: statement timed out after %s, %s: %v", e.Timeout, state, e.Err)
+	}
+	return fmt.Sprintf("rdsdata: statement timed out, %s: %v", state, e.Err)
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// WithStatementTimeout returns a new context that overrides Config.Timeout for the statements executed with it.
+// Zero disables the timeout.
+// It is a shorthand for WithOptions(ctx, Timeout(timeout)).
+func WithStatementTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	return WithOptions(ctx, Timeout(timeout))
+}
+
+// statementTimeout returns the timeout of the statements executed with ctx.
+func statementTimeout(ctx context.Context, cfg *Config) time.Duration {
+	if o := optionsFromContext(ctx); o.timeout != nil {
+		return *o.timeout
+	}
+	return cfg.Timeout
+}
+
+// withStatementTimeout calls f with the timeout of the statement,
+// and converts the timeout errors into TimeoutError.
+func withStatementTimeout(ctx context.Context, cfg *Config, continueAfterTimeout bool, f func(ctx context.Context) error) error {
+	timeout := statementTimeout(ctx, cfg)
+	callCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		callCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	err := f(callCtx)
+	if err == nil {
+		return nil
+	}
+
+	var timeoutErr *types.StatementTimeoutException
+	if errors.As(err, &timeoutErr) {
+		return &TimeoutError{
+			MayBeRunning: continueAfterTimeout,
+			Err:          err,
+		}
+	}
+	if timeout > 0 && ctx.Err() == nil && errors.Is(callCtx.Err(), context.DeadlineExceeded) {
+		// the request may have reached the server, which doesn't know that the driver gave up.
+		return &TimeoutError{
+			Timeout:      timeout,
+			MayBeRunning: true,
+			Err:          err,
+		}
+	}
+	return err
+}
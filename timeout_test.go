@@ -0,0 +1,104 @@
+package rdsdata
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata"
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata/types"
+)
+
+func openTimeoutDB(t *testing.T, cfg *Config, f func(ctx context.Context, input *rdsdata.ExecuteStatementInput) error) *sql.DB {
+	t.Helper()
+	cfg.ResourceArn = "arn:aws:rds:us-east-1:123456789012:cluster:cluster"
+	cfg.SecretArn = "arn:aws:secretsmanager:us-east-1:123456789012:secret:secretArn"
+	cfg.Engine = EngineMySQL
+	cfg.Client = &awsClientMock{
+		ExecuteStatementFunc: func(ctx context.Context, input *rdsdata.ExecuteStatementInput, optFns ...func(*rdsdata.Options)) (*rdsdata.ExecuteStatementOutput, error) {
+			if err := f(ctx, input); err != nil {
+				return nil, err
+			}
+			return &rdsdata.ExecuteStatementOutput{}, nil
+		},
+	}
+	db := sql.OpenDB(NewConnector(cfg))
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// waitForCancel blocks until the call is canceled.
+func waitForCancel(ctx context.Context, input *rdsdata.ExecuteStatementInput) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestTimeout(t *testing.T) {
+	t.Run("the driver times out", func(t *testing.T) {
+		db := openTimeoutDB(t, &Config{Timeout: 10 * time.Millisecond}, waitForCancel)
+		_, err := db.ExecContext(context.Background(), "ALTER TABLE users ADD COLUMN age INT")
+
+		var timeoutErr *TimeoutError
+		if !errors.As(err, &timeoutErr) {
+			t.Fatalf("want TimeoutError, got %v", err)
+		}
+		if timeoutErr.Timeout != 10*time.Millisecond {
+			t.Errorf("unexpected Timeout: %s", timeoutErr.Timeout)
+		}
+		if !timeoutErr.MayBeRunning {
+			t.Error("want MayBeRunning")
+		}
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("want context.DeadlineExceeded, got %v", err)
+		}
+	})
+
+	t.Run("overrides the timeout", func(t *testing.T) {
+		db := openTimeoutDB(t, &Config{Timeout: time.Hour}, waitForCancel)
+		ctx := WithStatementTimeout(context.Background(), 10*time.Millisecond)
+		_, err := db.ExecContext(ctx, "ALTER TABLE users ADD COLUMN age INT")
+
+		var timeoutErr *TimeoutError
+		if !errors.As(err, &timeoutErr) {
+			t.Fatalf("want TimeoutError, got %v", err)
+		}
+		if timeoutErr.Timeout != 10*time.Millisecond {
+			t.Errorf("unexpected Timeout: %s", timeoutErr.Timeout)
+		}
+	})
+
+	t.Run("the caller cancels", func(t *testing.T) {
+		db := openTimeoutDB(t, &Config{Timeout: time.Hour}, waitForCancel)
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_, err := db.ExecContext(ctx, "ALTER TABLE users ADD COLUMN age INT")
+
+		var timeoutErr *TimeoutError
+		if errors.As(err, &timeoutErr) {
+			t.Errorf("unexpected TimeoutError: %v", err)
+		}
+	})
+
+	t.Run("the Data API times out", func(t *testing.T) {
+		for _, continueAfterTimeout := range []bool{false, true} {
+			db := openTimeoutDB(t, &Config{ContinueAfterTimeout: continueAfterTimeout}, func(ctx context.Context, input *rdsdata.ExecuteStatementInput) error {
+				if input.ContinueAfterTimeout != continueAfterTimeout {
+					t.Errorf("unexpected ContinueAfterTimeout: %v", input.ContinueAfterTimeout)
+				}
+				return &types.StatementTimeoutException{Message: aws.String("statement timeout")}
+			})
+			_, err := db.ExecContext(context.Background(), "ALTER TABLE users ADD COLUMN age INT")
+
+			var timeoutErr *TimeoutError
+			if !errors.As(err, &timeoutErr) {
+				t.Fatalf("want TimeoutError, got %v", err)
+			}
+			if timeoutErr.MayBeRunning != continueAfterTimeout {
+				t.Errorf("unexpected MayBeRunning: %v", timeoutErr.MayBeRunning)
+			}
+		}
+	})
+}
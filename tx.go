@@ -16,6 +16,17 @@ type Tx struct {
 	id   *string
 	conn *Conn
 	done bool
+
+	// secretArn is the secret that began the transaction.
+	// The following calls in the transaction must use the same secret.
+	secretArn string
+
+	// database is the database of the transaction.
+	// The statements in the transaction can't switch to another database.
+	database string
+
+	// searchPath is the schema set to search_path in the transaction.
+	searchPath string
 }
 
 func (tx *Tx) Commit() error {
@@ -23,9 +34,9 @@ func (tx *Tx) Commit() error {
 		return sql.ErrTxDone
 	}
 
-	_, err := tx.conn.client.CommitTransaction(tx.ctx, &rdsdata.CommitTransactionInput{
+	_, err := tx.conn.commitTransaction(tx.ctx, &rdsdata.CommitTransactionInput{
 		ResourceArn:   &tx.conn.connector.cfg.ResourceArn,
-		SecretArn:     &tx.conn.connector.cfg.SecretArn,
+		SecretArn:     &tx.secretArn,
 		TransactionId: tx.id,
 	})
 	if err != nil {
@@ -43,9 +54,9 @@ func (tx *Tx) Rollback() error {
 	}
 
 	ctx := context.WithoutCancel(tx.ctx)
-	_, err := tx.conn.client.RollbackTransaction(ctx, &rdsdata.RollbackTransactionInput{
+	_, err := tx.conn.rollbackTransaction(ctx, &rdsdata.RollbackTransactionInput{
 		ResourceArn:   &tx.conn.connector.cfg.ResourceArn,
-		SecretArn:     &tx.conn.connector.cfg.SecretArn,
+		SecretArn:     &tx.secretArn,
 		TransactionId: tx.id,
 	})
 	if err != nil {
@@ -15,7 +15,7 @@ func TestTx_Commit(t *testing.T) {
 			if aws.ToString(input.ResourceArn) != "resourceArn" {
 				t.Errorf("unexpected ResourceArn: %s", aws.ToString(input.ResourceArn))
 			}
-			if aws.ToString(input.SecretArn) != "secretArn" {
+			if aws.ToString(input.SecretArn) != "arn:aws:secretsmanager:us-east-1:123456789012:secret:secretArn" {
 				t.Errorf("unexpected SecretArn: %s", aws.ToString(input.SecretArn))
 			}
 			if aws.ToString(input.Database) != "database" {
@@ -29,7 +29,7 @@ func TestTx_Commit(t *testing.T) {
 			if aws.ToString(input.ResourceArn) != "resourceArn" {
 				t.Errorf("unexpected ResourceArn: %s", aws.ToString(input.ResourceArn))
 			}
-			if aws.ToString(input.SecretArn) != "secretArn" {
+			if aws.ToString(input.SecretArn) != "arn:aws:secretsmanager:us-east-1:123456789012:secret:secretArn" {
 				t.Errorf("unexpected SecretArn: %s", aws.ToString(input.SecretArn))
 			}
 			if aws.ToString(input.TransactionId) != "transactionId" {
@@ -43,7 +43,7 @@ func TestTx_Commit(t *testing.T) {
 		connector: &Connector{
 			cfg: &Config{
 				ResourceArn: "resourceArn",
-				SecretArn:   "secretArn",
+				SecretArn:   "arn:aws:secretsmanager:us-east-1:123456789012:secret:secretArn",
 				Database:    "database",
 			},
 		},
@@ -65,7 +65,7 @@ func TestTx_Rollback(t *testing.T) {
 			if aws.ToString(input.ResourceArn) != "resourceArn" {
 				t.Errorf("unexpected ResourceArn: %s", aws.ToString(input.ResourceArn))
 			}
-			if aws.ToString(input.SecretArn) != "secretArn" {
+			if aws.ToString(input.SecretArn) != "arn:aws:secretsmanager:us-east-1:123456789012:secret:secretArn" {
 				t.Errorf("unexpected SecretArn: %s", aws.ToString(input.SecretArn))
 			}
 			if aws.ToString(input.Database) != "database" {
@@ -79,7 +79,7 @@ func TestTx_Rollback(t *testing.T) {
 			if aws.ToString(input.ResourceArn) != "resourceArn" {
 				t.Errorf("unexpected ResourceArn: %s", aws.ToString(input.ResourceArn))
 			}
-			if aws.ToString(input.SecretArn) != "secretArn" {
+			if aws.ToString(input.SecretArn) != "arn:aws:secretsmanager:us-east-1:123456789012:secret:secretArn" {
 				t.Errorf("unexpected SecretArn: %s", aws.ToString(input.SecretArn))
 			}
 			if aws.ToString(input.TransactionId) != "transactionId" {
@@ -93,7 +93,7 @@ func TestTx_Rollback(t *testing.T) {
 		connector: &Connector{
 			cfg: &Config{
 				ResourceArn: "resourceArn",
-				SecretArn:   "secretArn",
+				SecretArn:   "arn:aws:secretsmanager:us-east-1:123456789012:secret:secretArn",
 				Database:    "database",
 			},
 		},